@@ -0,0 +1,164 @@
+package app
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/steipete/blucli/internal/config"
+	"github.com/steipete/blucli/internal/discovery"
+	"github.com/steipete/blucli/internal/output"
+)
+
+func TestRunAliasAddListRm(t *testing.T) {
+	t.Parallel()
+
+	cfgPath := filepath.Join(t.TempDir(), "config.json")
+	run := func(args ...string) (int, string, string) {
+		var out, errOut bytes.Buffer
+		code := Run(context.Background(), append([]string{"--config", cfgPath, "alias"}, args...), &out, &errOut)
+		return code, out.String(), errOut.String()
+	}
+
+	if code, stdout, _ := run("list"); code != 0 || stdout != "no aliases\n" {
+		t.Fatalf("list empty: code=%d stdout=%q", code, stdout)
+	}
+	if code, _, stderr := run("add", "kitchen", "10.0.0.5:11000"); code != 0 {
+		t.Fatalf("add: code=%d stderr=%q", code, stderr)
+	}
+	// Alias targets are stored resolved.
+	if code, _, stderr := run("add", "k", "kitchen"); code != 0 {
+		t.Fatalf("add alias of alias: code=%d stderr=%q", code, stderr)
+	}
+	if code, _, stderr := run("add", "kitchen", "10.0.0.9"); code != 1 || !strings.Contains(stderr, "--force") {
+		t.Fatalf("add conflict: code=%d stderr=%q", code, stderr)
+	}
+	if code, _, stderr := run("add", "--force", "kitchen", "10.0.0.9"); code != 0 {
+		t.Fatalf("add --force: code=%d stderr=%q", code, stderr)
+	}
+
+	code, stdout, _ := run("list")
+	want := "ALIAS    DEVICE\nk        10.0.0.5:11000\nkitchen  10.0.0.9\n"
+	if code != 0 || stdout != want {
+		t.Fatalf("list: code=%d stdout=%q", code, stdout)
+	}
+
+	if code, _, _ := run("rm", "k", "kitchen"); code != 0 {
+		t.Fatalf("rm: code=%d", code)
+	}
+	if code, _, stderr := run("rm", "nope"); code != 1 || !strings.Contains(stderr, `unknown alias "nope"`) {
+		t.Fatalf("rm unknown: code=%d stderr=%q", code, stderr)
+	}
+	if cfg, err := config.Load(config.LoadOptions{Path: cfgPath}); err != nil || len(cfg.Aliases) != 0 {
+		t.Fatalf("aliases = %v, %v", cfg.Aliases, err)
+	}
+	if code, _, _ := run("add", "x"); code != 2 {
+		t.Fatalf("add usage: code=%d", code)
+	}
+}
+
+func TestAliasLearn(t *testing.T) {
+	t.Parallel()
+
+	player := func(syncName, statusName string) (string, int) {
+		srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Content-Type", "application/xml")
+			switch r.URL.Path {
+			case "/SyncStatus":
+				_, _ = w.Write([]byte(`<SyncStatus name="` + syncName + `"/>`))
+			case "/Status":
+				_, _ = w.Write([]byte(`<status><name>` + statusName + `</name></status>`))
+			}
+		}))
+		t.Cleanup(srv.Close)
+		u, _ := url.Parse(srv.URL)
+		host, portStr, _ := net.SplitHostPort(u.Host)
+		port, _ := strconv.Atoi(portStr)
+		return host, port
+	}
+	h1, p1 := player("Schlafzimmer", "")
+	h2, p2 := player("", "Living Room")
+	h3, p3 := player("Office", "")
+	h4, p4 := player("Den", "")
+
+	// A closed port stands in for a player that went away.
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+	gone := ln.Addr().String()
+	_ = ln.Close()
+
+	addr := func(h string, p int) string { return net.JoinHostPort(h, strconv.Itoa(p)) }
+	cfgPath := filepath.Join(t.TempDir(), "config.json")
+	cfg := config.Config{Aliases: map[string]string{
+		"office": "10.9.9.9:11000",
+		"bed":    addr(h4, p4),
+		"old":    gone,
+	}}
+	if err := config.SaveConfig(cfgPath, cfg); err != nil {
+		t.Fatalf("save: %v", err)
+	}
+
+	ctx := discovery.WithMDNSOverride(context.Background(), func(context.Context) ([]discovery.Device, error) {
+		return []discovery.Device{
+			{ID: "a", Host: h1, Port: p1},
+			{ID: "b", Host: h2, Port: p2, Name: "ignored"},
+			{ID: "c", Host: h3, Port: p3},
+			{ID: "d", Host: h4, Port: p4},
+		}, nil
+	})
+	ctx = discovery.WithLSDPOverride(ctx, func(context.Context) ([]discovery.Device, error) { return nil, nil })
+
+	var stdout, stderr bytes.Buffer
+	out := output.New(output.Options{JSON: true, Stdout: &stdout, Stderr: &stderr})
+	if code := cmdAlias(ctx, out, cfgPath, cfg, config.DiscoveryCache{}, 250*time.Millisecond, 300*time.Millisecond, []string{"learn", "--save"}); code != 0 {
+		t.Fatalf("code=%d stderr=%q", code, stderr.String())
+	}
+
+	var got []aliasProposal
+	if err := json.Unmarshal(stdout.Bytes(), &got); err != nil {
+		t.Fatalf("json: %v (%q)", err, stdout.String())
+	}
+	want := []aliasProposal{
+		{Alias: "schlafzimmer", Device: addr(h1, p1), Name: "Schlafzimmer", Status: aliasNew, Saved: true},
+		{Alias: "livingroom", Device: addr(h2, p2), Name: "Living Room", Status: aliasNew, Saved: true},
+		{Alias: "office", Device: addr(h3, p3), Name: "Office", Status: aliasConflict, Note: "alias points to 10.9.9.9:11000"},
+		{Alias: "den", Device: addr(h4, p4), Name: "Den", Status: aliasExists, Note: "as bed"},
+	}
+	if len(got) < len(want) {
+		t.Fatalf("proposals = %+v", got)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Fatalf("proposal %d = %+v; want %+v", i, got[i], want[i])
+		}
+	}
+	// office (10.9.9.9) and old are both unreachable.
+	stale := map[string]bool{}
+	for _, p := range got[len(want):] {
+		if p.Status != aliasStale {
+			t.Fatalf("extra proposal %+v", p)
+		}
+		stale[p.Alias] = true
+	}
+	if !stale["old"] {
+		t.Fatalf("stale = %v; want old", stale)
+	}
+
+	data, _ := os.ReadFile(cfgPath)
+	saved, err := config.Parse(data)
+	if err != nil || saved.Aliases["schlafzimmer"] != addr(h1, p1) || saved.Aliases["livingroom"] != addr(h2, p2) || saved.Aliases["office"] != "10.9.9.9:11000" {
+		t.Fatalf("saved aliases = %v, %v", saved.Aliases, err)
+	}
+}
@@ -3,6 +3,7 @@ package app
 import (
 	"bytes"
 	"context"
+	"encoding/json"
 	"net/http"
 	"net/http/httptest"
 	"os"
@@ -209,8 +210,101 @@ func TestRunPlayDryRunBlocksWrites(t *testing.T) {
 	if got := calls.Load(); got != 0 {
 		t.Fatalf("server calls = %d; want 0", got)
 	}
-	if got := errOut.String(); got == "" || !bytes.Contains([]byte(got), []byte("/Play")) {
-		t.Fatalf("stderr = %q; want contains /Play", got)
+	if got := errOut.String(); got == "" || !bytes.Contains([]byte(got), []byte("/Play BLOCKED (dry-run)")) {
+		t.Fatalf("stderr = %q; want /Play marked blocked", got)
+	}
+}
+
+func TestRunTraceHTTPFullJSONAndHAR(t *testing.T) {
+	t.Parallel()
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/xml")
+		_, _ = w.Write([]byte(`<status state="play" volume="15" mute="0" artist="A" title1="T"/>`))
+	}))
+	t.Cleanup(srv.Close)
+
+	cfgPath := writeTestConfig(t, srv.URL)
+	harPath := filepath.Join(t.TempDir(), "trace.har")
+
+	var out, errOut bytes.Buffer
+	code := Run(context.Background(), []string{"--config", cfgPath, "--discover=false", "--trace-http=full", "--trace-format", "json", "--har", harPath, "status"}, &out, &errOut)
+	if code != 0 {
+		t.Fatalf("exit code = %d; stderr=%q", code, errOut.String())
+	}
+	var line struct {
+		Status int    `json:"status"`
+		Body   string `json:"body"`
+	}
+	if err := json.Unmarshal(bytes.TrimSpace(errOut.Bytes()), &line); err != nil || line.Status != 200 || !strings.Contains(line.Body, `volume="15"`) {
+		t.Fatalf("stderr = %q (err %v); want one full json trace line", errOut.String(), err)
+	}
+	data, err := os.ReadFile(harPath)
+	if err != nil || !bytes.Contains(data, []byte(`"entries"`)) || !bytes.Contains(data, []byte("/Status")) {
+		t.Fatalf("har = %s (err %v)", data, err)
+	}
+
+	errOut.Reset()
+	if code := Run(context.Background(), []string{"--config", cfgPath, "--trace-format", "xml", "status"}, &out, &errOut); code != 2 || !strings.Contains(errOut.String(), "trace-format") {
+		t.Fatalf("code=%d stderr=%q; want trace-format usage error", code, errOut.String())
+	}
+}
+
+func TestRunLogLevelDebugJSON(t *testing.T) {
+	t.Parallel()
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/xml")
+		_, _ = w.Write([]byte(`<status state="play" volume="15" mute="0" artist="A" title1="T"/>`))
+	}))
+	t.Cleanup(srv.Close)
+
+	cfgPath := writeTestConfig(t, srv.URL)
+
+	var out, errOut bytes.Buffer
+	code := Run(context.Background(), []string{"--config", cfgPath, "--discover=false", "--log-level", "debug", "--log-format", "json", "status"}, &out, &errOut)
+	if code != 0 {
+		t.Fatalf("exit code = %d; stderr=%q", code, errOut.String())
+	}
+	msgs := map[string]map[string]any{}
+	for line := range strings.SplitSeq(strings.TrimSpace(errOut.String()), "\n") {
+		var rec map[string]any
+		if err := json.Unmarshal([]byte(line), &rec); err != nil {
+			t.Fatalf("log line %q: %v", line, err)
+		}
+		msgs[rec["msg"].(string)] = rec
+	}
+	if rec := msgs["device resolved"]; rec == nil || rec["step"] != "address" || rec["level"] != "DEBUG" {
+		t.Fatalf("device resolved = %v; logs:\n%s", rec, errOut.String())
+	}
+	if rec := msgs["http request"]; rec == nil || rec["status"] != float64(200) {
+		t.Fatalf("http request = %v; logs:\n%s", rec, errOut.String())
+	}
+
+	errOut.Reset()
+	if code := Run(context.Background(), []string{"--config", cfgPath, "--log-level", "trace", "status"}, &out, &errOut); code != 2 || !strings.Contains(errOut.String(), "log: unknown level") {
+		t.Fatalf("code=%d stderr=%q; want log level usage error", code, errOut.String())
+	}
+}
+
+func TestRunFormatTemplateFailureExitsNonZero(t *testing.T) {
+	t.Parallel()
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/xml")
+		_, _ = w.Write([]byte(`<status state="play" volume="15"/>`))
+	}))
+	t.Cleanup(srv.Close)
+
+	cfgPath := writeTestConfig(t, srv.URL)
+
+	var out, errOut bytes.Buffer
+	code := Run(context.Background(), []string{"--config", cfgPath, "--discover=false", "--format", "{{.Nope}}", "status"}, &out, &errOut)
+	if code != 1 {
+		t.Fatalf("exit code = %d; want 1 (stderr=%q)", code, errOut.String())
+	}
+	if out.Len() != 0 || !strings.Contains(errOut.String(), "can't evaluate field Nope") {
+		t.Fatalf("stdout=%q stderr=%q", out.String(), errOut.String())
 	}
 }
 
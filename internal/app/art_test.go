@@ -0,0 +1,84 @@
+package app
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"testing"
+)
+
+func TestRunArt(t *testing.T) {
+	t.Parallel()
+
+	cover := []byte("\x89PNG\r\n\x1a\nnot-really")
+	var noImage atomic.Bool
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/Status":
+			w.Header().Set("Content-Type", "application/xml")
+			img := `/Artwork?service=Qobuz&amp;songid=1`
+			if noImage.Load() {
+				img = ""
+			}
+			_, _ = w.Write([]byte(`<status state="play"><image>` + img + `</image></status>`))
+		case "/Artwork":
+			if r.URL.Query().Get("songid") != "1" {
+				t.Errorf("query = %q", r.URL.RawQuery)
+			}
+			w.Header().Set("Content-Type", "image/png")
+			_, _ = w.Write(cover)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	t.Cleanup(srv.Close)
+
+	cfgPath := writeTestConfig(t, srv.URL)
+	run := func(args ...string) (int, string, string) {
+		var out, errOut bytes.Buffer
+		code := Run(context.Background(), append([]string{"--config", cfgPath, "--discover=false"}, args...), &out, &errOut)
+		return code, out.String(), errOut.String()
+	}
+
+	// Not a terminal: raw bytes pass through.
+	code, stdout, stderr := run("art")
+	if code != 0 || stdout != string(cover) {
+		t.Fatalf("art: code=%d stdout=%q stderr=%q", code, stdout, stderr)
+	}
+
+	path := filepath.Join(t.TempDir(), "cover.png")
+	code, stdout, stderr = run("art", "--save", path)
+	if code != 0 || !strings.HasPrefix(stdout, "saved "+path) {
+		t.Fatalf("save: code=%d stdout=%q stderr=%q", code, stdout, stderr)
+	}
+	if data, err := os.ReadFile(path); err != nil || !bytes.Equal(data, cover) {
+		t.Fatalf("saved = %q, %v", data, err)
+	}
+
+	code, stdout, stderr = run("--json", "art")
+	if code != 0 {
+		t.Fatalf("json: code=%d stderr=%q", code, stderr)
+	}
+	var info artInfo
+	if err := json.Unmarshal([]byte(stdout), &info); err != nil {
+		t.Fatalf("json: %v (%q)", err, stdout)
+	}
+	if info.ContentType != "image/png" || info.Bytes != len(cover) || !strings.HasPrefix(info.URL, srv.URL+"/Artwork?") {
+		t.Fatalf("info = %+v", info)
+	}
+
+	if code, _, stderr = run("art", "--protocol", "braille"); code != 2 || !strings.Contains(stderr, "unknown protocol") {
+		t.Fatalf("protocol: code=%d stderr=%q", code, stderr)
+	}
+
+	noImage.Store(true)
+	if code, _, stderr = run("art"); code != 1 || !strings.Contains(stderr, "no cover art") {
+		t.Fatalf("no image: code=%d stderr=%q", code, stderr)
+	}
+}
@@ -0,0 +1,330 @@
+package app
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"net"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/steipete/blucli/internal/bluos"
+	"github.com/steipete/blucli/internal/config"
+	"github.com/steipete/blucli/internal/discovery"
+	"github.com/steipete/blucli/internal/output"
+)
+
+type aliasRow struct {
+	Alias  string `json:"alias"`
+	Device string `json:"device"`
+	Name   string `json:"name,omitempty"`
+}
+
+type aliasRows []aliasRow
+
+func (rows aliasRows) TableHeader() []string { return []string{"alias", "device", "name"} }
+
+func (rows aliasRows) TableRows() [][]string {
+	out := make([][]string, 0, len(rows))
+	for _, r := range rows {
+		out = append(out, []string{r.Alias, r.Device, r.Name})
+	}
+	return out
+}
+
+func (rows aliasRows) PrintHuman(p *output.Printer) {
+	if len(rows) == 0 {
+		p.Printf("no aliases\n")
+		return
+	}
+	p.Table(rows.TableHeader(), rows.TableRows(), nil)
+}
+
+// Learn outcomes for a proposed or existing alias.
+const (
+	aliasNew      = "new"
+	aliasExists   = "exists"
+	aliasConflict = "conflict"
+	aliasStale    = "stale"
+)
+
+type aliasProposal struct {
+	Alias  string `json:"alias"`
+	Device string `json:"device"`
+	Name   string `json:"name,omitempty"`
+	Status string `json:"status"`
+	Note   string `json:"note,omitempty"`
+	Saved  bool   `json:"saved,omitempty"`
+}
+
+type aliasProposals []aliasProposal
+
+func (rows aliasProposals) TableHeader() []string {
+	return []string{"alias", "device", "name", "status", "note", "saved"}
+}
+
+func (rows aliasProposals) TableRows() [][]string {
+	out := make([][]string, 0, len(rows))
+	for _, r := range rows {
+		out = append(out, []string{r.Alias, r.Device, r.Name, r.Status, r.Note, strconv.FormatBool(r.Saved)})
+	}
+	return out
+}
+
+func (rows aliasProposals) PrintHuman(p *output.Printer) {
+	if len(rows) == 0 {
+		p.Printf("no players discovered\n")
+		return
+	}
+	table := make([][]string, 0, len(rows))
+	pending, saved := 0, 0
+	for _, r := range rows {
+		status := r.Status
+		if r.Note != "" {
+			status += " (" + r.Note + ")"
+		}
+		switch {
+		case r.Saved:
+			saved++
+			status = "saved"
+		case r.Status == aliasNew:
+			pending++
+		}
+		table = append(table, []string{r.Alias, r.Device, r.Name, status})
+	}
+	p.Table([]string{"alias", "device", "name", "status"}, table, func(i int) string {
+		switch rows[i].Status {
+		case aliasConflict, aliasStale:
+			return output.Yellow
+		case aliasNew:
+			return output.Green
+		}
+		return ""
+	})
+	switch {
+	case saved > 0:
+		p.Printf("\nsaved %d aliases\n", saved)
+	case pending > 0:
+		p.Printf("\n%d new; run `blu alias learn --save` to add them\n", pending)
+	}
+}
+
+func cmdAlias(ctx context.Context, out *output.Printer, configPath string, cfg config.Config, cache config.DiscoveryCache, discoverTimeout, httpTimeout time.Duration, args []string) int {
+	if len(args) == 0 {
+		out.Errorf("alias: missing subcommand (add|rm|list|learn)")
+		return 2
+	}
+
+	sub := args[0]
+	flags := flag.NewFlagSet("alias "+sub, flag.ContinueOnError)
+	flags.SetOutput(out.Stderr())
+	force := false
+	save := false
+	switch sub {
+	case "add":
+		flags.BoolVar(&force, "force", false, "replace an existing alias")
+	case "learn":
+		flags.BoolVar(&save, "save", false, "add the new aliases to the config")
+	}
+	if err := flags.Parse(args[1:]); err != nil {
+		return 2
+	}
+	rest := flags.Args()
+
+	switch sub {
+	case "list", "ls":
+		if len(rest) > 0 {
+			out.Errorf("alias list: unexpected args: %q", strings.Join(rest, " "))
+			return 2
+		}
+		out.Print(listAliases(cfg, cache))
+		return 0
+	case "add":
+		if len(rest) != 2 {
+			out.Errorf("alias add: want <name> <device>")
+			return 2
+		}
+		name, target := strings.TrimSpace(rest[0]), strings.TrimSpace(rest[1])
+		if name == "" || target == "" {
+			out.Errorf("alias add: empty name or device")
+			return 2
+		}
+		err := config.Update(configPath, func(c *config.Config) error {
+			if resolved, ok := c.Aliases[target]; ok {
+				target = resolved
+			}
+			if existing, ok := c.Aliases[name]; ok && existing != target && !force {
+				return fmt.Errorf("%q already points to %s (use --force to replace)", name, existing)
+			}
+			c.Aliases[name] = target
+			return nil
+		})
+		if err != nil {
+			out.Errorf("alias add: %v", err)
+			return 1
+		}
+		return 0
+	case "rm", "remove":
+		if len(rest) == 0 {
+			out.Errorf("alias rm: want <name>...")
+			return 2
+		}
+		err := config.Update(configPath, func(c *config.Config) error {
+			for _, name := range rest {
+				if _, ok := c.Aliases[name]; !ok {
+					return fmt.Errorf("unknown alias %q", name)
+				}
+				delete(c.Aliases, name)
+			}
+			return nil
+		})
+		if err != nil {
+			out.Errorf("alias rm: %v", err)
+			return 1
+		}
+		return 0
+	case "learn":
+		if len(rest) > 0 {
+			out.Errorf("alias learn: unexpected args: %q", strings.Join(rest, " "))
+			return 2
+		}
+		discoverCtx, cancel := context.WithTimeout(ctx, discoverTimeout)
+		defer cancel()
+		devices, err := discovery.Discover(discoverCtx)
+		if err != nil && !errors.Is(err, context.DeadlineExceeded) {
+			out.Errorf("alias learn: %v", err)
+			return 1
+		}
+
+		proposals := learnAliases(ctx, cfg, devices, httpTimeout)
+		if save && slices.ContainsFunc(proposals, func(p aliasProposal) bool { return p.Status == aliasNew }) {
+			err := config.Update(configPath, func(c *config.Config) error {
+				for i, p := range proposals {
+					if p.Status != aliasNew {
+						continue
+					}
+					// Another process may have taken the name meanwhile.
+					if existing, ok := c.Aliases[p.Alias]; ok && aliasTargetKey(existing) != aliasTargetKey(p.Device) {
+						proposals[i].Status, proposals[i].Note = aliasConflict, "alias points to "+existing
+						continue
+					}
+					c.Aliases[p.Alias] = p.Device
+					proposals[i].Saved = true
+				}
+				return nil
+			})
+			if err != nil {
+				out.Errorf("alias learn: %v", err)
+				return 1
+			}
+		}
+		out.Print(proposals)
+		return 0
+	default:
+		out.Errorf("alias: unknown subcommand %q (add|rm|list|learn)", sub)
+		return 2
+	}
+}
+
+func listAliases(cfg config.Config, cache config.DiscoveryCache) aliasRows {
+	rows := make(aliasRows, 0, len(cfg.Aliases))
+	for alias, target := range cfg.Aliases {
+		row := aliasRow{Alias: alias, Device: target}
+		if d, ok := cache.Lookup(target); ok {
+			row.Name = d.Name
+		}
+		rows = append(rows, row)
+	}
+	sort.Slice(rows, func(i, j int) bool { return rows[i].Alias < rows[j].Alias })
+	return rows
+}
+
+// learnAliases proposes one alias per discovered player, named after its
+// normalized player name, and flags existing aliases whose host is gone.
+func learnAliases(ctx context.Context, cfg config.Config, devices []discovery.Device, httpTimeout time.Duration) aliasProposals {
+	byTarget := map[string]string{}
+	for alias, target := range cfg.Aliases {
+		byTarget[aliasTargetKey(target)] = alias
+	}
+
+	proposals := make(aliasProposals, 0, len(devices))
+	seen := map[string]bool{}
+	taken := map[string]int{}
+	for _, d := range devices {
+		addr := net.JoinHostPort(d.Host, strconv.Itoa(d.Port))
+		seen[aliasTargetKey(addr)] = true
+
+		name := playerName(ctx, d, httpTimeout)
+		p := aliasProposal{Alias: normalizeDeviceName(name), Device: addr, Name: name, Status: aliasNew}
+		switch existing, ok := cfg.Aliases[p.Alias]; {
+		case p.Alias == "":
+			p.Status, p.Note = aliasConflict, "no usable name"
+		case byTarget[aliasTargetKey(addr)] != "":
+			p.Status = aliasExists
+			if have := byTarget[aliasTargetKey(addr)]; have != p.Alias {
+				p.Note = "as " + have
+			}
+		case ok && aliasTargetKey(existing) != aliasTargetKey(addr):
+			p.Status, p.Note = aliasConflict, "alias points to "+existing
+		}
+		if p.Alias != "" {
+			taken[p.Alias]++
+		}
+		proposals = append(proposals, p)
+	}
+	for i, p := range proposals {
+		if p.Status == aliasNew && taken[p.Alias] > 1 {
+			proposals[i].Status, proposals[i].Note = aliasConflict, "several players share this name"
+		}
+	}
+
+	aliases := make([]string, 0, len(cfg.Aliases))
+	for alias := range cfg.Aliases {
+		aliases = append(aliases, alias)
+	}
+	sort.Strings(aliases)
+	for _, alias := range aliases {
+		target := cfg.Aliases[alias]
+		if seen[aliasTargetKey(target)] || likelyNameArg(target) {
+			continue
+		}
+		dev, err := config.ParseDevice(target)
+		if err != nil {
+			continue
+		}
+		client := bluos.NewClient(dev.BaseURL(), bluos.Options{Timeout: httpTimeout})
+		if _, err := client.SyncStatus(ctx, bluos.SyncStatusOptions{}); err != nil {
+			proposals = append(proposals, aliasProposal{Alias: alias, Device: target, Status: aliasStale, Note: "not answering"})
+		}
+	}
+	return proposals
+}
+
+// playerName prefers the name the player reports over the discovery name.
+func playerName(ctx context.Context, d discovery.Device, httpTimeout time.Duration) string {
+	dev := config.Device{Host: d.Host, Port: d.Port}
+	client := bluos.NewClient(dev.BaseURL(), bluos.Options{Timeout: httpTimeout})
+	if sync, err := client.SyncStatus(ctx, bluos.SyncStatusOptions{}); err == nil && strings.TrimSpace(sync.Name) != "" {
+		return strings.TrimSpace(sync.Name)
+	}
+	if status, err := client.Status(ctx, bluos.StatusOptions{}); err == nil && strings.TrimSpace(status.Name) != "" {
+		return strings.TrimSpace(status.Name)
+	}
+	return strings.TrimSpace(d.Name)
+}
+
+// aliasTargetKey canonicalizes "host", "host:11000" and URLs to host:port.
+func aliasTargetKey(target string) string {
+	if likelyNameArg(target) {
+		return strings.TrimSpace(target)
+	}
+	d, err := config.ParseDevice(target)
+	if err != nil {
+		return strings.TrimSpace(target)
+	}
+	return net.JoinHostPort(d.Host, strconv.Itoa(d.Port))
+}
@@ -0,0 +1,135 @@
+package app
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"image"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/steipete/blucli/internal/bluos"
+	"github.com/steipete/blucli/internal/config"
+	"github.com/steipete/blucli/internal/output"
+	"github.com/steipete/blucli/internal/termimg"
+)
+
+type artInfo struct {
+	URL         string `json:"url"`
+	ContentType string `json:"content_type"`
+	Bytes       int    `json:"bytes"`
+	Width       int    `json:"width,omitempty"`
+	Height      int    `json:"height,omitempty"`
+	Path        string `json:"path,omitempty"`
+}
+
+func (a artInfo) PrintHuman(p *output.Printer) {
+	size := fmt.Sprintf("%d bytes", a.Bytes)
+	if a.Width > 0 {
+		size = fmt.Sprintf("%dx%d, %s", a.Width, a.Height, size)
+	}
+	if a.Path != "" {
+		p.Printf("saved %s (%s)\n", a.Path, size)
+		return
+	}
+	p.Printf("%s (%s, %s)\n", a.URL, a.ContentType, size)
+}
+
+func cmdArt(ctx context.Context, out *output.Printer, cfg config.Config, cache config.DiscoveryCache, deviceArg string, allowDiscover bool, discoverTimeout, httpTimeout time.Duration, dryRun bool, trace bluos.Tracer, args []string) int {
+	flags := flag.NewFlagSet("art", flag.ContinueOnError)
+	flags.SetOutput(out.Stderr())
+	save := flags.String("save", "", "write the image to a file instead of rendering it")
+	protocolArg := flags.String("protocol", "auto", "auto|kitty|iterm|sixel|blocks")
+	width := flags.Int("width", 0, "width in terminal cells")
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+	if flags.NArg() > 1 {
+		out.Errorf("art: unexpected args: %q", strings.Join(flags.Args()[1:], " "))
+		return 2
+	}
+	protocol, err := termimg.ParseProtocol(*protocolArg)
+	if err != nil {
+		out.Errorf("art: %v", err)
+		return 2
+	}
+	if *width < 0 {
+		out.Errorf("art: --width must be >= 0")
+		return 2
+	}
+
+	device, resolveErr := resolveDevice(ctx, cfg, cache, deviceArg, allowDiscover, discoverTimeout)
+	if resolveErr != nil {
+		out.Errorf("device: %v", resolveErr)
+		return 1
+	}
+	out.SetDevice(device)
+
+	client := bluos.NewClient(device.BaseURL(), bluos.Options{Timeout: httpTimeout, DryRun: dryRun, Trace: trace})
+	ref := flags.Arg(0)
+	if ref == "" {
+		status, err := client.Status(ctx, bluos.StatusOptions{})
+		if err != nil {
+			out.Errorf("art: %v", err)
+			return 1
+		}
+		ref = status.Image
+	}
+
+	art, err := client.Artwork(ctx, ref)
+	if errors.Is(err, bluos.ErrNoArtwork) {
+		out.Errorf("art: no cover art for the current track")
+		return 1
+	}
+	if err != nil {
+		out.Errorf("art: %v", err)
+		return 1
+	}
+
+	info := artInfo{URL: art.URL, ContentType: art.ContentType, Bytes: len(art.Data)}
+	if c, _, err := image.DecodeConfig(bytes.NewReader(art.Data)); err == nil {
+		info.Width, info.Height = c.Width, c.Height
+	}
+
+	if *save != "" {
+		if err := os.WriteFile(*save, art.Data, 0o644); err != nil {
+			out.Errorf("art: %v", err)
+			return 1
+		}
+		info.Path = *save
+		out.Print(info)
+		return 0
+	}
+
+	if !out.Human() {
+		out.Print(info)
+		return 0
+	}
+	if !out.Interactive() {
+		// Piped: pass the original bytes through, e.g. `blu art > cover.jpg`.
+		if _, err := out.Stdout().Write(art.Data); err != nil {
+			out.Errorf("art: %v", err)
+			return 1
+		}
+		return 0
+	}
+
+	if protocol == termimg.Auto {
+		protocol = termimg.Detect(os.Getenv)
+	}
+	cols := *width
+	if cols == 0 {
+		cols = termimg.DefaultColumns
+		if w := out.Width(); w > 0 {
+			cols = min(cols, w)
+		}
+	}
+	if err := termimg.Render(out.Stdout(), art.Data, termimg.Options{Protocol: protocol, Columns: cols}); err != nil {
+		out.Errorf("art: %v", err)
+		return 1
+	}
+	return 0
+}
@@ -4,7 +4,6 @@ import (
 	"context"
 	"errors"
 	"flag"
-	"io"
 	"net/url"
 	"strings"
 	"time"
@@ -14,7 +13,7 @@ import (
 	"github.com/steipete/blucli/internal/output"
 )
 
-func cmdBrowse(ctx context.Context, out *output.Printer, cfg config.Config, cache config.DiscoveryCache, deviceArg string, allowDiscover bool, discoverTimeout, httpTimeout time.Duration, dryRun bool, trace io.Writer, args []string) int {
+func cmdBrowse(ctx context.Context, out *output.Printer, cfg config.Config, cache config.DiscoveryCache, deviceArg string, allowDiscover bool, discoverTimeout, httpTimeout time.Duration, dryRun bool, trace bluos.Tracer, args []string) int {
 	flags := flag.NewFlagSet("browse", flag.ContinueOnError)
 	flags.SetOutput(out.Stderr())
 
@@ -39,6 +38,7 @@ func cmdBrowse(ctx context.Context, out *output.Printer, cfg config.Config, cach
 		out.Errorf("device: %v", resolveErr)
 		return 1
 	}
+	out.SetDevice(device)
 	client := bluos.NewClient(device.BaseURL(), bluos.Options{Timeout: httpTimeout, DryRun: dryRun, Trace: trace})
 
 	browse, err := client.Browse(ctx, bluos.BrowseOptions{Key: key, Q: q, WithContextMenuItems: withContext})
@@ -50,7 +50,7 @@ func cmdBrowse(ctx context.Context, out *output.Printer, cfg config.Config, cach
 	return 0
 }
 
-func cmdPlaylists(ctx context.Context, out *output.Printer, cfg config.Config, cache config.DiscoveryCache, deviceArg string, allowDiscover bool, discoverTimeout, httpTimeout time.Duration, dryRun bool, trace io.Writer, args []string) int {
+func cmdPlaylists(ctx context.Context, out *output.Printer, cfg config.Config, cache config.DiscoveryCache, deviceArg string, allowDiscover bool, discoverTimeout, httpTimeout time.Duration, dryRun bool, trace bluos.Tracer, args []string) int {
 	flags := flag.NewFlagSet("playlists", flag.ContinueOnError)
 	flags.SetOutput(out.Stderr())
 
@@ -70,6 +70,7 @@ func cmdPlaylists(ctx context.Context, out *output.Printer, cfg config.Config, c
 		out.Errorf("device: %v", resolveErr)
 		return 1
 	}
+	out.SetDevice(device)
 	client := bluos.NewClient(device.BaseURL(), bluos.Options{Timeout: httpTimeout, DryRun: dryRun, Trace: trace})
 
 	playlists, err := client.Playlists(ctx, bluos.PlaylistsOptions{Service: service, Category: category, Expr: expr})
@@ -81,12 +82,13 @@ func cmdPlaylists(ctx context.Context, out *output.Printer, cfg config.Config, c
 	return 0
 }
 
-func cmdInputs(ctx context.Context, out *output.Printer, cfg config.Config, cache config.DiscoveryCache, deviceArg string, allowDiscover bool, discoverTimeout, httpTimeout time.Duration, dryRun bool, trace io.Writer, args []string) int {
+func cmdInputs(ctx context.Context, out *output.Printer, cfg config.Config, cache config.DiscoveryCache, deviceArg string, allowDiscover bool, discoverTimeout, httpTimeout time.Duration, dryRun bool, trace bluos.Tracer, args []string) int {
 	device, resolveErr := resolveDevice(ctx, cfg, cache, deviceArg, allowDiscover, discoverTimeout)
 	if resolveErr != nil {
 		out.Errorf("device: %v", resolveErr)
 		return 1
 	}
+	out.SetDevice(device)
 	client := bluos.NewClient(device.BaseURL(), bluos.Options{Timeout: httpTimeout, DryRun: dryRun, Trace: trace})
 
 	sub := ""
@@ -34,13 +34,13 @@ _blu_complete() {
 
   # global flags (best-effort)
   if [[ "$cur" == -* ]]; then
-    COMPREPLY=( $(compgen -W "--device --json --timeout --dry-run --trace-http --version -v --discover --discover-timeout --config -h --help" -- "$cur") )
+    COMPREPLY=( $(compgen -W "--device --json --output --envelope --format --timeout --dry-run --quiet --trace-http --trace-format --har --version -v --discover --discover-timeout --interface --config --record --replay --replay-ignore --log-level --log-format -h --help" -- "$cur") )
     return 0
   fi
 
   cmd="${COMP_WORDS[1]}"
   if [[ $COMP_CWORD -eq 1 ]]; then
-    COMPREPLY=( $(compgen -W "version completions devices status now watch play pause stop next prev shuffle repeat volume mute group queue presets browse playlists inputs tunein spotify sleep diag doctor raw help" -- "$cur") )
+    COMPREPLY=( $(compgen -W "version completions schema config alias devices lsdp status now art watch play pause stop next prev shuffle repeat volume mute group queue presets browse playlists inputs tunein spotify sleep history scrobble diag doctor inventory raw help" -- "$cur") )
     return 0
   fi
 
@@ -49,7 +49,62 @@ _blu_complete() {
       COMPREPLY=( $(compgen -W "bash zsh" -- "$cur") )
       ;;
     watch)
-      COMPREPLY=( $(compgen -W "status sync" -- "$cur") )
+      if [[ $COMP_CWORD -eq 2 ]]; then
+        COMPREPLY=( $(compgen -W "status sync" -- "$cur") )
+      elif [[ "$cur" == -* ]]; then
+        COMPREPLY=( $(compgen -W "--record --history-file --scrobble" -- "$cur") )
+      fi
+      ;;
+    lsdp)
+      if [[ $COMP_CWORD -eq 2 ]]; then
+        COMPREPLY=( $(compgen -W "dump" -- "$cur") )
+      elif [[ "$cur" == -* ]]; then
+        COMPREPLY=( $(compgen -W "--duration --passive --requery --hex" -- "$cur") )
+      fi
+      ;;
+    devices)
+      COMPREPLY=( $(compgen -W "--watch --interval --ttl --scan" -- "$cur") )
+      ;;
+    inventory)
+      COMPREPLY=( $(compgen -W "--diff" -- "$cur") )
+      ;;
+    doctor)
+      COMPREPLY=( $(compgen -W "--samples" -- "$cur") )
+      ;;
+    diag)
+      if [[ "${COMP_WORDS[COMP_CWORD-1]}" == "--redact" ]]; then
+        COMPREPLY=( $(compgen -W "tokens hosts all none" -- "$cur") )
+      else
+        COMPREPLY=( $(compgen -W "--bundle --redact" -- "$cur") )
+      fi
+      ;;
+    config)
+      if [[ $COMP_CWORD -eq 2 ]]; then
+        COMPREPLY=( $(compgen -W "get set unset show edit validate path" -- "$cur") )
+      elif [[ $COMP_CWORD -eq 3 && "${COMP_WORDS[2]}" =~ ^(get|set|unset)$ ]]; then
+        COMPREPLY=( $(compgen -W "default_device aliases. scrobble.url scrobble.token spotify.client_id defaults." -- "$cur") )
+      elif [[ "${COMP_WORDS[2]}" == "show" ]]; then
+        COMPREPLY=( $(compgen -W "--effective" -- "$cur") )
+      fi
+      ;;
+    alias)
+      if [[ $COMP_CWORD -eq 2 ]]; then
+        COMPREPLY=( $(compgen -W "list add rm learn" -- "$cur") )
+      elif [[ "$cur" == -* ]]; then
+        COMPREPLY=( $(compgen -W "--force --save" -- "$cur") )
+      fi
+      ;;
+    now)
+      if [[ "$cur" == -* ]]; then
+        COMPREPLY=( $(compgen -W "--follow" -- "$cur") )
+      fi
+      ;;
+    art)
+      if [[ "${COMP_WORDS[COMP_CWORD-1]}" == "--protocol" ]]; then
+        COMPREPLY=( $(compgen -W "auto kitty iterm sixel blocks" -- "$cur") )
+      elif [[ "$cur" == -* ]]; then
+        COMPREPLY=( $(compgen -W "--save --protocol --width" -- "$cur") )
+      fi
       ;;
     play)
       if [[ "$cur" == -* ]]; then
@@ -126,6 +181,20 @@ _blu_complete() {
         fi
       fi
       ;;
+    history)
+      if [[ $COMP_CWORD -eq 2 && "$cur" != -* ]]; then
+        COMPREPLY=( $(compgen -W "list stats record" -- "$cur") )
+      elif [[ "$cur" == -* ]]; then
+        COMPREPLY=( $(compgen -W "--since --device --limit --top --all --scrobble --file" -- "$cur") )
+      fi
+      ;;
+    scrobble)
+      if [[ $COMP_CWORD -eq 2 ]]; then
+        COMPREPLY=( $(compgen -W "run flush queue" -- "$cur") )
+      elif [[ "$cur" == -* ]]; then
+        COMPREPLY=( $(compgen -W "--all --queue" -- "$cur") )
+      fi
+      ;;
     raw)
       if [[ "$cur" == -* ]]; then
         COMPREPLY=( $(compgen -W "--param --write" -- "$cur") )
@@ -0,0 +1,296 @@
+package app
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strings"
+
+	"github.com/steipete/blucli/internal/config"
+	"github.com/steipete/blucli/internal/output"
+)
+
+type configPathInfo struct {
+	Path   string `json:"path"`
+	Exists bool   `json:"exists"`
+}
+
+func (c configPathInfo) PrintHuman(p *output.Printer) {
+	p.Printf("%s\n", c.Path)
+}
+
+type configValue struct {
+	Key   string `json:"key"`
+	Value any    `json:"value"`
+}
+
+func (c configValue) PrintHuman(p *output.Printer) {
+	if s, ok := c.Value.(string); ok {
+		p.Printf("%s\n", s)
+		return
+	}
+	data, err := json.MarshalIndent(c.Value, "", "  ")
+	if err != nil {
+		p.Printf("%v\n", c.Value)
+		return
+	}
+	p.Printf("%s\n", data)
+}
+
+type configValidation struct {
+	Path   string         `json:"path"`
+	Valid  bool           `json:"valid"`
+	Issues []config.Issue `json:"issues,omitempty"`
+}
+
+func (c configValidation) PrintHuman(p *output.Printer) {
+	for _, issue := range c.Issues {
+		color := output.Yellow
+		if issue.Severity == config.SeverityError {
+			color = output.Red
+		}
+		p.Printf("%s\n", p.Paint(color, issueLine(c.Path, issue)))
+	}
+	if c.Valid {
+		p.Printf("%s: ok\n", c.Path)
+	}
+}
+
+// cmdConfig reads the config file itself so `validate` and `edit` keep
+// working when it does not parse.
+func cmdConfig(ctx context.Context, out *output.Printer, path string, settings effectiveSettings, args []string) int {
+	if len(args) == 0 {
+		out.Errorf("config: missing subcommand (get|set|unset|show|edit|validate|path)")
+		return 2
+	}
+
+	sub, rest := args[0], args[1:]
+	switch sub {
+	case "path":
+		if len(rest) > 0 {
+			out.Errorf("config path: unexpected args: %q", strings.Join(rest, " "))
+			return 2
+		}
+		_, err := os.Stat(path)
+		out.Print(configPathInfo{Path: path, Exists: err == nil})
+		return 0
+	case "validate":
+		if len(rest) > 0 {
+			out.Errorf("config validate: unexpected args: %q", strings.Join(rest, " "))
+			return 2
+		}
+		data, err := os.ReadFile(path)
+		if errors.Is(err, os.ErrNotExist) {
+			out.Print(configValidation{Path: path, Valid: true})
+			return 0
+		}
+		if err != nil {
+			out.Errorf("config validate: %v", err)
+			return 1
+		}
+		result := configValidation{Path: path, Issues: config.Validate(data)}
+		result.Valid = !config.HasErrors(result.Issues)
+		out.Print(result)
+		if !result.Valid {
+			return 1
+		}
+		return 0
+	case "edit":
+		if len(rest) > 0 {
+			out.Errorf("config edit: unexpected args: %q", strings.Join(rest, " "))
+			return 2
+		}
+		return configEdit(ctx, out, path)
+	case "show":
+		flags := flag.NewFlagSet("config show", flag.ContinueOnError)
+		flags.SetOutput(out.Stderr())
+		effective := flags.Bool("effective", false, "list global settings and where each value comes from")
+		if err := flags.Parse(rest); err != nil {
+			return 2
+		}
+		if flags.NArg() > 0 {
+			out.Errorf("config show: unexpected args: %q", strings.Join(flags.Args(), " "))
+			return 2
+		}
+		if *effective {
+			out.Print(settings)
+			return 0
+		}
+	case "get", "set", "unset":
+	default:
+		out.Errorf("config: unknown subcommand %q (get|set|unset|show|edit|validate|path)", sub)
+		return 2
+	}
+
+	cfg, err := config.Load(config.LoadOptions{Path: path})
+	if err != nil {
+		out.Errorf("config: %v", err)
+		return 1
+	}
+
+	switch sub {
+	case "show":
+		out.Print(cfg)
+		return 0
+	case "get":
+		if len(rest) > 1 {
+			out.Errorf("config get: unexpected args: %q", strings.Join(rest[1:], " "))
+			return 2
+		}
+		if len(rest) == 0 {
+			out.Print(cfg)
+			return 0
+		}
+		value, err := config.Get(cfg, rest[0])
+		if err != nil {
+			out.Errorf("config get: %v", err)
+			return 1
+		}
+		out.Print(configValue{Key: rest[0], Value: value})
+		return 0
+	case "set":
+		if len(rest) != 2 {
+			out.Errorf("config set: want <key> <value>")
+			return 2
+		}
+		err := config.Update(path, func(c *config.Config) error {
+			next, err := config.Set(*c, rest[0], rest[1])
+			*c = next
+			return err
+		})
+		if err != nil {
+			out.Errorf("config set: %v", err)
+			return 1
+		}
+		return 0
+	default:
+		if len(rest) != 1 {
+			out.Errorf("config unset: want <key>")
+			return 2
+		}
+		var err error
+		if rest[0] == "scrobble.token" {
+			// Also scrub the copy in the secrets store.
+			err = config.ClearScrobbleToken(path)
+		} else {
+			err = config.Update(path, func(c *config.Config) error {
+				next, err := config.Unset(*c, rest[0])
+				*c = next
+				return err
+			})
+		}
+		if err != nil {
+			out.Errorf("config unset: %v", err)
+			return 1
+		}
+		return 0
+	}
+}
+
+// configEdit opens a copy of the config in $VISUAL/$EDITOR and only replaces
+// the real file when the edited copy validates.
+func configEdit(ctx context.Context, out *output.Printer, path string) int {
+	onDisk, err := os.ReadFile(path)
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		out.Errorf("config edit: %v", err)
+		return 1
+	}
+	if onDisk == nil {
+		onDisk = []byte{}
+	}
+	original := onDisk
+	if len(original) == 0 {
+		original = []byte("{\n}\n")
+	}
+
+	tmp, err := os.CreateTemp("", "blu-config-*.json")
+	if err != nil {
+		out.Errorf("config edit: %v", err)
+		return 1
+	}
+	tmpPath := tmp.Name()
+	_, werr := tmp.Write(original)
+	if cerr := tmp.Close(); werr == nil {
+		werr = cerr
+	}
+	if werr != nil {
+		_ = os.Remove(tmpPath)
+		out.Errorf("config edit: %v", werr)
+		return 1
+	}
+
+	if err := runEditor(ctx, tmpPath); err != nil {
+		_ = os.Remove(tmpPath)
+		out.Errorf("config edit: %v", err)
+		return 1
+	}
+
+	edited, err := os.ReadFile(tmpPath)
+	if err != nil {
+		out.Errorf("config edit: %v", err)
+		return 1
+	}
+	if bytes.Equal(edited, original) {
+		_ = os.Remove(tmpPath)
+		out.Infof("config: no changes")
+		return 0
+	}
+
+	issues := config.Validate(edited)
+	if config.HasErrors(issues) {
+		for _, issue := range issues {
+			out.Errorf("%s", issueLine(path, issue))
+		}
+		out.Warnf("config: not saved; your edits are in %s", tmpPath)
+		return 1
+	}
+	for _, issue := range issues {
+		out.Warnf("%s", issueLine(path, issue))
+	}
+
+	if err := config.WriteConfigFile(path, edited, onDisk); err != nil {
+		if errors.Is(err, config.ErrConfigChanged) {
+			err = errors.New("config.json changed while you were editing; not saved")
+		}
+		out.Errorf("config edit: %v (your edits are in %s)", err, tmpPath)
+		return 1
+	}
+	_ = os.Remove(tmpPath)
+	return 0
+}
+
+func issueLine(path string, issue config.Issue) string {
+	if issue.Line > 0 {
+		path = fmt.Sprintf("%s:%d:%d", path, issue.Line, issue.Column)
+		issue.Line = 0
+	}
+	return path + ": " + issue.String()
+}
+
+func runEditor(ctx context.Context, file string) error {
+	editor := strings.TrimSpace(os.Getenv("VISUAL"))
+	if editor == "" {
+		editor = strings.TrimSpace(os.Getenv("EDITOR"))
+	}
+	if editor == "" {
+		editor = "vi"
+		if runtime.GOOS == "windows" {
+			editor = "notepad"
+		}
+	}
+	// Allow editors with arguments, e.g. EDITOR="code --wait".
+	parts := strings.Fields(editor)
+	cmd := exec.CommandContext(ctx, parts[0], append(parts[1:], file)...)
+	cmd.Stdin, cmd.Stdout, cmd.Stderr = os.Stdin, os.Stdout, os.Stderr
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("%s: %w", filepath.Base(parts[0]), err)
+	}
+	return nil
+}
@@ -1,8 +1,11 @@
 package app
 
 import (
+	"cmp"
 	"context"
 	"errors"
+	"flag"
+	"strings"
 	"time"
 
 	"github.com/steipete/blucli/internal/config"
@@ -10,31 +13,82 @@ import (
 	"github.com/steipete/blucli/internal/output"
 )
 
-func cmdDevices(ctx context.Context, out *output.Printer, paths config.PathSet, cfg config.Config, cache config.DiscoveryCache, discoverTimeout time.Duration) int {
+type deviceEvent discovery.Event
+
+func (e deviceEvent) TableHeader() []string {
+	return []string{"time", "event", "id", "name", "type", "version", "changes"}
+}
+
+func (e deviceEvent) TableRows() [][]string {
+	return [][]string{{e.Time.Format(time.RFC3339), e.Kind, e.Device.ID, e.Device.Name, e.Device.Type, e.Device.Version, strings.Join(e.Changes, "; ")}}
+}
+
+func (e deviceEvent) PrintHuman(p *output.Printer) {
+	color := ""
+	switch e.Kind {
+	case discovery.EventJoin:
+		color = output.Green
+	case discovery.EventLeave:
+		color = output.Red
+	case discovery.EventChange:
+		color = output.Yellow
+	}
+	line := e.Time.Format("15:04:05") + " " + p.Paint(color, e.Kind) + " " + e.Device.ID
+	if e.Device.Name != "" {
+		line += " " + e.Device.Name
+	}
+	switch {
+	case len(e.Changes) > 0:
+		line += ": " + strings.Join(e.Changes, ", ")
+	case e.Kind == discovery.EventJoin && e.Device.Version != "":
+		line += " (" + strings.TrimSpace(e.Device.Type+" v"+e.Device.Version) + ")"
+	}
+	p.Printf("%s\n", line)
+}
+
+func cmdDevices(ctx context.Context, out *output.Printer, paths config.PathSet, cfg config.Config, cache config.DiscoveryCache, discoverTimeout, httpTimeout time.Duration, args []string) int {
 	_ = cfg
 
-	ctx, cancel := context.WithTimeout(ctx, discoverTimeout)
+	flags := flag.NewFlagSet("devices", flag.ContinueOnError)
+	flags.SetOutput(out.Stderr())
+	watch := flags.Bool("watch", false, "keep discovering and print join/leave/change events")
+	interval := flags.Duration("interval", discovery.DefaultWatchInterval, "re-browse/re-query interval (with --watch)")
+	ttl := flags.Duration("ttl", discovery.DefaultWatchTTL, "report a player as left after this long unheard (with --watch)")
+	scanTargets := flags.String("scan", "", "also probe :11000/SyncStatus on these subnets (CIDRs, comma-separated, or auto)")
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+	if flags.NArg() > 0 {
+		out.Errorf("devices: unexpected args: %q", strings.Join(flags.Args(), " "))
+		return 2
+	}
+	scanSet := false
+	flags.Visit(func(f *flag.Flag) { scanSet = scanSet || f.Name == "scan" })
+	if scanSet {
+		prefixes, err := discovery.ParseScanTargets(*scanTargets)
+		if err != nil {
+			out.Errorf("devices: %v", err)
+			return 2
+		}
+		ctx = discovery.WithScan(ctx, discovery.ScanOptions{Prefixes: prefixes})
+	}
+	if *watch {
+		return watchDevices(ctx, out, paths, httpTimeout, discovery.WatchOptions{Interval: *interval, TTL: *ttl})
+	}
+
+	discoverCtx, cancel := context.WithTimeout(ctx, discoverTimeout)
 	defer cancel()
 
-	devices, err := discovery.Discover(ctx)
+	devices, err := discovery.Discover(discoverCtx)
 	if err != nil && !errors.Is(err, context.DeadlineExceeded) {
 		out.Errorf("discover: %v", err)
 		return 1
 	}
 
-	now := time.Now()
-	cacheDevices := make([]config.Device, 0, len(devices))
-	for _, device := range devices {
-		cacheDevices = append(cacheDevices, config.Device{
-			ID:   device.ID,
-			Host: device.Host,
-			Port: device.Port,
-			Name: device.Name,
-			Type: device.Type,
-		})
-	}
-	newCache := config.NewDiscoveryCache(now, cacheDevices)
-	if err := config.SaveDiscoveryCache(paths.CachePath, newCache); err != nil {
+	identifyDevices(ctx, devices, httpTimeout, identified)
+	now, seen := time.Now(), cacheDevices(devices)
+	err = config.UpdateDiscoveryCache(paths.CachePath, func(c *config.DiscoveryCache) { *c = c.Merge(now, seen) })
+	if err != nil {
 		out.Errorf("cache write: %v", err)
 		return 1
 	}
@@ -46,3 +100,80 @@ func cmdDevices(ctx context.Context, out *output.Printer, paths config.PathSet,
 	out.Print(devices)
 	return 0
 }
+
+// watchDevices prints discovery events until interrupted and folds joins
+// and changes into the discovery cache as they happen. Events are handled
+// on a worker so SyncStatus lookups and cache writes never stall Watch's
+// loop; players are identified once, on join.
+func watchDevices(ctx context.Context, out *output.Printer, paths config.PathSet, httpTimeout time.Duration, opts discovery.WatchOptions) int {
+	events := make(chan discovery.Event, 64)
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		ids := watchIdentities{}
+		cacheWarned := false
+		for ev := range events {
+			switch ev.Kind {
+			case discovery.EventJoin:
+				if !identified(ev.Device) {
+					found := []discovery.Device{ev.Device}
+					identifyDevices(ctx, found, httpTimeout, identified)
+					ev.Device = found[0]
+				}
+				ids.remember(ev.Device)
+			case discovery.EventChange:
+				ev.Device = ids.fill(ev.Device)
+				ids.remember(ev.Device)
+			}
+			if ev.Kind != discovery.EventLeave {
+				seen := cacheDevices([]discovery.Device{ev.Device})
+				err := config.UpdateDiscoveryCache(paths.CachePath, func(c *config.DiscoveryCache) { *c = c.Merge(ev.Time, seen) })
+				if err != nil && !cacheWarned {
+					cacheWarned = true
+					out.Warnf("cache write: %v", err)
+				}
+			}
+			out.Print(deviceEvent(ev))
+		}
+	}()
+
+	err := discovery.Watch(ctx, opts, func(ev discovery.Event) {
+		select {
+		case events <- ev:
+		case <-ctx.Done():
+		}
+	})
+	close(events)
+	<-done
+	if err != nil {
+		out.Errorf("devices --watch: %v", err)
+		return 1
+	}
+	return 0
+}
+
+// watchIdentities remembers what SyncStatus told about each player (by ID
+// and MAC), so change events reuse it instead of probing again.
+type watchIdentities map[string]discovery.Device
+
+func (w watchIdentities) remember(d discovery.Device) {
+	w[d.ID] = d
+	if d.MAC != "" {
+		w[discovery.NormalizeMAC(d.MAC)] = d
+	}
+}
+
+func (w watchIdentities) fill(d discovery.Device) discovery.Device {
+	known, ok := w[d.ID]
+	if !ok && d.MAC != "" {
+		known, ok = w[discovery.NormalizeMAC(d.MAC)]
+	}
+	if !ok {
+		return d
+	}
+	d.MAC = cmp.Or(d.MAC, known.MAC)
+	d.Brand = cmp.Or(d.Brand, known.Brand)
+	d.ModelName = cmp.Or(d.ModelName, known.ModelName)
+	d.Model = cmp.Or(d.Model, known.Model)
+	return d
+}
@@ -2,89 +2,80 @@ package app
 
 import (
 	"context"
-	"errors"
-	"io"
+	"flag"
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
 	"time"
 
 	"github.com/steipete/blucli/internal/bluos"
 	"github.com/steipete/blucli/internal/config"
-	"github.com/steipete/blucli/internal/discovery"
 	"github.com/steipete/blucli/internal/output"
 )
 
-type doctorRow struct {
-	ID      string `json:"id"`
-	Host    string `json:"host"`
-	Port    int    `json:"port"`
-	Source  string `json:"source,omitempty"`
-	Version string `json:"version,omitempty"`
-
-	OK    bool   `json:"ok"`
-	Error string `json:"error,omitempty"`
-	State string `json:"state,omitempty"`
-	Name  string `json:"name,omitempty"`
-	Model string `json:"model,omitempty"`
+type diagReport struct {
+	Device  config.Device    `json:"device"`
+	Status  bluos.Status     `json:"status"`
+	Sync    bluos.SyncStatus `json:"sync"`
+	Presets bluos.Presets    `json:"presets"`
+	Queue   bluos.Playlist   `json:"queue"`
 }
 
-func cmdDoctor(ctx context.Context, out *output.Printer, cfg config.Config, cache config.DiscoveryCache, discoverTimeout, httpTimeout time.Duration) int {
-	_ = cfg
-	_ = cache
+func (r diagReport) PrintHuman(p *output.Printer) {
+	name := strings.TrimSpace(r.Status.Name)
+	if name == "" {
+		name = strings.TrimSpace(r.Device.Name)
+	}
+	if name == "" {
+		name = r.Device.ID
+	}
+	header := fmt.Sprintf("%s  %s", p.Paint(output.Bold, name), net.JoinHostPort(r.Device.Host, strconv.Itoa(r.Device.Port)))
+	if model := strings.TrimSpace(r.Status.Model); model != "" {
+		header += "  " + model
+	}
+	p.Printf("%s\n\n", header)
 
-	ctx, cancel := context.WithTimeout(ctx, discoverTimeout)
-	defer cancel()
+	p.Printf("%s\n", p.Paint(output.Bold, "Status:"))
+	p.Print(r.Status)
 
-	devices, err := discovery.Discover(ctx)
-	if err != nil && !errors.Is(err, context.DeadlineExceeded) {
-		out.Errorf("doctor discover: %v", err)
-		return 1
+	p.Printf("\n%s\n", p.Paint(output.Bold, "Group:"))
+	p.Print(r.Sync)
+
+	p.Printf("\n%s\n", p.Paint(output.Bold, fmt.Sprintf("Presets (%d):", len(r.Presets.Presets))))
+	p.Print(r.Presets)
+
+	p.Printf("\n%s\n", p.Paint(output.Bold, fmt.Sprintf("Queue (%d):", len(r.Queue.Songs))))
+	p.Print(r.Queue)
+}
+
+func cmdDiag(ctx context.Context, out *output.Printer, cfg config.Config, cache config.DiscoveryCache, deviceArg string, allowDiscover bool, discoverTimeout, httpTimeout time.Duration, dryRun bool, trace bluos.Tracer, args []string) int {
+	flags := flag.NewFlagSet("diag", flag.ContinueOnError)
+	flags.SetOutput(out.Stderr())
+	bundle := flags.String("bundle", "", "write a support bundle zip (all players, raw XML, discovery, config)")
+	redactFlag := flags.String("redact", redactTokens, "with --bundle: redact tokens, hosts, all or none")
+	if err := flags.Parse(args); err != nil {
+		return 2
 	}
-	if len(devices) == 0 {
-		out.Errorf("doctor: no devices discovered")
-		return 1
+	if flags.NArg() > 0 {
+		out.Errorf("diag: unexpected args: %q", strings.Join(flags.Args(), " "))
+		return 2
 	}
-
-	rows := make([]doctorRow, 0, len(devices))
-	for _, d := range devices {
-		row := doctorRow{
-			ID:      d.ID,
-			Host:    d.Host,
-			Port:    d.Port,
-			Source:  d.Source,
-			Version: d.Version,
-		}
-		dev := config.Device{Host: d.Host, Port: d.Port}
-		client := bluos.NewClient(dev.BaseURL(), bluos.Options{Timeout: httpTimeout})
-		status, err := client.Status(context.Background(), bluos.StatusOptions{})
+	if *bundle != "" {
+		redact, err := parseRedact(*redactFlag)
 		if err != nil {
-			row.OK = false
-			row.Error = err.Error()
-		} else {
-			row.OK = true
-			row.State = status.State
-			row.Name = status.Name
-			row.Model = status.Model
+			out.Errorf("diag: %v", err)
+			return 2
 		}
-		rows = append(rows, row)
+		return writeDiagBundle(ctx, out, cfg, cache, deviceArg, allowDiscover, discoverTimeout, httpTimeout, *bundle, redact)
 	}
 
-	out.Print(rows)
-	return 0
-}
-
-type diagReport struct {
-	Device  config.Device    `json:"device"`
-	Status  bluos.Status     `json:"status"`
-	Sync    bluos.SyncStatus `json:"sync"`
-	Presets bluos.Presets    `json:"presets"`
-	Queue   bluos.Playlist   `json:"queue"`
-}
-
-func cmdDiag(ctx context.Context, out *output.Printer, cfg config.Config, cache config.DiscoveryCache, deviceArg string, allowDiscover bool, discoverTimeout, httpTimeout time.Duration, dryRun bool, trace io.Writer) int {
 	device, resolveErr := resolveDevice(ctx, cfg, cache, deviceArg, allowDiscover, discoverTimeout)
 	if resolveErr != nil {
 		out.Errorf("device: %v", resolveErr)
 		return 1
 	}
+	out.SetDevice(device)
 	client := bluos.NewClient(device.BaseURL(), bluos.Options{Timeout: httpTimeout, DryRun: dryRun, Trace: trace})
 
 	status, err := client.Status(ctx, bluos.StatusOptions{})
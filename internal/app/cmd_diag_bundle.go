@@ -0,0 +1,360 @@
+package app
+
+import (
+	"archive/zip"
+	"bytes"
+	"cmp"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"maps"
+	"net"
+	"net/url"
+	"path"
+	"regexp"
+	"runtime"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/steipete/blucli/internal/bluos"
+	"github.com/steipete/blucli/internal/config"
+	"github.com/steipete/blucli/internal/discovery"
+	"github.com/steipete/blucli/internal/fileutil"
+	"github.com/steipete/blucli/internal/output"
+)
+
+// bundleFormat identifies the support bundle layout in manifest.json.
+const bundleFormat = "blu-bundle/v1"
+
+// bundleEndpoints are the read endpoints captured for every player, as
+// path plus raw query.
+var bundleEndpoints = []string{
+	"/Status",
+	"/SyncStatus",
+	"/Presets",
+	"/Playlist",
+	"/Browse",
+	"/Playlists",
+	"/RadioBrowse?service=Capture",
+}
+
+// Redaction classes for --redact.
+const (
+	redactTokens = "tokens"
+	redactHosts  = "hosts"
+)
+
+const redactedValue = "REDACTED"
+
+type bundleManifest struct {
+	Format   string         `json:"format"`
+	Created  time.Time      `json:"created"`
+	Blu      bundlePlatform `json:"blu"`
+	Redacted []string       `json:"redacted,omitempty"`
+	Players  []bundlePlayer `json:"players"`
+}
+
+type bundlePlatform struct {
+	Version string `json:"version"`
+	Go      string `json:"go"`
+	OS      string `json:"os"`
+	Arch    string `json:"arch"`
+}
+
+type bundlePlayer struct {
+	ID        string           `json:"id"`
+	Name      string           `json:"name,omitempty"`
+	Host      string           `json:"host"`
+	Port      int              `json:"port"`
+	Endpoints []bundleEndpoint `json:"endpoints"`
+}
+
+// bundleEndpoint maps one request to the file holding the raw response
+// body; File is empty when the request failed.
+type bundleEndpoint struct {
+	Path      string `json:"path"`
+	Query     string `json:"query,omitempty"`
+	File      string `json:"file,omitempty"`
+	Error     string `json:"error,omitempty"`
+	ElapsedMS int64  `json:"elapsed_ms"`
+}
+
+type bundleDiscovery struct {
+	Mechanisms []bundleMechanism  `json:"mechanisms"`
+	Devices    []discovery.Device `json:"devices"`
+}
+
+type bundleMechanism struct {
+	Mechanism string             `json:"mechanism"`
+	ElapsedMS int64              `json:"elapsed_ms"`
+	Error     string             `json:"error,omitempty"`
+	Devices   []discovery.Device `json:"devices"`
+}
+
+type diagBundle struct {
+	Path     string   `json:"path"`
+	Players  int      `json:"players"`
+	Files    int      `json:"files"`
+	Bytes    int      `json:"bytes"`
+	Failed   int      `json:"failed_requests"`
+	Redacted []string `json:"redacted,omitempty"`
+}
+
+func (b diagBundle) PrintHuman(p *output.Printer) {
+	line := fmt.Sprintf("wrote %s (%d players, %d files, %d bytes", b.Path, b.Players, b.Files, b.Bytes)
+	if len(b.Redacted) > 0 {
+		line += "; redacted: " + strings.Join(b.Redacted, ", ")
+	}
+	p.Printf("%s)\n", line)
+	if b.Failed > 0 {
+		p.Printf("%s\n", p.Paint(output.Yellow, fmt.Sprintf("%d requests failed; see manifest.json", b.Failed)))
+	}
+}
+
+// parseRedact parses --redact: a comma-separated list of tokens and hosts,
+// or all / none.
+func parseRedact(s string) ([]string, error) {
+	var out []string
+	for _, part := range strings.Split(s, ",") {
+		switch part = strings.ToLower(strings.TrimSpace(part)); part {
+		case "", "none":
+		case "all":
+			out = append(out, redactTokens, redactHosts)
+		case redactTokens, redactHosts:
+			out = append(out, part)
+		default:
+			return nil, fmt.Errorf("unknown redaction %q (want tokens, hosts, all or none)", part)
+		}
+	}
+	slices.Sort(out)
+	return slices.Compact(out), nil
+}
+
+// writeDiagBundle captures every read endpoint of every player plus
+// discovery, platform and config into a zip at target.
+func writeDiagBundle(ctx context.Context, out *output.Printer, cfg config.Config, cache config.DiscoveryCache, deviceArg string, allowDiscover bool, discoverTimeout, httpTimeout time.Duration, target string, redact []string) int {
+	var devices []discovery.Device
+	var mechanisms []discovery.MechanismResult
+	if allowDiscover {
+		discoverCtx, cancel := context.WithTimeout(ctx, discoverTimeout)
+		var err error
+		devices, mechanisms, err = discovery.DiscoverMechanisms(discoverCtx)
+		cancel()
+		if err != nil && !errors.Is(err, context.DeadlineExceeded) {
+			out.Warnf("diag --bundle discover: %v", err)
+		}
+	}
+
+	players := make([]bundlePlayer, 0, len(devices)+1)
+	for _, d := range devices {
+		players = append(players, bundlePlayer{ID: d.ID, Name: d.Name, Host: d.Host, Port: d.Port})
+	}
+	device, err := resolveDevice(ctx, cfg, cache, deviceArg, false, discoverTimeout)
+	switch {
+	case err == nil:
+		if !slices.ContainsFunc(players, func(p bundlePlayer) bool { return p.Host == device.Host && p.Port == device.Port }) {
+			players = append(players, bundlePlayer{ID: cmp.Or(device.ID, net.JoinHostPort(device.Host, strconv.Itoa(device.Port))), Name: device.Name, Host: device.Host, Port: device.Port})
+		}
+	case deviceArg != "":
+		out.Errorf("device: %v", err)
+		return 1
+	}
+	if len(players) == 0 {
+		out.Errorf("diag --bundle: no players (none discovered; pass --device)")
+		return 1
+	}
+
+	bodies := captureBundle(ctx, players, httpTimeout)
+
+	files := map[string][]byte{}
+	failed := 0
+	for i := range players {
+		for j, ep := range players[i].Endpoints {
+			if ep.Error != "" {
+				failed++
+				continue
+			}
+			files[ep.File] = bodies[i][j]
+		}
+	}
+
+	mechs := make([]bundleMechanism, 0, len(mechanisms))
+	for _, m := range mechanisms {
+		bm := bundleMechanism{Mechanism: m.Mechanism, ElapsedMS: m.Elapsed.Milliseconds(), Devices: append([]discovery.Device{}, m.Devices...)}
+		if m.Err != nil {
+			bm.Error = m.Err.Error()
+		}
+		mechs = append(mechs, bm)
+	}
+	manifest := bundleManifest{
+		Format:   bundleFormat,
+		Created:  time.Now().UTC(),
+		Blu:      bundlePlatform{Version: Version, Go: runtime.Version(), OS: runtime.GOOS, Arch: runtime.GOARCH},
+		Redacted: redact,
+		Players:  players,
+	}
+	if slices.Contains(redact, redactTokens) {
+		cfg = redactConfigTokens(cfg)
+	}
+	for name, v := range map[string]any{
+		"manifest.json":  manifest,
+		"discovery.json": bundleDiscovery{Mechanisms: mechs, Devices: append([]discovery.Device{}, devices...)},
+		"config.json":    cfg,
+	} {
+		data, err := json.MarshalIndent(v, "", "  ")
+		if err != nil {
+			out.Errorf("diag --bundle: %v", err)
+			return 1
+		}
+		files[name] = append(data, '\n')
+	}
+
+	if slices.Contains(redact, redactHosts) {
+		files = redactHostnames(files, players, devices, cfg)
+	}
+
+	data, err := zipFiles(files)
+	if err != nil {
+		out.Errorf("diag --bundle: %v", err)
+		return 1
+	}
+	if err := fileutil.WriteAtomic(target, data, 0o600); err != nil {
+		out.Errorf("diag --bundle: %v", err)
+		return 1
+	}
+	out.Print(diagBundle{Path: target, Players: len(players), Files: len(files), Bytes: len(data), Failed: failed, Redacted: redact})
+	return 0
+}
+
+// captureBundle fetches bundleEndpoints from every player concurrently,
+// filling in each player's Endpoints and returning the bodies by index.
+func captureBundle(ctx context.Context, players []bundlePlayer, timeout time.Duration) [][][]byte {
+	bodies := make([][][]byte, len(players))
+	var wg sync.WaitGroup
+	for i := range players {
+		wg.Add(1)
+		go func(p *bundlePlayer) {
+			defer wg.Done()
+			dir := "players/" + bundleDirName(p.Host, p.Port)
+			dev := config.Device{Host: p.Host, Port: p.Port}
+			client := bluos.NewClient(dev.BaseURL(), bluos.Options{Timeout: timeout})
+			for _, endpoint := range bundleEndpoints {
+				epPath, rawQuery, _ := strings.Cut(endpoint, "?")
+				params := map[string]string{}
+				q, _ := url.ParseQuery(rawQuery)
+				for k := range q {
+					params[k] = q.Get(k)
+				}
+				ep := bundleEndpoint{Path: epPath, Query: rawQuery}
+				start := time.Now()
+				body, err := client.RawGet(ctx, epPath, params, false)
+				ep.ElapsedMS = time.Since(start).Milliseconds()
+				if err != nil {
+					ep.Error = err.Error()
+				} else {
+					ep.File = path.Join(dir, bundleFileName(epPath, rawQuery))
+				}
+				p.Endpoints = append(p.Endpoints, ep)
+				bodies[i] = append(bodies[i], body)
+			}
+		}(&players[i])
+	}
+	wg.Wait()
+	return bodies
+}
+
+var unsafeFileChars = regexp.MustCompile(`[^A-Za-z0-9._-]+`)
+
+func bundleDirName(host string, port int) string {
+	return unsafeFileChars.ReplaceAllString(host, "_") + "_" + strconv.Itoa(port)
+}
+
+// bundleFileName is e.g. Status.xml or RadioBrowse_service=Capture.xml.
+func bundleFileName(epPath, rawQuery string) string {
+	name := strings.TrimPrefix(epPath, "/")
+	if rawQuery != "" {
+		name += "_" + rawQuery
+	}
+	return unsafeFileChars.ReplaceAllString(name, "_") + ".xml"
+}
+
+func redactConfigTokens(cfg config.Config) config.Config {
+	if cfg.Scrobble.Token != "" {
+		cfg.Scrobble.Token = redactedValue
+	}
+	if cfg.Spotify.Token.AccessToken != "" {
+		cfg.Spotify.Token.AccessToken = redactedValue
+	}
+	if cfg.Spotify.Token.RefreshToken != "" {
+		cfg.Spotify.Token.RefreshToken = redactedValue
+	}
+	return cfg
+}
+
+// redactHostnames replaces every player and configured host with a
+// placeholder in file contents and paths.
+func redactHostnames(files map[string][]byte, players []bundlePlayer, devices []discovery.Device, cfg config.Config) map[string][]byte {
+	hosts := map[string]bool{}
+	for _, p := range players {
+		hosts[p.Host] = true
+	}
+	for _, d := range devices {
+		hosts[d.Host] = true
+	}
+	for _, target := range append(slices.Collect(maps.Values(cfg.Aliases)), cfg.DefaultDevice) {
+		if d, err := config.ParseDevice(target); err == nil && !likelyNameArg(target) {
+			hosts[d.Host] = true
+		}
+	}
+	delete(hosts, "")
+
+	// Longest first, so 10.0.0.10 is replaced before 10.0.0.1.
+	ordered := slices.Collect(maps.Keys(hosts))
+	sort.Slice(ordered, func(i, j int) bool {
+		if len(ordered[i]) != len(ordered[j]) {
+			return len(ordered[i]) > len(ordered[j])
+		}
+		return ordered[i] < ordered[j]
+	})
+	pairs := make([]string, 0, 2*len(ordered))
+	for i, h := range ordered {
+		placeholder := "host-" + strconv.Itoa(i+1) + ".invalid"
+		pairs = append(pairs, h, placeholder)
+		// Player directories use the file-safe form (IPv6 colons, zones).
+		if safe := unsafeFileChars.ReplaceAllString(h, "_"); safe != h {
+			pairs = append(pairs, safe, placeholder)
+		}
+	}
+	replacer := strings.NewReplacer(pairs...)
+
+	out := make(map[string][]byte, len(files))
+	for name, data := range files {
+		out[replacer.Replace(name)] = []byte(replacer.Replace(string(data)))
+	}
+	return out
+}
+
+func zipFiles(files map[string][]byte) ([]byte, error) {
+	names := slices.Sorted(maps.Keys(files))
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	now := time.Now()
+	for _, name := range names {
+		w, err := zw.CreateHeader(&zip.FileHeader{Name: name, Method: zip.Deflate, Modified: now})
+		if err != nil {
+			return nil, err
+		}
+		if _, err := w.Write(files[name]); err != nil {
+			return nil, err
+		}
+	}
+	if err := zw.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
@@ -0,0 +1,546 @@
+package app
+
+import (
+	"cmp"
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"math"
+	"net"
+	"net/http"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/steipete/blucli/internal/bluos"
+	"github.com/steipete/blucli/internal/config"
+	"github.com/steipete/blucli/internal/discovery"
+	"github.com/steipete/blucli/internal/output"
+)
+
+// Doctor check outcomes.
+const (
+	checkOK   = "ok"
+	checkWarn = "warn"
+	checkFail = "fail"
+	checkSkip = "skip"
+)
+
+const (
+	// doctorLatencyWarn flags players whose p90 round trip is slower.
+	doctorLatencyWarn = 300 * time.Millisecond
+	// doctorSkewWarn flags player clocks further off (HTTP Date has
+	// second resolution).
+	doctorSkewWarn = 5 * time.Second
+	// doctorLongPoll is the /Status long-poll timeout in seconds; a player
+	// that honors the etag holds the request at least doctorLongPollHeld.
+	doctorLongPoll     = 1
+	doctorLongPollHeld = 500 * time.Millisecond
+)
+
+type doctorCheck struct {
+	Check string `json:"check"`
+	// Device is the player ID; empty for setup-wide checks.
+	Device string `json:"device,omitempty"`
+	Status string `json:"status"`
+	Detail string `json:"detail,omitempty"`
+	// Hint suggests a fix for warn/fail results.
+	Hint string `json:"hint,omitempty"`
+}
+
+type doctorLatency struct {
+	Samples int     `json:"samples"`
+	P50MS   float64 `json:"p50_ms"`
+	P90MS   float64 `json:"p90_ms"`
+	P99MS   float64 `json:"p99_ms"`
+	MaxMS   float64 `json:"max_ms"`
+}
+
+type doctorRow struct {
+	ID      string `json:"id"`
+	Host    string `json:"host"`
+	Port    int    `json:"port"`
+	Source  string `json:"source,omitempty"`
+	Version string `json:"version,omitempty"`
+
+	OK    bool   `json:"ok"`
+	Error string `json:"error,omitempty"`
+	State string `json:"state,omitempty"`
+	Name  string `json:"name,omitempty"`
+	Model string `json:"model,omitempty"`
+
+	Latency     *doctorLatency `json:"latency,omitempty"`
+	ClockSkewMS *int64         `json:"clock_skew_ms,omitempty"`
+	Checks      []doctorCheck  `json:"checks,omitempty"`
+}
+
+func (r doctorRow) label() string {
+	return cmp.Or(strings.TrimSpace(r.Name), r.ID)
+}
+
+// issues lists the names of the row's warn/fail checks.
+func (r doctorRow) issues() []string {
+	var out []string
+	for _, c := range r.Checks {
+		if c.Status == checkWarn || c.Status == checkFail {
+			out = append(out, c.Check)
+		}
+	}
+	return out
+}
+
+type doctorRows []doctorRow
+
+func (rows doctorRows) TableHeader() []string {
+	return []string{"id", "host", "port", "source", "version", "ok", "state", "name", "model", "error", "p50_ms", "p90_ms", "clock_skew_ms", "issues"}
+}
+
+func (rows doctorRows) TableRows() [][]string {
+	out := make([][]string, 0, len(rows))
+	for _, r := range rows {
+		var p50, p90, skew string
+		if r.Latency != nil {
+			p50 = strconv.FormatFloat(r.Latency.P50MS, 'f', -1, 64)
+			p90 = strconv.FormatFloat(r.Latency.P90MS, 'f', -1, 64)
+		}
+		if r.ClockSkewMS != nil {
+			skew = strconv.FormatInt(*r.ClockSkewMS, 10)
+		}
+		out = append(out, []string{r.ID, r.Host, strconv.Itoa(r.Port), r.Source, r.Version, strconv.FormatBool(r.OK), r.State, r.Name, r.Model, r.Error, p50, p90, skew, strings.Join(r.issues(), " ")})
+	}
+	return out
+}
+
+// doctorReport is the per-player rows plus setup-wide checks (discovery
+// agreement, firmware consistency, config aliases).
+type doctorReport struct {
+	Players doctorRows    `json:"players"`
+	Checks  []doctorCheck `json:"checks"`
+}
+
+func (r doctorReport) TableHeader() []string { return r.Players.TableHeader() }
+func (r doctorReport) TableRows() [][]string { return r.Players.TableRows() }
+
+func (r doctorReport) PrintHuman(p *output.Printer) {
+	failing := 0
+	table := make([][]string, 0, len(r.Players))
+	for _, row := range r.Players {
+		result := "ok"
+		if row.State != "" {
+			result = "ok (" + row.State + ")"
+		}
+		if !row.OK {
+			failing++
+			result = "FAIL: " + row.Error
+		}
+		latency := ""
+		if row.Latency != nil {
+			latency = fmt.Sprintf("%gms", row.Latency.P50MS)
+		}
+		table = append(table, []string{row.label(), row.Model, net.JoinHostPort(row.Host, strconv.Itoa(row.Port)), row.Version, row.Source, latency, result})
+	}
+	p.Table([]string{"name", "model", "address", "version", "source", "p50", "result"}, table, func(i int) string {
+		switch {
+		case !r.Players[i].OK:
+			return output.Red
+		case len(r.Players[i].issues()) > 0:
+			return output.Yellow
+		}
+		return ""
+	})
+
+	labels := map[string]string{}
+	var findings []doctorCheck
+	for _, row := range r.Players {
+		labels[row.ID] = row.label()
+		findings = append(findings, row.Checks...)
+	}
+	findings = append(findings, r.Checks...)
+	warnings := 0
+	printed := false
+	for _, c := range findings {
+		if c.Status != checkWarn && c.Status != checkFail {
+			continue
+		}
+		if c.Status == checkWarn {
+			warnings++
+		}
+		if !printed {
+			p.Printf("\n%s\n", p.Paint(output.Bold, "Findings:"))
+			printed = true
+		}
+		color := output.Yellow
+		if c.Status == checkFail {
+			color = output.Red
+		}
+		subject := c.Check
+		if c.Device != "" {
+			subject = cmp.Or(labels[c.Device], c.Device) + " " + c.Check
+		}
+		p.Printf("  %s %s: %s\n", p.Paint(color, fmt.Sprintf("%-4s", c.Status)), subject, c.Detail)
+		if c.Hint != "" {
+			p.Printf("       %s\n", p.Paint(output.Dim, "hint: "+c.Hint))
+		}
+	}
+
+	summary := p.Paint(output.Green, fmt.Sprintf("%d players, all ok", len(r.Players)))
+	if failing > 0 {
+		summary = p.Paint(output.Red, fmt.Sprintf("%d players, %d failing", len(r.Players), failing))
+	}
+	if warnings > 0 {
+		summary += ", " + p.Paint(output.Yellow, fmt.Sprintf("%d warnings", warnings))
+	}
+	p.Printf("\n%s\n", summary)
+}
+
+func cmdDoctor(ctx context.Context, out *output.Printer, cfg config.Config, cache config.DiscoveryCache, discoverTimeout, httpTimeout time.Duration, args []string) int {
+	flags := flag.NewFlagSet("doctor", flag.ContinueOnError)
+	flags.SetOutput(out.Stderr())
+	samples := flags.Int("samples", 5, "SyncStatus round trips per player for latency percentiles")
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+	if flags.NArg() > 0 {
+		out.Errorf("doctor: unexpected args: %q", strings.Join(flags.Args(), " "))
+		return 2
+	}
+	if *samples < 1 {
+		out.Errorf("doctor: --samples must be at least 1")
+		return 2
+	}
+
+	discoverCtx, cancel := context.WithTimeout(ctx, discoverTimeout)
+	defer cancel()
+	devices, mechanisms, err := discovery.DiscoverMechanisms(discoverCtx)
+	if err != nil && !errors.Is(err, context.DeadlineExceeded) {
+		out.Errorf("doctor discover: %v", err)
+		return 1
+	}
+	if len(devices) == 0 {
+		out.Errorf("doctor: no devices discovered")
+		return 1
+	}
+
+	report := doctorReport{Players: make(doctorRows, len(devices))}
+	var wg sync.WaitGroup
+	for i, d := range devices {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			report.Players[i] = probeDoctor(ctx, d, httpTimeout, *samples)
+		}()
+	}
+	wg.Wait()
+	if err := ctx.Err(); err != nil {
+		out.Errorf("doctor: %v", err)
+		return 1
+	}
+
+	report.Checks = append(report.Checks, discoveryChecks(devices, mechanisms, report.Players)...)
+	report.Checks = append(report.Checks, firmwareCheck(report.Players)...)
+	report.Checks = append(report.Checks, configChecks(ctx, cfg, cache, devices)...)
+
+	out.Print(report)
+	return 0
+}
+
+// probeDoctor checks one player: TCP port, /Status, SyncStatus latency and
+// clock, and /Status long-poll (etag) support.
+func probeDoctor(ctx context.Context, d discovery.Device, httpTimeout time.Duration, samples int) (row doctorRow) {
+	row = doctorRow{ID: d.ID, Host: d.Host, Port: d.Port, Source: d.Source, Version: d.Version, Name: d.Name}
+	check := func(name, status, detail, hint string) {
+		row.Checks = append(row.Checks, doctorCheck{Check: name, Device: d.ID, Status: status, Detail: detail, Hint: hint})
+		if status == checkFail && row.Error == "" {
+			row.Error = detail
+		}
+	}
+	defer func() { row.OK = row.Error == "" }()
+
+	dev := config.Device{Host: d.Host, Port: d.Port}
+	addr := net.JoinHostPort(d.Host, strconv.Itoa(d.Port))
+	dialer := net.Dialer{Timeout: httpTimeout}
+	conn, err := dialer.DialContext(ctx, "tcp", addr)
+	if err != nil {
+		check("port", checkFail, err.Error(), fmt.Sprintf("power the player on and make sure this host can reach TCP %d on it (same network/VLAN, no firewall in between)", d.Port))
+		return row
+	}
+	_ = conn.Close()
+	check("port", checkOK, addr+" accepts connections", "")
+
+	client := bluos.NewClient(dev.BaseURL(), bluos.Options{Timeout: httpTimeout})
+	status, err := client.Status(ctx, bluos.StatusOptions{})
+	if err != nil {
+		check("status", checkFail, err.Error(), "the port is open but the BluOS API is not answering; power-cycle the player")
+		return row
+	}
+	row.State = status.State
+	row.Name = cmp.Or(strings.TrimSpace(status.Name), row.Name)
+	row.Model = status.Model
+	check("status", checkOK, "/Status answers", "")
+
+	probeLatency(ctx, &row, dev, httpTimeout, samples, check)
+
+	if status.ETag == "" {
+		check("long-poll", checkWarn, "/Status has no etag", "update the player firmware; `blu watch` and `now --follow` rely on etag long-polling")
+		return row
+	}
+	poller := bluos.NewClient(dev.BaseURL(), bluos.Options{Timeout: httpTimeout + doctorLongPoll*time.Second})
+	start := time.Now()
+	polled, err := poller.Status(ctx, bluos.StatusOptions{TimeoutSeconds: doctorLongPoll, ETag: status.ETag})
+	held := time.Since(start)
+	switch {
+	case err != nil:
+		check("long-poll", checkWarn, "long-poll failed: "+err.Error(), "raise --timeout; a proxy or firewall may be cutting held connections")
+	case held >= doctorLongPollHeld:
+		check("long-poll", checkOK, fmt.Sprintf("etag honored (held %s)", held.Round(time.Millisecond)), "")
+	case polled.ETag != status.ETag:
+		check("long-poll", checkOK, "etag honored (state changed)", "")
+	default:
+		check("long-poll", checkWarn, fmt.Sprintf("etag ignored (answered in %s with the same etag)", held.Round(time.Millisecond)), "update the player firmware; `blu watch` and `now --follow` rely on etag long-polling")
+	}
+	return row
+}
+
+// probeLatency times raw /SyncStatus round trips and derives the clock
+// skew from the Date header of the fastest one.
+func probeLatency(ctx context.Context, row *doctorRow, dev config.Device, timeout time.Duration, samples int, check func(name, status, detail, hint string)) {
+	httpClient := &http.Client{Timeout: timeout}
+	url := dev.BaseURL().JoinPath("SyncStatus").String()
+
+	var rtts []time.Duration
+	var lastErr error
+	var skew time.Duration
+	best := time.Duration(math.MaxInt64)
+	haveDate := false
+	for range samples {
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+		if err != nil {
+			lastErr = err
+			break
+		}
+		start := time.Now()
+		resp, err := httpClient.Do(req)
+		if err != nil {
+			lastErr = err
+			continue
+		}
+		_, _ = io.Copy(io.Discard, resp.Body)
+		_ = resp.Body.Close()
+		rtt := time.Since(start)
+		if resp.StatusCode != http.StatusOK {
+			lastErr = fmt.Errorf("http %d", resp.StatusCode)
+			continue
+		}
+		rtts = append(rtts, rtt)
+		if date, err := http.ParseTime(resp.Header.Get("Date")); err == nil && rtt < best {
+			// Date is truncated to the second; compare its midpoint.
+			best, haveDate = rtt, true
+			skew = date.Add(500 * time.Millisecond).Sub(start.Add(rtt / 2))
+		}
+	}
+
+	if len(rtts) == 0 {
+		check("latency", checkFail, fmt.Sprintf("no /SyncStatus answer in %d tries: %v", samples, lastErr), "the player is flaky or overloaded; power-cycle it and check the network path")
+		return
+	}
+	slices.Sort(rtts)
+	row.Latency = &doctorLatency{
+		Samples: len(rtts),
+		P50MS:   durationMS(percentile(rtts, 50)),
+		P90MS:   durationMS(percentile(rtts, 90)),
+		P99MS:   durationMS(percentile(rtts, 99)),
+		MaxMS:   durationMS(rtts[len(rtts)-1]),
+	}
+	detail := fmt.Sprintf("p50 %gms, p90 %gms, p99 %gms (%d samples)", row.Latency.P50MS, row.Latency.P90MS, row.Latency.P99MS, len(rtts))
+	switch {
+	case len(rtts) < samples:
+		check("latency", checkWarn, fmt.Sprintf("%s; %d of %d requests failed: %v", detail, samples-len(rtts), samples, lastErr), "requests are being dropped; check Wi-Fi signal or use wired Ethernet")
+	case percentile(rtts, 90) > doctorLatencyWarn:
+		check("latency", checkWarn, detail, "slow network path (weak Wi-Fi, powerline); move the player to wired Ethernet or closer to the access point")
+	default:
+		check("latency", checkOK, detail, "")
+	}
+
+	if !haveDate {
+		check("clock", checkSkip, "player sends no Date header", "")
+		return
+	}
+	ms := skew.Milliseconds()
+	row.ClockSkewMS = &ms
+	detail = fmt.Sprintf("player clock %+.1fs from this host", skew.Seconds())
+	if skew.Abs() > doctorSkewWarn {
+		check("clock", checkWarn, detail, "the player syncs time via NTP over the internet; check its internet access (or this host's clock)")
+		return
+	}
+	check("clock", checkOK, detail, "")
+}
+
+// percentile is the nearest-rank percentile of sorted durations.
+func percentile(sorted []time.Duration, p int) time.Duration {
+	rank := int(math.Ceil(float64(p) / 100 * float64(len(sorted))))
+	return sorted[min(max(rank, 1), len(sorted))-1]
+}
+
+func durationMS(d time.Duration) float64 {
+	return math.Round(float64(d.Microseconds())/100) / 10
+}
+
+// discoveryChecks compares what mDNS and LSDP found on their own.
+func discoveryChecks(devices []discovery.Device, mechanisms []discovery.MechanismResult, rows doctorRows) []doctorCheck {
+	found := map[string]int{}
+	var checks []doctorCheck
+	for _, m := range mechanisms {
+		found[m.Mechanism] = len(m.Devices)
+		if m.Err == nil || errors.Is(m.Err, context.DeadlineExceeded) || errors.Is(m.Err, context.Canceled) {
+			continue
+		}
+		hint := "check the firewall and pick the LAN interface with --interface"
+		if m.Mechanism == "lsdp" {
+			hint = "UDP 11430 is probably held by another app (e.g. BluOS Controller) or blocked by a firewall"
+		}
+		checks = append(checks, doctorCheck{Check: "discovery", Status: checkWarn, Detail: m.Mechanism + " failed: " + m.Err.Error(), Hint: hint})
+	}
+
+	mdns, lsdp := found["mdns"], found["lsdp"]
+	switch {
+	case mdns == 0 && lsdp > 0:
+		checks = append(checks, doctorCheck{Check: "discovery", Status: checkWarn, Detail: fmt.Sprintf("mDNS found no players (LSDP found %d)", lsdp), Hint: "multicast is filtered (IGMP snooping, AP client isolation, VLANs); allow UDP 5353 multicast or use --interface"})
+	case lsdp == 0 && mdns > 0:
+		checks = append(checks, doctorCheck{Check: "discovery", Status: checkWarn, Detail: fmt.Sprintf("LSDP found no players (mDNS found %d)", mdns), Hint: "broadcasts to UDP 11430 are filtered or the port is held by another app (e.g. BluOS Controller)"})
+	case mdns > 0 && lsdp > 0:
+		disagree := 0
+		for i, d := range devices {
+			sawMDNS, sawLSDP := strings.Contains(d.Source, "mdns"), strings.Contains(d.Source, "lsdp")
+			if sawMDNS == sawLSDP {
+				continue
+			}
+			disagree++
+			only := "mdns"
+			if sawLSDP {
+				only = "lsdp"
+			}
+			rows[i].Checks = append(rows[i].Checks, doctorCheck{Check: "discovery", Device: d.ID, Status: checkWarn, Detail: "seen via " + only + " only", Hint: "the player or the network drops the other protocol; a longer --discover-timeout may help"})
+		}
+		if disagree == 0 {
+			checks = append(checks, doctorCheck{Check: "discovery", Status: checkOK, Detail: fmt.Sprintf("mDNS and LSDP agree on %d players", len(devices))})
+		}
+	}
+	return checks
+}
+
+// firmwareCheck flags players running different firmware versions.
+func firmwareCheck(rows doctorRows) []doctorCheck {
+	byVersion := map[string][]string{}
+	for _, r := range rows {
+		if r.Version != "" {
+			byVersion[r.Version] = append(byVersion[r.Version], r.label())
+		}
+	}
+	switch len(byVersion) {
+	case 0:
+		return []doctorCheck{{Check: "firmware", Status: checkSkip, Detail: "no player advertised a version"}}
+	case 1:
+		for v := range byVersion {
+			return []doctorCheck{{Check: "firmware", Status: checkOK, Detail: "all players on " + v}}
+		}
+	}
+	versions := make([]string, 0, len(byVersion))
+	for v := range byVersion {
+		versions = append(versions, v)
+	}
+	sort.Strings(versions)
+	parts := make([]string, 0, len(versions))
+	for _, v := range versions {
+		parts = append(parts, v+" ("+strings.Join(byVersion[v], ", ")+")")
+	}
+	return []doctorCheck{{Check: "firmware", Status: checkWarn, Detail: "mixed versions: " + strings.Join(parts, ", "), Hint: "update every player to the same BluOS release (BluOS app: Settings > Player > Check for updates); grouping across versions can misbehave"}}
+}
+
+// configChecks flags aliases and default_device that no longer reach a
+// player.
+func configChecks(ctx context.Context, cfg config.Config, cache config.DiscoveryCache, devices []discovery.Device) []doctorCheck {
+	var checks []doctorCheck
+	results := map[string]doctorCheck{}
+	aliases := make([]string, 0, len(cfg.Aliases))
+	for alias := range cfg.Aliases {
+		aliases = append(aliases, alias)
+	}
+	sort.Strings(aliases)
+	for _, alias := range aliases {
+		c, moved := configTargetCheck(ctx, cache, devices, cfg.Aliases[alias])
+		c.Check = "alias"
+		c.Detail = alias + " -> " + cfg.Aliases[alias] + ": " + c.Detail
+		if c.Status == checkFail {
+			c.Hint = fmt.Sprintf("blu alias add %s %s --force (or `blu alias learn`)", alias, cmp.Or(moved, "<device>"))
+		}
+		results[alias] = c
+		checks = append(checks, c)
+	}
+
+	if def := strings.TrimSpace(cfg.DefaultDevice); def != "" {
+		c, isAlias := results[def]
+		if isAlias {
+			c.Detail = "alias " + c.Detail
+		} else {
+			var moved string
+			c, moved = configTargetCheck(ctx, cache, devices, def)
+			c.Detail = def + ": " + c.Detail
+			if c.Status == checkFail {
+				c.Hint = "blu config set default_device " + cmp.Or(moved, "<device>")
+			}
+		}
+		c.Check = "default-device"
+		checks = append(checks, c)
+	}
+	return checks
+}
+
+// configTargetCheck resolves a configured device against the discovered
+// players; moved is the player's new address when the cache knows its MAC.
+func configTargetCheck(ctx context.Context, cache config.DiscoveryCache, devices []discovery.Device, target string) (check doctorCheck, moved string) {
+	if likelyNameArg(target) {
+		if d, ok := matchByName(target, devices); ok {
+			return doctorCheck{Status: checkOK, Detail: "matches " + net.JoinHostPort(d.Host, strconv.Itoa(d.Port))}, ""
+		}
+		return doctorCheck{Status: checkFail, Detail: "no discovered player has this name"}, ""
+	}
+	dev, err := config.ParseDevice(target)
+	if err != nil {
+		return doctorCheck{Status: checkFail, Detail: err.Error()}, ""
+	}
+	key := aliasTargetKey(target)
+	for _, d := range devices {
+		if net.JoinHostPort(d.Host, strconv.Itoa(d.Port)) == key {
+			return doctorCheck{Status: checkOK, Detail: "discovered " + cmp.Or(d.Name, d.ID)}, ""
+		}
+	}
+	if cached, ok := cache.Lookup(key); ok && cached.MAC != "" {
+		for _, d := range devices {
+			if d.MAC != "" && discovery.NormalizeMAC(d.MAC) == discovery.NormalizeMAC(cached.MAC) {
+				moved = net.JoinHostPort(d.Host, strconv.Itoa(d.Port))
+				return doctorCheck{Status: checkFail, Detail: "player moved to " + moved}, moved
+			}
+		}
+	}
+	if probeEndpoint(ctx, dev, time.Second) == nil {
+		return doctorCheck{Status: checkOK, Detail: "answers (not discovered)"}, ""
+	}
+	return doctorCheck{Status: checkFail, Detail: "not discovered and not answering"}, ""
+}
+
+// probeEndpoint checks that d accepts TCP connections.
+func probeEndpoint(ctx context.Context, d config.Device, timeout time.Duration) error {
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+	var dialer net.Dialer
+	conn, err := dialer.DialContext(ctx, "tcp", net.JoinHostPort(d.Host, strconv.Itoa(d.Port)))
+	if err != nil {
+		return err
+	}
+	return conn.Close()
+}
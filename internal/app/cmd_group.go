@@ -3,7 +3,6 @@ package app
 import (
 	"context"
 	"errors"
-	"io"
 	"strings"
 	"time"
 
@@ -12,7 +11,7 @@ import (
 	"github.com/steipete/blucli/internal/output"
 )
 
-func cmdGroup(ctx context.Context, out *output.Printer, cfg config.Config, cache config.DiscoveryCache, deviceArg string, allowDiscover bool, discoverTimeout, httpTimeout time.Duration, dryRun bool, trace io.Writer, args []string) int {
+func cmdGroup(ctx context.Context, out *output.Printer, cfg config.Config, cache config.DiscoveryCache, deviceArg string, allowDiscover bool, discoverTimeout, httpTimeout time.Duration, dryRun bool, trace bluos.Tracer, args []string) int {
 	if len(args) == 0 {
 		out.Errorf("group: missing subcommand (status|add|remove)")
 		return 2
@@ -46,6 +45,7 @@ func cmdGroup(ctx context.Context, out *output.Printer, cfg config.Config, cache
 		out.Errorf("device: %v", err)
 		return 1
 	}
+	out.SetDevice(device)
 
 	client := bluos.NewClient(device.BaseURL(), bluos.Options{Timeout: httpTimeout, DryRun: dryRun, Trace: trace})
 
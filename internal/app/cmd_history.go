@@ -0,0 +1,301 @@
+package app
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/steipete/blucli/internal/bluos"
+	"github.com/steipete/blucli/internal/config"
+	"github.com/steipete/blucli/internal/discovery"
+	"github.com/steipete/blucli/internal/history"
+	"github.com/steipete/blucli/internal/logging"
+	"github.com/steipete/blucli/internal/output"
+	"github.com/steipete/blucli/internal/scrobble"
+)
+
+const (
+	historyRetryDelay = 5 * time.Second
+	scrobbleTimeout   = 10 * time.Second
+)
+
+func cmdHistory(ctx context.Context, out *output.Printer, paths config.PathSet, cfg config.Config, cache config.DiscoveryCache, deviceArg string, allowDiscover bool, discoverTimeout, httpTimeout time.Duration, dryRun bool, trace bluos.Tracer, args []string) int {
+	sub := "list"
+	if len(args) > 0 && !strings.HasPrefix(args[0], "-") {
+		sub = args[0]
+		args = args[1:]
+	}
+
+	switch sub {
+	case "list", "stats":
+		return cmdHistoryQuery(out, paths, sub, args)
+	case "record":
+		return cmdHistoryRecord(ctx, out, paths, cfg, cache, deviceArg, allowDiscover, discoverTimeout, httpTimeout, dryRun, trace, args)
+	default:
+		out.Errorf("history: unknown subcommand %q (expected list|stats|record)", sub)
+		return 2
+	}
+}
+
+func cmdHistoryQuery(out *output.Printer, paths config.PathSet, sub string, args []string) int {
+	flags := flag.NewFlagSet("history "+sub, flag.ContinueOnError)
+	flags.SetOutput(out.Stderr())
+
+	var since string
+	var device string
+	var file string
+	var limit int
+	var top int
+	flags.StringVar(&since, "since", "", "only plays since (e.g. 36h, 7d, 2w, 2006-01-02)")
+	flags.StringVar(&device, "device", "", "only plays on this player (id or name)")
+	flags.StringVar(&file, "file", paths.HistoryPath, "history file")
+	flags.IntVar(&limit, "limit", 0, "show only the last n plays (list)")
+	flags.IntVar(&top, "top", 10, "number of top artists (stats)")
+
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+	if flags.NArg() > 0 {
+		out.Errorf("history %s: unexpected args: %q", sub, strings.Join(flags.Args(), " "))
+		return 2
+	}
+
+	sinceTime, err := history.ParseSince(since, time.Now())
+	if err != nil {
+		out.Errorf("history %s: %v", sub, err)
+		return 2
+	}
+
+	entries, err := history.Load(file)
+	if err != nil {
+		out.Errorf("history %s: %v", sub, err)
+		return 1
+	}
+	entries = history.Query(entries, history.Filter{Since: sinceTime, Device: device})
+
+	if sub == "stats" {
+		out.Print(history.Summarize(entries, top))
+		return 0
+	}
+	if limit > 0 && len(entries) > limit {
+		entries = entries[len(entries)-limit:]
+	}
+	out.Print(entries)
+	return 0
+}
+
+func cmdHistoryRecord(ctx context.Context, out *output.Printer, paths config.PathSet, cfg config.Config, cache config.DiscoveryCache, deviceArg string, allowDiscover bool, discoverTimeout, httpTimeout time.Duration, dryRun bool, trace bluos.Tracer, args []string) int {
+	flags := flag.NewFlagSet("history record", flag.ContinueOnError)
+	flags.SetOutput(out.Stderr())
+
+	var all bool
+	var file string
+	var withScrobble bool
+	flags.BoolVar(&all, "all", false, "record every known player")
+	flags.StringVar(&file, "file", paths.HistoryPath, "history file")
+	flags.BoolVar(&withScrobble, "scrobble", false, "also scrobble completed tracks")
+
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+	if flags.NArg() > 0 {
+		out.Errorf("history record: unexpected args: %q", strings.Join(flags.Args(), " "))
+		return 2
+	}
+
+	var scrobbler *scrobble.Scrobbler
+	if withScrobble {
+		s, err := newScrobbler(paths, cfg)
+		if err != nil {
+			out.Errorf("history record: %v", err)
+			return 1
+		}
+		scrobbler = s
+	}
+
+	sink := &historySink{path: file}
+	return runRecorders(ctx, out, cfg, cache, deviceArg, allowDiscover, discoverTimeout, httpTimeout, dryRun, trace, all, "history record", func(device config.Device) *historyRecorder {
+		rec := newHistoryRecorder(out, device)
+		rec.sink = sink
+		rec.scrobbler = scrobbler
+		rec.echo = true
+		return rec
+	})
+}
+
+func runRecorders(ctx context.Context, out *output.Printer, cfg config.Config, cache config.DiscoveryCache, deviceArg string, allowDiscover bool, discoverTimeout, httpTimeout time.Duration, dryRun bool, trace bluos.Tracer, all bool, label string, newRecorder func(config.Device) *historyRecorder) int {
+	var devices []config.Device
+	if all {
+		devices = cache.Devices
+		if len(devices) == 0 && allowDiscover {
+			dctx, cancel := context.WithTimeout(ctx, discoverTimeout)
+			found, err := discovery.Discover(dctx)
+			cancel()
+			if err != nil && !errors.Is(err, context.DeadlineExceeded) {
+				out.Errorf("%s: discover: %v", label, err)
+				return 1
+			}
+			for _, d := range found {
+				devices = append(devices, config.Device{ID: d.ID, Host: d.Host, Port: d.Port, Name: d.Name, Type: d.Type})
+			}
+		}
+		if len(devices) == 0 {
+			out.Errorf("%s: no devices known (run `blu devices`)", label)
+			return 1
+		}
+	} else {
+		device, err := resolveDevice(ctx, cfg, cache, deviceArg, allowDiscover, discoverTimeout)
+		if err != nil {
+			out.Errorf("device: %v", err)
+			return 1
+		}
+		out.SetDevice(device)
+		devices = []config.Device{device}
+	}
+
+	var wg sync.WaitGroup
+	for _, device := range devices {
+		wg.Add(1)
+		go func(device config.Device) {
+			defer wg.Done()
+			client := bluos.NewClient(device.BaseURL(), bluos.Options{Timeout: longPollTimeout(httpTimeout), DryRun: dryRun, Trace: trace})
+			recordLoop(ctx, client, newRecorder(device), label)
+		}(device)
+	}
+	wg.Wait()
+	return 0
+}
+
+func recordLoop(ctx context.Context, client *bluos.Client, rec *historyRecorder, label string) {
+	defer rec.flush(ctx)
+
+	var lastETag string
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		default:
+		}
+
+		status, err := client.Status(ctx, bluos.StatusOptions{TimeoutSeconds: 30, ETag: lastETag})
+		if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+			continue
+		}
+		if err != nil {
+			logging.From(ctx).Warn("status long-poll failed; retrying", "command", label, "device", rec.device.ID, "delay", historyRetryDelay, "err", err)
+			lastETag = ""
+			select {
+			case <-ctx.Done():
+				return
+			case <-time.After(historyRetryDelay):
+			}
+			continue
+		}
+		lastETag = status.ETag
+		rec.observe(ctx, status)
+	}
+}
+
+type historySink struct {
+	path string
+	mu   sync.Mutex
+}
+
+func (s *historySink) write(e history.Entry) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return history.Append(s.path, e)
+}
+
+// historyRecorder feeds Status snapshots of one player into a tracker and
+// hands completed plays to the history file and/or the scrobbler.
+type historyRecorder struct {
+	out       *output.Printer
+	device    config.Device
+	tracker   *history.Tracker
+	sink      *historySink
+	scrobbler *scrobble.Scrobbler
+	echo      bool
+
+	nowPlaying string
+}
+
+func newHistoryRecorder(out *output.Printer, device config.Device) *historyRecorder {
+	return &historyRecorder{
+		out:     out,
+		device:  device,
+		tracker: history.NewTracker(device.ID, device.Name),
+	}
+}
+
+func (r *historyRecorder) observe(ctx context.Context, status bluos.Status) {
+	if r == nil {
+		return
+	}
+	if e, ok := r.tracker.Observe(status, time.Now()); ok {
+		r.complete(ctx, e)
+	}
+	r.announce(ctx)
+}
+
+func (r *historyRecorder) flush(ctx context.Context) {
+	if r == nil {
+		return
+	}
+	if e, ok := r.tracker.Flush(time.Now()); ok {
+		// The caller's context is usually cancelled by now; still give the
+		// scrobbler a chance before falling back to the queue.
+		ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), scrobbleTimeout)
+		defer cancel()
+		r.complete(ctx, e)
+	}
+}
+
+func (r *historyRecorder) complete(ctx context.Context, e history.Entry) {
+	if r.sink != nil {
+		if err := r.sink.write(e); err != nil {
+			logging.From(ctx).Warn("history write failed", "device", r.device.ID, "err", err)
+		}
+	}
+	if r.scrobbler != nil && scrobble.Eligible(e) {
+		ctx, cancel := context.WithTimeout(ctx, scrobbleTimeout)
+		defer cancel()
+		if err := r.scrobbler.Scrobble(ctx, scrobble.FromEntry(e)); err != nil {
+			logging.From(ctx).Warn("scrobble failed", "device", r.device.ID, "err", err)
+		}
+	}
+	if r.echo {
+		r.out.Print(e)
+	}
+}
+
+func (r *historyRecorder) announce(ctx context.Context) {
+	if r.scrobbler == nil {
+		return
+	}
+	cur, ok := r.tracker.Current()
+	if !ok || !scrobble.HasMetadata(cur) {
+		r.nowPlaying = ""
+		return
+	}
+	key := cur.StartedAt.String() + "\x1f" + cur.Artist + "\x1f" + cur.Title
+	if key == r.nowPlaying {
+		return
+	}
+	r.nowPlaying = key
+	ctx, cancel := context.WithTimeout(ctx, scrobbleTimeout)
+	defer cancel()
+	if err := r.scrobbler.NowPlaying(ctx, scrobble.FromEntry(cur)); err != nil {
+		logging.From(ctx).Warn("scrobble now playing failed", "device", r.device.ID, "err", err)
+	}
+}
+
+func longPollTimeout(httpTimeout time.Duration) time.Duration {
+	if httpTimeout < 40*time.Second {
+		return 40 * time.Second
+	}
+	return httpTimeout
+}
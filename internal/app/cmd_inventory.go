@@ -0,0 +1,422 @@
+package app
+
+import (
+	"bytes"
+	"cmp"
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"net"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/steipete/blucli/internal/bluos"
+	"github.com/steipete/blucli/internal/config"
+	"github.com/steipete/blucli/internal/discovery"
+	"github.com/steipete/blucli/internal/output"
+)
+
+// Group roles of an inventory row.
+const (
+	roleStandalone = "standalone"
+	roleMaster     = "master"
+	roleSlave      = "slave"
+)
+
+// Change kinds of an inventory diff entry.
+const (
+	inventoryNew       = "new"
+	inventoryMissing   = "missing"
+	inventoryChanged   = "changed"
+	inventoryUnchanged = "unchanged"
+)
+
+type inventoryRow struct {
+	ID        string `json:"id"`
+	Name      string `json:"name,omitempty"`
+	Brand     string `json:"brand,omitempty"`
+	Model     string `json:"model,omitempty"`
+	ModelName string `json:"model_name,omitempty"`
+	Firmware  string `json:"firmware,omitempty"`
+	Host      string `json:"host"`
+	Port      int    `json:"port"`
+	MAC       string `json:"mac,omitempty"`
+	// GroupRole is standalone, master or slave; Master is the group
+	// master's address for slaves.
+	GroupRole string `json:"group_role,omitempty"`
+	Group     string `json:"group,omitempty"`
+	Master    string `json:"master,omitempty"`
+	// Source is the current playback service (Status service).
+	Source    string `json:"source,omitempty"`
+	Reachable bool   `json:"reachable"`
+	LatencyMS int64  `json:"latency_ms"`
+	Error     string `json:"error,omitempty"`
+}
+
+func (r inventoryRow) address() string {
+	if r.Host == "" {
+		return ""
+	}
+	return net.JoinHostPort(r.Host, strconv.Itoa(r.Port))
+}
+
+func (r inventoryRow) label() string {
+	return cmp.Or(strings.TrimSpace(r.Name), r.ID)
+}
+
+func (r inventoryRow) model() string {
+	return strings.TrimSpace(cmp.Or(r.ModelName, r.Model))
+}
+
+func (r inventoryRow) cells() []string {
+	return []string{r.ID, r.Name, r.Brand, r.Model, r.ModelName, r.Firmware, r.Host, strconv.Itoa(r.Port), r.MAC, r.GroupRole, r.Group, r.Master, r.Source, strconv.FormatBool(r.Reachable), strconv.FormatInt(r.LatencyMS, 10), r.Error}
+}
+
+var inventoryHeader = []string{"id", "name", "brand", "model", "model_name", "firmware", "host", "port", "mac", "group_role", "group", "master", "source", "reachable", "latency_ms", "error"}
+
+// humanCells is the condensed human table row.
+func (r inventoryRow) humanCells() []string {
+	group := r.GroupRole
+	if r.Group != "" {
+		group += " (" + r.Group + ")"
+	}
+	latency := ""
+	if r.Reachable {
+		latency = strconv.FormatInt(r.LatencyMS, 10) + "ms"
+	} else if r.Error != "" {
+		latency = "unreachable"
+	}
+	return []string{r.label(), strings.TrimSpace(r.Brand + " " + r.model()), r.Firmware, r.address(), r.MAC, group, r.Source, latency}
+}
+
+var inventoryHumanHeader = []string{"name", "model", "firmware", "address", "mac", "group", "source", "latency"}
+
+type inventoryRows []inventoryRow
+
+func (rows inventoryRows) TableHeader() []string { return inventoryHeader }
+
+func (rows inventoryRows) TableRows() [][]string {
+	out := make([][]string, 0, len(rows))
+	for _, r := range rows {
+		out = append(out, r.cells())
+	}
+	return out
+}
+
+func (rows inventoryRows) PrintHuman(p *output.Printer) {
+	if len(rows) == 0 {
+		p.Printf("no players\n")
+		return
+	}
+	unreachable := 0
+	table := make([][]string, 0, len(rows))
+	for _, r := range rows {
+		if !r.Reachable {
+			unreachable++
+		}
+		table = append(table, r.humanCells())
+	}
+	p.Table(inventoryHumanHeader, table, func(i int) string {
+		if !rows[i].Reachable {
+			return output.Red
+		}
+		return ""
+	})
+
+	summary := p.Paint(output.Green, fmt.Sprintf("%d players, all reachable", len(rows)))
+	if unreachable > 0 {
+		summary = p.Paint(output.Red, fmt.Sprintf("%d players, %d unreachable", len(rows), unreachable))
+	}
+	p.Printf("\n%s\n", summary)
+}
+
+type inventoryEntry struct {
+	inventoryRow
+	// Change is new, missing, changed or unchanged; missing entries carry
+	// the previous row.
+	Change  string   `json:"change"`
+	Changes []string `json:"changes,omitempty"`
+}
+
+type inventoryDiff []inventoryEntry
+
+func (d inventoryDiff) TableHeader() []string {
+	return append(slices.Clone(inventoryHeader), "change", "changes")
+}
+
+func (d inventoryDiff) TableRows() [][]string {
+	out := make([][]string, 0, len(d))
+	for _, e := range d {
+		out = append(out, append(e.cells(), e.Change, strings.Join(e.Changes, "; ")))
+	}
+	return out
+}
+
+func (d inventoryDiff) PrintHuman(p *output.Printer) {
+	counts := map[string]int{}
+	table := make([][]string, 0, len(d))
+	for _, e := range d {
+		counts[e.Change]++
+		change := e.Change
+		if len(e.Changes) > 0 {
+			change += ": " + strings.Join(e.Changes, ", ")
+		}
+		table = append(table, append(e.humanCells(), change))
+	}
+	p.Table(append(slices.Clone(inventoryHumanHeader), "change"), table, func(i int) string {
+		return inventoryChangeColor(d[i].Change)
+	})
+
+	var parts []string
+	for _, kind := range []string{inventoryNew, inventoryMissing, inventoryChanged} {
+		if counts[kind] > 0 {
+			parts = append(parts, p.Paint(inventoryChangeColor(kind), fmt.Sprintf("%d %s", counts[kind], kind)))
+		}
+	}
+	if len(parts) == 0 {
+		parts = append(parts, p.Paint(output.Green, "no changes"))
+	}
+	p.Printf("\n%d players: %s\n", len(d)-counts[inventoryMissing], strings.Join(parts, ", "))
+}
+
+func inventoryChangeColor(kind string) string {
+	switch kind {
+	case inventoryNew:
+		return output.Green
+	case inventoryMissing:
+		return output.Red
+	case inventoryChanged:
+		return output.Yellow
+	default:
+		return ""
+	}
+}
+
+func cmdInventory(ctx context.Context, out *output.Printer, discoverTimeout, httpTimeout time.Duration, trace bluos.Tracer, args []string) int {
+	flags := flag.NewFlagSet("inventory", flag.ContinueOnError)
+	flags.SetOutput(out.Stderr())
+	diffPath := flags.String("diff", "", "compare against a previous `blu inventory --json` file")
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+	if flags.NArg() > 0 {
+		out.Errorf("inventory: unexpected args: %q", strings.Join(flags.Args(), " "))
+		return 2
+	}
+
+	var previous []inventoryRow
+	if *diffPath != "" {
+		var err error
+		previous, err = loadInventory(*diffPath)
+		if err != nil {
+			out.Errorf("inventory --diff: %v", err)
+			return 1
+		}
+	}
+
+	discoverCtx, cancel := context.WithTimeout(ctx, discoverTimeout)
+	defer cancel()
+	devices, err := discovery.Discover(discoverCtx)
+	if err != nil && !errors.Is(err, context.DeadlineExceeded) {
+		out.Errorf("inventory discover: %v", err)
+		return 1
+	}
+	if len(devices) == 0 {
+		out.Warnf("no devices discovered (run with longer --discover-timeout)")
+	}
+
+	rows := collectInventory(ctx, devices, httpTimeout, trace)
+	if *diffPath != "" {
+		out.Print(diffInventory(previous, rows))
+		return 0
+	}
+	out.Print(rows)
+	return 0
+}
+
+// collectInventory queries SyncStatus and Status of every player
+// concurrently. Latency is the SyncStatus round trip.
+func collectInventory(ctx context.Context, devices []discovery.Device, timeout time.Duration, trace bluos.Tracer) inventoryRows {
+	rows := make(inventoryRows, len(devices))
+	var wg sync.WaitGroup
+	for i, d := range devices {
+		rows[i] = inventoryRow{
+			ID:        d.ID,
+			Name:      d.Name,
+			Brand:     d.Brand,
+			Model:     d.Model,
+			ModelName: d.ModelName,
+			Firmware:  d.Version,
+			Host:      d.Host,
+			Port:      d.Port,
+			MAC:       d.MAC,
+		}
+		wg.Add(1)
+		go func(r *inventoryRow) {
+			defer wg.Done()
+			dev := config.Device{Host: r.Host, Port: r.Port}
+			client := bluos.NewClient(dev.BaseURL(), bluos.Options{Timeout: timeout, Trace: trace})
+
+			var status bluos.Status
+			var statusErr error
+			statusDone := make(chan struct{})
+			go func() {
+				defer close(statusDone)
+				status, statusErr = client.Status(ctx, bluos.StatusOptions{})
+			}()
+
+			start := time.Now()
+			syncStatus, syncErr := client.SyncStatus(ctx, bluos.SyncStatusOptions{})
+			latency := time.Since(start)
+			<-statusDone
+
+			if syncErr == nil {
+				r.Reachable = true
+				r.LatencyMS = latency.Milliseconds()
+				r.Name = cmp.Or(strings.TrimSpace(syncStatus.Name), r.Name)
+				r.Brand = cmp.Or(r.Brand, strings.TrimSpace(syncStatus.Brand))
+				r.Model = cmp.Or(r.Model, strings.TrimSpace(syncStatus.Model))
+				r.ModelName = cmp.Or(r.ModelName, strings.TrimSpace(syncStatus.ModelName))
+				if r.MAC == "" {
+					r.MAC = discovery.NormalizeMAC(syncStatus.MAC)
+				}
+				r.Group = strings.TrimSpace(syncStatus.Group)
+				r.GroupRole = groupRole(syncStatus)
+				if syncStatus.Master != nil {
+					r.Master = net.JoinHostPort(syncStatus.Master.Host, strconv.Itoa(cmp.Or(syncStatus.Master.Port, 11000)))
+				}
+			}
+			if statusErr == nil {
+				r.Source = strings.TrimSpace(status.Service)
+				r.Group = cmp.Or(r.Group, strings.TrimSpace(status.GroupName))
+			}
+			var errs []string
+			if syncErr != nil {
+				errs = append(errs, "syncstatus: "+syncErr.Error())
+			}
+			if statusErr != nil {
+				errs = append(errs, "status: "+statusErr.Error())
+			}
+			r.Error = strings.Join(errs, "; ")
+		}(&rows[i])
+	}
+	wg.Wait()
+
+	slices.SortStableFunc(rows, func(a, b inventoryRow) int {
+		return cmp.Or(cmp.Compare(strings.ToLower(a.label()), strings.ToLower(b.label())), cmp.Compare(a.ID, b.ID))
+	})
+	return rows
+}
+
+func groupRole(s bluos.SyncStatus) string {
+	switch {
+	case s.Master != nil:
+		return roleSlave
+	case len(s.Slaves) > 0:
+		return roleMaster
+	default:
+		return roleStandalone
+	}
+}
+
+// loadInventory reads a previous inventory: a JSON array of rows, an
+// --envelope document, or an earlier --diff (missing entries are dropped).
+func loadInventory(path string) ([]inventoryRow, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	data = bytes.TrimSpace(data)
+	if bytes.HasPrefix(data, []byte("{")) {
+		var env struct {
+			Data json.RawMessage `json:"data"`
+		}
+		if err := json.Unmarshal(data, &env); err != nil {
+			return nil, fmt.Errorf("%s: %w", path, err)
+		}
+		if len(env.Data) == 0 {
+			return nil, fmt.Errorf("%s: envelope has no data", path)
+		}
+		data = env.Data
+	}
+	var entries []inventoryEntry
+	if err := json.Unmarshal(data, &entries); err != nil {
+		return nil, fmt.Errorf("%s: not a blu inventory: %w", path, err)
+	}
+	rows := make([]inventoryRow, 0, len(entries))
+	for _, e := range entries {
+		if e.Change != inventoryMissing {
+			rows = append(rows, e.inventoryRow)
+		}
+	}
+	return rows, nil
+}
+
+// diffInventory pairs current rows with previous ones by MAC, then ID,
+// then name, and lists unpaired previous rows as missing.
+func diffInventory(previous []inventoryRow, current inventoryRows) inventoryDiff {
+	used := make([]bool, len(previous))
+	match := func(same func(a, b inventoryRow) bool, r inventoryRow) int {
+		for i, prev := range previous {
+			if !used[i] && same(prev, r) {
+				return i
+			}
+		}
+		return -1
+	}
+	matchers := []func(a, b inventoryRow) bool{
+		func(a, b inventoryRow) bool { return a.MAC != "" && a.MAC == b.MAC },
+		func(a, b inventoryRow) bool { return a.ID == b.ID },
+		func(a, b inventoryRow) bool { return a.Name != "" && strings.EqualFold(a.Name, b.Name) },
+	}
+
+	diff := make(inventoryDiff, 0, len(current)+len(previous))
+	for _, r := range current {
+		entry := inventoryEntry{inventoryRow: r, Change: inventoryNew}
+		for _, same := range matchers {
+			if i := match(same, r); i >= 0 {
+				used[i] = true
+				entry.Changes = inventoryChanges(previous[i], r)
+				entry.Change = inventoryUnchanged
+				if len(entry.Changes) > 0 {
+					entry.Change = inventoryChanged
+				}
+				break
+			}
+		}
+		diff = append(diff, entry)
+	}
+	for i, prev := range previous {
+		if !used[i] {
+			diff = append(diff, inventoryEntry{inventoryRow: prev, Change: inventoryMissing})
+		}
+	}
+	return diff
+}
+
+func inventoryChanges(prev, cur inventoryRow) []string {
+	var changes []string
+	field := func(name, a, b string) {
+		if a != b {
+			changes = append(changes, fmt.Sprintf("%s %s -> %s", name, cmp.Or(a, "-"), cmp.Or(b, "-")))
+		}
+	}
+	field("name", prev.Name, cur.Name)
+	field("model", prev.model(), cur.model())
+	field("firmware", prev.Firmware, cur.Firmware)
+	field("address", prev.address(), cur.address())
+	if prev.MAC != "" && cur.MAC != "" {
+		field("mac", prev.MAC, cur.MAC)
+	}
+	field("group", prev.GroupRole, cur.GroupRole)
+	if prev.Reachable != cur.Reachable {
+		field("reachable", strconv.FormatBool(prev.Reachable), strconv.FormatBool(cur.Reachable))
+	}
+	return changes
+}
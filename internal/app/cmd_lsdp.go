@@ -0,0 +1,134 @@
+package app
+
+import (
+	"context"
+	"encoding/hex"
+	"flag"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/steipete/blucli/internal/discovery"
+	"github.com/steipete/blucli/internal/output"
+)
+
+type lsdpPacket struct {
+	discovery.LSDPPacket
+	hexDump bool
+}
+
+func (pk lsdpPacket) TableHeader() []string {
+	return []string{"time", "from", "type", "node_id", "address", "classes", "txt", "error"}
+}
+
+// TableRows prints one row per message (a row with just the error for
+// undecodable packets).
+func (pk lsdpPacket) TableRows() [][]string {
+	ts := pk.Time.Format(time.RFC3339Nano)
+	if len(pk.Messages) == 0 {
+		return [][]string{{ts, pk.From, "", "", "", "", "", pk.Error}}
+	}
+	rows := make([][]string, 0, len(pk.Messages))
+	for _, m := range pk.Messages {
+		var classes, txt []string
+		for _, r := range m.Records {
+			classes = append(classes, classLabel(r.LSDPClass))
+			txt = append(txt, formatTXT(r.TXT))
+		}
+		for _, c := range m.Classes {
+			classes = append(classes, classLabel(c))
+		}
+		rows = append(rows, []string{ts, pk.From, m.Type, m.NodeID, m.Address, strings.Join(classes, " "), strings.Join(txt, "; "), pk.Error})
+	}
+	return rows
+}
+
+func (pk lsdpPacket) PrintHuman(p *output.Printer) {
+	p.Printf("%s %s %d bytes\n", pk.Time.Format("15:04:05.000"), pk.From, pk.Size)
+	for _, m := range pk.Messages {
+		line := "  " + p.Paint(output.Yellow, m.Type)
+		if m.NodeID != "" {
+			line += " node " + m.NodeID
+		}
+		if m.Address != "" {
+			line += " at " + m.Address
+		}
+		for _, c := range m.Classes {
+			line += " " + classLabel(c)
+		}
+		p.Printf("%s\n", line)
+		for _, r := range m.Records {
+			p.Printf("    %s %s\n", classLabel(r.LSDPClass), formatTXT(r.TXT))
+		}
+	}
+	if pk.Error != "" {
+		p.Printf("  %s\n", p.Paint(output.Red, pk.Error))
+	}
+	if pk.hexDump {
+		raw, _ := hex.DecodeString(pk.Hex)
+		for _, line := range strings.SplitAfter(strings.TrimRight(hex.Dump(raw), "\n"), "\n") {
+			p.Printf("  %s", p.Paint(output.Dim, line))
+		}
+		p.Printf("\n")
+	}
+}
+
+func classLabel(c discovery.LSDPClass) string {
+	if c.Role == "" {
+		return c.Class
+	}
+	return c.Class + "(" + c.Role + ")"
+}
+
+func formatTXT(txt map[string]string) string {
+	keys := make([]string, 0, len(txt))
+	for k := range txt {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	parts := make([]string, 0, len(keys))
+	for _, k := range keys {
+		parts = append(parts, k+"="+txt[k])
+	}
+	return strings.Join(parts, " ")
+}
+
+func cmdLSDP(ctx context.Context, out *output.Printer, args []string) int {
+	if len(args) == 0 {
+		out.Errorf("lsdp: missing subcommand (dump)")
+		return 2
+	}
+	if args[0] != "dump" {
+		out.Errorf("lsdp: unknown subcommand %q (expected dump)", args[0])
+		return 2
+	}
+
+	flags := flag.NewFlagSet("lsdp dump", flag.ContinueOnError)
+	flags.SetOutput(out.Stderr())
+	duration := flags.Duration("duration", 0, "stop after this long (default: until interrupted)")
+	passive := flags.Bool("passive", false, "only listen; do not send query packets")
+	requery := flags.Duration("requery", 0, "re-send the query this often after the startup schedule")
+	showHex := flags.Bool("hex", false, "include a hex dump of each packet (human output)")
+	if err := flags.Parse(args[1:]); err != nil {
+		return 2
+	}
+	if flags.NArg() > 0 {
+		out.Errorf("lsdp dump: unexpected args: %q", strings.Join(flags.Args(), " "))
+		return 2
+	}
+	if *duration > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, *duration)
+		defer cancel()
+	}
+
+	opts := discovery.LSDPDumpOptions{Query: !*passive, Requery: *requery}
+	err := discovery.DumpLSDP(ctx, opts, func(pk discovery.LSDPPacket) {
+		out.Print(lsdpPacket{LSDPPacket: pk, hexDump: *showHex})
+	})
+	if err != nil {
+		out.Errorf("lsdp dump: %v (is another app, e.g. BluOS Controller, holding UDP 11430?)", err)
+		return 1
+	}
+	return 0
+}
@@ -0,0 +1,64 @@
+package app
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/steipete/blucli/internal/discovery"
+	"github.com/steipete/blucli/internal/output"
+)
+
+func TestCmdLSDP_Usage(t *testing.T) {
+	t.Parallel()
+
+	var stdout, stderr bytes.Buffer
+	out := output.New(output.Options{Stdout: &stdout, Stderr: &stderr})
+	if code := cmdLSDP(context.Background(), out, nil); code != 2 {
+		t.Fatalf("code=%d", code)
+	}
+	if code := cmdLSDP(context.Background(), out, []string{"sniff"}); code != 2 || !strings.Contains(stderr.String(), `unknown subcommand "sniff"`) {
+		t.Fatalf("code=%d stderr=%q", code, stderr.String())
+	}
+}
+
+func TestCmdLSDP_DumpPrintsPackets(t *testing.T) {
+	// No t.Parallel: uses UDP port binding.
+
+	tmp, err := net.ListenUDP("udp4", &net.UDPAddr{IP: net.IPv4zero, Port: 0})
+	if err != nil {
+		t.Fatalf("listen udp: %v", err)
+	}
+	port := tmp.LocalAddr().(*net.UDPAddr).Port
+	_ = tmp.Close()
+
+	go func() {
+		time.Sleep(25 * time.Millisecond)
+		conn, err := net.DialUDP("udp4", nil, &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1), Port: port})
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		// Delete message for node 90:56:82:9f:02:78, class 0x0001.
+		_, _ = conn.Write([]byte{6, 'L', 'S', 'D', 'P', 1, 12, 'D', 6, 0x90, 0x56, 0x82, 0x9f, 0x02, 0x78, 1, 0x00, 0x01})
+	}()
+
+	ctx := discovery.WithLSDPPortOverride(context.Background(), port)
+	var stdout, stderr bytes.Buffer
+	out := output.New(output.Options{Mode: output.ModeNDJSON, Stdout: &stdout, Stderr: &stderr})
+	if code := cmdLSDP(ctx, out, []string{"dump", "--passive", "--duration", "300ms"}); code != 0 {
+		t.Fatalf("code=%d stderr=%q", code, stderr.String())
+	}
+
+	var pk discovery.LSDPPacket
+	if err := json.Unmarshal(bytes.TrimSpace(stdout.Bytes()), &pk); err != nil {
+		t.Fatalf("json: %v\n%s", err, stdout.String())
+	}
+	if len(pk.Messages) != 1 || pk.Messages[0].Type != "delete" || pk.Messages[0].NodeID != "90:56:82:9f:02:78" || pk.Messages[0].Classes[0].Role != "player" {
+		t.Fatalf("packet = %+v", pk)
+	}
+}
@@ -2,26 +2,316 @@ package app
 
 import (
 	"context"
-	"io"
+	"errors"
+	"flag"
+	"fmt"
+	"strconv"
+	"strings"
 	"time"
 
 	"github.com/steipete/blucli/internal/bluos"
 	"github.com/steipete/blucli/internal/config"
+	"github.com/steipete/blucli/internal/logging"
 	"github.com/steipete/blucli/internal/output"
 )
 
-func cmdNow(ctx context.Context, out *output.Printer, cfg config.Config, cache config.DiscoveryCache, deviceArg string, allowDiscover bool, discoverTimeout, httpTimeout time.Duration, dryRun bool, trace io.Writer) int {
+type nowPlaying struct {
+	bluos.Status
+	Badge string              `json:"badge,omitempty"`
+	Group *bluos.SyncStatus   `json:"group,omitempty"`
+	Next  *bluos.PlaylistSong `json:"next,omitempty"`
+}
+
+func cmdNow(ctx context.Context, out *output.Printer, cfg config.Config, cache config.DiscoveryCache, deviceArg string, allowDiscover bool, discoverTimeout, httpTimeout time.Duration, dryRun bool, trace bluos.Tracer, args []string) int {
+	flags := flag.NewFlagSet("now", flag.ContinueOnError)
+	flags.SetOutput(out.Stderr())
+	follow := flags.Bool("follow", false, "keep updating via long-poll")
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+
 	device, resolveErr := resolveDevice(ctx, cfg, cache, deviceArg, allowDiscover, discoverTimeout)
 	if resolveErr != nil {
 		out.Errorf("device: %v", resolveErr)
 		return 1
 	}
+	out.SetDevice(device)
+
+	if *follow {
+		client := bluos.NewClient(device.BaseURL(), bluos.Options{Timeout: longPollTimeout(httpTimeout), DryRun: dryRun, Trace: trace})
+		return followNow(ctx, out, client)
+	}
+
 	client := bluos.NewClient(device.BaseURL(), bluos.Options{Timeout: httpTimeout, DryRun: dryRun, Trace: trace})
 	status, err := client.Status(ctx, bluos.StatusOptions{})
 	if err != nil {
 		out.Errorf("now: %v", err)
 		return 1
 	}
-	out.Print(status)
+	out.Print(buildNowPlaying(ctx, client, status))
 	return 0
 }
+
+// buildNowPlaying adds group and next-track context; both are best-effort.
+func buildNowPlaying(ctx context.Context, client *bluos.Client, status bluos.Status) nowPlaying {
+	now := nowPlaying{Status: status, Badge: qualityBadge(status.Quality, status.StreamFormat)}
+
+	if sync, err := client.SyncStatus(ctx, bluos.SyncStatusOptions{}); err == nil && (sync.Master != nil || len(sync.Slaves) > 0) {
+		now.Group = &sync
+	}
+
+	if status.TotLen > 0 {
+		next := status.Song + 1
+		if pl, err := client.Playlist(ctx, bluos.PlaylistOptions{Start: &next, End: &next}); err == nil {
+			for i := range pl.Songs {
+				if pl.Songs[i].ID == next {
+					now.Next = &pl.Songs[i]
+					break
+				}
+			}
+		}
+	}
+	return now
+}
+
+type nowUpdate struct {
+	now nowPlaying
+	at  time.Time
+	err error
+}
+
+// viewAt returns the view advanced to t, and false while nothing is playing.
+func (u nowUpdate) viewAt(t time.Time) (nowPlaying, bool) {
+	if u.at.IsZero() {
+		return nowPlaying{}, false
+	}
+	switch strings.TrimSpace(u.now.State) {
+	case "play", "stream":
+	default:
+		return nowPlaying{}, false
+	}
+	view := u.now
+	view.Secs += int(t.Sub(u.at) / time.Second)
+	if view.TotLen > 0 && view.Secs > view.TotLen {
+		view.Secs = view.TotLen
+	}
+	return view, true
+}
+
+func followNow(ctx context.Context, out *output.Printer, client *bluos.Client) int {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	updates := make(chan nowUpdate)
+	go func() {
+		var lastETag string
+		for {
+			status, err := client.Status(ctx, bluos.StatusOptions{TimeoutSeconds: 30, ETag: lastETag})
+			if ctx.Err() != nil {
+				return
+			}
+			if errors.Is(err, context.DeadlineExceeded) {
+				logging.From(ctx).Debug("long-poll ended without change; polling again", "endpoint", "/Status", "err", err)
+				continue
+			}
+			if err != nil {
+				select {
+				case updates <- nowUpdate{err: err}:
+				case <-ctx.Done():
+				}
+				return
+			}
+			if status.ETag != "" && status.ETag == lastETag {
+				continue
+			}
+			lastETag = status.ETag
+			select {
+			case updates <- nowUpdate{now: buildNowPlaying(ctx, client, status), at: time.Now()}:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	live := out.Interactive()
+	var ticks <-chan time.Time
+	if live {
+		ticker := time.NewTicker(time.Second)
+		defer ticker.Stop()
+		ticks = ticker.C
+	}
+
+	var current nowUpdate
+	for {
+		select {
+		case <-ctx.Done():
+			return 0
+		case u := <-updates:
+			if u.err != nil {
+				out.Errorf("now: %v", u.err)
+				return 1
+			}
+			current = u
+			if !live {
+				out.Print(current.now)
+				continue
+			}
+		case t := <-ticks:
+			view, ok := current.viewAt(t)
+			if !ok {
+				continue
+			}
+			out.Printf("\x1b[H\x1b[2J")
+			out.Print(view)
+			continue
+		}
+		out.Printf("\x1b[H\x1b[2J")
+		out.Print(current.now)
+	}
+}
+
+func (n nowPlaying) PrintHuman(p *output.Printer) {
+	title := strings.TrimSpace(n.Title)
+	artist := strings.TrimSpace(n.Artist)
+	album := strings.TrimSpace(n.Album)
+
+	icon, iconColor := stateIcon(n.State)
+	if title == "" {
+		p.Printf("%s %s\n", p.Paint(iconColor, icon), p.Paint(output.Dim, "(nothing playing)"))
+	} else {
+		p.Printf("%s %s\n", p.Paint(iconColor, icon), p.Paint(output.Bold, title))
+		if artist != "" {
+			p.Printf("  %s\n", artist)
+		}
+		if album != "" {
+			p.Printf("  %s\n", p.Paint(output.Dim, album))
+		}
+		p.Printf("  %s\n", progressLine(p, n.Secs, n.TotLen))
+	}
+
+	var meta []string
+	if service := strings.TrimSpace(n.Service); service != "" {
+		meta = append(meta, service)
+	}
+	if n.Badge != "" {
+		meta = append(meta, p.Paint(output.Cyan, n.Badge))
+	}
+	vol := fmt.Sprintf("vol %d", n.Volume)
+	if n.Mute {
+		vol += " " + p.Paint(output.Yellow, "muted")
+	}
+	meta = append(meta, vol)
+	p.Printf("  %s\n", strings.Join(meta, " · "))
+
+	if group := groupLine(n); group != "" {
+		p.Printf("  %s %s\n", p.Paint(output.Dim, "group"), group)
+	}
+	if n.Next != nil {
+		next := strings.TrimSpace(n.Next.Title)
+		if next == "" {
+			next = strings.TrimSpace(n.Next.Fn)
+		}
+		if a := strings.TrimSpace(n.Next.Artist); a != "" {
+			next = a + " — " + next
+		}
+		p.Printf("  %s  %s\n", p.Paint(output.Dim, "next"), next)
+	}
+}
+
+func stateIcon(state string) (string, string) {
+	switch strings.TrimSpace(state) {
+	case "play", "stream":
+		return "▶", output.Green
+	case "pause":
+		return "⏸", output.Yellow
+	case "connecting":
+		return "…", output.Yellow
+	default:
+		return "■", output.Dim
+	}
+}
+
+const progressWidth = 30
+
+func progressLine(p *output.Printer, secs, total int) string {
+	if total <= 0 {
+		return fmt.Sprintf("%s  %s", output.FormatSeconds(secs), p.Paint(output.Dim, "live"))
+	}
+	width := progressWidth
+	if w := p.Width(); w > 0 && w-16 < width {
+		width = max(10, w-16)
+	}
+	filled := 0
+	if secs > 0 {
+		filled = min(width, secs*width/total)
+	}
+	bar := p.Paint(output.Cyan, strings.Repeat("━", filled)) + p.Paint(output.Dim, strings.Repeat("─", width-filled))
+	return fmt.Sprintf("%s %s %s", output.FormatSeconds(secs), bar, output.FormatSeconds(total))
+}
+
+func groupLine(n nowPlaying) string {
+	name := strings.TrimSpace(n.GroupName)
+	if n.Group == nil {
+		return name
+	}
+	if name == "" {
+		name = strings.TrimSpace(n.Group.Group)
+	}
+	if name == "" {
+		name = "(unnamed)"
+	}
+	if m := n.Group.Master; m != nil {
+		return fmt.Sprintf("%s (follows %s:%d)", name, strings.TrimSpace(m.Host), m.Port)
+	}
+	return fmt.Sprintf("%s (leader, %d players)", name, len(n.Group.Slaves)+1)
+}
+
+// qualityBadge condenses BluOS quality/streamFormat into e.g. "FLAC 24/96",
+// "MQA" or "320 kbps".
+func qualityBadge(quality, streamFormat string) string {
+	quality = strings.TrimSpace(quality)
+	badge := ""
+	if codec, rest, ok := strings.Cut(strings.TrimSpace(streamFormat), " "); ok {
+		if rate, bits, ok := strings.Cut(rest, "/"); ok {
+			r, rerr := strconv.Atoi(rate)
+			b, berr := strconv.Atoi(bits)
+			if rerr == nil && berr == nil && r > 0 && b > 0 {
+				badge = fmt.Sprintf("%s %d/%s", codec, b, strconv.FormatFloat(float64(r)/1000, 'f', -1, 64))
+			}
+		}
+	}
+	if badge == "" {
+		badge = strings.TrimSpace(streamFormat)
+	}
+
+	label := ""
+	switch strings.ToLower(quality) {
+	case "":
+	case "cd":
+		label = "CD"
+	case "hd":
+		label = "Hi-Res"
+	case "mqa":
+		label = "MQA"
+	case "mqaauthored":
+		label = "MQA Studio"
+	case "dolbyaudio":
+		label = "Dolby"
+	default:
+		if bps, err := strconv.Atoi(quality); err == nil && bps > 0 {
+			label = fmt.Sprintf("%d kbps", bps/1000)
+		}
+	}
+
+	switch {
+	case badge == "":
+		return label
+	case strings.HasPrefix(label, "MQA") || label == "Dolby":
+		return label + " " + badge
+	case strings.HasSuffix(label, "kbps") && !strings.Contains(badge, " "):
+		// A bare codec such as "MP3" keeps the bitrate next to it.
+		return badge + " " + label
+	default:
+		return badge
+	}
+}
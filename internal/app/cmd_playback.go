@@ -4,7 +4,6 @@ import (
 	"context"
 	"errors"
 	"flag"
-	"io"
 	"strings"
 	"time"
 
@@ -13,12 +12,13 @@ import (
 	"github.com/steipete/blucli/internal/output"
 )
 
-func cmdPlayback(ctx context.Context, out *output.Printer, cfg config.Config, cache config.DiscoveryCache, deviceArg string, allowDiscover bool, discoverTimeout, httpTimeout time.Duration, dryRun bool, trace io.Writer, verb string, args []string) int {
+func cmdPlayback(ctx context.Context, out *output.Printer, cfg config.Config, cache config.DiscoveryCache, deviceArg string, allowDiscover bool, discoverTimeout, httpTimeout time.Duration, dryRun bool, trace bluos.Tracer, verb string, args []string) int {
 	device, resolveErr := resolveDevice(ctx, cfg, cache, deviceArg, allowDiscover, discoverTimeout)
 	if resolveErr != nil {
 		out.Errorf("device: %v", resolveErr)
 		return 1
 	}
+	out.SetDevice(device)
 
 	client := bluos.NewClient(device.BaseURL(), bluos.Options{Timeout: httpTimeout, DryRun: dryRun, Trace: trace})
 
@@ -86,7 +86,7 @@ func cmdPlayback(ctx context.Context, out *output.Printer, cfg config.Config, ca
 	return 0
 }
 
-func cmdShuffle(ctx context.Context, out *output.Printer, cfg config.Config, cache config.DiscoveryCache, deviceArg string, allowDiscover bool, discoverTimeout, httpTimeout time.Duration, dryRun bool, trace io.Writer, args []string) int {
+func cmdShuffle(ctx context.Context, out *output.Printer, cfg config.Config, cache config.DiscoveryCache, deviceArg string, allowDiscover bool, discoverTimeout, httpTimeout time.Duration, dryRun bool, trace bluos.Tracer, args []string) int {
 	if len(args) == 0 {
 		out.Errorf("shuffle: missing arg (on|off)")
 		return 2
@@ -97,6 +97,7 @@ func cmdShuffle(ctx context.Context, out *output.Printer, cfg config.Config, cac
 		out.Errorf("device: %v", err)
 		return 1
 	}
+	out.SetDevice(device)
 
 	client := bluos.NewClient(device.BaseURL(), bluos.Options{Timeout: httpTimeout, DryRun: dryRun, Trace: trace})
 
@@ -125,7 +126,7 @@ func cmdShuffle(ctx context.Context, out *output.Printer, cfg config.Config, cac
 	}
 }
 
-func cmdRepeat(ctx context.Context, out *output.Printer, cfg config.Config, cache config.DiscoveryCache, deviceArg string, allowDiscover bool, discoverTimeout, httpTimeout time.Duration, dryRun bool, trace io.Writer, args []string) int {
+func cmdRepeat(ctx context.Context, out *output.Printer, cfg config.Config, cache config.DiscoveryCache, deviceArg string, allowDiscover bool, discoverTimeout, httpTimeout time.Duration, dryRun bool, trace bluos.Tracer, args []string) int {
 	if len(args) == 0 {
 		out.Errorf("repeat: missing arg (off|track|queue)")
 		return 2
@@ -136,6 +137,7 @@ func cmdRepeat(ctx context.Context, out *output.Printer, cfg config.Config, cach
 		out.Errorf("device: %v", err)
 		return 1
 	}
+	out.SetDevice(device)
 
 	client := bluos.NewClient(device.BaseURL(), bluos.Options{Timeout: httpTimeout, DryRun: dryRun, Trace: trace})
 
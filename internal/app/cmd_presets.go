@@ -3,7 +3,6 @@ package app
 import (
 	"context"
 	"errors"
-	"io"
 	"time"
 
 	"github.com/steipete/blucli/internal/bluos"
@@ -11,7 +10,7 @@ import (
 	"github.com/steipete/blucli/internal/output"
 )
 
-func cmdPresets(ctx context.Context, out *output.Printer, cfg config.Config, cache config.DiscoveryCache, deviceArg string, allowDiscover bool, discoverTimeout, httpTimeout time.Duration, dryRun bool, trace io.Writer, args []string) int {
+func cmdPresets(ctx context.Context, out *output.Printer, cfg config.Config, cache config.DiscoveryCache, deviceArg string, allowDiscover bool, discoverTimeout, httpTimeout time.Duration, dryRun bool, trace bluos.Tracer, args []string) int {
 	if len(args) == 0 {
 		out.Errorf("presets: missing subcommand (list|load)")
 		return 2
@@ -22,6 +21,7 @@ func cmdPresets(ctx context.Context, out *output.Printer, cfg config.Config, cac
 		out.Errorf("device: %v", resolveErr)
 		return 1
 	}
+	out.SetDevice(device)
 	client := bluos.NewClient(device.BaseURL(), bluos.Options{Timeout: httpTimeout, DryRun: dryRun, Trace: trace})
 
 	switch args[0] {
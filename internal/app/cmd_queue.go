@@ -3,7 +3,6 @@ package app
 import (
 	"context"
 	"errors"
-	"io"
 	"strings"
 	"time"
 
@@ -12,7 +11,7 @@ import (
 	"github.com/steipete/blucli/internal/output"
 )
 
-func cmdQueue(ctx context.Context, out *output.Printer, cfg config.Config, cache config.DiscoveryCache, deviceArg string, allowDiscover bool, discoverTimeout, httpTimeout time.Duration, dryRun bool, trace io.Writer, args []string) int {
+func cmdQueue(ctx context.Context, out *output.Printer, cfg config.Config, cache config.DiscoveryCache, deviceArg string, allowDiscover bool, discoverTimeout, httpTimeout time.Duration, dryRun bool, trace bluos.Tracer, args []string) int {
 	if len(args) == 0 {
 		out.Errorf("queue: missing subcommand (list|clear|delete|move|save)")
 		return 2
@@ -76,6 +75,7 @@ func cmdQueue(ctx context.Context, out *output.Printer, cfg config.Config, cache
 		out.Errorf("device: %v", resolveErr)
 		return 1
 	}
+	out.SetDevice(device)
 	client := bluos.NewClient(device.BaseURL(), bluos.Options{Timeout: httpTimeout, DryRun: dryRun, Trace: trace})
 
 	switch sub[0] {
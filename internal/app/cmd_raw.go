@@ -3,7 +3,6 @@ package app
 import (
 	"context"
 	"errors"
-	"io"
 	"strings"
 	"time"
 
@@ -12,7 +11,16 @@ import (
 	"github.com/steipete/blucli/internal/output"
 )
 
-func cmdRaw(ctx context.Context, out *output.Printer, cfg config.Config, cache config.DiscoveryCache, deviceArg string, allowDiscover bool, discoverTimeout, httpTimeout time.Duration, dryRun bool, trace io.Writer, args []string) int {
+type rawResponse struct {
+	Path string `json:"path"`
+	XML  string `json:"xml"`
+}
+
+func (r rawResponse) PrintHuman(p *output.Printer) {
+	p.Print(bluos.RawXML{XML: r.XML})
+}
+
+func cmdRaw(ctx context.Context, out *output.Printer, cfg config.Config, cache config.DiscoveryCache, deviceArg string, allowDiscover bool, discoverTimeout, httpTimeout time.Duration, dryRun bool, trace bluos.Tracer, args []string) int {
 	var (
 		params   []string
 		mutating bool
@@ -57,6 +65,7 @@ func cmdRaw(ctx context.Context, out *output.Printer, cfg config.Config, cache c
 		out.Errorf("device: %v", resolveErr)
 		return 1
 	}
+	out.SetDevice(device)
 
 	query := map[string]string{}
 	for _, p := range params {
@@ -78,9 +87,6 @@ func cmdRaw(ctx context.Context, out *output.Printer, cfg config.Config, cache c
 		return 1
 	}
 
-	out.Print(map[string]any{
-		"path": path,
-		"xml":  string(data),
-	})
+	out.Print(rawResponse{Path: path, XML: string(data)})
 	return 0
 }
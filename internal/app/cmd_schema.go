@@ -0,0 +1,120 @@
+package app
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/steipete/blucli/internal/config"
+	"github.com/steipete/blucli/internal/output"
+)
+
+// appSchemas extends output.Schemas with the command-local result types.
+func appSchemas() []output.Schema {
+	return []output.Schema{
+		output.NewSchema("blu.now/v1", nowPlaying{}),
+		output.NewSchema("blu.art/v1", artInfo{}),
+		output.NewSchema("blu.config/v1", config.Config{}),
+		output.NewSchema("blu.aliases/v1", aliasRows{}),
+		output.NewSchema("blu.alias-learn/v1", aliasProposals{}),
+		output.NewSchema("blu.config-value/v1", configValue{}),
+		output.NewSchema("blu.config-path/v1", configPathInfo{}),
+		output.NewSchema("blu.config-validate/v1", configValidation{}),
+		output.NewSchema("blu.config-effective/v1", effectiveSettings{}),
+		output.NewSchema("blu.device-event/v1", deviceEvent{}),
+		output.NewSchema("blu.lsdp-packet/v1", lsdpPacket{}),
+		output.NewSchema("blu.volume/v1", volumeLevel{}),
+		output.NewSchema("blu.raw/v1", rawResponse{}),
+		output.NewSchema("blu.doctor/v2", doctorReport{}),
+		output.NewSchema("blu.diag/v1", diagReport{}),
+		output.NewSchema("blu.diag-bundle/v1", diagBundle{}),
+		output.NewSchema("blu.inventory/v1", inventoryRows{}),
+		output.NewSchema("blu.inventory-diff/v1", inventoryDiff{}),
+		output.NewSchema("blu.scrobble-flush/v1", scrobbleFlushResult{}),
+		output.NewSchema("blu.spotify-play/v1", spotifyPlayResult{}),
+	}
+}
+
+func allSchemas() []output.Schema {
+	return append(output.Schemas(), appSchemas()...)
+}
+
+func schemaDocument(name string) (map[string]any, bool) {
+	if name == output.ErrorSchema {
+		return output.ErrorJSONSchema(), true
+	}
+	for _, s := range allSchemas() {
+		if s.Name == name {
+			return s.JSONSchema(), true
+		}
+	}
+	return nil, false
+}
+
+func marshalSchema(doc map[string]any) ([]byte, error) {
+	data, err := json.MarshalIndent(doc, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	return append(data, '\n'), nil
+}
+
+func cmdSchema(out *output.Printer, args []string) int {
+	flags := flag.NewFlagSet("schema", flag.ContinueOnError)
+	flags.SetOutput(out.Stderr())
+	dir := flags.String("dir", "", "write all schemas to this directory")
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+
+	if *dir != "" {
+		if err := os.MkdirAll(*dir, 0o755); err != nil {
+			out.Errorf("schema: %v", err)
+			return 1
+		}
+		names := []string{output.ErrorSchema}
+		for _, s := range allSchemas() {
+			names = append(names, s.Name)
+		}
+		for _, name := range names {
+			doc, _ := schemaDocument(name)
+			data, err := marshalSchema(doc)
+			if err != nil {
+				out.Errorf("schema %s: %v", name, err)
+				return 1
+			}
+			path := filepath.Join(*dir, output.Schema{Name: name}.FileName())
+			if err := os.WriteFile(path, data, 0o644); err != nil {
+				out.Errorf("schema: %v", err)
+				return 1
+			}
+		}
+		return 0
+	}
+
+	rest := flags.Args()
+	if len(rest) == 0 {
+		for _, s := range allSchemas() {
+			fmt.Fprintln(out.Stdout(), s.Name)
+		}
+		fmt.Fprintln(out.Stdout(), output.ErrorSchema)
+		return 0
+	}
+
+	name := strings.TrimSpace(rest[0])
+	doc, ok := schemaDocument(name)
+	if !ok {
+		out.Errorf("schema: unknown schema %q (see `blu schema`)", name)
+		return 2
+	}
+	data, err := marshalSchema(doc)
+	if err != nil {
+		out.Errorf("schema: %v", err)
+		return 1
+	}
+	_, _ = out.Stdout().Write(data)
+	return 0
+}
@@ -0,0 +1,127 @@
+package app
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/steipete/blucli/internal/bluos"
+	"github.com/steipete/blucli/internal/config"
+	"github.com/steipete/blucli/internal/logging"
+	"github.com/steipete/blucli/internal/output"
+	"github.com/steipete/blucli/internal/scrobble"
+)
+
+type scrobbleFlushResult struct {
+	Submitted int `json:"submitted"`
+}
+
+func (r scrobbleFlushResult) PrintHuman(p *output.Printer) {
+	if r.Submitted == 0 {
+		p.Printf("nothing to submit\n")
+		return
+	}
+	p.Printf("submitted %d listens\n", r.Submitted)
+}
+
+func cmdScrobble(ctx context.Context, out *output.Printer, paths config.PathSet, cfg config.Config, cache config.DiscoveryCache, deviceArg string, allowDiscover bool, discoverTimeout, httpTimeout time.Duration, dryRun bool, trace bluos.Tracer, args []string) int {
+	if len(args) == 0 {
+		out.Errorf("scrobble: missing subcommand (run|flush|queue)")
+		return 2
+	}
+
+	sub := args[0]
+	flags := flag.NewFlagSet("scrobble "+sub, flag.ContinueOnError)
+	flags.SetOutput(out.Stderr())
+
+	var all bool
+	flags.StringVar(&paths.ScrobbleQueuePath, "queue", paths.ScrobbleQueuePath, "offline retry queue file")
+	if sub == "run" {
+		flags.BoolVar(&all, "all", false, "scrobble every known player")
+	}
+	if err := flags.Parse(args[1:]); err != nil {
+		return 2
+	}
+	if flags.NArg() > 0 {
+		out.Errorf("scrobble %s: unexpected args: %q", sub, strings.Join(flags.Args(), " "))
+		return 2
+	}
+
+	switch sub {
+	case "run":
+		scrobbler, err := newScrobbler(paths, cfg)
+		if err != nil {
+			out.Errorf("scrobble run: %v", err)
+			return 1
+		}
+		if n, err := scrobbler.Flush(ctx); err != nil {
+			logging.From(ctx).Warn("scrobble queue flush failed", "err", err)
+		} else if n > 0 {
+			out.Infof("scrobble: submitted %d queued listen(s)", n)
+		}
+		return runRecorders(ctx, out, cfg, cache, deviceArg, allowDiscover, discoverTimeout, httpTimeout, dryRun, trace, all, "scrobble run", func(device config.Device) *historyRecorder {
+			rec := newHistoryRecorder(out, device)
+			rec.scrobbler = scrobbler
+			return rec
+		})
+	case "flush":
+		scrobbler, err := newScrobbler(paths, cfg)
+		if err != nil {
+			out.Errorf("scrobble flush: %v", err)
+			return 1
+		}
+		n, err := scrobbler.Flush(ctx)
+		if err != nil {
+			out.Errorf("scrobble flush: %v", err)
+			return 1
+		}
+		out.Print(scrobbleFlushResult{Submitted: n})
+		return 0
+	case "queue":
+		listens, err := scrobble.LoadQueue(paths.ScrobbleQueuePath)
+		if err != nil {
+			out.Errorf("scrobble queue: %v", err)
+			return 1
+		}
+		if listens == nil {
+			listens = []scrobble.Listen{}
+		}
+		out.Print(listens)
+		return 0
+	default:
+		out.Errorf("scrobble: unknown subcommand %q (expected run|flush|queue)", sub)
+		return 2
+	}
+}
+
+func newScrobbler(paths config.PathSet, cfg config.Config) (*scrobble.Scrobbler, error) {
+	url := strings.TrimSpace(os.Getenv("BLU_SCROBBLE_URL"))
+	if url == "" {
+		url = strings.TrimSpace(cfg.Scrobble.URL)
+	}
+	token := strings.TrimSpace(os.Getenv("BLU_SCROBBLE_TOKEN"))
+	if token == "" {
+		stored, err := config.LoadScrobbleToken(paths.ConfigPath, cfg)
+		if err != nil {
+			return nil, fmt.Errorf("scrobble token: %w", err)
+		}
+		token = strings.TrimSpace(stored)
+	}
+	if token == "" {
+		return nil, fmt.Errorf("missing scrobble token (set BLU_SCROBBLE_TOKEN or config scrobble.token)")
+	}
+	client, err := scrobble.NewClient(scrobble.Options{
+		URL:           url,
+		Token:         token,
+		ClientVersion: Version,
+		HTTP:          &http.Client{Timeout: scrobbleTimeout},
+	})
+	if err != nil {
+		return nil, err
+	}
+	return scrobble.NewScrobbler(client, paths.ScrobbleQueuePath), nil
+}
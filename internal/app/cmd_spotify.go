@@ -3,7 +3,6 @@ package app
 import (
 	"context"
 	"errors"
-	"io"
 	"time"
 
 	"github.com/steipete/blucli/internal/bluos"
@@ -11,7 +10,7 @@ import (
 	"github.com/steipete/blucli/internal/output"
 )
 
-func cmdSpotify(ctx context.Context, out *output.Printer, paths config.PathSet, cfg config.Config, cache config.DiscoveryCache, deviceArg string, allowDiscover bool, discoverTimeout, httpTimeout time.Duration, dryRun bool, trace io.Writer, args []string) int {
+func cmdSpotify(ctx context.Context, out *output.Printer, paths config.PathSet, cfg config.Config, cache config.DiscoveryCache, deviceArg string, allowDiscover bool, discoverTimeout, httpTimeout time.Duration, dryRun bool, trace bluos.Tracer, args []string) int {
 	if len(args) == 0 {
 		out.Errorf("spotify: missing subcommand (login|logout|open|devices|search|play)")
 		return 2
@@ -21,8 +20,7 @@ func cmdSpotify(ctx context.Context, out *output.Printer, paths config.PathSet,
 	case "login":
 		return cmdSpotifyLogin(ctx, out, paths, cfg, args[1:])
 	case "logout":
-		cfg.Spotify.Token = config.SpotifyToken{}
-		if err := config.SaveConfig(paths.ConfigPath, cfg); err != nil {
+		if err := config.ClearSpotifyToken(paths.ConfigPath); err != nil {
 			out.Errorf("spotify logout: %v", err)
 			return 1
 		}
@@ -33,6 +31,7 @@ func cmdSpotify(ctx context.Context, out *output.Printer, paths config.PathSet,
 			out.Errorf("device: %v", resolveErr)
 			return 1
 		}
+		out.SetDevice(device)
 		client := bluos.NewClient(device.BaseURL(), bluos.Options{Timeout: httpTimeout, DryRun: dryRun, Trace: trace})
 		if err := client.Play(ctx, bluos.PlayOptions{URL: "Spotify:play"}); err != nil {
 			if errors.Is(err, bluos.ErrDryRun) {
@@ -155,15 +155,18 @@ func cmdSpotifyLogin(ctx context.Context, out *output.Printer, paths config.Path
 		return 1
 	}
 
-	cfg.Spotify.ClientID = clientID
-	cfg.Spotify.Token = config.SpotifyToken{
-		AccessToken:  tok.AccessToken,
-		RefreshToken: tok.RefreshToken,
-		ExpiresAt:    tok.ExpiresAt,
-		TokenType:    tok.TokenType,
-		Scope:        tok.Scope,
-	}
-	if err := config.SaveConfig(paths.ConfigPath, cfg); err != nil {
+	err = config.Update(paths.ConfigPath, func(c *config.Config) error {
+		c.Spotify.ClientID = clientID
+		c.Spotify.Token = config.SpotifyToken{
+			AccessToken:  tok.AccessToken,
+			RefreshToken: tok.RefreshToken,
+			ExpiresAt:    tok.ExpiresAt,
+			TokenType:    tok.TokenType,
+			Scope:        tok.Scope,
+		}
+		return nil
+	})
+	if err != nil {
 		out.Errorf("spotify login: %v", err)
 		return 1
 	}
@@ -4,7 +4,7 @@ import (
 	"context"
 	"errors"
 	"flag"
-	"io"
+	"fmt"
 	"net/http"
 	"os"
 	"strings"
@@ -16,7 +16,22 @@ import (
 	"github.com/steipete/blucli/internal/spotify"
 )
 
-func cmdSpotifyPlay(ctx context.Context, out *output.Printer, paths config.PathSet, cfg config.Config, cache config.DiscoveryCache, deviceArg string, allowDiscover bool, discoverTimeout, httpTimeout time.Duration, dryRun bool, trace io.Writer, args []string) int {
+type spotifyPlayResult struct {
+	Type   string `json:"type"`
+	URI    string `json:"uri,omitempty"`
+	Artist string `json:"artist,omitempty"`
+	Count  int    `json:"count,omitempty"`
+}
+
+func (r spotifyPlayResult) PrintHuman(p *output.Printer) {
+	if r.Type == "artist" {
+		p.Printf("playing %d top tracks by %s\n", r.Count, r.Artist)
+		return
+	}
+	p.Printf("playing %s\n", r.URI)
+}
+
+func cmdSpotifyPlay(ctx context.Context, out *output.Printer, paths config.PathSet, cfg config.Config, cache config.DiscoveryCache, deviceArg string, allowDiscover bool, discoverTimeout, httpTimeout time.Duration, dryRun bool, trace bluos.Tracer, args []string) int {
 	flags := flag.NewFlagSet("spotify play", flag.ContinueOnError)
 	flags.SetOutput(out.Stderr())
 
@@ -53,6 +68,7 @@ func cmdSpotifyPlay(ctx context.Context, out *output.Printer, paths config.PathS
 		out.Errorf("device: %v", resolveErr)
 		return 1
 	}
+	out.SetDevice(device)
 
 	accessToken, _, err := spotifyAccessToken(ctx, paths, cfg)
 	if err != nil {
@@ -144,7 +160,7 @@ func cmdSpotifyPlay(ctx context.Context, out *output.Printer, paths config.PathS
 			out.Errorf("spotify play: %v", err)
 			return 1
 		}
-		out.Print(map[string]any{"type": "track", "uri": uri})
+		out.Print(spotifyPlayResult{Type: "track", URI: uri})
 		return 0
 	case "artist":
 		if pick < 0 || pick >= len(res.Artists.Items) {
@@ -184,7 +200,7 @@ func cmdSpotifyPlay(ctx context.Context, out *output.Printer, paths config.PathS
 			out.Errorf("spotify play: %v", err)
 			return 1
 		}
-		out.Print(map[string]any{"type": "artist", "artist": artist.Name, "count": len(uris)})
+		out.Print(spotifyPlayResult{Type: "artist", Artist: artist.Name, Count: len(uris)})
 		return 0
 	default:
 		out.Errorf("spotify play: unknown --type %q (expected auto|artist|track)", playType)
@@ -258,7 +274,10 @@ func spotifyAccessToken(ctx context.Context, paths config.PathSet, cfg config.Co
 		return "", cfg, errors.New("missing spotify client id (run `blu spotify login` or set SPOTIFY_CLIENT_ID)")
 	}
 
-	tok := cfg.Spotify.Token
+	tok, err := config.LoadSpotifyToken(paths.ConfigPath, cfg)
+	if err != nil {
+		return "", cfg, fmt.Errorf("spotify token: %w", err)
+	}
 	stored := spotify.Token{
 		AccessToken:  strings.TrimSpace(tok.AccessToken),
 		RefreshToken: strings.TrimSpace(tok.RefreshToken),
@@ -292,15 +311,19 @@ func spotifyAccessToken(ctx context.Context, paths config.PathSet, cfg config.Co
 	if refreshed.RefreshToken == "" {
 		refreshed.RefreshToken = stored.RefreshToken
 	}
-	cfg.Spotify.ClientID = clientID
-	cfg.Spotify.Token = config.SpotifyToken{
-		AccessToken:  refreshed.AccessToken,
-		RefreshToken: refreshed.RefreshToken,
-		ExpiresAt:    refreshed.ExpiresAt,
-		TokenType:    refreshed.TokenType,
-		Scope:        refreshed.Scope,
-	}
-	if err := config.SaveConfig(paths.ConfigPath, cfg); err != nil {
+	err = config.Update(paths.ConfigPath, func(c *config.Config) error {
+		c.Spotify.ClientID = clientID
+		c.Spotify.Token = config.SpotifyToken{
+			AccessToken:  refreshed.AccessToken,
+			RefreshToken: refreshed.RefreshToken,
+			ExpiresAt:    refreshed.ExpiresAt,
+			TokenType:    refreshed.TokenType,
+			Scope:        refreshed.Scope,
+		}
+		cfg = *c
+		return nil
+	})
+	if err != nil {
 		return "", cfg, err
 	}
 	return refreshed.AccessToken, cfg, nil
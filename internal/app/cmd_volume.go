@@ -3,7 +3,7 @@ package app
 import (
 	"context"
 	"errors"
-	"io"
+	"fmt"
 	"time"
 
 	"github.com/steipete/blucli/internal/bluos"
@@ -11,7 +11,24 @@ import (
 	"github.com/steipete/blucli/internal/output"
 )
 
-func cmdVolume(ctx context.Context, out *output.Printer, cfg config.Config, cache config.DiscoveryCache, deviceArg string, allowDiscover bool, discoverTimeout, httpTimeout time.Duration, dryRun bool, trace io.Writer, args []string) int {
+type volumeLevel struct {
+	Volume int           `json:"volume"`
+	DB     float64       `json:"db"`
+	Mute   bluos.BoolInt `json:"mute"`
+}
+
+func (v volumeLevel) PrintHuman(p *output.Printer) {
+	line := fmt.Sprintf("volume %d", v.Volume)
+	if v.DB != 0 {
+		line += fmt.Sprintf(" (%.1f dB)", v.DB)
+	}
+	if v.Mute {
+		line += " " + p.Paint(output.Yellow, "muted")
+	}
+	p.Printf("%s\n", line)
+}
+
+func cmdVolume(ctx context.Context, out *output.Printer, cfg config.Config, cache config.DiscoveryCache, deviceArg string, allowDiscover bool, discoverTimeout, httpTimeout time.Duration, dryRun bool, trace bluos.Tracer, args []string) int {
 	if len(args) == 0 {
 		out.Errorf("volume: missing subcommand (get|set|up|down)")
 		return 2
@@ -22,6 +39,7 @@ func cmdVolume(ctx context.Context, out *output.Printer, cfg config.Config, cach
 		out.Errorf("device: %v", err)
 		return 1
 	}
+	out.SetDevice(device)
 
 	client := bluos.NewClient(device.BaseURL(), bluos.Options{Timeout: httpTimeout, DryRun: dryRun, Trace: trace})
 
@@ -32,7 +50,7 @@ func cmdVolume(ctx context.Context, out *output.Printer, cfg config.Config, cach
 			out.Errorf("status: %v", err)
 			return 1
 		}
-		out.Print(map[string]any{"volume": status.Volume, "db": status.DB, "mute": status.Mute})
+		out.Print(volumeLevel{Volume: status.Volume, DB: status.DB, Mute: status.Mute})
 		return 0
 	case "set":
 		if len(args) < 2 {
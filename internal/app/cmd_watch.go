@@ -3,16 +3,17 @@ package app
 import (
 	"context"
 	"errors"
+	"flag"
 	"fmt"
-	"io"
 	"time"
 
 	"github.com/steipete/blucli/internal/bluos"
 	"github.com/steipete/blucli/internal/config"
+	"github.com/steipete/blucli/internal/logging"
 	"github.com/steipete/blucli/internal/output"
 )
 
-func cmdWatch(ctx context.Context, out *output.Printer, cfg config.Config, cache config.DiscoveryCache, deviceArg string, allowDiscover bool, discoverTimeout, httpTimeout time.Duration, dryRun bool, trace io.Writer, args []string) int {
+func cmdWatch(ctx context.Context, out *output.Printer, paths config.PathSet, cfg config.Config, cache config.DiscoveryCache, deviceArg string, allowDiscover bool, discoverTimeout, httpTimeout time.Duration, dryRun bool, trace bluos.Tracer, args []string) int {
 	if len(args) == 0 {
 		out.Errorf("watch: missing type (status|sync)")
 		return 2
@@ -23,15 +24,41 @@ func cmdWatch(ctx context.Context, out *output.Printer, cfg config.Config, cache
 		out.Errorf("device: %v", resolveErr)
 		return 1
 	}
-	clientTimeout := httpTimeout
-	if clientTimeout < 40*time.Second {
-		clientTimeout = 40 * time.Second
-	}
-	client := bluos.NewClient(device.BaseURL(), bluos.Options{Timeout: clientTimeout, DryRun: dryRun, Trace: trace})
+	out.SetDevice(device)
+	client := bluos.NewClient(device.BaseURL(), bluos.Options{Timeout: longPollTimeout(httpTimeout), DryRun: dryRun, Trace: trace})
 
 	switch args[0] {
 	case "status":
-		return watchStatus(ctx, out, client)
+		flags := flag.NewFlagSet("watch status", flag.ContinueOnError)
+		flags.SetOutput(out.Stderr())
+
+		var record bool
+		var historyFile string
+		var withScrobble bool
+		flags.BoolVar(&record, "record", false, "also record completed tracks to the listening history")
+		flags.StringVar(&historyFile, "history-file", paths.HistoryPath, "history file (with --record)")
+		flags.BoolVar(&withScrobble, "scrobble", false, "also scrobble completed tracks")
+
+		if err := flags.Parse(args[1:]); err != nil {
+			return 2
+		}
+
+		var rec *historyRecorder
+		if record || withScrobble {
+			rec = newHistoryRecorder(out, device)
+		}
+		if record {
+			rec.sink = &historySink{path: historyFile}
+		}
+		if withScrobble {
+			scrobbler, err := newScrobbler(paths, cfg)
+			if err != nil {
+				out.Errorf("watch status: %v", err)
+				return 1
+			}
+			rec.scrobbler = scrobbler
+		}
+		return watchStatus(ctx, out, client, rec)
 	case "sync":
 		return watchSync(ctx, out, client)
 	default:
@@ -40,7 +67,9 @@ func cmdWatch(ctx context.Context, out *output.Printer, cfg config.Config, cache
 	}
 }
 
-func watchStatus(ctx context.Context, out *output.Printer, client *bluos.Client) int {
+func watchStatus(ctx context.Context, out *output.Printer, client *bluos.Client, rec *historyRecorder) int {
+	defer rec.flush(ctx)
+
 	var lastETag string
 	for {
 		select {
@@ -51,12 +80,14 @@ func watchStatus(ctx context.Context, out *output.Printer, client *bluos.Client)
 
 		status, err := client.Status(ctx, bluos.StatusOptions{TimeoutSeconds: 30, ETag: lastETag})
 		if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+			logging.From(ctx).Debug("long-poll ended without change; polling again", "endpoint", "/Status", "err", err)
 			continue
 		}
 		if err != nil {
 			out.Errorf("watch status: %v", err)
 			return 1
 		}
+		rec.observe(ctx, status)
 		if status.ETag == "" || status.ETag != lastETag {
 			lastETag = status.ETag
 			out.Print(status)
@@ -75,6 +106,7 @@ func watchSync(ctx context.Context, out *output.Printer, client *bluos.Client) i
 
 		sync, err := client.SyncStatus(ctx, bluos.SyncStatusOptions{TimeoutSeconds: 30, ETag: lastETag})
 		if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+			logging.From(ctx).Debug("long-poll ended without change; polling again", "endpoint", "/SyncStatus", "err", err)
 			continue
 		}
 		if err != nil {
@@ -88,12 +120,13 @@ func watchSync(ctx context.Context, out *output.Printer, client *bluos.Client) i
 	}
 }
 
-func cmdSleep(ctx context.Context, out *output.Printer, cfg config.Config, cache config.DiscoveryCache, deviceArg string, allowDiscover bool, discoverTimeout, httpTimeout time.Duration, dryRun bool, trace io.Writer) int {
+func cmdSleep(ctx context.Context, out *output.Printer, cfg config.Config, cache config.DiscoveryCache, deviceArg string, allowDiscover bool, discoverTimeout, httpTimeout time.Duration, dryRun bool, trace bluos.Tracer) int {
 	device, resolveErr := resolveDevice(ctx, cfg, cache, deviceArg, allowDiscover, discoverTimeout)
 	if resolveErr != nil {
 		out.Errorf("device: %v", resolveErr)
 		return 1
 	}
+	out.SetDevice(device)
 	client := bluos.NewClient(device.BaseURL(), bluos.Options{Timeout: httpTimeout, DryRun: dryRun, Trace: trace})
 	minutes, err := client.Sleep(ctx)
 	if errors.Is(err, bluos.ErrDryRun) {
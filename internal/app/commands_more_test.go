@@ -3,6 +3,7 @@ package app
 import (
 	"bytes"
 	"context"
+	"encoding/json"
 	"net/http"
 	"net/http/httptest"
 	"strings"
@@ -30,13 +31,19 @@ func TestRunVolumeGetUpDown(t *testing.T) {
 	{
 		var out bytes.Buffer
 		var errOut bytes.Buffer
-		code := Run(context.Background(), []string{"--config", cfgPath, "--discover=false", "volume", "get"}, &out, &errOut)
+		code := Run(context.Background(), []string{"--config", cfgPath, "--discover=false", "--json", "volume", "get"}, &out, &errOut)
 		if code != 0 {
 			t.Fatalf("volume get code=%d stderr=%q", code, errOut.String())
 		}
 		if got := out.String(); !strings.Contains(got, "\"volume\": 15") || !strings.Contains(got, "\"db\": -40") {
 			t.Fatalf("stdout = %q", got)
 		}
+
+		out.Reset()
+		code = Run(context.Background(), []string{"--config", cfgPath, "--discover=false", "volume", "get"}, &out, &errOut)
+		if code != 0 || out.String() != "volume 15 (-40.0 dB)\n" {
+			t.Fatalf("volume get code=%d stdout=%q", code, out.String())
+		}
 	}
 
 	{
@@ -57,7 +64,7 @@ func TestRunVolumeGetUpDown(t *testing.T) {
 		}
 	}
 
-	want := []string{"/Status", "/Volume?db=2&tell_slaves=1", "/Volume?db=-2&tell_slaves=1"}
+	want := []string{"/Status", "/Status", "/Volume?db=2&tell_slaves=1", "/Volume?db=-2&tell_slaves=1"}
 	for i := range want {
 		if got := <-seen; got != want[i] {
 			t.Fatalf("call[%d]=%q want %q", i, got, want[i])
@@ -224,3 +231,129 @@ func TestUsageCommand(t *testing.T) {
 		t.Fatalf("out=%q", got)
 	}
 }
+
+func TestRunFormatTemplate(t *testing.T) {
+	t.Parallel()
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/xml")
+		_, _ = w.Write([]byte(`<status state="play" volume="15" artist="A" title1="T" secs="61"/>`))
+	}))
+	t.Cleanup(srv.Close)
+
+	cfgPath := writeTestConfig(t, srv.URL)
+
+	var out bytes.Buffer
+	var errOut bytes.Buffer
+	code := Run(context.Background(), []string{"--config", cfgPath, "--discover=false", "--format", "{{.Artist}} – {{.Title}} {{duration .Secs}}", "status"}, &out, &errOut)
+	if code != 0 {
+		t.Fatalf("exit code = %d; stderr=%q", code, errOut.String())
+	}
+	if got := out.String(); got != "A – T 1:01\n" {
+		t.Fatalf("stdout = %q", got)
+	}
+
+	out.Reset()
+	errOut.Reset()
+	code = Run(context.Background(), []string{"--format", "{{.Artist", "status"}, &out, &errOut)
+	if code != 2 || !strings.Contains(errOut.String(), "format:") {
+		t.Fatalf("exit code = %d; stderr=%q", code, errOut.String())
+	}
+}
+
+func TestRunOutputModes(t *testing.T) {
+	t.Parallel()
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/xml")
+		_, _ = w.Write([]byte(`<presets><preset id="1" name="Jazz" url="TuneIn:s1"/><preset id="2" name="News" url="TuneIn:s2"/></presets>`))
+	}))
+	t.Cleanup(srv.Close)
+
+	cfgPath := writeTestConfig(t, srv.URL)
+
+	var out bytes.Buffer
+	var errOut bytes.Buffer
+	code := Run(context.Background(), []string{"--config", cfgPath, "--discover=false", "--output", "csv", "presets", "list"}, &out, &errOut)
+	if code != 0 {
+		t.Fatalf("exit code = %d; stderr=%q", code, errOut.String())
+	}
+	if got := out.String(); got != "id,name,url\n1,Jazz,TuneIn:s1\n2,News,TuneIn:s2\n" {
+		t.Fatalf("stdout = %q", got)
+	}
+
+	out.Reset()
+	errOut.Reset()
+	code = Run(context.Background(), []string{"--output", "xml", "presets", "list"}, &out, &errOut)
+	if code != 2 || !strings.Contains(errOut.String(), "output:") {
+		t.Fatalf("exit code = %d; stderr=%q", code, errOut.String())
+	}
+}
+
+func TestDoctorRowsTable(t *testing.T) {
+	t.Parallel()
+
+	rows := doctorRows{{ID: "d1", Host: "h", Port: 11000, OK: false, Error: "boom"}}
+	header, cells := rows.TableHeader(), rows.TableRows()
+	if len(cells) != 1 || len(cells[0]) != len(header) {
+		t.Fatalf("header=%v rows=%v", header, cells)
+	}
+	if cells[0][2] != "11000" || cells[0][5] != "false" || cells[0][9] != "boom" {
+		t.Fatalf("row = %v", cells[0])
+	}
+}
+
+func TestRunEnvelope(t *testing.T) {
+	t.Parallel()
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/xml")
+		_, _ = w.Write([]byte(`<status state="play" volume="15" db="-20.5" mute="0"/>`))
+	}))
+	t.Cleanup(srv.Close)
+
+	cfgPath := writeTestConfig(t, srv.URL)
+
+	var out bytes.Buffer
+	var errOut bytes.Buffer
+	code := Run(context.Background(), []string{"--config", cfgPath, "--discover=false", "--envelope", "volume", "get"}, &out, &errOut)
+	if code != 0 {
+		t.Fatalf("exit code = %d; stderr=%q", code, errOut.String())
+	}
+	var env struct {
+		Schema string         `json:"schema"`
+		Device map[string]any `json:"device"`
+		Data   volumeLevel    `json:"data"`
+	}
+	if err := json.Unmarshal(out.Bytes(), &env); err != nil {
+		t.Fatalf("unmarshal: %v (%q)", err, out.String())
+	}
+	if env.Schema != "blu.volume/v1" || env.Device["host"] == nil || env.Data.Volume != 15 || env.Data.DB != -20.5 {
+		t.Fatalf("envelope = %+v", env)
+	}
+
+	out.Reset()
+	errOut.Reset()
+	code = Run(context.Background(), []string{"--config", cfgPath, "--discover=false", "--envelope", "volume", "set", "500"}, &out, &errOut)
+	if code != 2 {
+		t.Fatalf("exit code = %d", code)
+	}
+	var failure struct {
+		Schema string `json:"schema"`
+		Error  struct {
+			Code    string `json:"code"`
+			Message string `json:"message"`
+		} `json:"error"`
+	}
+	if err := json.Unmarshal(out.Bytes(), &failure); err != nil {
+		t.Fatalf("unmarshal: %v (%q)", err, out.String())
+	}
+	if failure.Schema != "blu.error/v1" || failure.Error.Code != "usage" || !strings.Contains(failure.Error.Message, "out of range") {
+		t.Fatalf("failure = %+v", failure)
+	}
+
+	code = Run(context.Background(), []string{"--envelope", "--output", "csv", "status"}, &out, &errOut)
+	if code != 2 {
+		t.Fatalf("exit code = %d", code)
+	}
+}
@@ -0,0 +1,195 @@
+package app
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+)
+
+func runConfig(t *testing.T, cfgPath string, args ...string) (int, string, string) {
+	t.Helper()
+	var out, errOut bytes.Buffer
+	code := Run(context.Background(), append([]string{"--config", cfgPath, "config"}, args...), &out, &errOut)
+	return code, out.String(), errOut.String()
+}
+
+func TestRunConfigGetSetUnset(t *testing.T) {
+	t.Parallel()
+
+	cfgPath := filepath.Join(t.TempDir(), "blu", "config.json")
+
+	if code, stdout, _ := runConfig(t, cfgPath, "path"); code != 0 || stdout != cfgPath+"\n" {
+		t.Fatalf("path: code=%d stdout=%q", code, stdout)
+	}
+	if code, _, stderr := runConfig(t, cfgPath, "set", "aliases.kitchen", "10.0.0.5"); code != 0 {
+		t.Fatalf("set: code=%d stderr=%q", code, stderr)
+	}
+	if code, _, stderr := runConfig(t, cfgPath, "set", "default_device", "kitchen"); code != 0 {
+		t.Fatalf("set: code=%d stderr=%q", code, stderr)
+	}
+	if code, stdout, _ := runConfig(t, cfgPath, "get", "aliases.kitchen"); code != 0 || stdout != "10.0.0.5\n" {
+		t.Fatalf("get: code=%d stdout=%q", code, stdout)
+	}
+	if code, stdout, _ := runConfig(t, cfgPath, "get", "aliases"); code != 0 || stdout != "{\n  \"kitchen\": \"10.0.0.5\"\n}\n" {
+		t.Fatalf("get object: code=%d stdout=%q", code, stdout)
+	}
+
+	var out, errOut bytes.Buffer
+	if code := Run(context.Background(), []string{"--config", cfgPath, "--json", "config", "get", "default_device"}, &out, &errOut); code != 0 || strings.TrimSpace(out.String()) != "{\n  \"key\": \"default_device\",\n  \"value\": \"kitchen\"\n}" {
+		t.Fatalf("get json: code=%d stdout=%q", code, out.String())
+	}
+
+	if code, _, stderr := runConfig(t, cfgPath, "set", "nope", "x"); code != 1 || !strings.Contains(stderr, `unknown key "nope"`) {
+		t.Fatalf("set unknown: code=%d stderr=%q", code, stderr)
+	}
+	if code, _, _ := runConfig(t, cfgPath, "unset", "default_device"); code != 0 {
+		t.Fatalf("unset: code=%d", code)
+	}
+	if code, _, stderr := runConfig(t, cfgPath, "get", "default_device"); code != 1 || !strings.Contains(stderr, "not set") {
+		t.Fatalf("get after unset: code=%d stderr=%q", code, stderr)
+	}
+	if code, _, _ := runConfig(t, cfgPath, "set", "x"); code != 2 {
+		t.Fatalf("set usage: code=%d", code)
+	}
+	if code, _, _ := runConfig(t, cfgPath, "frobnicate"); code != 2 {
+		t.Fatalf("unknown sub: code=%d", code)
+	}
+}
+
+func TestRunConfigValidate(t *testing.T) {
+	t.Parallel()
+
+	cfgPath := filepath.Join(t.TempDir(), "config.json")
+	if err := os.WriteFile(cfgPath, []byte("{\n  \"aliases\": {\"den\": \"10.0.0.6:99999\"}\n}\n"), 0o644); err != nil {
+		t.Fatalf("write: %v", err)
+	}
+	code, stdout, _ := runConfig(t, cfgPath, "validate")
+	if code != 1 || !strings.Contains(stdout, cfgPath+":2:15: error: aliases.den: invalid device") {
+		t.Fatalf("validate: code=%d stdout=%q", code, stdout)
+	}
+
+	if err := os.WriteFile(cfgPath, []byte("{\n  \"default_device\": \"x\",\n}\n"), 0o644); err != nil {
+		t.Fatalf("write: %v", err)
+	}
+	code, stdout, _ = runConfig(t, cfgPath, "validate")
+	if code != 1 || !strings.HasPrefix(stdout, cfgPath+":3:1: error: invalid character '}'") {
+		t.Fatalf("validate syntax: code=%d stdout=%q", code, stdout)
+	}
+
+	// Other commands refuse a broken config and point at the fix.
+	var out, errOut bytes.Buffer
+	if code := Run(context.Background(), []string{"--config", cfgPath, "status"}, &out, &errOut); code != 1 || !strings.Contains(errOut.String(), cfgPath+":3:1:") || !strings.Contains(errOut.String(), "blu config edit") {
+		t.Fatalf("status: code=%d stderr=%q", code, errOut.String())
+	}
+
+	if err := os.WriteFile(cfgPath, []byte(`{"aliases": {"den": "10.0.0.6"}}`), 0o644); err != nil {
+		t.Fatalf("write: %v", err)
+	}
+	if code, stdout, _ := runConfig(t, cfgPath, "validate"); code != 0 || stdout != cfgPath+": ok\n" {
+		t.Fatalf("validate ok: code=%d stdout=%q", code, stdout)
+	}
+}
+
+func TestRunConfigEdit(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("shell editor script")
+	}
+
+	dir := t.TempDir()
+	cfgPath := filepath.Join(dir, "config.json")
+	if err := os.WriteFile(cfgPath, []byte(`{"default_device":"a"}`+"\n"), 0o644); err != nil {
+		t.Fatalf("write: %v", err)
+	}
+	editor := filepath.Join(dir, "editor.sh")
+	content := filepath.Join(dir, "content.json")
+	if err := os.WriteFile(editor, []byte("#!/bin/sh\ncat \""+content+"\" > \"$1\"\n"), 0o755); err != nil {
+		t.Fatalf("write editor: %v", err)
+	}
+	t.Setenv("TMPDIR", dir)
+	t.Setenv("VISUAL", "")
+	t.Setenv("EDITOR", editor)
+
+	// Invalid edits are rejected and the original is kept.
+	if err := os.WriteFile(content, []byte(`{"default_device": }`), 0o644); err != nil {
+		t.Fatalf("write: %v", err)
+	}
+	code, _, stderr := runConfig(t, cfgPath, "edit")
+	if code != 1 || !strings.Contains(stderr, cfgPath+":1:20: error:") || !strings.Contains(stderr, "warn: config: not saved; your edits are in ") {
+		t.Fatalf("edit invalid: code=%d stderr=%q", code, stderr)
+	}
+	if data, _ := os.ReadFile(cfgPath); string(data) != `{"default_device":"a"}`+"\n" {
+		t.Fatalf("config changed: %q", data)
+	}
+
+	if err := os.WriteFile(content, []byte(`{"default_device": "b", "extra": 1}`), 0o644); err != nil {
+		t.Fatalf("write: %v", err)
+	}
+	code, _, stderr = runConfig(t, cfgPath, "edit")
+	if code != 0 || !strings.Contains(stderr, "warn: "+cfgPath+":1:25: warning: extra: unknown key") {
+		t.Fatalf("edit: code=%d stderr=%q", code, stderr)
+	}
+	if data, _ := os.ReadFile(cfgPath); string(data) != `{"default_device": "b", "extra": 1}` {
+		t.Fatalf("config = %q", data)
+	}
+
+	// Saving without changes is a progress note, which --quiet drops.
+	code, _, stderr = runConfig(t, cfgPath, "edit")
+	if code != 0 || stderr != "config: no changes\n" {
+		t.Fatalf("edit unchanged: code=%d stderr=%q", code, stderr)
+	}
+	t.Setenv("BLU_QUIET", "1")
+	if code, _, stderr = runConfig(t, cfgPath, "edit"); code != 0 || stderr != "" {
+		t.Fatalf("edit unchanged --quiet: code=%d stderr=%q", code, stderr)
+	}
+}
+
+func TestRunConfigEditConcurrentChange(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("shell editor script")
+	}
+
+	dir := t.TempDir()
+	cfgPath := filepath.Join(dir, "config.json")
+	if err := os.WriteFile(cfgPath, []byte(`{"default_device":"a"}`+"\n"), 0o644); err != nil {
+		t.Fatalf("write: %v", err)
+	}
+	// The "editor" edits its copy while another writer changes config.json.
+	editor := filepath.Join(dir, "editor.sh")
+	script := "#!/bin/sh\necho '{\"default_device\":\"b\"}' > \"$1\"\necho '{\"default_device\":\"c\"}' > \"" + cfgPath + "\"\n"
+	if err := os.WriteFile(editor, []byte(script), 0o755); err != nil {
+		t.Fatalf("write editor: %v", err)
+	}
+	t.Setenv("TMPDIR", dir)
+	t.Setenv("VISUAL", "")
+	t.Setenv("EDITOR", editor)
+
+	code, _, stderr := runConfig(t, cfgPath, "edit")
+	if code != 1 || !strings.Contains(stderr, "changed while you were editing") {
+		t.Fatalf("edit: code=%d stderr=%q", code, stderr)
+	}
+	if data, _ := os.ReadFile(cfgPath); string(data) != `{"default_device":"c"}`+"\n" {
+		t.Fatalf("config = %q", data)
+	}
+}
+
+func TestRunWarnsWhenTokenMigrationFails(t *testing.T) {
+	t.Setenv("BLU_SECRETS_BACKEND", "encrypted")
+	t.Setenv("BLU_SECRETS_PASSPHRASE", "")
+
+	cfgPath := filepath.Join(t.TempDir(), "config.json")
+	data := `{"scrobble":{"token":"LBTOKEN"}}`
+	if err := os.WriteFile(cfgPath, []byte(data), 0o644); err != nil {
+		t.Fatalf("write: %v", err)
+	}
+	code, _, stderr := runConfig(t, cfgPath, "path")
+	if code != 0 || !strings.Contains(stderr, "tokens left in config.json") || !strings.Contains(stderr, "BLU_SECRETS_PASSPHRASE") {
+		t.Fatalf("code=%d stderr=%q", code, stderr)
+	}
+	if after, _ := os.ReadFile(cfgPath); string(after) != data {
+		t.Fatalf("config = %s; want untouched", after)
+	}
+}
@@ -3,6 +3,8 @@ package app
 import (
 	"bytes"
 	"context"
+	"encoding/json"
+	"fmt"
 	"net"
 	"net/http"
 	"net/http/httptest"
@@ -10,6 +12,7 @@ import (
 	"path/filepath"
 	"strconv"
 	"strings"
+	"sync/atomic"
 	"testing"
 	"time"
 
@@ -33,7 +36,7 @@ func TestCmdDevices_WritesCacheAndPrints(t *testing.T) {
 	var stderr bytes.Buffer
 	out := output.New(output.Options{Stdout: &stdout, Stderr: &stderr})
 
-	code := cmdDevices(ctx, out, paths, config.Config{}, config.DiscoveryCache{}, 250*time.Millisecond)
+	code := cmdDevices(ctx, out, paths, config.Config{}, config.DiscoveryCache{}, 250*time.Millisecond, 250*time.Millisecond, nil)
 	if code != 0 {
 		t.Fatalf("code=%d stderr=%q", code, stderr.String())
 	}
@@ -64,7 +67,7 @@ func TestCmdDevices_WarnsWhenDiscoveryEmptyButCacheHasDevices(t *testing.T) {
 	var stderr bytes.Buffer
 	out := output.New(output.Options{Stdout: &stdout, Stderr: &stderr})
 
-	code := cmdDevices(ctx, out, paths, config.Config{}, cache, 250*time.Millisecond)
+	code := cmdDevices(ctx, out, paths, config.Config{}, cache, 250*time.Millisecond, 250*time.Millisecond, nil)
 	if code != 0 {
 		t.Fatalf("code=%d stderr=%q", code, stderr.String())
 	}
@@ -80,12 +83,18 @@ func TestCmdDoctor_StubbedDiscoveryAndStatus(t *testing.T) {
 	t.Parallel()
 
 	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		if r.URL.Path != "/Status" {
+		w.Header().Set("Content-Type", "application/xml")
+		switch r.URL.Path {
+		case "/Status":
+			if r.URL.Query().Get("etag") != "" {
+				time.Sleep(600 * time.Millisecond)
+			}
+			_, _ = w.Write([]byte(`<status etag="e1" state="play" volume="10" mute="0" name="Room" model="M"/>`))
+		case "/SyncStatus":
+			_, _ = w.Write([]byte(`<SyncStatus name="Room"/>`))
+		default:
 			http.NotFound(w, r)
-			return
 		}
-		w.Header().Set("Content-Type", "application/xml")
-		_, _ = w.Write([]byte(`<status state="play" volume="10" mute="0" name="Room" model="M"/>`))
 	}))
 	t.Cleanup(srv.Close)
 
@@ -103,10 +112,19 @@ func TestCmdDoctor_StubbedDiscoveryAndStatus(t *testing.T) {
 	var stderr bytes.Buffer
 	out := output.New(output.Options{Stdout: &stdout, Stderr: &stderr})
 
-	code := cmdDoctor(ctx, out, config.Config{}, config.DiscoveryCache{}, 250*time.Millisecond, 2*time.Second)
+	code := cmdDoctor(ctx, out, config.Config{}, config.DiscoveryCache{}, 250*time.Millisecond, 2*time.Second, nil)
 	if code != 0 {
 		t.Fatalf("code=%d stderr=%q", code, stderr.String())
 	}
+	if got := stdout.String(); !strings.Contains(got, "ok (play)") || !strings.Contains(got, "Room") || !strings.Contains(got, "1 players, all ok") {
+		t.Fatalf("stdout = %q", got)
+	}
+
+	stdout.Reset()
+	out = output.New(output.Options{JSON: true, Stdout: &stdout, Stderr: &stderr})
+	if code := cmdDoctor(ctx, out, config.Config{}, config.DiscoveryCache{}, 250*time.Millisecond, 2*time.Second, nil); code != 0 {
+		t.Fatalf("code=%d stderr=%q", code, stderr.String())
+	}
 	if got := stdout.String(); !strings.Contains(got, "\"ok\": true") || !strings.Contains(got, "\"name\": \"Room\"") {
 		t.Fatalf("stdout = %q", got)
 	}
@@ -123,7 +141,7 @@ func TestCmdDoctor_NoDevices(t *testing.T) {
 	var stderr bytes.Buffer
 	out := output.New(output.Options{Stdout: &stdout, Stderr: &stderr})
 
-	code := cmdDoctor(ctx, out, config.Config{}, config.DiscoveryCache{}, 250*time.Millisecond, 250*time.Millisecond)
+	code := cmdDoctor(ctx, out, config.Config{}, config.DiscoveryCache{}, 250*time.Millisecond, 250*time.Millisecond, nil)
 	if code != 1 {
 		t.Fatalf("code=%d; want 1", code)
 	}
@@ -131,3 +149,234 @@ func TestCmdDoctor_NoDevices(t *testing.T) {
 		t.Fatalf("stderr = %q", got)
 	}
 }
+
+func TestCmdDevices_WatchPrintsEventsAndUpdatesCache(t *testing.T) {
+	t.Parallel()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 300*time.Millisecond)
+	defer cancel()
+	ctx = discovery.WithMDNSOverride(ctx, func(context.Context) ([]discovery.Device, error) {
+		return []discovery.Device{{ID: "127.0.0.1:1", Host: "127.0.0.1", Port: 1, Name: "Kitchen", Type: "musc", Version: "4.2.1"}}, nil
+	})
+	ctx = discovery.WithLSDPOverride(ctx, func(context.Context) ([]discovery.Device, error) { return nil, nil })
+
+	paths := config.PathSet{CachePath: filepath.Join(t.TempDir(), "cache.json")}
+	var stdout, stderr bytes.Buffer
+	out := output.New(output.Options{Stdout: &stdout, Stderr: &stderr})
+
+	code := cmdDevices(ctx, out, paths, config.Config{}, config.DiscoveryCache{}, 250*time.Millisecond, 250*time.Millisecond, []string{"--watch", "--interval", "50ms"})
+	if code != 0 {
+		t.Fatalf("code=%d stderr=%q", code, stderr.String())
+	}
+	if got := stdout.String(); strings.Count(got, "join") != 1 || !strings.Contains(got, "127.0.0.1:1 Kitchen (musc v4.2.1)") {
+		t.Fatalf("stdout = %q", got)
+	}
+	loaded, err := config.LoadDiscoveryCache(paths.CachePath)
+	if err != nil || len(loaded.Devices) != 1 || loaded.Devices[0].Name != "Kitchen" {
+		t.Fatalf("cache = %+v, %v", loaded, err)
+	}
+}
+
+func TestCmdDevices_WatchIdentifiesOnceOnJoin(t *testing.T) {
+	t.Parallel()
+
+	var probes atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		probes.Add(1)
+		_, _ = w.Write([]byte(`<SyncStatus name="Vault" modelName="VAULT 2i" brand="Bluesound" mac="90:56:82:9F:02:78"/>`))
+	}))
+	t.Cleanup(srv.Close)
+	u, _ := url.Parse(srv.URL)
+	port, _ := strconv.Atoi(u.Port())
+
+	ctx, cancel := context.WithTimeout(context.Background(), 400*time.Millisecond)
+	defer cancel()
+	// Every browse reports a new firmware version: one join, then changes.
+	var browses atomic.Int32
+	ctx = discovery.WithMDNSOverride(ctx, func(context.Context) ([]discovery.Device, error) {
+		v := fmt.Sprintf("4.%d", browses.Add(1))
+		return []discovery.Device{{ID: u.Host, Host: u.Hostname(), Port: port, Name: "Vault", Type: "musc", Version: v}}, nil
+	})
+	ctx = discovery.WithLSDPOverride(ctx, func(context.Context) ([]discovery.Device, error) { return nil, nil })
+
+	paths := config.PathSet{CachePath: filepath.Join(t.TempDir(), "cache.json")}
+	var stdout, stderr bytes.Buffer
+	out := output.New(output.Options{Stdout: &stdout, Stderr: &stderr})
+	if code := cmdDevices(ctx, out, paths, config.Config{}, config.DiscoveryCache{}, 250*time.Millisecond, time.Second, []string{"--watch", "--interval", "50ms"}); code != 0 {
+		t.Fatalf("code=%d stderr=%q", code, stderr.String())
+	}
+	if got := stdout.String(); strings.Count(got, "join") != 1 || strings.Count(got, "change") < 2 {
+		t.Fatalf("stdout = %q; want a join and changes", got)
+	}
+	if n := probes.Load(); n != 1 {
+		t.Fatalf("SyncStatus probes = %d; want 1 (on join only)", n)
+	}
+	loaded, err := config.LoadDiscoveryCache(paths.CachePath)
+	if err != nil || len(loaded.Devices) != 1 || loaded.Devices[0].MAC != "90:56:82:9f:02:78" {
+		t.Fatalf("cache = %+v, %v", loaded, err)
+	}
+}
+
+func TestCmdDevices_ScanRejectsBadTargets(t *testing.T) {
+	t.Parallel()
+
+	var stdout, stderr bytes.Buffer
+	out := output.New(output.Options{Stdout: &stdout, Stderr: &stderr})
+
+	code := cmdDevices(context.Background(), out, config.PathSet{}, config.Config{}, config.DiscoveryCache{}, 250*time.Millisecond, 250*time.Millisecond, []string{"--scan", "10.0.0.0/33"})
+	if code != 2 {
+		t.Fatalf("code=%d", code)
+	}
+	if got := stderr.String(); !strings.Contains(got, "scan target") {
+		t.Fatalf("stderr = %q", got)
+	}
+}
+
+func TestCmdDevices_MergesServiceTypesAndFingerprints(t *testing.T) {
+	t.Parallel()
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/SyncStatus" {
+			http.NotFound(w, r)
+			return
+		}
+		_, _ = w.Write([]byte(`<SyncStatus name="Vault" model="N132" modelName="VAULT 2i" brand="Bluesound" mac="90:56:82:9F:02:78"/>`))
+	}))
+	t.Cleanup(srv.Close)
+	u, _ := url.Parse(srv.URL)
+	host, portStr, _ := net.SplitHostPort(u.Host)
+	port, _ := strconv.Atoi(portStr)
+
+	ctx := discovery.WithMDNSOverride(context.Background(), func(context.Context) ([]discovery.Device, error) {
+		return []discovery.Device{
+			{ID: host + ":" + portStr, Host: host, Port: port, Name: "Vault", Type: "musc", Roles: []string{"player"}, Source: "mdns"},
+			{ID: host + ":1", Host: host, Port: 1, Name: "Vault", Type: "musp", Roles: []string{"secondary"}, Source: "mdns"},
+		}, nil
+	})
+	ctx = discovery.WithLSDPOverride(ctx, func(context.Context) ([]discovery.Device, error) { return nil, nil })
+
+	paths := config.PathSet{CachePath: filepath.Join(t.TempDir(), "cache.json")}
+	var stdout, stderr bytes.Buffer
+	out := output.New(output.Options{Stdout: &stdout, Stderr: &stderr})
+	if code := cmdDevices(ctx, out, paths, config.Config{}, config.DiscoveryCache{}, 250*time.Millisecond, 2*time.Second, nil); code != 0 {
+		t.Fatalf("code=%d stderr=%q", code, stderr.String())
+	}
+	want := "Vault  " + host + ":" + portStr + " (musc) · Bluesound VAULT 2i [player, secondary]\n"
+	if got := stdout.String(); got != want {
+		t.Fatalf("stdout = %q; want %q", got, want)
+	}
+	loaded, err := config.LoadDiscoveryCache(paths.CachePath)
+	if err != nil || len(loaded.Devices) != 1 || loaded.Devices[0].MAC != "90:56:82:9f:02:78" {
+		t.Fatalf("cache = %+v, %v", loaded, err)
+	}
+}
+
+func TestCmdDoctor_Findings(t *testing.T) {
+	t.Parallel()
+
+	// Ignores the etag (answers long-polls at once) and runs an hour ahead.
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Date", time.Now().Add(time.Hour).UTC().Format(http.TimeFormat))
+		switch r.URL.Path {
+		case "/Status":
+			_, _ = w.Write([]byte(`<status etag="e1" state="stop" name="Kitchen"/>`))
+		case "/SyncStatus":
+			_, _ = w.Write([]byte(`<SyncStatus name="Kitchen"/>`))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	t.Cleanup(srv.Close)
+	u, _ := url.Parse(srv.URL)
+	host, portStr, _ := net.SplitHostPort(u.Host)
+	port, _ := strconv.Atoi(portStr)
+
+	kitchen := discovery.Device{ID: u.Host, Host: host, Port: port, Name: "Kitchen", Version: "4.2.1", MAC: "90:56:82:aa:bb:cc"}
+	dead := discovery.Device{ID: "127.0.0.1:1", Host: "127.0.0.1", Port: 1, Name: "Attic", Version: "4.4.0"}
+	ctx := discovery.WithMDNSOverride(context.Background(), func(context.Context) ([]discovery.Device, error) {
+		k, d := kitchen, dead
+		k.Source, d.Source = "mdns", "mdns"
+		return []discovery.Device{k, d}, nil
+	})
+	ctx = discovery.WithLSDPOverride(ctx, func(context.Context) ([]discovery.Device, error) {
+		k := kitchen
+		k.Source = "lsdp"
+		return []discovery.Device{k}, nil
+	})
+
+	cfg := config.Config{
+		DefaultDevice: "kitchen",
+		Aliases:       map[string]string{"kitchen": "10.0.0.5:11000", "attic": "127.0.0.1:1", "den": "Den", "gone": "127.0.0.1:2"},
+	}
+	cache := config.NewDiscoveryCache(time.Now(), []config.Device{{ID: "10.0.0.5:11000", Host: "10.0.0.5", Port: 11000, MAC: "90:56:82:aa:bb:cc"}})
+
+	var stdout, stderr bytes.Buffer
+	out := output.New(output.Options{Mode: output.ModeJSON, Stdout: &stdout, Stderr: &stderr})
+	if code := cmdDoctor(ctx, out, cfg, cache, 250*time.Millisecond, 2*time.Second, []string{"--samples", "3"}); code != 0 {
+		t.Fatalf("code=%d stderr=%q", code, stderr.String())
+	}
+	var report doctorReport
+	if err := json.Unmarshal(stdout.Bytes(), &report); err != nil {
+		t.Fatalf("json: %v\n%s", err, stdout.String())
+	}
+	status := map[string]string{}
+	for _, row := range report.Players {
+		for _, c := range row.Checks {
+			status[row.Name+" "+c.Check] = c.Status
+		}
+	}
+	for _, c := range report.Checks {
+		status[c.Check+" "+strings.SplitN(c.Detail, " ", 2)[0]] = c.Status
+		if c.Status == checkFail && c.Hint == "" {
+			t.Errorf("check %+v has no hint", c)
+		}
+	}
+	want := map[string]string{
+		"Kitchen port":         checkOK,
+		"Kitchen latency":      checkOK,
+		"Kitchen clock":        checkWarn,
+		"Kitchen long-poll":    checkWarn,
+		"Attic port":           checkFail,
+		"Attic discovery":      checkWarn,
+		"firmware mixed":       checkWarn,
+		"alias attic":          checkOK,
+		"alias gone":           checkFail,
+		"alias den":            checkFail,
+		"alias kitchen":        checkFail,
+		"default-device alias": checkFail,
+	}
+	for k, v := range want {
+		if status[k] != v {
+			t.Errorf("%s = %q; want %q (all: %v)", k, status[k], v, status)
+		}
+	}
+	if len(report.Players) != 2 || report.Players[0].OK == report.Players[1].OK {
+		t.Fatalf("players = %+v", report.Players)
+	}
+	if skew := report.Players[1].ClockSkewMS; report.Players[1].Name != "Kitchen" || skew == nil || *skew < 3500_000 {
+		t.Fatalf("kitchen = %+v", report.Players[1])
+	}
+	if !strings.Contains(stdout.String(), "blu alias add kitchen "+u.Host+" --force") {
+		t.Fatalf("moved alias hint missing: %s", stdout.String())
+	}
+
+	stdout.Reset()
+	out = output.New(output.Options{Stdout: &stdout, Stderr: &stderr})
+	if code := cmdDoctor(ctx, out, cfg, cache, 250*time.Millisecond, 2*time.Second, []string{"--samples", "1"}); code != 0 {
+		t.Fatalf("code=%d stderr=%q", code, stderr.String())
+	}
+	if got := stdout.String(); !strings.Contains(got, "Findings:") || !strings.Contains(got, "hint: ") || !strings.Contains(got, "2 players, 1 failing") {
+		t.Fatalf("stdout = %q", got)
+	}
+}
+
+func TestPercentile(t *testing.T) {
+	t.Parallel()
+
+	d := []time.Duration{1, 2, 3, 4, 5, 6, 7, 8, 9, 10}
+	for p, want := range map[int]time.Duration{50: 5, 90: 9, 99: 10, 100: 10, 0: 1} {
+		if got := percentile(d, p); got != want {
+			t.Errorf("percentile(%d) = %v; want %v", p, got, want)
+		}
+	}
+}
@@ -0,0 +1,124 @@
+package app
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/steipete/blucli/internal/config"
+	"github.com/steipete/blucli/internal/discovery"
+	"github.com/steipete/blucli/internal/output"
+)
+
+func readZip(t *testing.T, path string) map[string]string {
+	t.Helper()
+	zr, err := zip.OpenReader(path)
+	if err != nil {
+		t.Fatalf("open zip: %v", err)
+	}
+	defer zr.Close()
+	files := map[string]string{}
+	for _, f := range zr.File {
+		rc, err := f.Open()
+		if err != nil {
+			t.Fatal(err)
+		}
+		data, _ := io.ReadAll(rc)
+		_ = rc.Close()
+		files[f.Name] = string(data)
+	}
+	return files
+}
+
+func TestCmdDiag_Bundle(t *testing.T) {
+	t.Parallel()
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/xml")
+		switch r.URL.Path {
+		case "/Status":
+			_, _ = w.Write([]byte(`<status state="play" name="Room"/>`))
+		case "/SyncStatus":
+			_, _ = w.Write([]byte(`<SyncStatus name="Room" id="` + r.Host + `"/>`))
+		case "/RadioBrowse":
+			_, _ = w.Write([]byte(`<radiotime service="` + r.URL.Query().Get("service") + `"/>`))
+		case "/Presets":
+			http.Error(w, "nope", http.StatusInternalServerError)
+		default:
+			_, _ = w.Write([]byte(`<` + strings.ToLower(strings.TrimPrefix(r.URL.Path, "/")) + `/>`))
+		}
+	}))
+	t.Cleanup(srv.Close)
+	u, _ := url.Parse(srv.URL)
+	host, portStr, _ := net.SplitHostPort(u.Host)
+	port, _ := strconv.Atoi(portStr)
+
+	ctx := discovery.WithMDNSOverride(t.Context(), func(context.Context) ([]discovery.Device, error) {
+		return []discovery.Device{{ID: u.Host, Host: host, Port: port, Name: "Room", Source: "mdns"}}, nil
+	})
+	ctx = discovery.WithLSDPOverride(ctx, func(context.Context) ([]discovery.Device, error) { return nil, nil })
+	cfg := config.Config{
+		Aliases:  map[string]string{"room": u.Host},
+		Scrobble: config.ScrobbleConfig{URL: "https://scrobble.example/", Token: "s3cret"},
+	}
+
+	target := filepath.Join(t.TempDir(), "bundle.zip")
+	var stdout, stderr bytes.Buffer
+	out := output.New(output.Options{Stdout: &stdout, Stderr: &stderr})
+	if code := cmdDiag(ctx, out, cfg, config.DiscoveryCache{}, "", true, 100*time.Millisecond, 2*time.Second, false, nil, []string{"--bundle", target}); code != 0 {
+		t.Fatalf("code=%d stderr=%q", code, stderr.String())
+	}
+	if got := stdout.String(); !strings.Contains(got, "wrote "+target+" (1 players") || !strings.Contains(got, "redacted: tokens") || !strings.Contains(got, "1 requests failed") {
+		t.Fatalf("stdout = %q", got)
+	}
+
+	files := readZip(t, target)
+	dir := "players/127.0.0.1_" + portStr + "/"
+	if files[dir+"Status.xml"] != `<status state="play" name="Room"/>` || files[dir+"RadioBrowse_service_Capture.xml"] != `<radiotime service="Capture"/>` {
+		t.Fatalf("files = %v", files)
+	}
+	if _, ok := files[dir+"Presets.xml"]; ok {
+		t.Fatalf("failed request was written")
+	}
+	var manifest bundleManifest
+	if err := json.Unmarshal([]byte(files["manifest.json"]), &manifest); err != nil {
+		t.Fatal(err)
+	}
+	if manifest.Format != bundleFormat || manifest.Blu.OS == "" || len(manifest.Players) != 1 || len(manifest.Players[0].Endpoints) != len(bundleEndpoints) {
+		t.Fatalf("manifest = %+v", manifest)
+	}
+	if ep := manifest.Players[0].Endpoints[2]; ep.Path != "/Presets" || ep.File != "" || !strings.Contains(ep.Error, "http 500") {
+		t.Fatalf("presets endpoint = %+v", ep)
+	}
+	if !strings.Contains(files["discovery.json"], `"mechanism": "mdns"`) || !strings.Contains(files["discovery.json"], `"mechanism": "lsdp"`) {
+		t.Fatalf("discovery.json = %s", files["discovery.json"])
+	}
+	if c := files["config.json"]; strings.Contains(c, "s3cret") || !strings.Contains(c, redactedValue) || !strings.Contains(c, u.Host) {
+		t.Fatalf("config.json = %s", c)
+	}
+
+	stdout.Reset()
+	if code := cmdDiag(ctx, out, cfg, config.DiscoveryCache{}, "", true, 100*time.Millisecond, 2*time.Second, false, nil, []string{"--bundle", target, "--redact", "all"}); code != 0 {
+		t.Fatalf("code=%d stderr=%q", code, stderr.String())
+	}
+	for name, data := range readZip(t, target) {
+		if strings.Contains(name, "127.0.0.1") || strings.Contains(data, "127.0.0.1") || strings.Contains(data, "s3cret") {
+			t.Fatalf("%s not redacted: %s", name, data)
+		}
+	}
+
+	if code := cmdDiag(ctx, out, cfg, config.DiscoveryCache{}, "", true, 100*time.Millisecond, time.Second, false, nil, []string{"--bundle", target, "--redact", "names"}); code != 2 {
+		t.Fatalf("code=%d; want 2", code)
+	}
+}
@@ -35,13 +35,25 @@ func TestRunDiag(t *testing.T) {
 
 	var out bytes.Buffer
 	var errOut bytes.Buffer
-	code := Run(context.Background(), []string{"--config", cfgPath, "--discover=false", "diag"}, &out, &errOut)
+	code := Run(context.Background(), []string{"--config", cfgPath, "--discover=false", "--json", "diag"}, &out, &errOut)
 	if code != 0 {
 		t.Fatalf("exit code = %d; stderr=%q", code, errOut.String())
 	}
 	if got := out.String(); !strings.Contains(got, "\"device\"") || !strings.Contains(got, "\"status\"") || !strings.Contains(got, "\"sync\"") {
 		t.Fatalf("stdout = %q; want json report", got)
 	}
+
+	out.Reset()
+	code = Run(context.Background(), []string{"--config", cfgPath, "--discover=false", "diag"}, &out, &errOut)
+	if code != 0 {
+		t.Fatalf("exit code = %d; stderr=%q", code, errOut.String())
+	}
+	got := out.String()
+	for _, want := range []string{"Room  127.0.0.1:", "Status:\nplay | vol=10", "Group:\ngroup: G", "Presets (1):\n 1  A", "Queue (0):"} {
+		if !strings.Contains(got, want) {
+			t.Fatalf("stdout = %q; want %q", got, want)
+		}
+	}
 }
 
 func TestRunSleep(t *testing.T) {
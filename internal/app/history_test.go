@@ -0,0 +1,143 @@
+package app
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/steipete/blucli/internal/history"
+)
+
+func TestRunHistoryListAndStats(t *testing.T) {
+	t.Parallel()
+
+	file := filepath.Join(t.TempDir(), "history.jsonl")
+	now := time.Now()
+	if err := history.Append(file,
+		history.Entry{Device: "a:11000", DeviceName: "Kitchen", Artist: "A", Title: "Old", Service: "Spotify", StartedAt: now.Add(-30 * 24 * time.Hour), ListenedSeconds: 100},
+		history.Entry{Device: "a:11000", DeviceName: "Kitchen", Artist: "A", Title: "New", Service: "Spotify", StartedAt: now.Add(-time.Hour), ListenedSeconds: 200},
+		history.Entry{Device: "b:11000", DeviceName: "Office", Artist: "B", Title: "Other", Service: "TuneIn", StartedAt: now.Add(-2 * time.Hour), ListenedSeconds: 300},
+	); err != nil {
+		t.Fatalf("append: %v", err)
+	}
+
+	var out bytes.Buffer
+	var errOut bytes.Buffer
+	code := Run(context.Background(), []string{"history", "--file", file, "--since", "7d", "--device", "kitchen"}, &out, &errOut)
+	if code != 0 {
+		t.Fatalf("exit code = %d; stderr=%q", code, errOut.String())
+	}
+	if got := out.String(); !strings.Contains(got, "A — New") || strings.Contains(got, "Old") || strings.Contains(got, "Other") {
+		t.Fatalf("stdout = %q; want only recent kitchen play", got)
+	}
+
+	out.Reset()
+	code = Run(context.Background(), []string{"--json", "history", "stats", "--file", file, "--since", "7d"}, &out, &errOut)
+	if code != 0 {
+		t.Fatalf("exit code = %d; stderr=%q", code, errOut.String())
+	}
+	var stats history.Stats
+	if err := json.Unmarshal(out.Bytes(), &stats); err != nil {
+		t.Fatalf("json: %v (%q)", err, out.String())
+	}
+	if stats.Plays != 2 || len(stats.Rooms) != 2 || stats.Rooms[0].Name != "Office" {
+		t.Fatalf("stats = %+v", stats)
+	}
+
+	out.Reset()
+	code = Run(context.Background(), []string{"history", "--since", "soon", "--file", file}, &out, &errOut)
+	if code != 2 {
+		t.Fatalf("exit code = %d; want 2", code)
+	}
+}
+
+func TestRunWatchStatusRecordsHistory(t *testing.T) {
+	t.Parallel()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+
+	var calls atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/Status" {
+			http.NotFound(w, r)
+			return
+		}
+		w.Header().Set("Content-Type", "application/xml")
+		switch calls.Add(1) {
+		case 1:
+			_, _ = w.Write([]byte(`<status state="play" name="Kitchen" artist="A" title1="First" service="Qobuz" secs="100" totlen="240" etag="e1"/>`))
+		default:
+			_, _ = w.Write([]byte(`<status state="play" name="Kitchen" artist="A" title1="Second" service="Qobuz" secs="0" totlen="240" etag="e2"/>`))
+			cancel()
+		}
+	}))
+	t.Cleanup(srv.Close)
+
+	cfgPath := writeTestConfig(t, srv.URL)
+	file := filepath.Join(t.TempDir(), "history.jsonl")
+
+	var out bytes.Buffer
+	var errOut bytes.Buffer
+	code := Run(ctx, []string{"--config", cfgPath, "--discover=false", "watch", "status", "--record", "--history-file", file}, &out, &errOut)
+	if code != 0 {
+		t.Fatalf("exit code = %d; stderr=%q", code, errOut.String())
+	}
+
+	entries, err := history.Load(file)
+	if err != nil {
+		t.Fatalf("load: %v", err)
+	}
+	if len(entries) != 1 || entries[0].Title != "First" || entries[0].DeviceName != "Kitchen" || entries[0].Service != "Qobuz" {
+		t.Fatalf("entries = %+v", entries)
+	}
+	if entries[0].ListenedSeconds < 100 {
+		t.Fatalf("ListenedSeconds = %d; want >= 100", entries[0].ListenedSeconds)
+	}
+}
+
+func TestRunHistoryRecordLogsLongPollFailureOnce(t *testing.T) {
+	t.Parallel()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "boom", http.StatusInternalServerError)
+		// Stop once the failure is reported (the retry waits on ctx).
+		go func() { time.Sleep(50 * time.Millisecond); cancel() }()
+	}))
+	t.Cleanup(srv.Close)
+
+	cfgPath := writeTestConfig(t, srv.URL)
+	file := filepath.Join(t.TempDir(), "history.jsonl")
+
+	var out, errOut bytes.Buffer
+	code := Run(ctx, []string{"--config", cfgPath, "--discover=false", "--log-format", "json", "history", "record", "--file", file}, &out, &errOut)
+	if code != 0 {
+		t.Fatalf("exit code = %d; stderr=%q", code, errOut.String())
+	}
+	var failures int
+	for line := range strings.SplitSeq(strings.TrimSpace(errOut.String()), "\n") {
+		var rec map[string]any
+		if err := json.Unmarshal([]byte(line), &rec); err != nil {
+			t.Fatalf("stderr line %q is not a JSON log record: %v", line, err)
+		}
+		if rec["msg"] == "status long-poll failed; retrying" {
+			failures++
+			if rec["level"] != "WARN" {
+				t.Fatalf("level = %v; want WARN", rec["level"])
+			}
+		}
+	}
+	if failures != 1 {
+		t.Fatalf("failure logged %d times; want once:\n%s", failures, errOut.String())
+	}
+}
@@ -0,0 +1,173 @@
+package app
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/steipete/blucli/internal/discovery"
+	"github.com/steipete/blucli/internal/output"
+)
+
+func inventoryServer(t *testing.T, sync, status string) discovery.Device {
+	t.Helper()
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/xml")
+		switch r.URL.Path {
+		case "/SyncStatus":
+			_, _ = w.Write([]byte(sync))
+		case "/Status":
+			_, _ = w.Write([]byte(status))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	t.Cleanup(srv.Close)
+
+	u, _ := url.Parse(srv.URL)
+	host, portStr, _ := net.SplitHostPort(u.Host)
+	port, _ := strconv.Atoi(portStr)
+	return discovery.Device{ID: u.Host, Host: host, Port: port, Source: "mdns", Version: "4.2.1"}
+}
+
+func inventoryContext(devices ...discovery.Device) context.Context {
+	ctx := discovery.WithMDNSOverride(context.Background(), func(context.Context) ([]discovery.Device, error) { return devices, nil })
+	return discovery.WithLSDPOverride(ctx, func(context.Context) ([]discovery.Device, error) { return nil, nil })
+}
+
+func TestCmdInventory_RowsPerPlayer(t *testing.T) {
+	t.Parallel()
+
+	kitchen := inventoryServer(t,
+		`<SyncStatus name="Kitchen" brand="Bluesound" modelName="NODE 2i" model="N130" mac="90:56:82:AA:BB:CC" group="Downstairs"><slave id="10.0.0.9" port="11000"/></SyncStatus>`,
+		`<status><state>play</state><service>Tidal</service></status>`)
+	den := inventoryServer(t,
+		`<SyncStatus name="Den" model="P230"><master port="11000">10.0.0.5</master></SyncStatus>`,
+		`<status><state>stop</state></status>`)
+	gone := discovery.Device{ID: "127.0.0.1:1", Host: "127.0.0.1", Port: 1, Name: "Attic"}
+	ctx := inventoryContext(kitchen, den, gone)
+
+	var stdout, stderr bytes.Buffer
+	out := output.New(output.Options{Mode: output.ModeJSON, Stdout: &stdout, Stderr: &stderr})
+	if code := cmdInventory(ctx, out, 250*time.Millisecond, 2*time.Second, nil, nil); code != 0 {
+		t.Fatalf("code=%d stderr=%q", code, stderr.String())
+	}
+	var rows []inventoryRow
+	if err := json.Unmarshal(stdout.Bytes(), &rows); err != nil {
+		t.Fatalf("json: %v\n%s", err, stdout.String())
+	}
+	if len(rows) != 3 || rows[0].Name != "Attic" || rows[1].Name != "Den" || rows[2].Name != "Kitchen" {
+		t.Fatalf("rows = %+v", rows)
+	}
+	if r := rows[0]; r.Reachable || !strings.Contains(r.Error, "syncstatus:") {
+		t.Fatalf("attic = %+v", r)
+	}
+	if r := rows[1]; !r.Reachable || r.GroupRole != roleSlave || r.Master != "10.0.0.5:11000" || r.Model != "P230" {
+		t.Fatalf("den = %+v", r)
+	}
+	if r := rows[2]; !r.Reachable || r.GroupRole != roleMaster || r.Group != "Downstairs" || r.Source != "Tidal" ||
+		r.Brand != "Bluesound" || r.ModelName != "NODE 2i" || r.MAC != "90:56:82:aa:bb:cc" || r.Firmware != "4.2.1" {
+		t.Fatalf("kitchen = %+v", r)
+	}
+
+	stdout.Reset()
+	out = output.New(output.Options{Mode: output.ModeCSV, Stdout: &stdout, Stderr: &stderr})
+	if code := cmdInventory(ctx, out, 250*time.Millisecond, 2*time.Second, nil, nil); code != 0 {
+		t.Fatalf("code=%d stderr=%q", code, stderr.String())
+	}
+	if got := stdout.String(); !strings.HasPrefix(got, "id,name,brand,model,model_name,firmware,host,port,mac,group_role,") || !strings.Contains(got, ",Kitchen,Bluesound,N130,NODE 2i,4.2.1,") {
+		t.Fatalf("csv = %q", got)
+	}
+
+	stdout.Reset()
+	out = output.New(output.Options{Stdout: &stdout, Stderr: &stderr})
+	if code := cmdInventory(ctx, out, 250*time.Millisecond, 2*time.Second, nil, nil); code != 0 {
+		t.Fatalf("code=%d stderr=%q", code, stderr.String())
+	}
+	if got := stdout.String(); !strings.Contains(got, "Bluesound NODE 2i") || !strings.Contains(got, "master (Downstairs)") || !strings.Contains(got, "3 players, 1 unreachable") {
+		t.Fatalf("human = %q", got)
+	}
+}
+
+func TestCmdInventory_Diff(t *testing.T) {
+	t.Parallel()
+
+	kitchen := inventoryServer(t, `<SyncStatus name="Kitchen" mac="90:56:82:aa:bb:cc"/>`, `<status/>`)
+	kitchen.Version = "4.4.0"
+	office := inventoryServer(t, `<SyncStatus name="Office"/>`, `<status/>`)
+	ctx := inventoryContext(kitchen, office)
+
+	previous := []inventoryRow{
+		{ID: "10.0.0.5:11000", Name: "Kitchen", Host: "10.0.0.5", Port: 11000, MAC: "90:56:82:aa:bb:cc", Firmware: "4.2.1", GroupRole: roleStandalone, Reachable: true},
+		{ID: "10.0.0.6:11000", Name: "Den", Host: "10.0.0.6", Port: 11000, Reachable: true},
+	}
+	data, _ := json.Marshal(map[string]any{"schema": "blu.inventory/v1", "data": previous})
+	path := filepath.Join(t.TempDir(), "previous.json")
+	if err := os.WriteFile(path, data, 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	var stdout, stderr bytes.Buffer
+	out := output.New(output.Options{Mode: output.ModeJSON, Stdout: &stdout, Stderr: &stderr})
+	if code := cmdInventory(ctx, out, 250*time.Millisecond, 2*time.Second, nil, []string{"--diff", path}); code != 0 {
+		t.Fatalf("code=%d stderr=%q", code, stderr.String())
+	}
+	var diff []inventoryEntry
+	if err := json.Unmarshal(stdout.Bytes(), &diff); err != nil {
+		t.Fatalf("json: %v\n%s", err, stdout.String())
+	}
+	got := map[string]inventoryEntry{}
+	for _, e := range diff {
+		got[e.Name] = e
+	}
+	if e := got["Kitchen"]; e.Change != inventoryChanged || !strings.Contains(strings.Join(e.Changes, ";"), "firmware 4.2.1 -> 4.4.0") || !strings.Contains(strings.Join(e.Changes, ";"), "address 10.0.0.5:11000 -> ") {
+		t.Fatalf("kitchen = %+v", e)
+	}
+	if got["Office"].Change != inventoryNew || got["Den"].Change != inventoryMissing || len(diff) != 3 {
+		t.Fatalf("diff = %+v", diff)
+	}
+
+	// A saved diff is a valid baseline; its missing entries are dropped.
+	if err := os.WriteFile(path, stdout.Bytes(), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	stdout.Reset()
+	out = output.New(output.Options{Stdout: &stdout, Stderr: &stderr})
+	if code := cmdInventory(ctx, out, 250*time.Millisecond, 2*time.Second, nil, []string{"--diff", path}); code != 0 {
+		t.Fatalf("code=%d stderr=%q", code, stderr.String())
+	}
+	if got := stdout.String(); !strings.Contains(got, "no changes") || strings.Contains(got, "Den") {
+		t.Fatalf("human = %q", got)
+	}
+}
+
+func TestCmdInventory_DiffBadFile(t *testing.T) {
+	t.Parallel()
+
+	path := filepath.Join(t.TempDir(), "previous.json")
+	if err := os.WriteFile(path, []byte(`{"name":"x"}`), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	var stdout, stderr bytes.Buffer
+	out := output.New(output.Options{Stdout: &stdout, Stderr: &stderr})
+	if code := cmdInventory(inventoryContext(), out, 10*time.Millisecond, time.Second, nil, []string{"--diff", path}); code != 1 {
+		t.Fatalf("code=%d; want 1", code)
+	}
+	if got := stderr.String(); !strings.Contains(got, "envelope has no data") {
+		t.Fatalf("stderr = %q", got)
+	}
+	if code := cmdInventory(inventoryContext(), out, 10*time.Millisecond, time.Second, nil, []string{"extra"}); code != 2 {
+		t.Fatalf("code=%d; want 2", code)
+	}
+}
@@ -0,0 +1,143 @@
+package app
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/steipete/blucli/internal/bluos"
+	"github.com/steipete/blucli/internal/output"
+)
+
+func TestQualityBadge(t *testing.T) {
+	t.Parallel()
+
+	cases := []struct {
+		quality, format, want string
+	}{
+		{"hd", "FLAC 96000/24", "FLAC 24/96"},
+		{"cd", "FLAC 44100/16", "FLAC 16/44.1"},
+		{"mqa", "FLAC 48000/24", "MQA FLAC 24/48"},
+		{"320000", "", "320 kbps"},
+		{"hd", "", "Hi-Res"},
+		{"", "MP3 320 kb/s", "MP3 320 kb/s"},
+		{"", "MP3", "MP3"},
+		{"320000", "AAC", "AAC 320 kbps"},
+		{"hd", "FLAC", "FLAC"},
+		{"", "", ""},
+	}
+	for _, tc := range cases {
+		if got := qualityBadge(tc.quality, tc.format); got != tc.want {
+			t.Fatalf("qualityBadge(%q, %q) = %q; want %q", tc.quality, tc.format, got, tc.want)
+		}
+	}
+}
+
+func TestNowUpdateViewAtTicksWhilePlaying(t *testing.T) {
+	t.Parallel()
+
+	start := time.Now()
+	for _, state := range []string{"play", "stream"} {
+		u := nowUpdate{now: nowPlaying{Status: bluos.Status{State: state, Secs: 10, TotLen: 12}}, at: start}
+		view, ok := u.viewAt(start.Add(time.Second))
+		if !ok || view.Secs != 11 {
+			t.Fatalf("%s: viewAt(+1s) = %d, %v; want 11, true", state, view.Secs, ok)
+		}
+		if view, _ := u.viewAt(start.Add(5 * time.Second)); view.Secs != 12 {
+			t.Fatalf("%s: viewAt(+5s) = %d; want clamped to 12", state, view.Secs)
+		}
+	}
+	for _, u := range []nowUpdate{
+		{now: nowPlaying{Status: bluos.Status{State: "pause", Secs: 10}}, at: start},
+		{now: nowPlaying{Status: bluos.Status{State: "play", Secs: 10}}},
+	} {
+		if _, ok := u.viewAt(start.Add(time.Second)); ok {
+			t.Fatalf("viewAt(%+v) ticked", u)
+		}
+	}
+}
+
+func TestNowPlayingHuman(t *testing.T) {
+	t.Parallel()
+
+	var out bytes.Buffer
+	p := output.New(output.Options{Stdout: &out, Stderr: &out})
+	p.Print(nowPlaying{
+		Status: bluos.Status{State: "pause", Title: "Song", Artist: "Band", Album: "LP", Secs: 60, TotLen: 240, Volume: 20, Mute: true, Service: "Qobuz"},
+		Badge:  "FLAC 24/96",
+		Group:  &bluos.SyncStatus{Group: "Downstairs", Slaves: []bluos.SyncSlave{{ID: "10.0.0.3", Port: 11000}}},
+		Next:   &bluos.PlaylistSong{ID: 4, Title: "Next Song", Artist: "Band"},
+	})
+
+	want := "⏸ Song\n" +
+		"  Band\n" +
+		"  LP\n" +
+		"  1:00 " + strings.Repeat("━", 7) + strings.Repeat("─", 23) + " 4:00\n" +
+		"  Qobuz · FLAC 24/96 · vol 20 muted\n" +
+		"  group Downstairs (leader, 2 players)\n" +
+		"  next  Band — Next Song\n"
+	if got := out.String(); got != want {
+		t.Fatalf("stdout =\n%s\nwant\n%s", got, want)
+	}
+
+	out.Reset()
+	p.Print(nowPlaying{Status: bluos.Status{State: "stop", Volume: 5}})
+	if got := out.String(); got != "■ (nothing playing)\n  vol 5\n" {
+		t.Fatalf("stdout = %q", got)
+	}
+}
+
+func TestRunNowFollow(t *testing.T) {
+	t.Parallel()
+
+	var polls atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/xml")
+		switch r.URL.Path {
+		case "/Status":
+			switch polls.Add(1) {
+			case 1:
+				_, _ = w.Write([]byte(`<status etag="a" state="play" volume="10" title1="One" artist="A" secs="5" totlen="200" song="0"/>`))
+			case 2:
+				_, _ = w.Write([]byte(`<status etag="b" state="play" volume="10" title1="Two" artist="A" secs="0" totlen="180" song="1"/>`))
+			default:
+				<-r.Context().Done()
+			}
+		case "/Playlist":
+			_, _ = w.Write([]byte(`<playlist id="1" length="3"><song id="1"><title>Two</title><art>A</art></song><song id="2"><title>Three</title><art>A</art></song></playlist>`))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	t.Cleanup(srv.Close)
+
+	cfgPath := writeTestConfig(t, srv.URL)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go func() {
+		for polls.Load() < 3 {
+			time.Sleep(10 * time.Millisecond)
+		}
+		cancel()
+	}()
+
+	var out bytes.Buffer
+	var errOut bytes.Buffer
+	code := Run(ctx, []string{"--config", cfgPath, "--discover=false", "now", "--follow"}, &out, &errOut)
+	if code != 0 {
+		t.Fatalf("exit code = %d; stderr=%q", code, errOut.String())
+	}
+	got := out.String()
+	if strings.Contains(got, "\x1b[") {
+		t.Fatalf("stdout has escape codes off a terminal: %q", got)
+	}
+	if !strings.Contains(got, "▶ One\n") || !strings.Contains(got, "next  A — Two\n") || !strings.Contains(got, "▶ Two\n") || !strings.Contains(got, "next  A — Three\n") {
+		t.Fatalf("stdout = %q", got)
+	}
+}
@@ -32,7 +32,7 @@ func TestRunNow(t *testing.T) {
 	if code != 0 {
 		t.Fatalf("exit code = %d; stderr=%q", code, errOut.String())
 	}
-	if got := out.String(); got == "" || !strings.Contains(got, "A — T") {
+	if got := out.String(); !strings.HasPrefix(got, "▶ T\n  A\n") || !strings.Contains(got, "vol 15") {
 		t.Fatalf("stdout = %q; want now output", got)
 	}
 }
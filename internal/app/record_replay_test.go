@@ -0,0 +1,82 @@
+package app
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/steipete/blucli/internal/config"
+	"github.com/steipete/blucli/internal/discovery"
+)
+
+func TestRunRecordThenReplay(t *testing.T) {
+	// The replayed device comes from the discovery cache, under a temp dir.
+	dir := t.TempDir()
+	t.Setenv("XDG_CACHE_HOME", filepath.Join(dir, "cache"))
+	t.Setenv("HOME", dir)
+	t.Setenv("LocalAppData", filepath.Join(dir, "cache"))
+	t.Setenv("BLU_DEVICE", "")
+	t.Setenv("BLU_DISCOVER", "")
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(`<volume db="-20" mute="0">42</volume>`))
+	}))
+	u, _ := url.Parse(srv.URL)
+	port, _ := strconv.Atoi(u.Port())
+	cassette := filepath.Join(dir, "cassette")
+	cfgPath := filepath.Join(dir, "config.json")
+
+	paths, err := config.Paths()
+	if err != nil {
+		t.Fatalf("paths: %v", err)
+	}
+	if err := config.SaveDiscoveryCache(paths.CachePath, config.NewDiscoveryCache(time.Now(), []config.Device{{Host: u.Hostname(), Port: port, Name: "Kitchen"}})); err != nil {
+		t.Fatalf("save cache: %v", err)
+	}
+	cacheBefore, _ := os.ReadFile(paths.CachePath)
+
+	// Replay must not discover (or probe) anything.
+	ctx := discovery.WithMDNSOverride(context.Background(), func(context.Context) ([]discovery.Device, error) {
+		t.Error("replay ran mDNS discovery")
+		return nil, nil
+	})
+	ctx = discovery.WithLSDPOverride(ctx, func(context.Context) ([]discovery.Device, error) {
+		t.Error("replay ran LSDP discovery")
+		return nil, nil
+	})
+
+	run := func(args ...string) (int, string, string) {
+		var out, errOut bytes.Buffer
+		code := Run(ctx, append([]string{"--config", cfgPath, "--device", "kitchen"}, args...), &out, &errOut)
+		return code, out.String(), errOut.String()
+	}
+
+	if code, stdout, stderr := run("--record", cassette, "raw", "/Volume"); code != 0 || !strings.Contains(stdout, ">42<") {
+		t.Fatalf("record: code=%d stdout=%q stderr=%q", code, stdout, stderr)
+	}
+	srv.Close()
+
+	if code, stdout, stderr := run("--replay", cassette, "raw", "/Volume"); code != 0 || !strings.Contains(stdout, ">42<") {
+		t.Fatalf("replay: code=%d stdout=%q stderr=%q", code, stdout, stderr)
+	}
+	if code, _, stderr := run("--replay", cassette, "raw", "/Status"); code != 1 || !strings.Contains(stderr, "replay: no recorded response for GET /Status") {
+		t.Fatalf("replay miss: code=%d stderr=%q", code, stderr)
+	}
+	if code, _, stderr := run("--record", cassette, "--replay", cassette, "raw", "/Volume"); code != 2 || !strings.Contains(stderr, "cannot be combined") {
+		t.Fatalf("record+replay: code=%d stderr=%q", code, stderr)
+	}
+	if code, _, stderr := run("--replay", filepath.Join(dir, "missing"), "raw", "/Volume"); code != 1 || !strings.Contains(stderr, "replay:") {
+		t.Fatalf("replay missing: code=%d stderr=%q", code, stderr)
+	}
+	if after, _ := os.ReadFile(paths.CachePath); !bytes.Equal(after, cacheBefore) {
+		t.Fatalf("replay changed the cache:\n%s", after)
+	}
+}
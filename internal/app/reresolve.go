@@ -0,0 +1,132 @@
+package app
+
+import (
+	"cmp"
+	"context"
+	"errors"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/steipete/blucli/internal/bluos"
+	"github.com/steipete/blucli/internal/config"
+	"github.com/steipete/blucli/internal/discovery"
+	"github.com/steipete/blucli/internal/logging"
+)
+
+// identifyTimeout bounds the SyncStatus lookups that fill in MACs while
+// re-resolving.
+const identifyTimeout = time.Second
+
+// reresolver finds a cached player whose endpoint stopped answering (e.g.
+// after a DHCP lease change) once a request to it fails: it rediscovers,
+// finds the same player by MAC or name, saves the refreshed cache and
+// returns the new endpoint. Each endpoint is looked up at most once.
+func reresolver(cache config.DiscoveryCache, discoverTimeout time.Duration) bluos.Reresolver {
+	var mu sync.Mutex
+	found := map[string]*url.URL{}
+	return func(ctx context.Context, base *url.URL) (*url.URL, bool) {
+		mu.Lock()
+		defer mu.Unlock()
+		moved, tried := found[base.Host]
+		if !tried {
+			if d, ok := cachedAt(cache, base); ok {
+				if m, ok := rediscover(ctx, cache, d, discoverTimeout); ok {
+					moved = m.BaseURL()
+				}
+			}
+			found[base.Host] = moved
+		}
+		return moved, moved != nil
+	}
+}
+
+func cachedAt(cache config.DiscoveryCache, base *url.URL) (config.Device, bool) {
+	for _, d := range cache.Devices {
+		if d.BaseURL().Host == base.Host {
+			return d, true
+		}
+	}
+	return config.Device{}, false
+}
+
+func rediscover(ctx context.Context, cache config.DiscoveryCache, d config.Device, discoverTimeout time.Duration) (config.Device, bool) {
+	// Lookups below must not re-resolve themselves.
+	ctx = bluos.WithReresolver(ctx, nil)
+	log := logging.From(ctx)
+	log.Info("cached endpoint unreachable; rediscovering", "id", d.ID)
+
+	discoverCtx, cancel := context.WithTimeout(ctx, discoverTimeout)
+	defer cancel()
+	devices, err := discovery.Discover(discoverCtx)
+	if err != nil && !errors.Is(err, context.DeadlineExceeded) {
+		log.Info("rediscovery failed; keeping cached endpoint", "id", d.ID, "err", err)
+		return d, false
+	}
+	if d.MAC != "" {
+		identifyDevices(ctx, devices, identifyTimeout, hasMAC)
+	}
+
+	now, seen := time.Now(), cacheDevices(devices)
+	moved, ok := cache.Merge(now, seen).Find(d)
+	if !ok || moved.ID == d.ID {
+		log.Info("player not found at a new address; keeping cached endpoint", "id", d.ID)
+		return d, false
+	}
+	log.Info("player moved", "from", d.ID, "to", moved.ID)
+	if path := cache.Path(); path != "" {
+		// Best effort: the command can proceed with the new endpoint either way.
+		_ = config.UpdateDiscoveryCache(path, func(c *config.DiscoveryCache) { *c = c.Merge(now, seen) })
+	}
+	return moved, true
+}
+
+// identifyDevices fills in missing MACs, brand and model names from the
+// SyncStatus of each player for which done reports false.
+func identifyDevices(ctx context.Context, devices []discovery.Device, timeout time.Duration, done func(discovery.Device) bool) {
+	var wg sync.WaitGroup
+	for i := range devices {
+		if done(devices[i]) {
+			continue
+		}
+		wg.Add(1)
+		go func(d *discovery.Device) {
+			defer wg.Done()
+			dev := config.Device{Host: d.Host, Port: d.Port}
+			client := bluos.NewClient(dev.BaseURL(), bluos.Options{Timeout: timeout})
+			sync, err := client.SyncStatus(ctx, bluos.SyncStatusOptions{})
+			if err != nil {
+				return
+			}
+			if d.MAC == "" {
+				d.MAC = discovery.NormalizeMAC(sync.MAC)
+			}
+			d.Brand = cmp.Or(d.Brand, strings.TrimSpace(sync.Brand))
+			d.ModelName = cmp.Or(d.ModelName, strings.TrimSpace(sync.ModelName))
+			d.Model = cmp.Or(d.Model, strings.TrimSpace(sync.Model))
+		}(&devices[i])
+	}
+	wg.Wait()
+}
+
+func hasMAC(d discovery.Device) bool { return d.MAC != "" }
+
+func identified(d discovery.Device) bool {
+	return d.MAC != "" && d.ModelName != ""
+}
+
+func cacheDevices(devices []discovery.Device) []config.Device {
+	out := make([]config.Device, 0, len(devices))
+	for _, device := range devices {
+		out = append(out, config.Device{
+			ID:   device.ID,
+			Host: device.Host,
+			Port: device.Port,
+			Name: device.Name,
+			Type: device.Type,
+			MAC:  device.MAC,
+		})
+	}
+	return out
+}
@@ -0,0 +1,126 @@
+package app
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/steipete/blucli/internal/bluos"
+	"github.com/steipete/blucli/internal/config"
+	"github.com/steipete/blucli/internal/discovery"
+	"github.com/steipete/blucli/internal/logging"
+)
+
+// closedEndpoint returns a local host:port nothing listens on.
+func closedEndpoint(t *testing.T) (string, int) {
+	t.Helper()
+	srv := httptest.NewServer(http.NotFoundHandler())
+	u, _ := url.Parse(srv.URL)
+	srv.Close()
+	port, _ := strconv.Atoi(u.Port())
+	return u.Hostname(), port
+}
+
+func TestReresolverFollowsMovedPlayer(t *testing.T) {
+	t.Parallel()
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(`<status state="play" volume="15"/>`))
+	}))
+	t.Cleanup(srv.Close)
+	now, _ := url.Parse(srv.URL)
+	nowPort, _ := strconv.Atoi(now.Port())
+	oldHost, oldPort := closedEndpoint(t)
+	oldID := oldHost + ":" + strconv.Itoa(oldPort)
+
+	path := filepath.Join(t.TempDir(), "discovery.json")
+	seed := config.NewDiscoveryCache(time.Now().Add(-time.Hour), []config.Device{
+		{Host: oldHost, Port: oldPort, Name: "Kitchen", MAC: "90:56:82:9f:02:78"},
+	})
+	if err := config.SaveDiscoveryCache(path, seed); err != nil {
+		t.Fatalf("save: %v", err)
+	}
+	cache, err := config.LoadDiscoveryCache(path)
+	if err != nil {
+		t.Fatalf("load: %v", err)
+	}
+
+	var logs strings.Builder
+	logger, _ := logging.New(&logs, "debug", "text")
+	var discoveries int
+	ctx := discovery.WithMDNSOverride(logging.With(context.Background(), logger), func(context.Context) ([]discovery.Device, error) { return nil, nil })
+	ctx = discovery.WithLSDPOverride(ctx, func(context.Context) ([]discovery.Device, error) {
+		discoveries++
+		return []discovery.Device{{ID: now.Host, Host: now.Hostname(), Port: nowPort, Name: "Kitchen", MAC: "90:56:82:9f:02:78"}}, nil
+	})
+
+	// Resolution trusts the cache; nothing is probed or discovered up front.
+	cfg := config.Config{Aliases: map[string]string{"kitchen": oldID}}
+	d, err := resolveDevice(ctx, cfg, cache, "kitchen", true, 250*time.Millisecond)
+	if err != nil || d.ID != oldID || discoveries != 0 {
+		t.Fatalf("device = %+v, %v (discoveries=%d)", d, err, discoveries)
+	}
+
+	ctx = bluos.WithReresolver(ctx, reresolver(cache, 250*time.Millisecond))
+	client := bluos.NewClient(d.BaseURL(), bluos.Options{Timeout: 2 * time.Second})
+	for range 2 {
+		if st, err := client.Status(ctx, bluos.StatusOptions{}); err != nil || st.Volume != 15 {
+			t.Fatalf("status = %+v, %v", st, err)
+		}
+	}
+	if discoveries != 1 {
+		t.Fatalf("discoveries = %d; want 1", discoveries)
+	}
+	for _, want := range []string{
+		`msg="cached endpoint unreachable; rediscovering" id=` + oldID,
+		`msg="player moved" from=` + oldID + ` to=` + now.Host,
+	} {
+		if !strings.Contains(logs.String(), want) {
+			t.Fatalf("logs missing %q:\n%s", want, logs.String())
+		}
+	}
+
+	saved, err := config.LoadDiscoveryCache(path)
+	if err != nil {
+		t.Fatalf("reload: %v", err)
+	}
+	if got, ok := saved.Lookup(oldID); !ok || got.ID != now.Host {
+		t.Fatalf("saved cache = %+v", saved.Devices)
+	}
+}
+
+func TestReresolverKeepsEntryWhenPlayerNotRediscovered(t *testing.T) {
+	t.Parallel()
+
+	host, port := closedEndpoint(t)
+	cache := config.NewDiscoveryCache(time.Now(), []config.Device{{Host: host, Port: port, Name: "Kitchen"}})
+	var discoveries int
+	ctx := discovery.WithMDNSOverride(context.Background(), func(context.Context) ([]discovery.Device, error) { return nil, nil })
+	ctx = discovery.WithLSDPOverride(ctx, func(context.Context) ([]discovery.Device, error) {
+		discoveries++
+		return nil, nil
+	})
+
+	r := reresolver(cache, 250*time.Millisecond)
+	base := cache.Devices[0].BaseURL()
+	for range 2 {
+		if moved, ok := r(ctx, base); ok {
+			t.Fatalf("moved = %v; want not found", moved)
+		}
+	}
+	if discoveries != 1 {
+		t.Fatalf("discoveries = %d; want 1 (result remembered)", discoveries)
+	}
+
+	// Endpoints that are not cached are not looked up at all.
+	other, _ := url.Parse("http://192.0.2.1:11000/")
+	if _, ok := r(ctx, other); ok || discoveries != 1 {
+		t.Fatalf("uncached endpoint: ok=%v discoveries=%d", ok, discoveries)
+	}
+}
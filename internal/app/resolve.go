@@ -5,35 +5,62 @@ import (
 	"errors"
 	"fmt"
 	"net"
-	"os"
 	"strings"
 	"time"
 	"unicode"
 
 	"github.com/steipete/blucli/internal/config"
 	"github.com/steipete/blucli/internal/discovery"
+	"github.com/steipete/blucli/internal/logging"
 )
 
-func resolveDevice(ctx context.Context, cfg config.Config, cache config.DiscoveryCache, arg string, allowDiscover bool, discoverTimeout time.Duration) (config.Device, error) {
-	raw := strings.TrimSpace(arg)
-	if raw == "" {
-		raw = strings.TrimSpace(os.Getenv("BLU_DEVICE"))
+// deviceSettingKey carries the layered --device setting, so resolution can
+// tell whether its query came from the flag, BLU_DEVICE or default_device.
+type deviceSettingKey struct{}
+
+func withDeviceSetting(ctx context.Context, s effectiveSetting) context.Context {
+	return context.WithValue(ctx, deviceSettingKey{}, s)
+}
+
+// deviceSource reports where query came from: the --device setting's
+// source and origin when it is that value, "arg" for a command argument
+// (e.g. `group add <slave>`), "default" when nothing was asked for.
+func deviceSource(ctx context.Context, query string) (source, origin string) {
+	if query == "" {
+		return sourceDefault, ""
+	}
+	if s, ok := ctx.Value(deviceSettingKey{}).(effectiveSetting); ok && strings.TrimSpace(s.Value) == query {
+		return s.Source, s.Origin
 	}
-	if raw == "" {
-		raw = strings.TrimSpace(cfg.DefaultDevice)
+	return "arg", ""
+}
+
+// resolveDevice turns arg (already layered from --device, BLU_DEVICE and
+// default_device) into a player: alias, cache, name, address, or the only
+// player around.
+func resolveDevice(ctx context.Context, cfg config.Config, cache config.DiscoveryCache, arg string, allowDiscover bool, discoverTimeout time.Duration) (config.Device, error) {
+	query := strings.TrimSpace(arg)
+	source, origin := deviceSource(ctx, query)
+	log := logging.From(ctx)
+	picked := func(step string, d config.Device) config.Device {
+		log.Debug("device resolved", "step", step, "query", query, "id", d.ID, "name", d.Name, "source", source, "origin", origin)
+		return d
 	}
 
+	raw := query
+
 	if raw != "" {
 		if resolved, ok := cfg.Aliases[raw]; ok {
+			log.Debug("device alias", "alias", raw, "target", resolved)
 			raw = resolved
 		}
 		if fromCache, ok := cache.Lookup(raw); ok {
-			return fromCache, nil
+			return picked("cache", fromCache), nil
 		}
 
 		if likelyNameArg(raw) {
 			if matches := cache.FindByName(raw); len(matches) == 1 {
-				return matches[0], nil
+				return picked("cache-name", matches[0]), nil
 			} else if len(matches) > 1 {
 				return config.Device{}, fmt.Errorf("ambiguous device name %q; matches: %s", raw, formatCandidates(matches))
 			}
@@ -47,7 +74,7 @@ func resolveDevice(ctx context.Context, cfg config.Config, cache config.Discover
 					return config.Device{}, derr
 				}
 				if d, ok := matchByName(raw, devices); ok {
-					return config.Device{ID: d.ID, Host: d.Host, Port: d.Port, Name: d.Name, Type: d.Type}, nil
+					return picked("discovery-name", config.Device{ID: d.ID, Host: d.Host, Port: d.Port, Name: d.Name, Type: d.Type}), nil
 				}
 				if ms := matchManyByName(raw, devices); len(ms) > 1 {
 					return config.Device{}, fmt.Errorf("ambiguous device name %q; matches: %s", raw, formatDiscoveryCandidates(ms))
@@ -57,14 +84,18 @@ func resolveDevice(ctx context.Context, cfg config.Config, cache config.Discover
 
 		device, err := config.ParseDevice(raw)
 		if err == nil {
-			return device, nil
+			return picked("address", device), nil
 		}
 
-		return config.Device{}, fmt.Errorf("unable to resolve %q (set --device or BLU_DEVICE)", raw)
+		from := ""
+		if origin != "" {
+			from = " from " + origin
+		}
+		return config.Device{}, fmt.Errorf("unable to resolve %q%s (set --device or BLU_DEVICE)", raw, from)
 	}
 
 	if len(cache.Devices) == 1 {
-		return cache.Devices[0], nil
+		return picked("only-cached", cache.Devices[0]), nil
 	}
 
 	if !allowDiscover {
@@ -79,7 +110,7 @@ func resolveDevice(ctx context.Context, cfg config.Config, cache config.Discover
 		return config.Device{}, err
 	}
 	if len(devices) == 1 {
-		return config.Device{ID: devices[0].ID, Host: devices[0].Host, Port: devices[0].Port}, nil
+		return picked("only-discovered", config.Device{ID: devices[0].ID, Host: devices[0].Host, Port: devices[0].Port}), nil
 	}
 	if len(devices) == 0 {
 		return config.Device{}, errors.New("no devices discovered (run `blu devices` or set --device)")
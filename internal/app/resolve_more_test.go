@@ -2,6 +2,10 @@ package app
 
 import (
 	"context"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
 	"testing"
 	"time"
 
@@ -55,13 +59,26 @@ func TestResolveDevice_ArgNameAmbiguousFromCache(t *testing.T) {
 	}
 }
 
-func TestResolveDevice_UsesEnvBLUDevice(t *testing.T) {
-	t.Setenv("BLU_DEVICE", "192.0.2.2:11001")
+func TestRunResolvesEnvBLUDeviceAndLogsSource(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(`<status state="play" volume="15"/>`))
+	}))
+	t.Cleanup(srv.Close)
+	t.Setenv("BLU_DEVICE", srv.URL)
+
+	var out, errOut strings.Builder
+	cfgPath := filepath.Join(t.TempDir(), "config.json")
+	code := Run(t.Context(), []string{"--config", cfgPath, "--discover=false", "--log-level", "debug", "status"}, &out, &errOut)
+	if code != 0 || !strings.Contains(out.String(), "vol=15") {
+		t.Fatalf("code=%d stdout=%q stderr=%q", code, out.String(), errOut.String())
+	}
+	if logs := errOut.String(); !strings.Contains(logs, "msg=\"device resolved\" step=address query="+srv.URL) || !strings.Contains(logs, "source=env origin=BLU_DEVICE") {
+		t.Fatalf("stderr = %q; want resolution logged with source=env", errOut.String())
+	}
 
-	cfg := config.Config{Aliases: map[string]string{}}
-	d, err := resolveDevice(context.Background(), cfg, config.DiscoveryCache{}, "", false, 0)
-	if err != nil || d.Host != "192.0.2.2" || d.Port != 11001 {
-		t.Fatalf("d=%+v err=%v", d, err)
+	// Without the global setting in the context, nothing falls back to env.
+	if _, err := resolveDevice(t.Context(), config.Config{DefaultDevice: srv.URL}, config.DiscoveryCache{}, "", false, 0); err == nil {
+		t.Fatalf("resolveDevice(\"\") err = nil; want no device selected")
 	}
 }
 
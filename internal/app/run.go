@@ -1,16 +1,21 @@
 package app
 
 import (
+	"cmp"
 	"context"
 	"errors"
 	"flag"
 	"fmt"
 	"io"
 	"os"
+	"strconv"
+	"strings"
 	"time"
 
 	"github.com/steipete/blucli/internal/bluos"
 	"github.com/steipete/blucli/internal/config"
+	"github.com/steipete/blucli/internal/discovery"
+	"github.com/steipete/blucli/internal/logging"
 	"github.com/steipete/blucli/internal/output"
 )
 
@@ -19,7 +24,7 @@ const (
 	defaultHTTPTimeout      = 5 * time.Second
 )
 
-func Run(ctx context.Context, args []string, stdout, stderr io.Writer) int {
+func Run(ctx context.Context, args []string, stdout, stderr io.Writer) (code int) {
 	if len(args) == 0 {
 		usage(stderr)
 		return 2
@@ -31,18 +36,32 @@ func Run(ctx context.Context, args []string, stdout, stderr io.Writer) int {
 	var (
 		flagDevice     = global.String("device", "", "device id/name/alias (host[:port], discovery name, or alias)")
 		flagJSON       = global.Bool("json", false, "json output")
+		flagOutput     = global.String("output", "", "output: table|csv|tsv|markdown|yaml|json|ndjson")
+		flagEnvelope   = global.Bool("envelope", false, "wrap json/yaml output as {schema, device, data|error}")
+		flagFormat     = global.String("format", "", "Go template output (e.g. '{{.Artist}} – {{.Title}}')")
 		flagTimeout    = global.Duration("timeout", defaultHTTPTimeout, "http timeout")
 		flagDryRun     = global.Bool("dry-run", false, "log requests; block mutating requests")
-		flagTraceHTTP  = global.Bool("trace-http", false, "log HTTP requests to stderr")
+		flagQuiet      = global.Bool("quiet", false, "suppress progress notes on stderr (warnings and errors still print)")
+		flagTraceHTTP  = traceHTTPFlag("false")
+		flagTraceFmt   = global.String("trace-format", "text", "--trace-http/--dry-run log format: text|json")
+		flagHAR        = global.String("har", "", "write BluOS HTTP requests to this HAR file")
 		flagHelp       = global.Bool("help", false, "print help")
 		flagH          = global.Bool("h", false, "print help")
 		flagVersion    = global.Bool("version", false, "print version")
 		flagV          = global.Bool("v", false, "print version")
 		flagDiscover   = global.Bool("discover", true, "allow discovery when needed")
 		flagDiscTO     = global.Duration("discover-timeout", defaultDiscoveryTimeout, "discovery timeout")
+		flagInterface  = global.String("interface", "", "restrict discovery to these network interfaces (comma-separated)")
 		flagConfigPath = global.String("config", "", "config path (optional)")
+		flagLogLevel   = global.String("log-level", logging.DefaultLevel, "log level: debug|info|warn|error (logs go to stderr)")
+		flagLogFormat  = global.String("log-format", "text", "log format: text|json")
+		flagRecord     = global.String("record", "", "record BluOS HTTP requests + raw responses into this directory")
+		flagReplay     = global.String("replay", "", "answer BluOS HTTP requests from a --record directory or diag bundle zip")
+		flagReplayIgn  = global.String("replay-ignore", strings.Join(bluos.DefaultReplayIgnore, ","), "query params --replay ignores when matching (comma-separated)")
 	)
 
+	global.Var(&flagTraceHTTP, "trace-http", "log HTTP requests to stderr (=full adds size and body)")
+
 	if err := global.Parse(args); err != nil {
 		return 2
 	}
@@ -63,6 +82,12 @@ func Run(ctx context.Context, args []string, stdout, stderr io.Writer) int {
 		return 2
 	}
 
+	settings, err := layerGlobalFlags(global, config.Config{}, os.Getenv, globalSettings[:1])
+	if err != nil {
+		fmt.Fprintln(stderr, err)
+		return 2
+	}
+
 	paths, err := config.Paths()
 	if err != nil {
 		fmt.Fprintf(stderr, "config paths: %v\n", err)
@@ -73,25 +98,141 @@ func Run(ctx context.Context, args []string, stdout, stderr io.Writer) int {
 		return 1
 	} else {
 		paths.ConfigPath = resolved
+		settings[0].Value = resolved
 	}
 
 	cfg, err := config.Load(config.LoadOptions{Path: paths.ConfigPath})
-	if err != nil {
-		fmt.Fprintf(stderr, "config: %v\n", err)
+	if err != nil && cmdArgs[0] != "config" {
+		fmt.Fprintf(stderr, "config: %v (fix with `blu config edit` or `blu config validate`)\n", err)
 		return 1
 	}
 
+	layered, err := layerGlobalFlags(global, cfg, os.Getenv, globalSettings[1:])
+	if err != nil && cmdArgs[0] == "config" && strings.HasPrefix(err.Error(), "config ") {
+		// Let `config validate|edit|unset` repair a bad defaults.* value.
+		layered, err = layerGlobalFlags(global, config.Config{}, os.Getenv, globalSettings[1:])
+	}
+	if err != nil {
+		fmt.Fprintln(stderr, err)
+		return 2
+	}
+	settings = append(settings, layered...)
+
+	logger, err := logging.New(stderr, *flagLogLevel, *flagLogFormat)
+	if err != nil {
+		fmt.Fprintf(stderr, "log: %v\n", err)
+		return 2
+	}
+	ctx = logging.With(ctx, logger)
+	if err := config.MigrateSecrets(paths.ConfigPath, cfg); err != nil {
+		logger.Warn("tokens left in config.json, not moved to the secrets store", "path", paths.ConfigPath, "err", err)
+	}
+	for _, s := range settings {
+		if s.Setting == "device" {
+			ctx = withDeviceSetting(ctx, s)
+		}
+	}
+
+	if *flagInterface != "" {
+		names, err := discovery.ParseInterfaces(*flagInterface)
+		if err != nil {
+			fmt.Fprintf(stderr, "interface%s: %v\n", settings.origin("interface"), err)
+			return 2
+		}
+		ctx = discovery.WithInterfaces(ctx, names)
+	}
+
+	switch {
+	case *flagRecord != "" && *flagReplay != "":
+		fmt.Fprintln(stderr, "record: cannot be combined with --replay")
+		return 2
+	case *flagRecord != "":
+		rec, err := bluos.NewRecorder(*flagRecord, nil)
+		if err != nil {
+			fmt.Fprintf(stderr, "record%s: %v\n", settings.origin("record"), err)
+			return 1
+		}
+		ctx = bluos.WithTransport(ctx, rec)
+	case *flagReplay != "":
+		rep, err := bluos.NewReplayer(*flagReplay, bluos.ReplayOptions{Ignore: strings.FieldsFunc(*flagReplayIgn, func(r rune) bool { return r == ',' || r == ' ' })})
+		if err != nil {
+			fmt.Fprintf(stderr, "replay%s: %v\n", settings.origin("replay"), err)
+			return 1
+		}
+		ctx = bluos.WithTransport(ctx, rep)
+	}
+
+	traceOpts := bluos.TraceOptions{Full: flagTraceHTTP == "full"}
+	switch *flagTraceFmt {
+	case "text":
+	case "json":
+		traceOpts.JSON = true
+	default:
+		fmt.Fprintf(stderr, "trace-format%s: unknown format %q (want text|json)\n", settings.origin("trace-format"), *flagTraceFmt)
+		return 2
+	}
+
+	mode, err := output.ParseMode(*flagOutput)
+	if err != nil {
+		fmt.Fprintf(stderr, "output%s: %v\n", settings.origin("output"), err)
+		return 2
+	}
+
+	tmpl, err := output.ParseTemplate(*flagFormat)
+	if err != nil {
+		fmt.Fprintf(stderr, "format%s: %v\n", settings.origin("format"), err)
+		return 2
+	}
+	if *flagEnvelope && (tmpl != nil || (mode != output.ModeHuman && mode != output.ModeJSON && mode != output.ModeYAML)) {
+		fmt.Fprintln(stderr, "envelope: needs json or yaml output")
+		return 2
+	}
+
 	cache, err := config.LoadDiscoveryCache(paths.CachePath)
 	if err != nil && !errors.Is(err, os.ErrNotExist) {
 		fmt.Fprintf(stderr, "cache: %v\n", err)
 		return 1
 	}
+	switch {
+	case !*flagDiscover, *flagReplay != "":
+		// --replay stays offline and leaves the cache alone.
+	case cmdArgs[0] == "devices", cmdArgs[0] == "doctor", cmdArgs[0] == "inventory":
+		// These discover on their own and report unreachable players.
+	default:
+		ctx = bluos.WithReresolver(ctx, reresolver(cache, *flagDiscTO))
+	}
 
 	out := output.New(output.Options{
-		JSON:   *flagJSON,
-		Stdout: stdout,
-		Stderr: stderr,
+		JSON:     *flagJSON,
+		Mode:     mode,
+		Envelope: *flagEnvelope,
+		Schemas:  appSchemas(),
+		Template: tmpl,
+		Width:    terminalWidth(stdout),
+		Color:    colorEnabled(stdout),
+		TTY:      isTerminal(stdout),
+		Quiet:    *flagQuiet,
+		Stdout:   stdout,
+		Stderr:   stderr,
 	})
+	defer func() {
+		if out.Failed() {
+			code = cmp.Or(code, 1)
+		}
+		out.Finish(code)
+	}()
+
+	trace := traceWriter(flagTraceHTTP, traceOpts, *flagDryRun, stderr)
+	if *flagHAR != "" {
+		har := bluos.NewHAR("blu", Version)
+		trace = bluos.MultiTracer(trace, har)
+		defer func() {
+			if err := har.WriteFile(*flagHAR); err != nil {
+				fmt.Fprintf(stderr, "har: %v\n", err)
+				code = cmp.Or(code, 1)
+			}
+		}()
+	}
 
 	if len(cmdArgs) > 1 && (cmdArgs[1] == "-h" || cmdArgs[1] == "--help" || cmdArgs[1] == "help") {
 		if usageCommand(stdout, cmdArgs[0]) {
@@ -113,15 +254,24 @@ func Run(ctx context.Context, args []string, stdout, stderr io.Writer) int {
 		return 0
 	case "completions":
 		return cmdCompletions(out, cmdArgs[1:])
+	case "schema":
+		return cmdSchema(out, cmdArgs[1:])
+	case "config":
+		return cmdConfig(ctx, out, paths.ConfigPath, settings, cmdArgs[1:])
+	case "alias":
+		return cmdAlias(ctx, out, paths.ConfigPath, cfg, cache, *flagDiscTO, *flagTimeout, cmdArgs[1:])
 	case "devices":
-		return cmdDevices(ctx, out, paths, cfg, cache, *flagDiscTO)
+		return cmdDevices(ctx, out, paths, cfg, cache, *flagDiscTO, *flagTimeout, cmdArgs[1:])
+	case "lsdp":
+		return cmdLSDP(ctx, out, cmdArgs[1:])
 	case "status":
 		device, resolveErr := resolveDevice(ctx, cfg, cache, *flagDevice, *flagDiscover, *flagDiscTO)
 		if resolveErr != nil {
 			out.Errorf("device: %v", resolveErr)
 			return 1
 		}
-		client := bluos.NewClient(device.BaseURL(), bluos.Options{Timeout: *flagTimeout, DryRun: *flagDryRun, Trace: traceWriter(*flagTraceHTTP, *flagDryRun, stderr)})
+		out.SetDevice(device)
+		client := bluos.NewClient(device.BaseURL(), bluos.Options{Timeout: *flagTimeout, DryRun: *flagDryRun, Trace: trace})
 		status, err := client.Status(ctx, bluos.StatusOptions{})
 		if err != nil {
 			out.Errorf("status: %v", err)
@@ -130,43 +280,51 @@ func Run(ctx context.Context, args []string, stdout, stderr io.Writer) int {
 		out.Print(status)
 		return 0
 	case "now":
-		return cmdNow(ctx, out, cfg, cache, *flagDevice, *flagDiscover, *flagDiscTO, *flagTimeout, *flagDryRun, traceWriter(*flagTraceHTTP, *flagDryRun, stderr))
+		return cmdNow(ctx, out, cfg, cache, *flagDevice, *flagDiscover, *flagDiscTO, *flagTimeout, *flagDryRun, trace, cmdArgs[1:])
+	case "art":
+		return cmdArt(ctx, out, cfg, cache, *flagDevice, *flagDiscover, *flagDiscTO, *flagTimeout, *flagDryRun, trace, cmdArgs[1:])
 	case "watch":
-		return cmdWatch(ctx, out, cfg, cache, *flagDevice, *flagDiscover, *flagDiscTO, *flagTimeout, *flagDryRun, traceWriter(*flagTraceHTTP, *flagDryRun, stderr), cmdArgs[1:])
+		return cmdWatch(ctx, out, paths, cfg, cache, *flagDevice, *flagDiscover, *flagDiscTO, *flagTimeout, *flagDryRun, trace, cmdArgs[1:])
 	case "play", "pause", "stop", "next", "prev":
-		return cmdPlayback(ctx, out, cfg, cache, *flagDevice, *flagDiscover, *flagDiscTO, *flagTimeout, *flagDryRun, traceWriter(*flagTraceHTTP, *flagDryRun, stderr), cmdArgs[0], cmdArgs[1:])
+		return cmdPlayback(ctx, out, cfg, cache, *flagDevice, *flagDiscover, *flagDiscTO, *flagTimeout, *flagDryRun, trace, cmdArgs[0], cmdArgs[1:])
 	case "shuffle":
-		return cmdShuffle(ctx, out, cfg, cache, *flagDevice, *flagDiscover, *flagDiscTO, *flagTimeout, *flagDryRun, traceWriter(*flagTraceHTTP, *flagDryRun, stderr), cmdArgs[1:])
+		return cmdShuffle(ctx, out, cfg, cache, *flagDevice, *flagDiscover, *flagDiscTO, *flagTimeout, *flagDryRun, trace, cmdArgs[1:])
 	case "repeat":
-		return cmdRepeat(ctx, out, cfg, cache, *flagDevice, *flagDiscover, *flagDiscTO, *flagTimeout, *flagDryRun, traceWriter(*flagTraceHTTP, *flagDryRun, stderr), cmdArgs[1:])
+		return cmdRepeat(ctx, out, cfg, cache, *flagDevice, *flagDiscover, *flagDiscTO, *flagTimeout, *flagDryRun, trace, cmdArgs[1:])
 	case "volume":
-		return cmdVolume(ctx, out, cfg, cache, *flagDevice, *flagDiscover, *flagDiscTO, *flagTimeout, *flagDryRun, traceWriter(*flagTraceHTTP, *flagDryRun, stderr), cmdArgs[1:])
+		return cmdVolume(ctx, out, cfg, cache, *flagDevice, *flagDiscover, *flagDiscTO, *flagTimeout, *flagDryRun, trace, cmdArgs[1:])
 	case "mute":
-		return cmdMute(ctx, out, cfg, cache, *flagDevice, *flagDiscover, *flagDiscTO, *flagTimeout, *flagDryRun, traceWriter(*flagTraceHTTP, *flagDryRun, stderr), cmdArgs[1:])
+		return cmdMute(ctx, out, cfg, cache, *flagDevice, *flagDiscover, *flagDiscTO, *flagTimeout, *flagDryRun, trace, cmdArgs[1:])
 	case "group":
-		return cmdGroup(ctx, out, cfg, cache, *flagDevice, *flagDiscover, *flagDiscTO, *flagTimeout, *flagDryRun, traceWriter(*flagTraceHTTP, *flagDryRun, stderr), cmdArgs[1:])
+		return cmdGroup(ctx, out, cfg, cache, *flagDevice, *flagDiscover, *flagDiscTO, *flagTimeout, *flagDryRun, trace, cmdArgs[1:])
 	case "queue":
-		return cmdQueue(ctx, out, cfg, cache, *flagDevice, *flagDiscover, *flagDiscTO, *flagTimeout, *flagDryRun, traceWriter(*flagTraceHTTP, *flagDryRun, stderr), cmdArgs[1:])
+		return cmdQueue(ctx, out, cfg, cache, *flagDevice, *flagDiscover, *flagDiscTO, *flagTimeout, *flagDryRun, trace, cmdArgs[1:])
 	case "presets":
-		return cmdPresets(ctx, out, cfg, cache, *flagDevice, *flagDiscover, *flagDiscTO, *flagTimeout, *flagDryRun, traceWriter(*flagTraceHTTP, *flagDryRun, stderr), cmdArgs[1:])
+		return cmdPresets(ctx, out, cfg, cache, *flagDevice, *flagDiscover, *flagDiscTO, *flagTimeout, *flagDryRun, trace, cmdArgs[1:])
 	case "browse":
-		return cmdBrowse(ctx, out, cfg, cache, *flagDevice, *flagDiscover, *flagDiscTO, *flagTimeout, *flagDryRun, traceWriter(*flagTraceHTTP, *flagDryRun, stderr), cmdArgs[1:])
+		return cmdBrowse(ctx, out, cfg, cache, *flagDevice, *flagDiscover, *flagDiscTO, *flagTimeout, *flagDryRun, trace, cmdArgs[1:])
 	case "playlists":
-		return cmdPlaylists(ctx, out, cfg, cache, *flagDevice, *flagDiscover, *flagDiscTO, *flagTimeout, *flagDryRun, traceWriter(*flagTraceHTTP, *flagDryRun, stderr), cmdArgs[1:])
+		return cmdPlaylists(ctx, out, cfg, cache, *flagDevice, *flagDiscover, *flagDiscTO, *flagTimeout, *flagDryRun, trace, cmdArgs[1:])
 	case "inputs":
-		return cmdInputs(ctx, out, cfg, cache, *flagDevice, *flagDiscover, *flagDiscTO, *flagTimeout, *flagDryRun, traceWriter(*flagTraceHTTP, *flagDryRun, stderr), cmdArgs[1:])
+		return cmdInputs(ctx, out, cfg, cache, *flagDevice, *flagDiscover, *flagDiscTO, *flagTimeout, *flagDryRun, trace, cmdArgs[1:])
 	case "tunein":
-		return cmdTuneIn(ctx, out, cfg, cache, *flagDevice, *flagDiscover, *flagDiscTO, *flagTimeout, *flagDryRun, traceWriter(*flagTraceHTTP, *flagDryRun, stderr), cmdArgs[1:])
+		return cmdTuneIn(ctx, out, cfg, cache, *flagDevice, *flagDiscover, *flagDiscTO, *flagTimeout, *flagDryRun, trace, cmdArgs[1:])
 	case "spotify":
-		return cmdSpotify(ctx, out, paths, cfg, cache, *flagDevice, *flagDiscover, *flagDiscTO, *flagTimeout, *flagDryRun, traceWriter(*flagTraceHTTP, *flagDryRun, stderr), cmdArgs[1:])
+		return cmdSpotify(ctx, out, paths, cfg, cache, *flagDevice, *flagDiscover, *flagDiscTO, *flagTimeout, *flagDryRun, trace, cmdArgs[1:])
 	case "sleep":
-		return cmdSleep(ctx, out, cfg, cache, *flagDevice, *flagDiscover, *flagDiscTO, *flagTimeout, *flagDryRun, traceWriter(*flagTraceHTTP, *flagDryRun, stderr))
+		return cmdSleep(ctx, out, cfg, cache, *flagDevice, *flagDiscover, *flagDiscTO, *flagTimeout, *flagDryRun, trace)
+	case "history":
+		return cmdHistory(ctx, out, paths, cfg, cache, *flagDevice, *flagDiscover, *flagDiscTO, *flagTimeout, *flagDryRun, trace, cmdArgs[1:])
+	case "scrobble":
+		return cmdScrobble(ctx, out, paths, cfg, cache, *flagDevice, *flagDiscover, *flagDiscTO, *flagTimeout, *flagDryRun, trace, cmdArgs[1:])
 	case "diag":
-		return cmdDiag(ctx, out, cfg, cache, *flagDevice, *flagDiscover, *flagDiscTO, *flagTimeout, *flagDryRun, traceWriter(*flagTraceHTTP, *flagDryRun, stderr))
+		return cmdDiag(ctx, out, cfg, cache, *flagDevice, *flagDiscover, *flagDiscTO, *flagTimeout, *flagDryRun, trace, cmdArgs[1:])
 	case "doctor":
-		return cmdDoctor(ctx, out, cfg, cache, *flagDiscTO, *flagTimeout)
+		return cmdDoctor(ctx, out, cfg, cache, *flagDiscTO, *flagTimeout, cmdArgs[1:])
+	case "inventory":
+		return cmdInventory(ctx, out, *flagDiscTO, *flagTimeout, trace, cmdArgs[1:])
 	case "raw":
-		return cmdRaw(ctx, out, cfg, cache, *flagDevice, *flagDiscover, *flagDiscTO, *flagTimeout, *flagDryRun, traceWriter(*flagTraceHTTP, *flagDryRun, stderr), cmdArgs[1:])
+		return cmdRaw(ctx, out, cfg, cache, *flagDevice, *flagDiscover, *flagDiscTO, *flagTimeout, *flagDryRun, trace, cmdArgs[1:])
 	default:
 		out.Errorf("unknown command: %q", cmdArgs[0])
 		usage(stderr)
@@ -178,14 +336,20 @@ func usage(w io.Writer) {
 	fmt.Fprintln(w, "blu — BluOS CLI")
 	fmt.Fprintln(w)
 	fmt.Fprintln(w, "Usage:")
-	fmt.Fprintln(w, "  blu [--help] [--version] [--device <id|name|alias>] [--json] <command> [args]")
+	fmt.Fprintln(w, "  blu [--help] [--version] [--device <id|name|alias>] [--json|--output <fmt>|--format <tmpl>] [--envelope] <command> [args]")
 	fmt.Fprintln(w)
 	fmt.Fprintln(w, "Commands:")
 	fmt.Fprintln(w, "  version")
 	fmt.Fprintln(w, "  completions bash|zsh")
-	fmt.Fprintln(w, "  devices")
-	fmt.Fprintln(w, "  status|now")
-	fmt.Fprintln(w, "  watch status|sync")
+	fmt.Fprintln(w, "  schema [<name>|--dir <dir>]")
+	fmt.Fprintln(w, "  config get [<key>]|set <key> <value>|unset <key>|show [--effective]|edit|validate|path")
+	fmt.Fprintln(w, "  alias list|add <name> <device> [--force]|rm <name>|learn [--save]")
+	fmt.Fprintln(w, "  devices [--scan <cidr,...|auto>] [--watch [--interval <dur>] [--ttl <dur>]]")
+	fmt.Fprintln(w, "  lsdp dump [--duration <dur>] [--passive] [--requery <dur>] [--hex]")
+	fmt.Fprintln(w, "  status")
+	fmt.Fprintln(w, "  now [--follow]")
+	fmt.Fprintln(w, "  art [--save <path>] [--protocol auto|kitty|iterm|sixel|blocks] [--width <cells>] [<image-url>]")
+	fmt.Fprintln(w, "  watch status [--record] [--scrobble]|sync")
 	fmt.Fprintln(w, "  play [--url <url>] [--seek <seconds>] [--id <n>]")
 	fmt.Fprintln(w, "  pause|stop|next|prev")
 	fmt.Fprintln(w, "  shuffle on|off")
@@ -201,11 +365,21 @@ func usage(w io.Writer) {
 	fmt.Fprintln(w, "  tunein search|play [--pick <n>] [--id <id>] <query>")
 	fmt.Fprintln(w, "  spotify login|logout|open|devices|search|play")
 	fmt.Fprintln(w, "  sleep")
-	fmt.Fprintln(w, "  diag|doctor")
+	fmt.Fprintln(w, "  history [list|stats] [--since 7d] [--device <x>]|record [--all] [--scrobble]")
+	fmt.Fprintln(w, "  scrobble run [--all]|flush|queue")
+	fmt.Fprintln(w, "  diag [--bundle <out.zip> [--redact tokens|hosts|all|none]]")
+	fmt.Fprintln(w, "  doctor [--samples <n>]")
+	fmt.Fprintln(w, "  inventory [--diff <previous.json>]")
 	fmt.Fprintln(w, "  raw <path> [--param k=v ...] [--write]")
 	fmt.Fprintln(w)
 	fmt.Fprintln(w, "Env:")
 	fmt.Fprintln(w, "  BLU_DEVICE  default device id/name/alias")
+	fmt.Fprintln(w, "  BLU_CONFIG, BLU_JSON, BLU_OUTPUT, BLU_ENVELOPE, BLU_FORMAT, BLU_TIMEOUT, BLU_DRY_RUN, BLU_TRACE_HTTP,")
+	fmt.Fprintln(w, "  BLU_DISCOVER, BLU_DISCOVER_TIMEOUT, BLU_INTERFACE  defaults for the matching flags (flag > env > config defaults > built-in)")
+	fmt.Fprintln(w, "  BLU_TRACE_FORMAT, BLU_HAR, BLU_RECORD, BLU_REPLAY, BLU_REPLAY_IGNORE,")
+	fmt.Fprintln(w, "  BLU_LOG_LEVEL, BLU_LOG_FORMAT  defaults for the matching flags (flag > env)")
+	fmt.Fprintln(w, "  BLU_SCROBBLE_URL, BLU_SCROBBLE_TOKEN  scrobble endpoint + token")
+	fmt.Fprintln(w, "  BLU_SECRETS_BACKEND  file|keyring|encrypted (credential store; BLU_SECRETS_PASSPHRASE for encrypted)")
 }
 
 func usageCommand(w io.Writer, cmd string) bool {
@@ -213,9 +387,144 @@ func usageCommand(w io.Writer, cmd string) bool {
 	case "devices":
 		fmt.Fprintln(w, "Usage:")
 		fmt.Fprintln(w, "  blu devices")
+		fmt.Fprintln(w, "  blu devices --watch [--interval 10s] [--ttl 45s]")
+		fmt.Fprintln(w, "  blu devices --scan 192.168.1.0/24[,10.0.5.0/24]|auto")
 		fmt.Fprintln(w)
 		fmt.Fprintln(w, "Notes:")
 		fmt.Fprintln(w, "  - Runs discovery and refreshes the discovery cache.")
+		fmt.Fprintln(w, "  - --watch keeps mDNS/LSDP open and prints join/leave/change events (new address, name, firmware)")
+		fmt.Fprintln(w, "    until interrupted; players unheard for --ttl leave. The cache is updated live.")
+		fmt.Fprintln(w, "  - --scan probes port 11000 /SyncStatus on each host (rate-limited) for networks that filter")
+		fmt.Fprintln(w, "    multicast; auto uses the local IPv4 subnets (at most /24 each). Found players show source scan.")
+		fmt.Fprintln(w, "  - LSDP node IDs show as mac/node_id and announced classes as roles (player, secondary,")
+		fmt.Fprintln(w, "    pair-slave, hub, server); with --watch an LSDP delete reports the player as left.")
+		return true
+	case "lsdp":
+		fmt.Fprintln(w, "Usage:")
+		fmt.Fprintln(w, "  blu lsdp dump [--duration 30s] [--passive] [--requery 10s] [--hex]")
+		fmt.Fprintln(w)
+		fmt.Fprintln(w, "Notes:")
+		fmt.Fprintln(w, "  - Binds UDP 11430 and prints every LSDP packet (announce, delete, query) with node IDs,")
+		fmt.Fprintln(w, "    addresses, classes/roles and TXT records; undecodable packets show the parse error.")
+		fmt.Fprintln(w, "  - Sends query-all packets on the startup schedule unless --passive; our own queries loop back.")
+		fmt.Fprintln(w, "  - --hex adds a hex dump (human output); JSON always carries the raw packet as hex.")
+		return true
+	case "diag":
+		fmt.Fprintln(w, "Usage:")
+		fmt.Fprintln(w, "  blu diag")
+		fmt.Fprintln(w, "  blu diag --bundle out.zip [--redact tokens|hosts|all|none]")
+		fmt.Fprintln(w)
+		fmt.Fprintln(w, "Notes:")
+		fmt.Fprintln(w, "  - Without --bundle: status, group, presets and queue of the selected player.")
+		fmt.Fprintln(w, "  - --bundle saves the raw XML of every read endpoint of every discovered player (plus --device),")
+		fmt.Fprintln(w, "    mDNS/LSDP results with timings, the blu version/platform and the config, for bug reports.")
+		fmt.Fprintln(w, "  - --redact (default tokens) blanks scrobble/Spotify tokens; hosts replaces player and alias")
+		fmt.Fprintln(w, "    hostnames/IPs with placeholders everywhere, file names included.")
+		fmt.Fprintln(w, "  - manifest.json maps each request (path + query) to its response file, so the bundle can be replayed.")
+		return true
+	case "doctor":
+		fmt.Fprintln(w, "Usage:")
+		fmt.Fprintln(w, "  blu doctor [--samples 5]")
+		fmt.Fprintln(w)
+		fmt.Fprintln(w, "Notes:")
+		fmt.Fprintln(w, "  - Probes every discovered player concurrently: TCP port, /Status, SyncStatus latency percentiles")
+		fmt.Fprintln(w, "    (--samples round trips), clock skew (HTTP Date) and /Status long-poll (etag) support.")
+		fmt.Fprintln(w, "  - Also checks that mDNS and LSDP agree, that all players run the same firmware, and that")
+		fmt.Fprintln(w, "    aliases and default_device still reach a player.")
+		fmt.Fprintln(w, "  - Every warning or failure comes with a hint on how to fix it.")
+		return true
+	case "inventory":
+		fmt.Fprintln(w, "Usage:")
+		fmt.Fprintln(w, "  blu inventory [--output csv|markdown|json]")
+		fmt.Fprintln(w, "  blu inventory --diff previous.json")
+		fmt.Fprintln(w)
+		fmt.Fprintln(w, "Notes:")
+		fmt.Fprintln(w, "  - Discovers all players and queries SyncStatus/Status concurrently: one row per player with name,")
+		fmt.Fprintln(w, "    brand/model, firmware, address, MAC, group role, current source and SyncStatus latency.")
+		fmt.Fprintln(w, "  - Save a baseline with `blu inventory --json > inventory.json`; --diff marks players as new,")
+		fmt.Fprintln(w, "    missing or changed (firmware, address, name, model, group role, reachability).")
+		fmt.Fprintln(w, "  - Players are matched by MAC, then id, then name; --envelope files and earlier diffs load too.")
+		return true
+	case "schema":
+		fmt.Fprintln(w, "Usage:")
+		fmt.Fprintln(w, "  blu schema            list schema names")
+		fmt.Fprintln(w, "  blu schema <name>     print the JSON Schema (e.g. blu.status/v1)")
+		fmt.Fprintln(w, "  blu schema --dir <d>  write every schema to <d>")
+		fmt.Fprintln(w)
+		fmt.Fprintln(w, "Notes:")
+		fmt.Fprintln(w, "  - Schemas describe `--envelope` output: {schema, device, data} or {schema, device, error}.")
+		fmt.Fprintln(w, "  - Schemas are generated from the Go types; published copies live in docs/schemas.")
+		return true
+	case "config":
+		fmt.Fprintln(w, "Usage:")
+		fmt.Fprintln(w, "  blu config get [<key>]         print the config or one key (e.g. aliases.kitchen)")
+		fmt.Fprintln(w, "  blu config set <key> <value>  set a key; JSON values (numbers, objects) are accepted")
+		fmt.Fprintln(w, "  blu config unset <key>")
+		fmt.Fprintln(w, "  blu config show [--effective]  print the config; --effective lists global settings and their source")
+		fmt.Fprintln(w, "  blu config edit               open in $VISUAL/$EDITOR; saved only if it validates")
+		fmt.Fprintln(w, "  blu config validate           check syntax, unknown keys, aliases and default_device")
+		fmt.Fprintln(w, "  blu config path               print the resolved config path")
+		fmt.Fprintln(w)
+		fmt.Fprintln(w, "Notes:")
+		fmt.Fprintln(w, "  - Keys are dotted JSON paths: default_device, aliases.<name>, scrobble.url, spotify.client_id.")
+		fmt.Fprintln(w, "  - Errors point at line:column in config.json; `validate` exits 1 on errors (warnings pass).")
+		fmt.Fprintln(w, "  - defaults.{json,output,envelope,format,timeout,dry_run,trace_http,discover,discover_timeout,interface} set")
+		fmt.Fprintln(w, "    global flag defaults; flags win over BLU_* env vars, which win over the config.")
+		return true
+	case "alias":
+		fmt.Fprintln(w, "Usage:")
+		fmt.Fprintln(w, "  blu alias list")
+		fmt.Fprintln(w, "  blu alias add <name> <device> [--force]")
+		fmt.Fprintln(w, "  blu alias rm <name>...")
+		fmt.Fprintln(w, "  blu alias learn [--save]")
+		fmt.Fprintln(w)
+		fmt.Fprintln(w, "Notes:")
+		fmt.Fprintln(w, "  - <device> is host[:port], a URL, a discovery name, or another alias (stored resolved).")
+		fmt.Fprintln(w, "  - learn discovers players and proposes aliases from their names (\"Living Room\" -> livingroom);")
+		fmt.Fprintln(w, "    conflicts with existing aliases are reported, never overwritten; aliases whose host no longer answers show as stale.")
+		fmt.Fprintln(w, "  - learn only prints proposals; --save adds the new ones.")
+		return true
+	case "now":
+		fmt.Fprintln(w, "Usage:")
+		fmt.Fprintln(w, "  blu now [--follow]")
+		fmt.Fprintln(w)
+		fmt.Fprintln(w, "Notes:")
+		fmt.Fprintln(w, "  - Shows title/artist/album, progress, service + stream quality, group and the next queue item.")
+		fmt.Fprintln(w, "  - --follow long-polls and redraws on a terminal (one block per change otherwise); stop with Ctrl-C.")
+		fmt.Fprintln(w, "  - Color only on a terminal; NO_COLOR=1 disables it.")
+		return true
+	case "art":
+		fmt.Fprintln(w, "Usage:")
+		fmt.Fprintln(w, "  blu art [--save <path>] [--protocol auto|kitty|iterm|sixel|blocks] [--width <cells>] [<image-url>]")
+		fmt.Fprintln(w)
+		fmt.Fprintln(w, "Notes:")
+		fmt.Fprintln(w, "  - Fetches the current cover via the player (/Artwork?...); <image-url> takes a browse item's image instead.")
+		fmt.Fprintln(w, "  - auto picks kitty (kitty, Ghostty), iterm (iTerm2, WezTerm), sixel (foot, mlterm) or ANSI half-blocks.")
+		fmt.Fprintln(w, "  - When stdout is not a terminal the raw image bytes are written (blu art > cover.jpg).")
+		fmt.Fprintln(w, "  - --json prints url, content type, size and dimensions instead.")
+		return true
+	case "history":
+		fmt.Fprintln(w, "Usage:")
+		fmt.Fprintln(w, "  blu history [list] [--since <dur|date>] [--device <id|name>] [--limit <n>] [--file <path>]")
+		fmt.Fprintln(w, "  blu history stats [--since <dur|date>] [--device <id|name>] [--top <n>] [--file <path>]")
+		fmt.Fprintln(w, "  blu history record [--all] [--scrobble] [--file <path>]")
+		fmt.Fprintln(w)
+		fmt.Fprintln(w, "Notes:")
+		fmt.Fprintln(w, "  - record runs in the foreground and long-polls Status; stop with Ctrl-C.")
+		fmt.Fprintln(w, "  - `blu watch status --record` records while watching.")
+		fmt.Fprintln(w, "  - Plays shorter than 5s are not recorded.")
+		return true
+	case "scrobble":
+		fmt.Fprintln(w, "Usage:")
+		fmt.Fprintln(w, "  blu scrobble run [--all] [--queue <path>]")
+		fmt.Fprintln(w, "  blu scrobble flush [--queue <path>]")
+		fmt.Fprintln(w, "  blu scrobble queue [--queue <path>]")
+		fmt.Fprintln(w)
+		fmt.Fprintln(w, "Notes:")
+		fmt.Fprintln(w, "  - Speaks the ListenBrainz submit API (ListenBrainz, Maloja /apis/listenbrainz, ...).")
+		fmt.Fprintln(w, "  - Endpoint + token: BLU_SCROBBLE_URL/BLU_SCROBBLE_TOKEN or config scrobble.url/scrobble.token.")
+		fmt.Fprintln(w, "  - A track scrobbles after half its length or 4 minutes; radio without artist metadata is skipped.")
+		fmt.Fprintln(w, "  - Failed submissions are queued on disk and retried with the next scrobble.")
 		return true
 	case "spotify":
 		fmt.Fprintln(w, "Usage:")
@@ -231,9 +540,57 @@ func usageCommand(w io.Writer, cmd string) bool {
 	}
 }
 
-func traceWriter(traceHTTP, dryRun bool, stderr io.Writer) io.Writer {
-	if !traceHTTP && !dryRun {
+// traceWriter logs requests to stderr with --trace-http, and always under
+// --dry-run so blocked requests are visible.
+func traceWriter(traceHTTP traceHTTPFlag, opts bluos.TraceOptions, dryRun bool, stderr io.Writer) bluos.Tracer {
+	if traceHTTP == "false" && !dryRun {
 		return nil
 	}
-	return stderr
+	return bluos.NewTraceWriter(stderr, opts)
+}
+
+// traceHTTPFlag is --trace-http: a bool flag that also takes =full.
+type traceHTTPFlag string
+
+func (f *traceHTTPFlag) String() string { return string(*f) }
+
+func (f *traceHTTPFlag) IsBoolFlag() bool { return true }
+
+func (f *traceHTTPFlag) Set(v string) error {
+	mode, err := config.ParseTraceHTTP(v)
+	if err != nil {
+		return err
+	}
+	*f = traceHTTPFlag(mode)
+	return nil
+}
+
+// terminalWidth returns the width of the terminal stdout is on, else 0 (no
+// limit). A positive $COLUMNS overrides the size the terminal reports.
+func terminalWidth(stdout io.Writer) int {
+	if !isTerminal(stdout) {
+		return 0
+	}
+	if n, err := strconv.Atoi(strings.TrimSpace(os.Getenv("COLUMNS"))); err == nil && n > 0 {
+		return n
+	}
+	return ttyWidth(stdout.(*os.File))
+}
+
+// colorEnabled follows https://no-color.org: color only on a terminal and
+// never when NO_COLOR is set.
+func colorEnabled(stdout io.Writer) bool {
+	if os.Getenv("NO_COLOR") != "" || os.Getenv("TERM") == "dumb" {
+		return false
+	}
+	return isTerminal(stdout)
+}
+
+func isTerminal(w io.Writer) bool {
+	f, ok := w.(*os.File)
+	if !ok {
+		return false
+	}
+	info, err := f.Stat()
+	return err == nil && info.Mode()&os.ModeCharDevice != 0
 }
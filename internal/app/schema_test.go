@@ -0,0 +1,87 @@
+package app
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/steipete/blucli/internal/output"
+)
+
+// The published schemas are the compatibility contract for --envelope
+// consumers; regenerate with `go run ./cmd/blu schema --dir docs/schemas`.
+func TestPublishedSchemasUpToDate(t *testing.T) {
+	t.Parallel()
+
+	dir := filepath.Join("..", "..", "docs", "schemas")
+	names := []string{output.ErrorSchema}
+	for _, s := range allSchemas() {
+		names = append(names, s.Name)
+	}
+
+	for _, name := range names {
+		doc, ok := schemaDocument(name)
+		if !ok {
+			t.Fatalf("schemaDocument(%q) missing", name)
+		}
+		want, err := marshalSchema(doc)
+		if err != nil {
+			t.Fatalf("marshal %s: %v", name, err)
+		}
+		path := filepath.Join(dir, output.Schema{Name: name}.FileName())
+		got, err := os.ReadFile(path)
+		if err != nil {
+			t.Fatalf("read %s: %v", path, err)
+		}
+		if !bytes.Equal(got, want) {
+			t.Fatalf("%s is stale; run: go run ./cmd/blu schema --dir docs/schemas", path)
+		}
+	}
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatalf("read dir: %v", err)
+	}
+	if len(entries) != len(names) {
+		t.Fatalf("docs/schemas has %d files; want %d", len(entries), len(names))
+	}
+}
+
+func TestSchemaNamesUnique(t *testing.T) {
+	t.Parallel()
+
+	seen := map[string]bool{}
+	for _, s := range allSchemas() {
+		if seen[s.Name] {
+			t.Fatalf("duplicate schema %q", s.Name)
+		}
+		seen[s.Name] = true
+	}
+}
+
+func TestRunSchema(t *testing.T) {
+	t.Parallel()
+
+	var out, errOut bytes.Buffer
+	if code := Run(context.Background(), []string{"schema"}, &out, &errOut); code != 0 {
+		t.Fatalf("exit code = %d; stderr=%q", code, errOut.String())
+	}
+	if !strings.Contains(out.String(), "blu.status/v1\n") || !strings.Contains(out.String(), "blu.volume/v1\n") {
+		t.Fatalf("stdout = %q", out.String())
+	}
+
+	out.Reset()
+	if code := Run(context.Background(), []string{"schema", "blu.status/v1"}, &out, &errOut); code != 0 {
+		t.Fatalf("exit code = %d; stderr=%q", code, errOut.String())
+	}
+	if !strings.Contains(out.String(), `"const": "blu.status/v1"`) {
+		t.Fatalf("stdout = %q", out.String())
+	}
+
+	if code := Run(context.Background(), []string{"schema", "blu.nope/v1"}, &out, &errOut); code != 2 {
+		t.Fatalf("exit code = %d", code)
+	}
+}
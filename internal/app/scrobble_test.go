@@ -0,0 +1,139 @@
+package app
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/steipete/blucli/internal/scrobble"
+)
+
+func newScrobbleStub(t *testing.T) (*httptest.Server, func() []string) {
+	t.Helper()
+
+	var mu sync.Mutex
+	var bodies []string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/1/submit-listens" || r.Header.Get("Authorization") != "Token tok" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		data, _ := io.ReadAll(r.Body)
+		mu.Lock()
+		bodies = append(bodies, string(data))
+		mu.Unlock()
+		_, _ = w.Write([]byte(`{"status":"ok"}`))
+	}))
+	t.Cleanup(srv.Close)
+	return srv, func() []string {
+		mu.Lock()
+		defer mu.Unlock()
+		return append([]string(nil), bodies...)
+	}
+}
+
+func writeScrobbleConfig(t *testing.T, deviceURL, scrobbleURL string) string {
+	t.Helper()
+
+	path := filepath.Join(t.TempDir(), "config.json")
+	data, _ := json.Marshal(map[string]any{
+		"default_device": deviceURL,
+		"scrobble":       map[string]string{"url": scrobbleURL, "token": "tok"},
+	})
+	if err := os.WriteFile(path, data, 0o644); err != nil {
+		t.Fatalf("write config: %v", err)
+	}
+	return path
+}
+
+func TestRunScrobbleFlushSubmitsQueue(t *testing.T) {
+	t.Parallel()
+
+	srv, bodies := newScrobbleStub(t)
+	cfgPath := writeScrobbleConfig(t, "127.0.0.1:1", srv.URL)
+	queue := filepath.Join(t.TempDir(), "queue.json")
+	if err := scrobble.SaveQueue(queue, []scrobble.Listen{{Artist: "A", Track: "T", ListenedAt: time.Unix(10, 0)}}); err != nil {
+		t.Fatalf("save queue: %v", err)
+	}
+
+	var out bytes.Buffer
+	var errOut bytes.Buffer
+	code := Run(context.Background(), []string{"--config", cfgPath, "scrobble", "queue", "--queue", queue}, &out, &errOut)
+	if code != 0 || !strings.Contains(out.String(), "A — T") {
+		t.Fatalf("queue: code=%d stdout=%q stderr=%q", code, out.String(), errOut.String())
+	}
+
+	out.Reset()
+	code = Run(context.Background(), []string{"--config", cfgPath, "--json", "scrobble", "flush", "--queue", queue}, &out, &errOut)
+	if code != 0 {
+		t.Fatalf("exit code = %d; stderr=%q", code, errOut.String())
+	}
+	if !strings.Contains(out.String(), `"submitted": 1`) {
+		t.Fatalf("stdout = %q", out.String())
+	}
+	if got := bodies(); len(got) != 1 || !strings.Contains(got[0], `"track_name":"T"`) {
+		t.Fatalf("bodies = %q", got)
+	}
+	if _, err := os.Stat(queue); !os.IsNotExist(err) {
+		t.Fatalf("queue file still present: %v", err)
+	}
+}
+
+func TestRunScrobbleRunSubmitsCompletedTrack(t *testing.T) {
+	t.Parallel()
+
+	scrobbleSrv, bodies := newScrobbleStub(t)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+
+	var calls atomic.Int32
+	player := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/xml")
+		switch calls.Add(1) {
+		case 1:
+			_, _ = w.Write([]byte(`<status state="play" name="Kitchen" artist="A" title1="First" secs="200" totlen="240" etag="e1"/>`))
+		default:
+			_, _ = w.Write([]byte(`<status state="play" name="Kitchen" artist="A" title1="Second" secs="0" totlen="240" etag="e2"/>`))
+			go func() {
+				time.Sleep(20 * time.Millisecond)
+				cancel()
+			}()
+			<-r.Context().Done()
+		}
+	}))
+	t.Cleanup(player.Close)
+
+	cfgPath := writeScrobbleConfig(t, player.URL, scrobbleSrv.URL)
+	queue := filepath.Join(t.TempDir(), "queue.json")
+
+	var out bytes.Buffer
+	var errOut bytes.Buffer
+	code := Run(ctx, []string{"--config", cfgPath, "--discover=false", "scrobble", "run", "--queue", queue}, &out, &errOut)
+	if code != 0 {
+		t.Fatalf("exit code = %d; stderr=%q", code, errOut.String())
+	}
+
+	var single int
+	for _, body := range bodies() {
+		if strings.Contains(body, `"listen_type":"single"`) {
+			single++
+			if !strings.Contains(body, `"track_name":"First"`) || !strings.Contains(body, `"player_name":"Kitchen"`) {
+				t.Fatalf("single body = %s", body)
+			}
+		}
+	}
+	if single != 1 {
+		t.Fatalf("bodies = %q; want one scrobble of First", bodies())
+	}
+}
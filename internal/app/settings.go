@@ -0,0 +1,152 @@
+package app
+
+import (
+	"flag"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/steipete/blucli/internal/config"
+	"github.com/steipete/blucli/internal/output"
+)
+
+// Layers a global setting can come from, highest precedence first.
+const (
+	sourceFlag    = "flag"
+	sourceEnv     = "env"
+	sourceConfig  = "config"
+	sourceDefault = "default"
+)
+
+type effectiveSetting struct {
+	Setting string `json:"setting"`
+	Value   string `json:"value"`
+	Source  string `json:"source"`
+	// Origin names the env var or config key the value came from.
+	Origin string `json:"origin,omitempty"`
+}
+
+type effectiveSettings []effectiveSetting
+
+func (rows effectiveSettings) TableHeader() []string {
+	return []string{"setting", "value", "source", "origin"}
+}
+
+func (rows effectiveSettings) TableRows() [][]string {
+	out := make([][]string, 0, len(rows))
+	for _, r := range rows {
+		out = append(out, []string{r.Setting, r.Value, r.Source, r.Origin})
+	}
+	return out
+}
+
+func (rows effectiveSettings) PrintHuman(p *output.Printer) {
+	table := make([][]string, 0, len(rows))
+	for _, r := range rows {
+		value, source := r.Value, r.Source
+		if value == "" {
+			value = "-"
+		}
+		if r.Origin != "" {
+			source += " (" + r.Origin + ")"
+		}
+		table = append(table, []string{"--" + r.Setting, value, source})
+	}
+	p.Table([]string{"setting", "value", "source"}, table, func(i int) string {
+		if rows[i].Source == sourceDefault {
+			return output.Dim
+		}
+		return ""
+	})
+}
+
+// origin describes where a non-flag value came from, for error messages.
+func (rows effectiveSettings) origin(setting string) string {
+	for _, r := range rows {
+		if r.Setting == setting && r.Origin != "" {
+			return " (from " + r.Origin + ")"
+		}
+	}
+	return ""
+}
+
+type globalSetting struct {
+	flag   string
+	env    string
+	key    string
+	config func(config.Config) (string, bool)
+}
+
+// globalSettings maps global flags to their BLU_* env var and config key.
+// --config, --quiet, --trace-format, --har, the log flags and the
+// record/replay flags can only come from the flag or env.
+var globalSettings = []globalSetting{
+	{flag: "config", env: "BLU_CONFIG"},
+	{flag: "device", env: "BLU_DEVICE", key: "default_device", config: func(c config.Config) (string, bool) {
+		return c.DefaultDevice, strings.TrimSpace(c.DefaultDevice) != ""
+	}},
+	{flag: "json", env: "BLU_JSON", key: "defaults.json", config: boolDefault(func(d config.DefaultsConfig) *bool { return d.JSON })},
+	{flag: "output", env: "BLU_OUTPUT", key: "defaults.output", config: stringDefault(func(d config.DefaultsConfig) string { return d.Output })},
+	{flag: "envelope", env: "BLU_ENVELOPE", key: "defaults.envelope", config: boolDefault(func(d config.DefaultsConfig) *bool { return d.Envelope })},
+	{flag: "format", env: "BLU_FORMAT", key: "defaults.format", config: stringDefault(func(d config.DefaultsConfig) string { return d.Format })},
+	{flag: "timeout", env: "BLU_TIMEOUT", key: "defaults.timeout", config: stringDefault(func(d config.DefaultsConfig) string { return d.Timeout })},
+	{flag: "dry-run", env: "BLU_DRY_RUN", key: "defaults.dry_run", config: boolDefault(func(d config.DefaultsConfig) *bool { return d.DryRun })},
+	{flag: "trace-http", env: "BLU_TRACE_HTTP", key: "defaults.trace_http", config: stringDefault(func(d config.DefaultsConfig) string { return d.TraceHTTP })},
+	{flag: "discover", env: "BLU_DISCOVER", key: "defaults.discover", config: boolDefault(func(d config.DefaultsConfig) *bool { return d.Discover })},
+	{flag: "discover-timeout", env: "BLU_DISCOVER_TIMEOUT", key: "defaults.discover_timeout", config: stringDefault(func(d config.DefaultsConfig) string { return d.DiscoverTimeout })},
+	{flag: "interface", env: "BLU_INTERFACE", key: "defaults.interface", config: stringDefault(func(d config.DefaultsConfig) string { return d.Interface })},
+	{flag: "quiet", env: "BLU_QUIET"},
+	{flag: "trace-format", env: "BLU_TRACE_FORMAT"},
+	{flag: "har", env: "BLU_HAR"},
+	{flag: "log-level", env: "BLU_LOG_LEVEL"},
+	{flag: "log-format", env: "BLU_LOG_FORMAT"},
+	{flag: "record", env: "BLU_RECORD"},
+	{flag: "replay", env: "BLU_REPLAY"},
+	{flag: "replay-ignore", env: "BLU_REPLAY_IGNORE"},
+}
+
+func boolDefault(get func(config.DefaultsConfig) *bool) func(config.Config) (string, bool) {
+	return func(c config.Config) (string, bool) {
+		if v := get(c.Defaults); v != nil {
+			return strconv.FormatBool(*v), true
+		}
+		return "", false
+	}
+}
+
+func stringDefault(get func(config.DefaultsConfig) string) func(config.Config) (string, bool) {
+	return func(c config.Config) (string, bool) {
+		v := strings.TrimSpace(get(c.Defaults))
+		return v, v != ""
+	}
+}
+
+// layerGlobalFlags fills global flags that were not passed on the command
+// line from their env var, then from cfg, and records each value's source.
+func layerGlobalFlags(fs *flag.FlagSet, cfg config.Config, getenv func(string) string, settings []globalSetting) (effectiveSettings, error) {
+	passed := map[string]bool{}
+	fs.Visit(func(f *flag.Flag) { passed[f.Name] = true })
+
+	rows := make(effectiveSettings, 0, len(settings))
+	for _, s := range settings {
+		row := effectiveSetting{Setting: s.flag, Source: sourceDefault}
+		if passed[s.flag] {
+			row.Source = sourceFlag
+		} else if v := strings.TrimSpace(getenv(s.env)); v != "" {
+			if err := fs.Set(s.flag, v); err != nil {
+				return nil, fmt.Errorf("%s=%q: invalid value for --%s", s.env, v, s.flag)
+			}
+			row.Source, row.Origin = sourceEnv, s.env
+		} else if s.config != nil {
+			if v, ok := s.config(cfg); ok {
+				if err := fs.Set(s.flag, v); err != nil {
+					return nil, fmt.Errorf("config %s=%q: invalid value for --%s", s.key, v, s.flag)
+				}
+				row.Source, row.Origin = sourceConfig, s.key
+			}
+		}
+		row.Value = fs.Lookup(s.flag).Value.String()
+		rows = append(rows, row)
+	}
+	return rows, nil
+}
@@ -0,0 +1,139 @@
+package app
+
+import (
+	"encoding/json"
+	"flag"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/steipete/blucli/internal/config"
+)
+
+func TestLayerGlobalFlagsPrecedence(t *testing.T) {
+	fs := flag.NewFlagSet("blu", flag.ContinueOnError)
+	fs.SetOutput(io.Discard)
+	timeout := fs.Duration("timeout", 5*time.Second, "")
+	discTO := fs.Duration("discover-timeout", 4*time.Second, "")
+	discover := fs.Bool("discover", true, "")
+	asJSON := fs.Bool("json", false, "")
+	if err := fs.Parse([]string{"--timeout", "1s"}); err != nil {
+		t.Fatalf("parse: %v", err)
+	}
+
+	f := false
+	cfg := config.Config{Defaults: config.DefaultsConfig{Timeout: "9s", DiscoverTimeout: "8s", Discover: &f}}
+	env := map[string]string{"BLU_TIMEOUT": "2s", "BLU_DISCOVER_TIMEOUT": "3s"}
+	settings := []globalSetting{}
+	for _, s := range globalSettings {
+		if fs.Lookup(s.flag) != nil {
+			settings = append(settings, s)
+		}
+	}
+	rows, err := layerGlobalFlags(fs, cfg, func(k string) string { return env[k] }, settings)
+	if err != nil {
+		t.Fatalf("layer: %v", err)
+	}
+	if *timeout != time.Second || *discTO != 3*time.Second || *discover || *asJSON {
+		t.Fatalf("timeout=%v discover-timeout=%v discover=%v json=%v", *timeout, *discTO, *discover, *asJSON)
+	}
+
+	got := map[string]string{}
+	for _, r := range rows {
+		got[r.Setting] = r.Source + " " + r.Origin
+	}
+	want := map[string]string{
+		"json":             "default ",
+		"timeout":          "flag ",
+		"discover":         "config defaults.discover",
+		"discover-timeout": "env BLU_DISCOVER_TIMEOUT",
+	}
+	for k, v := range want {
+		if got[k] != v {
+			t.Fatalf("%s: source = %q; want %q (all: %v)", k, got[k], v, got)
+		}
+	}
+}
+
+func TestRunInvalidEnvDefault(t *testing.T) {
+	t.Setenv("BLU_TIMEOUT", "soon")
+	code, _, stderr := runConfig(t, filepath.Join(t.TempDir(), "config.json"), "path")
+	if code != 2 || !strings.Contains(stderr, `BLU_TIMEOUT="soon": invalid value for --timeout`) {
+		t.Fatalf("code=%d stderr=%q", code, stderr)
+	}
+}
+
+func TestRunConfigShowEffective(t *testing.T) {
+	dir := t.TempDir()
+	cfgPath := filepath.Join(dir, "config.json")
+	if err := os.WriteFile(cfgPath, []byte(`{"default_device":"kitchen","defaults":{"output":"json","timeout":"9s","trace_http":"full"}}`), 0o644); err != nil {
+		t.Fatalf("write: %v", err)
+	}
+	t.Setenv("BLU_CONFIG", cfgPath)
+	t.Setenv("BLU_TIMEOUT", "2s")
+	t.Setenv("BLU_DEVICE", "")
+	t.Setenv("BLU_TRACE_HTTP", "")
+
+	var out, errOut strings.Builder
+	code := Run(t.Context(), []string{"config", "show", "--effective"}, &out, &errOut)
+	if code != 0 {
+		t.Fatalf("code=%d stderr=%q", code, errOut.String())
+	}
+	var rows []effectiveSetting
+	if err := json.Unmarshal([]byte(out.String()), &rows); err != nil {
+		t.Fatalf("json (defaults.output=json): %v\n%s", err, out.String())
+	}
+	got := map[string]effectiveSetting{}
+	for _, r := range rows {
+		got[r.Setting] = r
+	}
+	if r := got["config"]; r.Value != cfgPath || r.Source != sourceEnv || r.Origin != "BLU_CONFIG" {
+		t.Fatalf("config = %+v", r)
+	}
+	if r := got["device"]; r.Value != "kitchen" || r.Origin != "default_device" {
+		t.Fatalf("device = %+v", r)
+	}
+	if r := got["timeout"]; r.Value != "2s" || r.Source != sourceEnv {
+		t.Fatalf("timeout = %+v", r)
+	}
+	if r := got["output"]; r.Value != "json" || r.Source != sourceConfig {
+		t.Fatalf("output = %+v", r)
+	}
+	if r := got["trace-http"]; r.Value != "full" || r.Source != sourceConfig {
+		t.Fatalf("trace-http = %+v", r)
+	}
+	if r := got["discover-timeout"]; r.Value != "4s" || r.Source != sourceDefault {
+		t.Fatalf("discover-timeout = %+v", r)
+	}
+}
+
+func TestRunUnknownInterfaceFromConfig(t *testing.T) {
+	cfgPath := filepath.Join(t.TempDir(), "config.json")
+	if err := os.WriteFile(cfgPath, []byte(`{"defaults":{"interface":"definitely-not-a-nic0"}}`), 0o644); err != nil {
+		t.Fatalf("write: %v", err)
+	}
+	t.Setenv("BLU_INTERFACE", "")
+	code, _, stderr := runConfig(t, cfgPath, "path")
+	if code != 2 || !strings.Contains(stderr, `interface (from defaults.interface): interface "definitely-not-a-nic0": no such network interface`) {
+		t.Fatalf("code=%d stderr=%q", code, stderr)
+	}
+}
+
+func TestRunConfigValidateReportsBadTraceHTTP(t *testing.T) {
+	cfgPath := filepath.Join(t.TempDir(), "config.json")
+	if err := os.WriteFile(cfgPath, []byte(`{"defaults":{"trace_http":"yes"}}`), 0o644); err != nil {
+		t.Fatalf("write: %v", err)
+	}
+	t.Setenv("BLU_TRACE_HTTP", "")
+	var errOut strings.Builder
+	if code := Run(t.Context(), []string{"--config", cfgPath, "schema"}, io.Discard, &errOut); code != 2 || !strings.Contains(errOut.String(), `config defaults.trace_http="yes": invalid value for --trace-http`) {
+		t.Fatalf("schema: code=%d stderr=%q", code, errOut.String())
+	}
+	code, stdout, _ := runConfig(t, cfgPath, "validate")
+	if code != 1 || !strings.Contains(stdout, `defaults.trace_http: want true, false or full, got "yes"`) {
+		t.Fatalf("validate: code=%d stdout=%q", code, stdout)
+	}
+}
@@ -85,7 +85,7 @@ func TestSpotifyDevicesSearchAndPlay_StubbedAPI(t *testing.T) {
 	{
 		var stdout bytes.Buffer
 		var stderr bytes.Buffer
-		out := output.New(output.Options{Stdout: &stdout, Stderr: &stderr})
+		out := output.New(output.Options{JSON: true, Stdout: &stdout, Stderr: &stderr})
 		code := cmdSpotifyDevices(context.Background(), out, paths, cfg, nil)
 		if code != 0 {
 			t.Fatalf("devices code=%d stderr=%q", code, stderr.String())
@@ -98,7 +98,7 @@ func TestSpotifyDevicesSearchAndPlay_StubbedAPI(t *testing.T) {
 	{
 		var stdout bytes.Buffer
 		var stderr bytes.Buffer
-		out := output.New(output.Options{Stdout: &stdout, Stderr: &stderr})
+		out := output.New(output.Options{JSON: true, Stdout: &stdout, Stderr: &stderr})
 		code := cmdSpotifySearch(context.Background(), out, paths, cfg, []string{"Garrett"})
 		if code != 0 {
 			t.Fatalf("search code=%d stderr=%q", code, stderr.String())
@@ -112,7 +112,7 @@ func TestSpotifyDevicesSearchAndPlay_StubbedAPI(t *testing.T) {
 		var stdout bytes.Buffer
 		var stderr bytes.Buffer
 		out := output.New(output.Options{Stdout: &stdout, Stderr: &stderr})
-		code := cmdSpotifyPlay(context.Background(), out, paths, cfg, config.DiscoveryCache{}, "", false, 0, 3*time.Second, false, nil,
+		code := cmdSpotifyPlay(context.Background(), out, paths, cfg, config.DiscoveryCache{}, cfg.DefaultDevice, false, 0, 3*time.Second, false, nil,
 			[]string{"--no-activate", "--type", "track", "--pick", "0", "--wait", "50ms", "anything"},
 		)
 		if code != 0 {
@@ -139,7 +139,7 @@ func TestSpotifyDevicesSearchAndPlay_StubbedAPI(t *testing.T) {
 		var stdout bytes.Buffer
 		var stderr bytes.Buffer
 		out := output.New(output.Options{Stdout: &stdout, Stderr: &stderr})
-		code := cmdSpotifyPlay(context.Background(), out, paths, cfg, config.DiscoveryCache{}, "", false, 0, 3*time.Second, false, nil,
+		code := cmdSpotifyPlay(context.Background(), out, paths, cfg, config.DiscoveryCache{}, cfg.DefaultDevice, false, 0, 3*time.Second, false, nil,
 			[]string{"--no-activate", "--type", "artist", "--pick", "0", "--market", "US", "--wait", "50ms", "Garrett Emery"},
 		)
 		if code != 0 {
@@ -189,7 +189,7 @@ func TestSpotifyPlay_NoDeviceCandidates(t *testing.T) {
 	var stdout bytes.Buffer
 	var stderr bytes.Buffer
 	out := output.New(output.Options{Stdout: &stdout, Stderr: &stderr})
-	code := cmdSpotifyPlay(context.Background(), out, paths, cfg, config.DiscoveryCache{}, "", false, 0, 3*time.Second, false, nil,
+	code := cmdSpotifyPlay(context.Background(), out, paths, cfg, config.DiscoveryCache{}, cfg.DefaultDevice, false, 0, 3*time.Second, false, nil,
 		[]string{"--no-activate", "--wait", "1ms", "anything"},
 	)
 	if code != 1 {
@@ -11,6 +11,7 @@ import (
 	"net/url"
 	"os"
 	"path/filepath"
+	"runtime"
 	"strings"
 	"testing"
 	"time"
@@ -106,7 +107,20 @@ func TestSpotifyLogin_SuccessRoundTrip(t *testing.T) {
 	if err != nil {
 		t.Fatalf("read config: %v", err)
 	}
-	if !bytes.Contains(data, []byte(`"access_token": "AT"`)) || !bytes.Contains(data, []byte(`"refresh_token": "RT"`)) {
+	if bytes.Contains(data, []byte("access_token")) || !bytes.Contains(data, []byte(`"client_id": "CID"`)) {
 		t.Fatalf("config = %s", string(data))
 	}
+
+	secretsPath := filepath.Join(filepath.Dir(paths.ConfigPath), "secrets.json")
+	info, err := os.Stat(secretsPath)
+	if err != nil {
+		t.Fatalf("stat secrets: %v", err)
+	}
+	if runtime.GOOS != "windows" && info.Mode().Perm() != 0o600 {
+		t.Fatalf("secrets mode = %v; want 0600", info.Mode().Perm())
+	}
+	tok, err := config.LoadSpotifyToken(paths.ConfigPath, config.Config{})
+	if err != nil || tok.AccessToken != "AT" || tok.RefreshToken != "RT" {
+		t.Fatalf("stored token = %+v, %v", tok, err)
+	}
 }
@@ -73,7 +73,7 @@ func TestSpotifyPlay_Branches(t *testing.T) {
 		var stdout bytes.Buffer
 		var stderr bytes.Buffer
 		out := output.New(output.Options{Stdout: &stdout, Stderr: &stderr})
-		code := cmdSpotifyPlay(context.Background(), out, paths, cfg, config.DiscoveryCache{}, "", false, 0, 2*time.Second, false, nil, args)
+		code := cmdSpotifyPlay(context.Background(), out, paths, cfg, config.DiscoveryCache{}, cfg.DefaultDevice, false, 0, 2*time.Second, false, nil, args)
 		return code, stdout.String(), stderr.String()
 	}
 
@@ -101,7 +101,7 @@ func TestSpotifyPlay_Branches(t *testing.T) {
 	if code != 0 {
 		t.Fatalf("code=%d stderr=%q", code, errOut)
 	}
-	if !strings.Contains(stdout, "\"type\": \"track\"") {
+	if stdout != "playing spotify:track:1\n" {
 		t.Fatalf("stdout=%q", stdout)
 	}
 	if playCalls == 0 {
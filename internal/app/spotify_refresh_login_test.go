@@ -62,8 +62,12 @@ func TestSpotifyAccessToken_RefreshPath_StubbedAccounts(t *testing.T) {
 	if err != nil {
 		t.Fatalf("read config: %v", err)
 	}
-	if !bytes.Contains(after, []byte(`"access_token": "AT2"`)) {
-		t.Fatalf("config = %s; want updated token", string(after))
+	if bytes.Contains(after, []byte("access_token")) {
+		t.Fatalf("config = %s; want token in secrets store", string(after))
+	}
+	stored, err := config.LoadSpotifyToken(p.ConfigPath, config.Config{})
+	if err != nil || stored.AccessToken != "AT2" || stored.RefreshToken != "RT" {
+		t.Fatalf("stored token = %+v, %v; want updated token", stored, err)
 	}
 }
 
@@ -138,6 +138,10 @@ func TestRunSpotifyLogoutWritesConfig(t *testing.T) {
 	if strings.Contains(string(after), "access_token") {
 		t.Fatalf("config still contains access_token: %s", string(after))
 	}
+	// Run migrated the inline token to secrets.json; logout scrubs it.
+	if _, err := os.Stat(filepath.Join(dir, "secrets.json")); !os.IsNotExist(err) {
+		t.Fatalf("secrets.json still exists: %v", err)
+	}
 }
 
 func TestSpotifyHelpers(t *testing.T) {
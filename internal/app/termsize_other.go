@@ -0,0 +1,8 @@
+//go:build !unix && !windows
+
+package app
+
+import "os"
+
+// Platforms without a terminal size query (wasm, plan9) rely on $COLUMNS.
+func ttyWidth(*os.File) int { return 0 }
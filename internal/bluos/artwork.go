@@ -0,0 +1,53 @@
+package bluos
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+const maxArtworkBytes = 16 << 20
+
+var ErrNoArtwork = errors.New("no artwork")
+
+type Artwork struct {
+	URL         string
+	ContentType string
+	Data        []byte
+}
+
+// Artwork fetches cover art for an image reference from Status or browse
+// items. Relative paths (/Artwork?...) are served by the player; absolute
+// URLs are fetched as-is.
+func (c *Client) Artwork(ctx context.Context, ref string) (Artwork, error) {
+	ref = strings.TrimSpace(ref)
+	if ref == "" {
+		return Artwork{}, ErrNoArtwork
+	}
+	parsed, err := url.Parse(ref)
+	if err != nil {
+		return Artwork{}, fmt.Errorf("invalid image url %q: %w", ref, err)
+	}
+	u := c.base().ResolveReference(parsed)
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u.String(), nil)
+	if err != nil {
+		return Artwork{}, err
+	}
+
+	data, header, err := c.do(req, maxArtworkBytes)
+	if err != nil {
+		return Artwork{}, err
+	}
+	if len(data) == 0 {
+		return Artwork{}, ErrNoArtwork
+	}
+	contentType := header.Get("Content-Type")
+	if contentType == "" || contentType == "application/octet-stream" {
+		contentType = http.DetectContentType(data)
+	}
+	return Artwork{URL: u.String(), ContentType: contentType, Data: data}, nil
+}
@@ -0,0 +1,336 @@
+package bluos
+
+import (
+	"archive/zip"
+	"bytes"
+	"cmp"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+type transportKey struct{}
+
+// WithTransport makes every Client use rt for requests made with ctx, e.g.
+// a Recorder or Replayer.
+func WithTransport(ctx context.Context, rt http.RoundTripper) context.Context {
+	return context.WithValue(ctx, transportKey{}, rt)
+}
+
+func transportFrom(ctx context.Context) http.RoundTripper {
+	rt, _ := ctx.Value(transportKey{}).(http.RoundTripper)
+	return rt
+}
+
+// DefaultReplayIgnore lists the query parameters replay matching ignores
+// by default: long-poll etags and timeouts differ between sessions.
+var DefaultReplayIgnore = []string{"etag", "timeout"}
+
+// Interaction is one recorded request. The raw response body lives next
+// to it in BodyFile.
+type Interaction struct {
+	Method    string      `json:"method"`
+	URL       string      `json:"url"`
+	Host      string      `json:"host"`
+	Path      string      `json:"path"`
+	Query     string      `json:"query,omitempty"`
+	Status    int         `json:"status,omitempty"`
+	Header    http.Header `json:"header,omitempty"`
+	BodyFile  string      `json:"body_file,omitempty"`
+	Error     string      `json:"error,omitempty"`
+	ElapsedMS int64       `json:"elapsed_ms"`
+	Recorded  time.Time   `json:"recorded"`
+}
+
+// Recorder is an http.RoundTripper that saves every request and raw
+// response to a cassette directory as NNNN-<path>.json plus .body files.
+type Recorder struct {
+	dir  string
+	next http.RoundTripper
+
+	mu  sync.Mutex
+	seq int
+}
+
+var cassetteName = regexp.MustCompile(`^(\d+)-.*\.json$`)
+
+// NewRecorder records into dir (created if needed), continuing the
+// numbering of an existing cassette. next defaults to
+// http.DefaultTransport.
+func NewRecorder(dir string, next http.RoundTripper) (*Recorder, error) {
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return nil, err
+	}
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	r := &Recorder{dir: dir, next: next}
+	if r.next == nil {
+		r.next = http.DefaultTransport
+	}
+	for _, e := range entries {
+		if m := cassetteName.FindStringSubmatch(e.Name()); m != nil {
+			if n, _ := strconv.Atoi(m[1]); n > r.seq {
+				r.seq = n
+			}
+		}
+	}
+	return r, nil
+}
+
+func (r *Recorder) RoundTrip(req *http.Request) (*http.Response, error) {
+	start := time.Now()
+	resp, err := r.next.RoundTrip(req)
+	it := Interaction{
+		Method:    req.Method,
+		URL:       req.URL.String(),
+		Host:      req.URL.Host,
+		Path:      req.URL.Path,
+		Query:     req.URL.RawQuery,
+		ElapsedMS: time.Since(start).Milliseconds(),
+		Recorded:  start.UTC(),
+	}
+	var body []byte
+	if err != nil {
+		it.Error = err.Error()
+	} else {
+		body, err = io.ReadAll(resp.Body)
+		_ = resp.Body.Close()
+		if err != nil {
+			return nil, err
+		}
+		resp.Body = io.NopCloser(bytes.NewReader(body))
+		it.Status = resp.StatusCode
+		it.Header = resp.Header.Clone()
+	}
+	if serr := r.save(it, body); serr != nil {
+		return nil, fmt.Errorf("record: %w", serr)
+	}
+	return resp, err
+}
+
+func (r *Recorder) save(it Interaction, body []byte) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.seq++
+	base := fmt.Sprintf("%04d-%s", r.seq, cassetteSlug(it.Path))
+	if it.Error == "" {
+		it.BodyFile = base + ".body"
+		if err := os.WriteFile(filepath.Join(r.dir, it.BodyFile), body, 0o600); err != nil {
+			return err
+		}
+	}
+	data, err := json.MarshalIndent(it, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(filepath.Join(r.dir, base+".json"), append(data, '\n'), 0o600)
+}
+
+var unsafeSlugChars = regexp.MustCompile(`[^A-Za-z0-9._-]+`)
+
+func cassetteSlug(path string) string {
+	slug := strings.Trim(unsafeSlugChars.ReplaceAllString(path, "_"), "_")
+	if slug == "" {
+		return "root"
+	}
+	return slug
+}
+
+// ReplayOptions configures request matching.
+type ReplayOptions struct {
+	// Ignore lists query parameters dropped before matching.
+	Ignore []string
+}
+
+type replayEntry struct {
+	it   Interaction
+	body []byte
+}
+
+// Replayer is an http.RoundTripper that answers from a cassette directory
+// (or a `blu diag --bundle` zip) instead of the network. Requests match on
+// method, path and normalized query; recordings of the same host win.
+// Repeated requests get the recorded responses in order, then the last
+// one again (after the request's long-poll timeout, if any).
+type Replayer struct {
+	ignore  []string
+	entries map[string][]replayEntry
+
+	mu   sync.Mutex
+	next map[string]int
+}
+
+// NewReplayer loads a cassette directory or a diag bundle zip.
+func NewReplayer(path string, opts ReplayOptions) (*Replayer, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, err
+	}
+	r := &Replayer{ignore: opts.Ignore, entries: map[string][]replayEntry{}, next: map[string]int{}}
+	var loaded []replayEntry
+	if info.IsDir() {
+		loaded, err = loadCassette(path)
+	} else {
+		loaded, err = loadBundle(path)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("replay %s: %w", path, err)
+	}
+	if len(loaded) == 0 {
+		return nil, fmt.Errorf("replay %s: no recorded requests", path)
+	}
+	for _, e := range loaded {
+		key := r.key(e.it.Method, e.it.Path, e.it.Query)
+		r.entries[key] = append(r.entries[key], e)
+	}
+	return r, nil
+}
+
+func (r *Replayer) key(method, path, rawQuery string) string {
+	q, _ := url.ParseQuery(rawQuery)
+	for _, name := range r.ignore {
+		q.Del(name)
+	}
+	return method + " " + path + "?" + q.Encode()
+}
+
+func (r *Replayer) RoundTrip(req *http.Request) (*http.Response, error) {
+	key := r.key(req.Method, req.URL.Path, req.URL.RawQuery)
+	candidates := r.entries[key]
+	if sameHost := slices.DeleteFunc(slices.Clone(candidates), func(e replayEntry) bool { return e.it.Host != req.URL.Host }); len(sameHost) > 0 {
+		candidates = sameHost
+		key += " " + req.URL.Host
+	}
+	if len(candidates) == 0 {
+		return nil, fmt.Errorf("replay: no recorded response for %s %s", req.Method, req.URL.RequestURI())
+	}
+
+	r.mu.Lock()
+	i := r.next[key]
+	r.next[key] = i + 1
+	r.mu.Unlock()
+	if i >= len(candidates) {
+		// Nothing new recorded: behave like a long-poll with no change.
+		if secs, err := strconv.Atoi(req.URL.Query().Get("timeout")); err == nil && secs > 0 {
+			select {
+			case <-req.Context().Done():
+				return nil, req.Context().Err()
+			case <-time.After(time.Duration(secs) * time.Second):
+			}
+		}
+		i = len(candidates) - 1
+	}
+
+	e := candidates[i]
+	if e.it.Error != "" {
+		return nil, errors.New(e.it.Error)
+	}
+	status := cmp.Or(e.it.Status, http.StatusOK)
+	return &http.Response{
+		Status:        fmt.Sprintf("%d %s", status, http.StatusText(status)),
+		StatusCode:    status,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        e.it.Header.Clone(),
+		Body:          io.NopCloser(bytes.NewReader(e.body)),
+		ContentLength: int64(len(e.body)),
+		Request:       req,
+	}, nil
+}
+
+func loadCassette(dir string) ([]replayEntry, error) {
+	names, err := filepath.Glob(filepath.Join(dir, "*.json"))
+	if err != nil {
+		return nil, err
+	}
+	slices.Sort(names)
+	out := make([]replayEntry, 0, len(names))
+	for _, name := range names {
+		data, err := os.ReadFile(name)
+		if err != nil {
+			return nil, err
+		}
+		var e replayEntry
+		if err := json.Unmarshal(data, &e.it); err != nil {
+			return nil, fmt.Errorf("%s: %w", filepath.Base(name), err)
+		}
+		if e.it.BodyFile != "" {
+			if e.body, err = os.ReadFile(filepath.Join(dir, e.it.BodyFile)); err != nil {
+				return nil, err
+			}
+		}
+		out = append(out, e)
+	}
+	return out, nil
+}
+
+// bundleManifest mirrors the parts of a `blu diag --bundle` manifest.json
+// replay needs.
+type bundleManifest struct {
+	Players []struct {
+		Host      string `json:"host"`
+		Port      int    `json:"port"`
+		Endpoints []struct {
+			Path  string `json:"path"`
+			Query string `json:"query"`
+			File  string `json:"file"`
+			Error string `json:"error"`
+		} `json:"endpoints"`
+	} `json:"players"`
+}
+
+func loadBundle(path string) ([]replayEntry, error) {
+	zr, err := zip.OpenReader(path)
+	if err != nil {
+		return nil, err
+	}
+	defer zr.Close()
+	read := func(name string) ([]byte, error) {
+		f, err := zr.Open(name)
+		if err != nil {
+			return nil, err
+		}
+		defer f.Close()
+		return io.ReadAll(f)
+	}
+
+	data, err := read("manifest.json")
+	if err != nil {
+		return nil, err
+	}
+	var manifest bundleManifest
+	if err := json.Unmarshal(data, &manifest); err != nil {
+		return nil, fmt.Errorf("manifest.json: %w", err)
+	}
+	var out []replayEntry
+	for _, p := range manifest.Players {
+		host := net.JoinHostPort(p.Host, strconv.Itoa(p.Port))
+		for _, ep := range p.Endpoints {
+			e := replayEntry{it: Interaction{Method: http.MethodGet, Host: host, Path: ep.Path, Query: ep.Query, Error: ep.Error}}
+			if ep.File != "" {
+				if e.body, err = read(ep.File); err != nil {
+					return nil, err
+				}
+				e.it.Header = http.Header{"Content-Type": {"text/xml"}}
+			}
+			out = append(out, e)
+		}
+	}
+	return out, nil
+}
@@ -0,0 +1,156 @@
+package bluos
+
+import (
+	"archive/zip"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestRecordThenReplay(t *testing.T) {
+	t.Parallel()
+
+	var calls atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n := calls.Add(1)
+		w.Header().Set("Content-Type", "application/xml")
+		_, _ = fmt.Fprintf(w, `<status etag="e%d"><state>play</state><volume>%d</volume></status>`, n, 10*n)
+	}))
+
+	dir := filepath.Join(t.TempDir(), "cassette")
+	rec, err := NewRecorder(dir, nil)
+	if err != nil {
+		t.Fatalf("NewRecorder() err = %v", err)
+	}
+	baseURL, _ := url.Parse(srv.URL)
+	client := NewClient(baseURL, Options{Timeout: 2 * time.Second})
+	ctx := WithTransport(t.Context(), rec)
+
+	first, err := client.Status(ctx, StatusOptions{})
+	if err != nil {
+		t.Fatalf("Status() err = %v", err)
+	}
+	if _, err := client.Status(ctx, StatusOptions{ETag: first.ETag, TimeoutSeconds: 1}); err != nil {
+		t.Fatalf("Status(etag) err = %v", err)
+	}
+	srv.Close()
+	if _, err := client.Status(ctx, StatusOptions{}); err == nil {
+		t.Fatalf("Status() after close err = nil; want error")
+	}
+
+	names, _ := filepath.Glob(filepath.Join(dir, "*.json"))
+	if len(names) != 3 || filepath.Base(names[0]) != "0001-Status.json" {
+		t.Fatalf("recorded = %v; want 3 interactions starting with 0001-Status.json", names)
+	}
+
+	rep, err := NewReplayer(dir, ReplayOptions{Ignore: DefaultReplayIgnore})
+	if err != nil {
+		t.Fatalf("NewReplayer() err = %v", err)
+	}
+	ctx = WithTransport(t.Context(), rep)
+	for i, want := range []int{10, 20} {
+		status, err := client.Status(ctx, StatusOptions{ETag: "other", TimeoutSeconds: 100})
+		if err != nil {
+			t.Fatalf("replay %d err = %v", i, err)
+		}
+		if status.Volume != want {
+			t.Fatalf("replay %d volume = %d; want %d", i, status.Volume, want)
+		}
+	}
+	// The recorded failure comes back as an error; after that the last
+	// recording repeats.
+	if _, err := client.Status(ctx, StatusOptions{}); err == nil {
+		t.Fatalf("replay 2 err = nil; want recorded error")
+	}
+	if _, err := client.Status(ctx, StatusOptions{}); err == nil {
+		t.Fatalf("replay 3 err = nil; want last recording repeated")
+	}
+
+	_, err = client.SyncStatus(ctx, SyncStatusOptions{})
+	if err == nil || !strings.Contains(err.Error(), "replay: no recorded response for GET /SyncStatus") {
+		t.Fatalf("SyncStatus() err = %v; want replay miss", err)
+	}
+}
+
+func TestRecorderContinuesNumbering(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "0007-Status.json"), []byte(`{}`), 0o600); err != nil {
+		t.Fatalf("write: %v", err)
+	}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(`<volume>5</volume>`))
+	}))
+	t.Cleanup(srv.Close)
+
+	rec, err := NewRecorder(dir, nil)
+	if err != nil {
+		t.Fatalf("NewRecorder() err = %v", err)
+	}
+	baseURL, _ := url.Parse(srv.URL)
+	client := NewClient(baseURL, Options{Timeout: 2 * time.Second})
+	if _, err := client.RawGet(WithTransport(t.Context(), rec), "/Volume", nil, false); err != nil {
+		t.Fatalf("RawGet() err = %v", err)
+	}
+	for _, name := range []string{"0008-Volume.json", "0008-Volume.body"} {
+		if _, err := os.Stat(filepath.Join(dir, name)); err != nil {
+			t.Fatalf("stat %s: %v", name, err)
+		}
+	}
+}
+
+func TestReplayDiagBundle(t *testing.T) {
+	t.Parallel()
+
+	path := filepath.Join(t.TempDir(), "bundle.zip")
+	f, err := os.Create(path)
+	if err != nil {
+		t.Fatalf("create: %v", err)
+	}
+	zw := zip.NewWriter(f)
+	files := map[string]string{
+		"manifest.json": `{"format":"blu-bundle/v1","players":[{"id":"10.0.0.5:11000","host":"10.0.0.5","port":11000,"endpoints":[
+			{"path":"/Status","file":"players/10.0.0.5_11000/Status.xml"},
+			{"path":"/RadioBrowse","query":"service=Capture","error":"http 500: boom"}]}]}`,
+		"players/10.0.0.5_11000/Status.xml": `<status><state>pause</state><volume>33</volume></status>`,
+	}
+	for name, body := range files {
+		w, err := zw.Create(name)
+		if err != nil {
+			t.Fatalf("zip create: %v", err)
+		}
+		_, _ = w.Write([]byte(body))
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatalf("zip close: %v", err)
+	}
+	_ = f.Close()
+
+	rep, err := NewReplayer(path, ReplayOptions{Ignore: DefaultReplayIgnore})
+	if err != nil {
+		t.Fatalf("NewReplayer() err = %v", err)
+	}
+	ctx := WithTransport(t.Context(), rep)
+
+	// A different host still gets the only recording for the path.
+	baseURL, _ := url.Parse("http://192.0.2.1:11000")
+	client := NewClient(baseURL, Options{Timeout: 2 * time.Second})
+	status, err := client.Status(ctx, StatusOptions{})
+	if err != nil {
+		t.Fatalf("Status() err = %v", err)
+	}
+	if status.State != "pause" || status.Volume != 33 {
+		t.Fatalf("status = %+v", status)
+	}
+	if _, err := client.RawGet(ctx, "/RadioBrowse", map[string]string{"service": "Capture"}, false); err == nil || !strings.Contains(err.Error(), "boom") {
+		t.Fatalf("RadioBrowse err = %v; want recorded error", err)
+	}
+}
@@ -7,26 +7,71 @@ import (
 	"errors"
 	"fmt"
 	"io"
+	"net"
 	"net/http"
 	"net/url"
 	"strconv"
 	"strings"
+	"sync"
+	"syscall"
 	"time"
+
+	"github.com/steipete/blucli/internal/logging"
 )
 
 var ErrDryRun = errors.New("dry-run")
 
+// StatusError is a non-2xx response; Body holds its start.
+type StatusError struct {
+	Code int
+	Body string
+}
+
+func (e *StatusError) Error() string {
+	return fmt.Sprintf("http %d: %s", e.Code, e.Body)
+}
+
+// Reads are retried up to readRetries times after a transient failure (a
+// dropped keep-alive connection, 502/503/504 while the player is busy),
+// waiting retryBackoff, then twice that. Writes are never retried: BluOS
+// mutates via GET, and a repeated /Volume?db=+2 or /Skip is not idempotent.
+const readRetries = 2
+
+var retryBackoff = 250 * time.Millisecond
+
 type Options struct {
 	Timeout time.Duration
 	DryRun  bool
-	Trace   io.Writer
+	Trace   Tracer
 }
 
 type Client struct {
+	mu      sync.Mutex
 	baseURL *url.URL
 	client  *http.Client
 	dryRun  bool
-	trace   io.Writer
+	trace   Tracer
+}
+
+// base returns the player's current base URL; a Reresolver may move it.
+func (c *Client) base() *url.URL {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.baseURL
+}
+
+// Reresolver returns the current base URL of the player last known at base,
+// after a request to base could not reach it (e.g. its DHCP lease changed).
+// ok is false when the player was not found elsewhere.
+type Reresolver func(ctx context.Context, base *url.URL) (moved *url.URL, ok bool)
+
+type reresolverKey struct{}
+
+// WithReresolver makes every Client consult r when a request made with ctx
+// cannot reach its player, then repeat the request at the new address.
+// A nil r turns re-resolution off again.
+func WithReresolver(ctx context.Context, r Reresolver) context.Context {
+	return context.WithValue(ctx, reresolverKey{}, r)
 }
 
 func NewClient(baseURL *url.URL, opts Options) *Client {
@@ -60,11 +105,19 @@ type Status struct {
 	DB     float64 `xml:"-" json:"db,omitempty"`
 	Mute   BoolInt `xml:"-" json:"mute"`
 
-	Secs int `xml:"-" json:"secs,omitempty"`
+	Secs   int `xml:"-" json:"secs,omitempty"`
+	TotLen int `xml:"-" json:"totlen,omitempty"`
+	Song   int `xml:"-" json:"song,omitempty"`
 
-	Title  string `xml:"-" json:"title,omitempty"`
-	Artist string `xml:"-" json:"artist,omitempty"`
-	Album  string `xml:"-" json:"album,omitempty"`
+	Title   string `xml:"-" json:"title,omitempty"`
+	Artist  string `xml:"-" json:"artist,omitempty"`
+	Album   string `xml:"-" json:"album,omitempty"`
+	Service string `xml:"-" json:"service,omitempty"`
+
+	Quality      string `xml:"-" json:"quality,omitempty"`
+	StreamFormat string `xml:"-" json:"streamFormat,omitempty"`
+	Image        string `xml:"-" json:"image,omitempty"`
+	GroupName    string `xml:"-" json:"groupName,omitempty"`
 
 	ETag string `xml:"-" json:"etag,omitempty"`
 
@@ -82,8 +135,12 @@ type Status struct {
 	MuteAttr   *BoolInt `xml:"mute,attr" json:"-"`
 	MuteElem   *BoolInt `xml:"mute" json:"-"`
 
-	SecsAttr *int `xml:"secs,attr" json:"-"`
-	SecsElem *int `xml:"secs" json:"-"`
+	SecsAttr   *int `xml:"secs,attr" json:"-"`
+	SecsElem   *int `xml:"secs" json:"-"`
+	TotLenAttr *int `xml:"totlen,attr" json:"-"`
+	TotLenElem *int `xml:"totlen" json:"-"`
+	SongAttr   *int `xml:"song,attr" json:"-"`
+	SongElem   *int `xml:"song" json:"-"`
 
 	TitleAttr  string `xml:"title1,attr" json:"-"`
 	TitleElem  string `xml:"title1" json:"-"`
@@ -92,6 +149,18 @@ type Status struct {
 	AlbumAttr  string `xml:"album,attr" json:"-"`
 	AlbumElem  string `xml:"album" json:"-"`
 
+	ServiceAttr string `xml:"service,attr" json:"-"`
+	ServiceElem string `xml:"service" json:"-"`
+
+	QualityAttr      string `xml:"quality,attr" json:"-"`
+	QualityElem      string `xml:"quality" json:"-"`
+	StreamFormatAttr string `xml:"streamFormat,attr" json:"-"`
+	StreamFormatElem string `xml:"streamFormat" json:"-"`
+	ImageAttr        string `xml:"image,attr" json:"-"`
+	ImageElem        string `xml:"image" json:"-"`
+	GroupNameAttr    string `xml:"groupName,attr" json:"-"`
+	GroupNameElem    string `xml:"groupName" json:"-"`
+
 	ETagAttr string `xml:"etag,attr" json:"-"`
 	ETagElem string `xml:"etag" json:"-"`
 
@@ -163,6 +232,18 @@ func (s *Status) normalize() {
 		s.Secs = *s.SecsElem
 	}
 
+	if s.TotLenAttr != nil {
+		s.TotLen = *s.TotLenAttr
+	} else if s.TotLenElem != nil {
+		s.TotLen = *s.TotLenElem
+	}
+
+	if s.SongAttr != nil {
+		s.Song = *s.SongAttr
+	} else if s.SongElem != nil {
+		s.Song = *s.SongElem
+	}
+
 	if s.TitleAttr != "" {
 		s.Title = s.TitleAttr
 	} else {
@@ -178,6 +259,31 @@ func (s *Status) normalize() {
 	} else {
 		s.Album = strings.TrimSpace(s.AlbumElem)
 	}
+	if s.ServiceAttr != "" {
+		s.Service = s.ServiceAttr
+	} else {
+		s.Service = strings.TrimSpace(s.ServiceElem)
+	}
+	if s.QualityAttr != "" {
+		s.Quality = s.QualityAttr
+	} else {
+		s.Quality = strings.TrimSpace(s.QualityElem)
+	}
+	if s.StreamFormatAttr != "" {
+		s.StreamFormat = s.StreamFormatAttr
+	} else {
+		s.StreamFormat = strings.TrimSpace(s.StreamFormatElem)
+	}
+	if s.ImageAttr != "" {
+		s.Image = s.ImageAttr
+	} else {
+		s.Image = strings.TrimSpace(s.ImageElem)
+	}
+	if s.GroupNameAttr != "" {
+		s.GroupName = s.GroupNameAttr
+	} else {
+		s.GroupName = strings.TrimSpace(s.GroupNameElem)
+	}
 
 	if s.ETagAttr != "" {
 		s.ETag = s.ETagAttr
@@ -199,6 +305,11 @@ type SyncStatus struct {
 	Model   string `xml:"model,attr" json:"model,omitempty"`
 	Group   string `xml:"group,attr" json:"group,omitempty"`
 	Version string `xml:"schemaVersion,attr" json:"schemaVersion,omitempty"`
+	MAC     string `xml:"mac,attr" json:"mac,omitempty"`
+	// Brand and ModelName are the marketing names (e.g. Bluesound, NODE 2i);
+	// Model is the short code (N130).
+	Brand     string `xml:"brand,attr" json:"brand,omitempty"`
+	ModelName string `xml:"modelName,attr" json:"modelName,omitempty"`
 
 	Volume int     `xml:"volume,attr" json:"volume"`
 	DB     float64 `xml:"db,attr" json:"db,omitempty"`
@@ -422,39 +533,142 @@ func (c *Client) getWrite(ctx context.Context, path string, query url.Values) ([
 }
 
 func (c *Client) get(ctx context.Context, path string, query url.Values, mutating bool) ([]byte, error) {
-	u := c.baseURL.ResolveReference(&url.URL{Path: path})
+	if mutating && c.dryRun {
+		u := c.url(path, query)
+		if c.trace != nil {
+			c.trace.TraceHTTP(TraceEvent{Start: time.Now(), Method: http.MethodGet, URL: u.String(), RequestHeader: http.Header{}, Err: ErrDryRun, Blocked: true})
+		}
+		return nil, ErrDryRun
+	}
+
+	data, err := c.getURL(ctx, c.url(path, query), mutating)
+	if err != nil && unreachable(err) && c.reresolve(ctx) {
+		// The request never got to the player, so repeating it at the new
+		// address is safe for writes too.
+		return c.getURL(ctx, c.url(path, query), mutating)
+	}
+	return data, err
+}
+
+func (c *Client) url(path string, query url.Values) *url.URL {
+	u := c.base().ResolveReference(&url.URL{Path: path})
 	if query != nil {
 		u.RawQuery = query.Encode()
 	}
+	return u
+}
 
+func (c *Client) getURL(ctx context.Context, u *url.URL, mutating bool) ([]byte, error) {
 	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u.String(), nil)
 	if err != nil {
 		return nil, err
 	}
-
-	if c.trace != nil {
-		fmt.Fprintf(c.trace, "http: GET %s\n", u.String())
-	}
-	if mutating && c.dryRun {
-		return nil, ErrDryRun
+	for attempt := 1; ; attempt++ {
+		data, _, err := c.do(req, 2<<20)
+		if err == nil || mutating || attempt > readRetries || !transient(err) {
+			return data, err
+		}
+		wait := time.Duration(attempt) * retryBackoff
+		logging.From(ctx).Info("http retry", "url", u.String(), "attempt", attempt, "wait", wait, "err", err)
+		select {
+		case <-ctx.Done():
+			return nil, err
+		case <-time.After(wait):
+		}
+	}
+}
+
+// reresolve asks the context's Reresolver for the player's new address and
+// moves the client there. It reports whether the address changed.
+func (c *Client) reresolve(ctx context.Context) bool {
+	r, _ := ctx.Value(reresolverKey{}).(Reresolver)
+	if r == nil || ctx.Err() != nil {
+		return false
+	}
+	base := c.base()
+	moved, ok := r(ctx, base)
+	if !ok || moved.Host == base.Host {
+		return false
+	}
+	c.mu.Lock()
+	c.baseURL = moved
+	c.mu.Unlock()
+	return true
+}
+
+// unreachable reports a request that could not reach the player: the dial
+// failed, or nothing answered before the timeout.
+func unreachable(err error) bool {
+	var op *net.OpError
+	if errors.As(err, &op) && op.Op == "dial" {
+		return true
+	}
+	var ue *url.Error
+	return errors.As(err, &ue) && ue.Timeout()
+}
+
+// transient reports failures worth retrying a read for. Timeouts and
+// refused connections are not: they mean the player is gone, and callers
+// (re-resolution, doctor) want to know quickly.
+func transient(err error) bool {
+	var se *StatusError
+	if errors.As(err, &se) {
+		return se.Code == http.StatusBadGateway || se.Code == http.StatusServiceUnavailable || se.Code == http.StatusGatewayTimeout
+	}
+	return errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) || errors.Is(err, syscall.ECONNRESET)
+}
+
+func (c *Client) do(req *http.Request, limit int64) ([]byte, http.Header, error) {
+	start := time.Now()
+	data, header, status, err := c.send(req, limit)
+	logging.From(req.Context()).Debug("http request", "method", req.Method, "url", req.URL.String(), "status", status, "elapsed", time.Since(start), "bytes", len(data), "err", err)
+	if c.trace == nil {
+		if err != nil {
+			return nil, nil, err
+		}
+		return data, header, nil
+	}
+	c.trace.TraceHTTP(TraceEvent{
+		Start:          start,
+		Method:         req.Method,
+		URL:            req.URL.String(),
+		Status:         status,
+		Elapsed:        time.Since(start),
+		Body:           data,
+		RequestHeader:  req.Header,
+		ResponseHeader: header,
+		Err:            err,
+	})
+	if err != nil {
+		return nil, nil, err
 	}
+	return data, header, nil
+}
 
-	resp, err := c.client.Do(req)
+// send performs req. On a non-2xx status it returns the start of the body
+// (for tracing) along with the error.
+func (c *Client) send(req *http.Request, limit int64) ([]byte, http.Header, int, error) {
+	hc := c.client
+	if rt := transportFrom(req.Context()); rt != nil {
+		clone := *c.client
+		clone.Transport = rt
+		hc = &clone
+	}
+	resp, err := hc.Do(req)
 	if err != nil {
-		return nil, err
+		return nil, nil, 0, err
 	}
+	defer resp.Body.Close()
 	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
-		defer resp.Body.Close()
 		data, _ := io.ReadAll(io.LimitReader(resp.Body, 4096))
-		return nil, fmt.Errorf("http %d: %s", resp.StatusCode, string(data))
+		return data, resp.Header, resp.StatusCode, &StatusError{Code: resp.StatusCode, Body: string(data)}
 	}
 
-	defer resp.Body.Close()
-	data, err := io.ReadAll(io.LimitReader(resp.Body, 2<<20))
+	data, err := io.ReadAll(io.LimitReader(resp.Body, limit))
 	if err != nil {
-		return nil, err
+		return nil, resp.Header, resp.StatusCode, err
 	}
-	return data, nil
+	return data, resp.Header, resp.StatusCode, nil
 }
 
 type BoolInt bool
@@ -6,6 +6,7 @@ import (
 	"net/http"
 	"net/http/httptest"
 	"net/url"
+	"sync/atomic"
 	"testing"
 	"time"
 )
@@ -37,6 +38,30 @@ func TestStatusParsingMuteInt(t *testing.T) {
 	}
 }
 
+func TestStatusParsingPlaybackFields(t *testing.T) {
+	t.Parallel()
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/xml")
+		_, _ = w.Write([]byte(`<status etag="1"><state>play</state><service>Qobuz</service><secs>42</secs><totlen>240</totlen><song>3</song><quality>hd</quality><streamFormat>FLAC 96000/24</streamFormat><image>/Artwork?service=Qobuz&amp;songid=1</image><groupName>Downstairs</groupName></status>`))
+	}))
+	t.Cleanup(srv.Close)
+
+	baseURL, _ := url.Parse(srv.URL)
+	client := NewClient(baseURL, Options{Timeout: 2 * time.Second})
+
+	status, err := client.Status(context.Background(), StatusOptions{})
+	if err != nil {
+		t.Fatalf("Status() err = %v", err)
+	}
+	if status.Service != "Qobuz" || status.Secs != 42 || status.TotLen != 240 || status.Song != 3 {
+		t.Fatalf("status = %+v", status)
+	}
+	if status.Quality != "hd" || status.StreamFormat != "FLAC 96000/24" || status.Image != "/Artwork?service=Qobuz&songid=1" || status.GroupName != "Downstairs" {
+		t.Fatalf("status = %+v", status)
+	}
+}
+
 func TestStatusParsingElements(t *testing.T) {
 	t.Parallel()
 
@@ -188,3 +213,78 @@ func TestDryRunAllowsReadsBlocksWrites(t *testing.T) {
 	default:
 	}
 }
+
+func TestReadsRetryTransientFailures(t *testing.T) {
+	defer func(d time.Duration) { retryBackoff = d }(retryBackoff)
+	retryBackoff = time.Millisecond
+
+	var calls, fail atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if calls.Add(1) <= fail.Load() {
+			http.Error(w, "busy", http.StatusServiceUnavailable)
+			return
+		}
+		_, _ = w.Write([]byte(`<status state="play"/>`))
+	}))
+	t.Cleanup(srv.Close)
+	baseURL, _ := url.Parse(srv.URL)
+	client := NewClient(baseURL, Options{Timeout: 2 * time.Second})
+
+	fail.Store(2)
+	if st, err := client.Status(t.Context(), StatusOptions{}); err != nil || st.State != "play" || calls.Load() != 3 {
+		t.Fatalf("Status() = %+v, %v after %d calls; want success on the third", st, err, calls.Load())
+	}
+
+	calls.Store(0)
+	fail.Store(10)
+	var se *StatusError
+	if _, err := client.Status(t.Context(), StatusOptions{}); !errors.As(err, &se) || se.Code != http.StatusServiceUnavailable || calls.Load() != 1+readRetries {
+		t.Fatalf("Status() err = %v after %d calls; want 503 after %d", err, calls.Load(), 1+readRetries)
+	}
+
+	calls.Store(0)
+	if err := client.Play(t.Context(), PlayOptions{}); err == nil || calls.Load() != 1 {
+		t.Fatalf("Play() err = %v after %d calls; want one attempt", err, calls.Load())
+	}
+}
+
+func TestUnreachablePlayerIsReresolved(t *testing.T) {
+	t.Parallel()
+
+	var plays atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/Play" {
+			plays.Add(1)
+		}
+		_, _ = w.Write([]byte(`<status state="play"/>`))
+	}))
+	t.Cleanup(srv.Close)
+	moved, _ := url.Parse(srv.URL)
+
+	gone := httptest.NewServer(http.NotFoundHandler())
+	old, _ := url.Parse(gone.URL)
+	gone.Close()
+
+	var lookups atomic.Int32
+	ctx := WithReresolver(t.Context(), func(_ context.Context, base *url.URL) (*url.URL, bool) {
+		lookups.Add(1)
+		if base.Host != old.Host {
+			t.Errorf("reresolve base = %s; want %s", base, old)
+		}
+		return moved, true
+	})
+
+	client := NewClient(old, Options{Timeout: 2 * time.Second})
+	if st, err := client.Status(ctx, StatusOptions{}); err != nil || st.State != "play" {
+		t.Fatalf("Status() = %+v, %v", st, err)
+	}
+	// Later requests go straight to the new address, writes included.
+	if err := client.Play(ctx, PlayOptions{}); err != nil || plays.Load() != 1 || lookups.Load() != 1 {
+		t.Fatalf("Play() err = %v plays=%d lookups=%d", err, plays.Load(), lookups.Load())
+	}
+
+	// Without a Reresolver the dial error is returned as is.
+	if _, err := NewClient(old, Options{Timeout: 2 * time.Second}).Status(t.Context(), StatusOptions{}); err == nil {
+		t.Fatalf("Status() on a closed port succeeded")
+	}
+}
@@ -282,9 +282,9 @@ func TestLoadPresetFallbacks(t *testing.T) {
 	if err != nil {
 		t.Fatalf("LoadPreset(weird) err = %v", err)
 	}
-	m, ok := v.(map[string]string)
-	if !ok || m["xml"] == "" {
-		t.Fatalf("type=%T value=%v; want xml map", v, v)
+	raw, ok := v.(RawXML)
+	if !ok || raw.XML == "" {
+		t.Fatalf("type=%T value=%v; want RawXML", v, v)
 	}
 }
 
@@ -441,13 +441,13 @@ func TestTraceWritesRequestLine(t *testing.T) {
 	t.Cleanup(srv.Close)
 
 	baseURL, _ := url.Parse(srv.URL)
-	client := NewClient(baseURL, Options{Trace: &trace})
+	client := NewClient(baseURL, Options{Trace: NewTraceWriter(&trace, TraceOptions{})})
 
 	if _, err := client.Status(context.Background(), StatusOptions{}); err != nil {
 		t.Fatalf("Status() err = %v", err)
 	}
-	if got := trace.String(); got == "" || !strings.Contains(got, "http: GET") || !strings.Contains(got, "/Status") {
-		t.Fatalf("trace = %q; want request line", got)
+	if got := trace.String(); got == "" || !strings.Contains(got, "http: GET") || !strings.Contains(got, "/Status 200 ") {
+		t.Fatalf("trace = %q; want request line with status", got)
 	}
 }
 
@@ -476,3 +476,46 @@ func TestContextCancellationReturnsError(t *testing.T) {
 		t.Fatalf("err = %v; want context.Canceled", err)
 	}
 }
+
+func TestArtworkRelativeAndAbsolute(t *testing.T) {
+	t.Parallel()
+
+	png := []byte("\x89PNG\r\n\x1a\nrest")
+	var gotQuery string
+	player := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/Artwork" {
+			t.Fatalf("path = %q; want /Artwork", r.URL.Path)
+		}
+		gotQuery = r.URL.RawQuery
+		w.Header().Set("Content-Type", "image/jpeg")
+		_, _ = w.Write([]byte("jpeg"))
+	}))
+	t.Cleanup(player.Close)
+	cdn := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write(png)
+	}))
+	t.Cleanup(cdn.Close)
+
+	baseURL, _ := url.Parse(player.URL)
+	client := NewClient(baseURL, Options{})
+
+	art, err := client.Artwork(context.Background(), "/Artwork?service=Qobuz&songid=Qobuz%3A1")
+	if err != nil {
+		t.Fatalf("Artwork() err = %v", err)
+	}
+	if gotQuery != "service=Qobuz&songid=Qobuz%3A1" || art.ContentType != "image/jpeg" || string(art.Data) != "jpeg" {
+		t.Fatalf("art = %+v query=%q", art, gotQuery)
+	}
+
+	art, err = client.Artwork(context.Background(), cdn.URL+"/cover.png")
+	if err != nil {
+		t.Fatalf("Artwork(abs) err = %v", err)
+	}
+	if art.URL != cdn.URL+"/cover.png" || art.ContentType != "image/png" {
+		t.Fatalf("art = %+v", art)
+	}
+
+	if _, err := client.Artwork(context.Background(), " "); !errors.Is(err, ErrNoArtwork) {
+		t.Fatalf("err = %v; want ErrNoArtwork", err)
+	}
+}
@@ -44,6 +44,11 @@ type LoadedResponse struct {
 	Entries int    `xml:"entries" json:"entries,omitempty"`
 }
 
+// RawXML carries a response body that matched no known shape.
+type RawXML struct {
+	XML string `json:"xml"`
+}
+
 func (c *Client) LoadPreset(ctx context.Context, id string) (any, error) {
 	if id == "" {
 		return nil, fmt.Errorf("missing id")
@@ -66,5 +71,5 @@ func (c *Client) LoadPreset(ctx context.Context, id string) (any, error) {
 		return presets, nil
 	}
 
-	return map[string]string{"xml": string(data)}, nil
+	return RawXML{XML: string(data)}, nil
 }
@@ -0,0 +1,326 @@
+package bluos
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"maps"
+	"net/http"
+	"net/url"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+
+	"github.com/steipete/blucli/internal/fileutil"
+)
+
+// TraceEvent describes one finished (or dry-run blocked) request.
+type TraceEvent struct {
+	Start          time.Time
+	Method         string
+	URL            string
+	Status         int
+	Elapsed        time.Duration
+	Body           []byte
+	RequestHeader  http.Header
+	ResponseHeader http.Header
+	Err            error
+	// Blocked marks a mutating request that --dry-run kept from being sent.
+	Blocked bool
+}
+
+// Tracer receives an event per request. Implementations must be safe for
+// concurrent use.
+type Tracer interface {
+	TraceHTTP(TraceEvent)
+}
+
+// MultiTracer fans events out to every non-nil tracer; it returns nil when
+// there is none.
+func MultiTracer(tracers ...Tracer) Tracer {
+	var out multiTracer
+	for _, t := range tracers {
+		if t != nil {
+			out = append(out, t)
+		}
+	}
+	switch len(out) {
+	case 0:
+		return nil
+	case 1:
+		return out[0]
+	}
+	return out
+}
+
+type multiTracer []Tracer
+
+func (m multiTracer) TraceHTTP(ev TraceEvent) {
+	for _, t := range m {
+		t.TraceHTTP(ev)
+	}
+}
+
+// TraceOptions configures NewTraceWriter.
+type TraceOptions struct {
+	// Full adds response size and the (truncated) body.
+	Full bool
+	// JSON writes one JSON object per request instead of text lines.
+	JSON bool
+}
+
+// traceBodyLimit caps the body a trace writer prints; HAR keeps it all.
+const traceBodyLimit = 2048
+
+// NewTraceWriter logs requests to w: `http: GET <url> 200 12.3ms` lines, or
+// NDJSON with TraceOptions.JSON.
+func NewTraceWriter(w io.Writer, opts TraceOptions) Tracer {
+	return &traceWriter{w: w, opts: opts}
+}
+
+type traceWriter struct {
+	mu   sync.Mutex
+	w    io.Writer
+	opts TraceOptions
+}
+
+type traceLine struct {
+	Time          time.Time `json:"time"`
+	Method        string    `json:"method"`
+	URL           string    `json:"url"`
+	Status        int       `json:"status,omitempty"`
+	ElapsedMS     float64   `json:"elapsed_ms"`
+	Bytes         int       `json:"bytes"`
+	Body          string    `json:"body,omitempty"`
+	BodyTruncated bool      `json:"body_truncated,omitempty"`
+	Error         string    `json:"error,omitempty"`
+	Blocked       bool      `json:"dry_run_blocked,omitempty"`
+}
+
+func (t *traceWriter) TraceHTTP(ev TraceEvent) {
+	body, truncated := traceBody(ev.Body)
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if t.opts.JSON {
+		line := traceLine{
+			Time:      ev.Start.UTC(),
+			Method:    ev.Method,
+			URL:       ev.URL,
+			Status:    ev.Status,
+			ElapsedMS: elapsedMS(ev.Elapsed),
+			Bytes:     len(ev.Body),
+			Blocked:   ev.Blocked,
+		}
+		if ev.Err != nil && !ev.Blocked {
+			line.Error = ev.Err.Error()
+		}
+		if t.opts.Full {
+			line.Body, line.BodyTruncated = body, truncated
+		}
+		data, _ := json.Marshal(line)
+		_, _ = fmt.Fprintf(t.w, "%s\n", data)
+		return
+	}
+
+	switch {
+	case ev.Blocked:
+		fmt.Fprintf(t.w, "http: %s %s BLOCKED (dry-run)\n", ev.Method, ev.URL)
+		return
+	case ev.Status == 0:
+		fmt.Fprintf(t.w, "http: %s %s failed %.1fms: %v\n", ev.Method, ev.URL, elapsedMS(ev.Elapsed), ev.Err)
+		return
+	case !t.opts.Full:
+		fmt.Fprintf(t.w, "http: %s %s %d %.1fms\n", ev.Method, ev.URL, ev.Status, elapsedMS(ev.Elapsed))
+		return
+	}
+	fmt.Fprintf(t.w, "http: %s %s %d %.1fms %dB\n", ev.Method, ev.URL, ev.Status, elapsedMS(ev.Elapsed), len(ev.Body))
+	if body == "" {
+		return
+	}
+	for line := range strings.SplitSeq(strings.TrimRight(body, "\n"), "\n") {
+		fmt.Fprintf(t.w, "  %s\n", line)
+	}
+	if truncated {
+		fmt.Fprintf(t.w, "  … (%d more bytes)\n", len(ev.Body)-traceBodyLimit)
+	}
+}
+
+// traceBody returns printable body text: binary bodies are summarized and
+// long ones cut at traceBodyLimit.
+func traceBody(data []byte) (string, bool) {
+	if len(data) == 0 {
+		return "", false
+	}
+	if !utf8.Valid(data) {
+		return fmt.Sprintf("<%d bytes %s>", len(data), http.DetectContentType(data)), false
+	}
+	if len(data) <= traceBodyLimit {
+		return string(data), false
+	}
+	return strings.ToValidUTF8(string(data[:traceBodyLimit]), ""), true
+}
+
+func elapsedMS(d time.Duration) float64 {
+	return float64(d.Microseconds()) / 1000
+}
+
+// HAR collects requests as an HTTP Archive (HAR 1.2) for browser devtools.
+type HAR struct {
+	creator, version string
+
+	mu      sync.Mutex
+	entries []harEntry
+}
+
+// NewHAR starts an empty archive; creator/version name the tool.
+func NewHAR(creator, version string) *HAR {
+	return &HAR{creator: creator, version: version}
+}
+
+type harLog struct {
+	Log struct {
+		Version string     `json:"version"`
+		Creator harCreator `json:"creator"`
+		Entries []harEntry `json:"entries"`
+	} `json:"log"`
+}
+
+type harCreator struct {
+	Name    string `json:"name"`
+	Version string `json:"version"`
+}
+
+type harEntry struct {
+	StartedDateTime string      `json:"startedDateTime"`
+	Time            float64     `json:"time"`
+	Request         harRequest  `json:"request"`
+	Response        harResponse `json:"response"`
+	Cache           struct{}    `json:"cache"`
+	Timings         harTimings  `json:"timings"`
+	Comment         string      `json:"comment,omitempty"`
+}
+
+type harRequest struct {
+	Method      string    `json:"method"`
+	URL         string    `json:"url"`
+	HTTPVersion string    `json:"httpVersion"`
+	Cookies     []harPair `json:"cookies"`
+	Headers     []harPair `json:"headers"`
+	QueryString []harPair `json:"queryString"`
+	HeadersSize int       `json:"headersSize"`
+	BodySize    int       `json:"bodySize"`
+}
+
+type harResponse struct {
+	Status      int        `json:"status"`
+	StatusText  string     `json:"statusText"`
+	HTTPVersion string     `json:"httpVersion"`
+	Cookies     []harPair  `json:"cookies"`
+	Headers     []harPair  `json:"headers"`
+	Content     harContent `json:"content"`
+	RedirectURL string     `json:"redirectURL"`
+	HeadersSize int        `json:"headersSize"`
+	BodySize    int        `json:"bodySize"`
+}
+
+type harContent struct {
+	Size     int    `json:"size"`
+	MimeType string `json:"mimeType"`
+	Text     string `json:"text,omitempty"`
+	Encoding string `json:"encoding,omitempty"`
+}
+
+type harPair struct {
+	Name  string `json:"name"`
+	Value string `json:"value"`
+}
+
+type harTimings struct {
+	Send    float64 `json:"send"`
+	Wait    float64 `json:"wait"`
+	Receive float64 `json:"receive"`
+}
+
+func (h *HAR) TraceHTTP(ev TraceEvent) {
+	e := harEntry{
+		StartedDateTime: ev.Start.UTC().Format(time.RFC3339Nano),
+		Time:            elapsedMS(ev.Elapsed),
+		Request: harRequest{
+			Method:      ev.Method,
+			URL:         ev.URL,
+			HTTPVersion: "HTTP/1.1",
+			Cookies:     []harPair{},
+			Headers:     harHeaders(ev.RequestHeader),
+			QueryString: []harPair{},
+			HeadersSize: -1,
+		},
+		Response: harResponse{
+			Status:      ev.Status,
+			StatusText:  http.StatusText(ev.Status),
+			HTTPVersion: "HTTP/1.1",
+			Cookies:     []harPair{},
+			Headers:     harHeaders(ev.ResponseHeader),
+			Content:     harContent{Size: len(ev.Body), MimeType: ev.ResponseHeader.Get("Content-Type")},
+			HeadersSize: -1,
+			BodySize:    len(ev.Body),
+		},
+		Timings: harTimings{Wait: elapsedMS(ev.Elapsed)},
+	}
+	if u, err := url.Parse(ev.URL); err == nil {
+		q := u.Query()
+		for _, name := range slices.Sorted(maps.Keys(q)) {
+			for _, v := range q[name] {
+				e.Request.QueryString = append(e.Request.QueryString, harPair{Name: name, Value: v})
+			}
+		}
+	}
+	if e.Response.Content.MimeType == "" {
+		e.Response.Content.MimeType = "x-unknown"
+	}
+	if utf8.Valid(ev.Body) {
+		e.Response.Content.Text = string(ev.Body)
+	} else {
+		e.Response.Content.Text = base64.StdEncoding.EncodeToString(ev.Body)
+		e.Response.Content.Encoding = "base64"
+	}
+	switch {
+	case ev.Blocked:
+		e.Comment = "blocked by --dry-run (not sent)"
+	case ev.Err != nil && ev.Status == 0:
+		e.Comment = "failed: " + ev.Err.Error()
+	}
+
+	h.mu.Lock()
+	h.entries = append(h.entries, e)
+	h.mu.Unlock()
+}
+
+func harHeaders(header http.Header) []harPair {
+	out := []harPair{}
+	for _, name := range slices.Sorted(maps.Keys(header)) {
+		for _, v := range header[name] {
+			out = append(out, harPair{Name: name, Value: v})
+		}
+	}
+	return out
+}
+
+// WriteFile writes the archive atomically.
+func (h *HAR) WriteFile(path string) error {
+	var doc harLog
+	doc.Log.Version = "1.2"
+	doc.Log.Creator = harCreator{Name: h.creator, Version: h.version}
+	h.mu.Lock()
+	doc.Log.Entries = append([]harEntry{}, h.entries...)
+	h.mu.Unlock()
+
+	data, err := json.MarshalIndent(doc, "", "  ")
+	if err != nil {
+		return err
+	}
+	return fileutil.WriteAtomic(path, append(data, '\n'), 0o644)
+}
@@ -0,0 +1,128 @@
+package bluos
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func traceServer(t *testing.T) *url.URL {
+	t.Helper()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/Status":
+			w.Header().Set("Content-Type", "text/xml")
+			_, _ = w.Write([]byte(`<status etag="1"><state>play</state>` + strings.Repeat("<x/>", 1000) + `</status>`))
+		default:
+			http.Error(w, "nope", http.StatusNotFound)
+		}
+	}))
+	t.Cleanup(srv.Close)
+	baseURL, _ := url.Parse(srv.URL)
+	return baseURL
+}
+
+func TestTraceWriterFull(t *testing.T) {
+	t.Parallel()
+
+	var trace strings.Builder
+	client := NewClient(traceServer(t), Options{Timeout: 2 * time.Second, DryRun: true, Trace: NewTraceWriter(&trace, TraceOptions{Full: true})})
+
+	if _, err := client.Status(t.Context(), StatusOptions{}); err != nil {
+		t.Fatalf("Status() err = %v", err)
+	}
+	if _, err := client.RawGet(t.Context(), "/Missing", nil, false); err == nil {
+		t.Fatalf("RawGet(/Missing) err = nil; want 404")
+	}
+	if err := client.Pause(t.Context(), PauseOptions{}); !errors.Is(err, ErrDryRun) {
+		t.Fatalf("Pause() err = %v; want ErrDryRun", err)
+	}
+
+	got := trace.String()
+	for _, want := range []string{
+		"/Status 200 ",
+		"B\n  <status etag=\"1\"><state>play</state>",
+		"… (1997 more bytes)",
+		"/Missing 404 ",
+		"  nope",
+		"/Pause BLOCKED (dry-run)",
+	} {
+		if !strings.Contains(got, want) {
+			t.Fatalf("trace missing %q:\n%s", want, got)
+		}
+	}
+}
+
+func TestTraceWriterJSON(t *testing.T) {
+	t.Parallel()
+
+	var trace strings.Builder
+	client := NewClient(traceServer(t), Options{Timeout: 2 * time.Second, DryRun: true, Trace: NewTraceWriter(&trace, TraceOptions{JSON: true})})
+
+	if _, err := client.Status(t.Context(), StatusOptions{}); err != nil {
+		t.Fatalf("Status() err = %v", err)
+	}
+	_ = client.Pause(t.Context(), PauseOptions{})
+
+	lines := strings.Split(strings.TrimSpace(trace.String()), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("lines = %q; want 2", lines)
+	}
+	var status, blocked traceLine
+	if err := json.Unmarshal([]byte(lines[0]), &status); err != nil {
+		t.Fatalf("json: %v", err)
+	}
+	if status.Method != "GET" || status.Status != 200 || status.Bytes != 4045 || status.Body != "" || status.ElapsedMS <= 0 {
+		t.Fatalf("status line = %+v", status)
+	}
+	if err := json.Unmarshal([]byte(lines[1]), &blocked); err != nil {
+		t.Fatalf("json: %v", err)
+	}
+	if !blocked.Blocked || blocked.Status != 0 || blocked.Error != "" || !strings.HasSuffix(blocked.URL, "/Pause") {
+		t.Fatalf("blocked line = %+v", blocked)
+	}
+}
+
+func TestHARWriteFile(t *testing.T) {
+	t.Parallel()
+
+	har := NewHAR("blu", "test")
+	client := NewClient(traceServer(t), Options{Timeout: 2 * time.Second, DryRun: true, Trace: har})
+	if _, err := client.Status(t.Context(), StatusOptions{ETag: "1", TimeoutSeconds: 5}); err != nil {
+		t.Fatalf("Status() err = %v", err)
+	}
+	_ = client.Pause(t.Context(), PauseOptions{})
+
+	path := filepath.Join(t.TempDir(), "trace.har")
+	if err := har.WriteFile(path); err != nil {
+		t.Fatalf("WriteFile() err = %v", err)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("read: %v", err)
+	}
+	var doc harLog
+	if err := json.Unmarshal(data, &doc); err != nil {
+		t.Fatalf("json: %v", err)
+	}
+	if doc.Log.Version != "1.2" || doc.Log.Creator.Name != "blu" || len(doc.Log.Entries) != 2 {
+		t.Fatalf("har = %+v", doc.Log)
+	}
+	e := doc.Log.Entries[0]
+	if e.Response.Status != 200 || e.Response.Content.MimeType != "text/xml" || !strings.HasPrefix(e.Response.Content.Text, "<status") {
+		t.Fatalf("entry = %+v", e.Response)
+	}
+	if len(e.Request.QueryString) != 2 || e.Request.QueryString[0] != (harPair{Name: "etag", Value: "1"}) {
+		t.Fatalf("queryString = %+v", e.Request.QueryString)
+	}
+	if blocked := doc.Log.Entries[1]; blocked.Response.Status != 0 || !strings.Contains(blocked.Comment, "dry-run") {
+		t.Fatalf("blocked entry = %+v", blocked)
+	}
+}
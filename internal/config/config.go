@@ -1,22 +1,39 @@
 package config
 
 import (
+	"bytes"
 	"encoding/json"
 	"errors"
+	"fmt"
 	"os"
 	"path/filepath"
+	"runtime"
 	"time"
+
+	"github.com/steipete/blucli/internal/fileutil"
 )
 
 type Config struct {
 	DefaultDevice string            `json:"default_device,omitempty"`
 	Aliases       map[string]string `json:"aliases,omitempty"`
 	Spotify       SpotifyConfig     `json:"spotify,omitempty"`
+	Scrobble      ScrobbleConfig    `json:"scrobble,omitempty"`
+	Secrets       SecretsConfig     `json:"secrets,omitzero"`
+	Defaults      DefaultsConfig    `json:"defaults,omitzero"`
+}
+
+type ScrobbleConfig struct {
+	URL string `json:"url,omitempty"`
+	// Token lives in the secrets store; config.json only carries it until
+	// the next save.
+	Token string `json:"token,omitempty"`
 }
 
 type SpotifyConfig struct {
-	ClientID string       `json:"client_id,omitempty"`
-	Token    SpotifyToken `json:"token,omitempty"`
+	ClientID string `json:"client_id,omitempty"`
+	// Token lives in the secrets store; config.json only carries it when
+	// written by older versions.
+	Token SpotifyToken `json:"token,omitzero"`
 }
 
 type SpotifyToken struct {
@@ -37,6 +54,24 @@ func Load(opts LoadOptions) (Config, error) {
 		return Config{}, err
 	}
 
+	cfg, err := readConfig(path)
+	if err != nil {
+		return Config{}, err
+	}
+	return cfg, nil
+}
+
+// MigrateSecrets moves tokens older versions kept inline in config.json
+// into the secrets store. It is a no-op unless cfg (as loaded from path)
+// still carries one; errors leave config.json untouched.
+func MigrateSecrets(path string, cfg Config) error {
+	if !hasInlineSecrets(cfg) {
+		return nil
+	}
+	return Update(path, func(*Config) error { return nil })
+}
+
+func readConfig(path string) (Config, error) {
 	data, err := os.ReadFile(path)
 	if err != nil {
 		if errors.Is(err, os.ErrNotExist) {
@@ -45,19 +80,22 @@ func Load(opts LoadOptions) (Config, error) {
 		return Config{}, err
 	}
 
-	var cfg Config
-	if err := json.Unmarshal(data, &cfg); err != nil {
-		return Config{}, err
+	cfg, err := Parse(data)
+	var pe *ParseError
+	if errors.As(err, &pe) {
+		return Config{}, fmt.Errorf("%s:%w", path, err)
 	}
-	if cfg.Aliases == nil {
-		cfg.Aliases = map[string]string{}
+	if err != nil {
+		return Config{}, err
 	}
 	return cfg, nil
 }
 
 type PathSet struct {
-	ConfigPath string
-	CachePath  string
+	ConfigPath        string
+	CachePath         string
+	HistoryPath       string
+	ScrobbleQueuePath string
 }
 
 func Paths() (PathSet, error) {
@@ -69,13 +107,43 @@ func Paths() (PathSet, error) {
 	if err != nil {
 		return PathSet{}, err
 	}
+	stateDir, err := userStateDir()
+	if err != nil {
+		return PathSet{}, err
+	}
 
 	return PathSet{
-		ConfigPath: filepath.Join(configDir, "blu", "config.json"),
-		CachePath:  filepath.Join(cacheDir, "blu", "discovery.json"),
+		ConfigPath:  filepath.Join(configDir, "blu", "config.json"),
+		CachePath:   filepath.Join(cacheDir, "blu", "discovery.json"),
+		HistoryPath: filepath.Join(stateDir, "blu", "history.jsonl"),
+
+		ScrobbleQueuePath: filepath.Join(stateDir, "blu", "scrobble-queue.json"),
 	}, nil
 }
 
+// userStateDir is where blu keeps data it writes on its own (history, the
+// scrobble queue): $XDG_STATE_HOME or ~/.local/state on Unix, the same
+// directory as the config on macOS, and %LocalAppData% on Windows.
+func userStateDir() (string, error) {
+	switch runtime.GOOS {
+	case "darwin", "ios", "plan9":
+		return os.UserConfigDir()
+	case "windows":
+		return os.UserCacheDir()
+	}
+	if dir := os.Getenv("XDG_STATE_HOME"); dir != "" {
+		if !filepath.IsAbs(dir) {
+			return "", errors.New("path in $XDG_STATE_HOME is relative")
+		}
+		return dir, nil
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(home, ".local", "state"), nil
+}
+
 func configPath(explicit string) (string, error) {
 	if explicit != "" {
 		return explicit, nil
@@ -91,27 +159,74 @@ func ConfigPath(explicit string) (string, error) {
 	return configPath(explicit)
 }
 
-func ensureParentDir(path string) error {
-	dir := filepath.Dir(path)
-	return os.MkdirAll(dir, 0o755)
+// SaveConfig replaces config.json with cfg. Commands that change a loaded
+// config should prefer Update, which cannot clobber concurrent writers.
+func SaveConfig(path string, cfg Config) error {
+	path, err := configPath(path)
+	if err != nil {
+		return err
+	}
+	return fileutil.WithLock(path, func() error { return writeConfig(path, cfg) })
 }
 
-func SaveConfig(path string, cfg Config) error {
-	if path == "" {
-		paths, err := Paths()
+// Update applies fn to the current on-disk config under an exclusive lock and
+// saves the result, so concurrent blu processes (daemons refreshing tokens,
+// alias edits) never lose each other's changes. fn returning an error
+// aborts without writing.
+func Update(path string, fn func(*Config) error) error {
+	path, err := configPath(path)
+	if err != nil {
+		return err
+	}
+	return fileutil.WithLock(path, func() error {
+		cfg, err := readConfig(path)
 		if err != nil {
 			return err
 		}
-		path = paths.ConfigPath
-	}
+		if cfg.Aliases == nil {
+			cfg.Aliases = map[string]string{}
+		}
+		if err := fn(&cfg); err != nil {
+			return err
+		}
+		return writeConfig(path, cfg)
+	})
+}
 
-	if err := ensureParentDir(path); err != nil {
-		return err
+func writeConfig(path string, cfg Config) error {
+	cfg, err := storeSecrets(path, cfg)
+	if err != nil {
+		return fmt.Errorf("secrets: %w", err)
 	}
 	data, err := json.MarshalIndent(cfg, "", "  ")
 	if err != nil {
 		return err
 	}
-	data = append(data, '\n')
-	return os.WriteFile(path, data, 0o644)
+	return fileutil.WriteAtomic(path, append(data, '\n'), 0o644)
+}
+
+// ErrConfigChanged reports that config.json changed since it was read.
+var ErrConfigChanged = errors.New("config changed on disk")
+
+// WriteConfigFile writes raw config.json contents, e.g. after `config edit`.
+// With a non-nil prev it only writes while the file still holds prev (nil
+// content for a missing file is passed as an empty slice). Contents with a
+// token go through the same path as SaveConfig, which moves it to the
+// secrets store and rewrites the rest.
+func WriteConfigFile(path string, data, prev []byte) error {
+	return fileutil.WithLock(path, func() error {
+		if prev != nil {
+			cur, err := os.ReadFile(path)
+			if err != nil && !errors.Is(err, os.ErrNotExist) {
+				return err
+			}
+			if !bytes.Equal(cur, prev) {
+				return ErrConfigChanged
+			}
+		}
+		if cfg, err := Parse(data); err == nil && hasInlineSecrets(cfg) {
+			return writeConfig(path, cfg)
+		}
+		return fileutil.WriteAtomic(path, data, 0o644)
+	})
 }
@@ -3,6 +3,7 @@ package config
 import (
 	"os"
 	"path/filepath"
+	"runtime"
 	"strings"
 	"testing"
 	"time"
@@ -103,6 +104,32 @@ func TestPaths_ReturnsBluPaths(t *testing.T) {
 	}
 }
 
+func TestPaths_HistoryAndQueueLiveInStateDir(t *testing.T) {
+	if runtime.GOOS == "darwin" || runtime.GOOS == "windows" {
+		t.Skip("XDG_STATE_HOME applies to Unix only")
+	}
+	state := t.TempDir()
+	t.Setenv("XDG_STATE_HOME", state)
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+
+	p, err := Paths()
+	if err != nil {
+		t.Fatalf("err = %v", err)
+	}
+	if want := filepath.Join(state, "blu", "history.jsonl"); p.HistoryPath != want {
+		t.Fatalf("HistoryPath = %q; want %q", p.HistoryPath, want)
+	}
+	if want := filepath.Join(state, "blu", "scrobble-queue.json"); p.ScrobbleQueuePath != want {
+		t.Fatalf("ScrobbleQueuePath = %q; want %q", p.ScrobbleQueuePath, want)
+	}
+
+	t.Setenv("XDG_STATE_HOME", "")
+	t.Setenv("HOME", state)
+	if p, err := Paths(); err != nil || p.HistoryPath != filepath.Join(state, ".local", "state", "blu", "history.jsonl") {
+		t.Fatalf("HistoryPath = %q, %v; want under ~/.local/state", p.HistoryPath, err)
+	}
+}
+
 func TestConfigPath_Default(t *testing.T) {
 	t.Parallel()
 
@@ -128,3 +155,15 @@ func TestSaveConfig_ParentDirError(t *testing.T) {
 		t.Fatalf("want error")
 	}
 }
+
+func TestParse_TraceHTTPFull(t *testing.T) {
+	t.Parallel()
+
+	cfg, err := Parse([]byte(`{"defaults":{"trace_http":"full"}}`))
+	if err != nil || cfg.Defaults.TraceHTTP != "full" {
+		t.Fatalf("Parse = %q, %v; want full", cfg.Defaults.TraceHTTP, err)
+	}
+	if _, err := Parse([]byte(`{"defaults":{"trace_http":true}}`)); err == nil {
+		t.Fatalf("Parse(trace_http=true) err = nil")
+	}
+}
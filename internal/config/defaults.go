@@ -0,0 +1,38 @@
+package config
+
+import (
+	"errors"
+	"strings"
+)
+
+// DefaultsConfig holds fallbacks for global flags. A flag wins over its
+// BLU_* environment variable, which wins over these; unset fields keep the
+// built-in default.
+type DefaultsConfig struct {
+	JSON            *bool  `json:"json,omitempty"`
+	Output          string `json:"output,omitempty"`
+	Envelope        *bool  `json:"envelope,omitempty"`
+	Format          string `json:"format,omitempty"`
+	Timeout         string `json:"timeout,omitempty"`
+	DryRun          *bool  `json:"dry_run,omitempty"`
+	TraceHTTP       string `json:"trace_http,omitempty"`
+	Discover        *bool  `json:"discover,omitempty"`
+	DiscoverTimeout string `json:"discover_timeout,omitempty"`
+	// Interface restricts discovery to comma-separated NIC names.
+	Interface string `json:"interface,omitempty"`
+}
+
+// ParseTraceHTTP normalizes a --trace-http, BLU_TRACE_HTTP or
+// defaults.trace_http value to false, true or full.
+func ParseTraceHTTP(s string) (string, error) {
+	switch strings.ToLower(strings.TrimSpace(s)) {
+	case "full":
+		return "full", nil
+	case "", "1", "t", "true", "on", "basic":
+		return "true", nil
+	case "0", "f", "false", "off":
+		return "false", nil
+	default:
+		return "", errors.New("want true|false|full")
+	}
+}
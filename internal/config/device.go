@@ -6,6 +6,7 @@ import (
 	"net/url"
 	"strconv"
 	"strings"
+	"time"
 )
 
 type Device struct {
@@ -14,8 +15,14 @@ type Device struct {
 	Port int    `json:"port"`
 	Name string `json:"name,omitempty"`
 	Type string `json:"type,omitempty"`
+	MAC  string `json:"mac,omitempty"`
+	// LastSeen and Previous are only kept in the discovery cache.
+	LastSeen time.Time `json:"last_seen,omitzero"`
+	Previous []string  `json:"previous,omitempty"`
 }
 
+// BaseURL returns the player's HTTP endpoint. IPv6 hosts are bracketed and
+// a link-local zone (fe80::1%eth0) is kept, escaped as %25 in the URL.
 func (d Device) BaseURL() *url.URL {
 	host := d.Host
 	if host == "" {
@@ -59,8 +66,7 @@ func ParseDevice(s string) (Device, error) {
 			}
 			port = p
 		}
-		id := fmt.Sprintf("%s:%d", host, port)
-		return Device{ID: id, Host: host, Port: port}, nil
+		return Device{ID: net.JoinHostPort(host, strconv.Itoa(port)), Host: host, Port: port}, nil
 	}
 
 	host, portStr, err := net.SplitHostPort(s)
@@ -82,7 +88,8 @@ func ParseDevice(s string) (Device, error) {
 	if host == "" {
 		return Device{}, fmt.Errorf("missing host")
 	}
+	// Accept a zone copied from a URL (fe80::1%25eth0).
+	host = strings.Replace(host, "%25", "%", 1)
 
-	id := fmt.Sprintf("%s:%d", host, port)
-	return Device{ID: id, Host: host, Port: port}, nil
+	return Device{ID: net.JoinHostPort(host, strconv.Itoa(port)), Host: host, Port: port}, nil
 }
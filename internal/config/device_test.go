@@ -28,3 +28,32 @@ func TestParseDevice(t *testing.T) {
 		})
 	}
 }
+
+func TestParseDeviceIPv6(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		in   string
+		host string
+		id   string
+		url  string
+	}{
+		{in: "fd00::10", host: "fd00::10", id: "[fd00::10]:11000", url: "http://[fd00::10]:11000/"},
+		{in: "[fd00::10]:12000", host: "fd00::10", id: "[fd00::10]:12000", url: "http://[fd00::10]:12000/"},
+		{in: "[fe80::1%eth0]:11000", host: "fe80::1%eth0", id: "[fe80::1%eth0]:11000", url: "http://[fe80::1%25eth0]:11000/"},
+		{in: "fe80::1%25eth0", host: "fe80::1%eth0", id: "[fe80::1%eth0]:11000", url: "http://[fe80::1%25eth0]:11000/"},
+		{in: "http://[fe80::1%25eth0]:11000", host: "fe80::1%eth0", id: "[fe80::1%eth0]:11000", url: "http://[fe80::1%25eth0]:11000/"},
+	}
+	for _, tt := range tests {
+		device, err := ParseDevice(tt.in)
+		if err != nil {
+			t.Fatalf("ParseDevice(%q) err = %v", tt.in, err)
+		}
+		if device.Host != tt.host || device.ID != tt.id {
+			t.Fatalf("ParseDevice(%q) = %+v; want host=%q id=%q", tt.in, device, tt.host, tt.id)
+		}
+		if got := device.BaseURL().String(); got != tt.url {
+			t.Fatalf("BaseURL(%q) = %q; want %q", tt.in, got, tt.url)
+		}
+	}
+}
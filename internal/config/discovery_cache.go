@@ -2,16 +2,30 @@ package config
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
 	"os"
+	"slices"
 	"strings"
 	"time"
 	"unicode"
+
+	"github.com/steipete/blucli/internal/discovery"
+	"github.com/steipete/blucli/internal/fileutil"
 )
 
+// DeviceTTL is how long a device that stopped answering discovery stays in
+// the cache.
+const DeviceTTL = 30 * 24 * time.Hour
+
+// maxPrevious bounds the former endpoints remembered per device.
+const maxPrevious = 4
+
 type DiscoveryCache struct {
 	UpdatedAt time.Time `json:"updated_at"`
 	Devices   []Device  `json:"devices"`
+
+	path string
 }
 
 func NewDiscoveryCache(updatedAt time.Time, devices []Device) DiscoveryCache {
@@ -23,36 +37,128 @@ func NewDiscoveryCache(updatedAt time.Time, devices []Device) DiscoveryCache {
 		if device.ID == "" {
 			device.ID = fmt.Sprintf("%s:%d", device.Host, device.Port)
 		}
+		if device.LastSeen.IsZero() {
+			device.LastSeen = updatedAt
+		}
+		device.MAC = discovery.NormalizeMAC(device.MAC)
 		out = append(out, device)
 	}
 	return DiscoveryCache{UpdatedAt: updatedAt, Devices: out}
 }
 
+// LoadDiscoveryCache returns the cache at path. Even on error the result
+// remembers path, so a refreshed cache can be saved back (see Path).
 func LoadDiscoveryCache(path string) (DiscoveryCache, error) {
 	data, err := os.ReadFile(path)
 	if err != nil {
-		return DiscoveryCache{}, err
+		return DiscoveryCache{path: path}, err
 	}
 
 	var cache DiscoveryCache
 	if err := json.Unmarshal(data, &cache); err != nil {
-		return DiscoveryCache{}, err
+		return DiscoveryCache{path: path}, err
 	}
+	// Caches from older versions only have the global timestamp.
+	for i := range cache.Devices {
+		if cache.Devices[i].LastSeen.IsZero() {
+			cache.Devices[i].LastSeen = cache.UpdatedAt
+		}
+	}
+	cache.path = path
 	return cache, nil
 }
 
+// Path is where the cache was loaded from ("" for an in-memory cache).
+func (c DiscoveryCache) Path() string { return c.path }
+
+// SaveDiscoveryCache replaces the cache at path. Commands that fold new
+// sightings into a cache should prefer UpdateDiscoveryCache.
 func SaveDiscoveryCache(path string, cache DiscoveryCache) error {
-	if err := ensureParentDir(path); err != nil {
-		return err
-	}
+	return fileutil.WithLock(path, func() error { return writeDiscoveryCache(path, cache) })
+}
+
+// UpdateDiscoveryCache applies fn to the current on-disk cache under an
+// exclusive lock and saves the result, so concurrent blu processes
+// (`devices --watch`, `devices`, re-resolution) never drop each other's
+// entries. A missing cache starts out empty.
+func UpdateDiscoveryCache(path string, fn func(*DiscoveryCache)) error {
+	return fileutil.WithLock(path, func() error {
+		cache, err := LoadDiscoveryCache(path)
+		if err != nil && !errors.Is(err, os.ErrNotExist) {
+			return err
+		}
+		fn(&cache)
+		return writeDiscoveryCache(path, cache)
+	})
+}
+
+func writeDiscoveryCache(path string, cache DiscoveryCache) error {
 	data, err := json.MarshalIndent(cache, "", "  ")
 	if err != nil {
 		return err
 	}
-	data = append(data, '\n')
-	return os.WriteFile(path, data, 0o644)
+	return fileutil.WriteAtomic(path, append(data, '\n'), 0o644)
+}
+
+// Merge folds freshly discovered devices into the cache. A device is
+// matched by identity (MAC, else ID, else name), so a player that moved to
+// a new address keeps one entry and remembers the old endpoint. Entries
+// that were not seen are kept until DeviceTTL expires; an endpoint now used
+// by another player is dropped from the old entry.
+func (c DiscoveryCache) Merge(now time.Time, seen []Device) DiscoveryCache {
+	fresh := NewDiscoveryCache(now, seen).Devices
+	claimed := map[string]bool{}
+	for _, d := range fresh {
+		claimed[d.ID] = true
+	}
+
+	used := make([]bool, len(c.Devices))
+	out := make([]Device, 0, len(fresh)+len(c.Devices))
+	for _, d := range fresh {
+		d.LastSeen = now
+		for i, old := range c.Devices {
+			if used[i] || !old.SameIdentity(d) {
+				continue
+			}
+			used[i] = true
+			if d.MAC == "" {
+				d.MAC = old.MAC
+			}
+			d.Previous = old.Previous
+			if old.ID != d.ID {
+				d.Previous = append([]string{old.ID}, d.Previous...)
+			}
+			break
+		}
+		d.Previous = slices.DeleteFunc(slices.Clone(d.Previous), func(id string) bool { return id == d.ID })
+		if len(d.Previous) > maxPrevious {
+			d.Previous = d.Previous[:maxPrevious]
+		}
+		out = append(out, d)
+	}
+	for i, old := range c.Devices {
+		if used[i] || claimed[old.ID] || now.Sub(old.LastSeen) > DeviceTTL {
+			continue
+		}
+		out = append(out, old)
+	}
+	return DiscoveryCache{UpdatedAt: now, Devices: out, path: c.path}
 }
 
+// SameIdentity reports whether d and o are the same player: equal MACs when
+// both are known, else equal IDs, else equal (normalized) names.
+func (d Device) SameIdentity(o Device) bool {
+	if d.MAC != "" && o.MAC != "" {
+		return discovery.NormalizeMAC(d.MAC) == discovery.NormalizeMAC(o.MAC)
+	}
+	if d.ID != "" && d.ID == o.ID {
+		return true
+	}
+	n := normalizeName(d.Name)
+	return n != "" && n == normalizeName(o.Name)
+}
+
+// Lookup finds a device by ID or host:port, then by a former endpoint.
 func (c DiscoveryCache) Lookup(idOrHostPort string) (Device, bool) {
 	for _, device := range c.Devices {
 		if device.ID == idOrHostPort {
@@ -62,6 +168,21 @@ func (c DiscoveryCache) Lookup(idOrHostPort string) (Device, bool) {
 			return device, true
 		}
 	}
+	for _, device := range c.Devices {
+		if slices.Contains(device.Previous, idOrHostPort) {
+			return device, true
+		}
+	}
+	return Device{}, false
+}
+
+// Find returns the cached entry for the same player as d.
+func (c DiscoveryCache) Find(d Device) (Device, bool) {
+	for _, device := range c.Devices {
+		if device.SameIdentity(d) {
+			return device, true
+		}
+	}
 	return Device{}, false
 }
 
@@ -103,3 +103,68 @@ func TestSaveDiscoveryCache_ParentDirError(t *testing.T) {
 		t.Fatalf("want error")
 	}
 }
+
+func TestDiscoveryCache_MergeFollowsMovedDevice(t *testing.T) {
+	t.Parallel()
+
+	then := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	now := then.Add(48 * time.Hour)
+	cache := NewDiscoveryCache(then, []Device{
+		{Host: "10.0.0.5", Port: 11000, Name: "Kitchen", MAC: "90:56:82:9F:02:78"},
+		{Host: "10.0.0.6", Port: 11000, Name: "Den"},
+		{Host: "10.0.0.7", Port: 11000, Name: "Gone", LastSeen: now.Add(-DeviceTTL - time.Hour)},
+	})
+
+	// Kitchen moved to .9, and another player now holds .6.
+	merged := cache.Merge(now, []Device{
+		{Host: "10.0.0.9", Port: 11000, Name: "Kitchen renamed", MAC: "90-56-82-9f-02-78"},
+		{Host: "10.0.0.6", Port: 11000, Name: "Office", MAC: "aa:bb:cc:dd:ee:ff"},
+	})
+	if len(merged.Devices) != 2 {
+		t.Fatalf("devices = %+v", merged.Devices)
+	}
+	kitchen, ok := merged.Lookup("10.0.0.5:11000")
+	if !ok || kitchen.ID != "10.0.0.9:11000" || kitchen.MAC != "90:56:82:9f:02:78" || !kitchen.LastSeen.Equal(now) {
+		t.Fatalf("kitchen = %+v, %v", kitchen, ok)
+	}
+	if office, ok := merged.Lookup("10.0.0.6:11000"); !ok || office.Name != "Office" {
+		t.Fatalf("10.0.0.6 = %+v, %v", office, ok)
+	}
+	if _, ok := merged.Find(Device{Name: "Gone"}); ok {
+		t.Fatalf("expired device kept")
+	}
+}
+
+func TestDeviceSameIdentityBareHexMAC(t *testing.T) {
+	t.Parallel()
+
+	// Some TXT records carry the MAC without separators.
+	cached := Device{ID: "10.0.0.5:11000", MAC: "90:56:82:9f:02:78"}
+	if !cached.SameIdentity(Device{ID: "10.0.0.9:11000", MAC: "9056829F0278"}) {
+		t.Fatalf("bare hex MAC did not match")
+	}
+	if got := NewDiscoveryCache(time.Now(), []Device{{Host: "10.0.0.9", Port: 11000, MAC: "9056829F0278"}}).Devices[0].MAC; got != "90:56:82:9f:02:78" {
+		t.Fatalf("cached MAC = %q", got)
+	}
+}
+
+func TestDiscoveryCache_MergeKeepsUnseenAndMatchesByName(t *testing.T) {
+	t.Parallel()
+
+	then := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	now := then.Add(time.Hour)
+	cache := NewDiscoveryCache(then, []Device{
+		{Host: "10.0.0.5", Port: 11000, Name: "Kitchen", MAC: "90:56:82:9f:02:78"},
+		{Host: "10.0.0.6", Port: 11000, Name: "Den"},
+	})
+	merged := cache.Merge(now, []Device{{Host: "10.0.0.8", Port: 11000, Name: "kitchen"}})
+
+	kitchen, ok := merged.Lookup("10.0.0.8:11000")
+	if !ok || kitchen.MAC != "90:56:82:9f:02:78" || len(kitchen.Previous) != 1 {
+		t.Fatalf("kitchen = %+v, %v", kitchen, ok)
+	}
+	den, ok := merged.Lookup("10.0.0.6:11000")
+	if !ok || !den.LastSeen.Equal(then) {
+		t.Fatalf("den = %+v, %v", den, ok)
+	}
+}
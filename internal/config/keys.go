@@ -0,0 +1,148 @@
+package config
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+var ErrKeyNotSet = errors.New("not set")
+
+// Get returns the value at a dotted key such as "default_device" or
+// "aliases.kitchen".
+func Get(cfg Config, key string) (any, error) {
+	m, err := toMap(cfg)
+	if err != nil {
+		return nil, err
+	}
+	var cur any = m
+	for _, part := range splitKey(key) {
+		obj, ok := cur.(map[string]any)
+		if !ok {
+			return nil, fmt.Errorf("%s: %w", key, ErrKeyNotSet)
+		}
+		if cur, ok = obj[part]; !ok {
+			return nil, fmt.Errorf("%s: %w", key, ErrKeyNotSet)
+		}
+	}
+	return cur, nil
+}
+
+// Set stores value at a dotted key. Values that parse as JSON are stored as
+// such (numbers, objects); anything else, or a JSON value the field does not
+// accept, is stored as a string.
+func Set(cfg Config, key, value string) (Config, error) {
+	parts := splitKey(key)
+	if len(parts) == 0 {
+		return Config{}, errors.New("empty key")
+	}
+
+	var parsed any
+	dec := json.NewDecoder(strings.NewReader(value))
+	dec.UseNumber()
+	if err := dec.Decode(&parsed); err == nil && !dec.More() {
+		if next, err := setKey(cfg, parts, parsed); err == nil {
+			return next, nil
+		}
+	}
+	return setKey(cfg, parts, value)
+}
+
+// Unset removes a dotted key.
+func Unset(cfg Config, key string) (Config, error) {
+	parts := splitKey(key)
+	if len(parts) == 0 {
+		return Config{}, errors.New("empty key")
+	}
+	m, err := toMap(cfg)
+	if err != nil {
+		return Config{}, err
+	}
+	obj := m
+	for _, part := range parts[:len(parts)-1] {
+		next, ok := obj[part].(map[string]any)
+		if !ok {
+			return Config{}, fmt.Errorf("%s: %w", key, ErrKeyNotSet)
+		}
+		obj = next
+	}
+	last := parts[len(parts)-1]
+	if _, ok := obj[last]; !ok {
+		return Config{}, fmt.Errorf("%s: %w", key, ErrKeyNotSet)
+	}
+	delete(obj, last)
+	return fromMap(m, key)
+}
+
+func setKey(cfg Config, parts []string, value any) (Config, error) {
+	m, err := toMap(cfg)
+	if err != nil {
+		return Config{}, err
+	}
+	obj := m
+	for i, part := range parts[:len(parts)-1] {
+		next, ok := obj[part]
+		if !ok {
+			created := map[string]any{}
+			obj[part] = created
+			obj = created
+			continue
+		}
+		nextObj, ok := next.(map[string]any)
+		if !ok {
+			return Config{}, fmt.Errorf("%s is not an object", strings.Join(parts[:i+1], "."))
+		}
+		obj = nextObj
+	}
+	obj[parts[len(parts)-1]] = value
+	return fromMap(m, strings.Join(parts, "."))
+}
+
+func splitKey(key string) []string {
+	key = strings.Trim(strings.TrimSpace(key), ".")
+	if key == "" {
+		return nil
+	}
+	return strings.Split(key, ".")
+}
+
+func toMap(cfg Config) (map[string]any, error) {
+	data, err := json.Marshal(cfg)
+	if err != nil {
+		return nil, err
+	}
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	var m map[string]any
+	if err := dec.Decode(&m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+// fromMap decodes m strictly, so keys Config does not declare are rejected.
+func fromMap(m map[string]any, key string) (Config, error) {
+	data, err := json.Marshal(m)
+	if err != nil {
+		return Config{}, err
+	}
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.DisallowUnknownFields()
+	var cfg Config
+	if err := dec.Decode(&cfg); err != nil {
+		if strings.HasPrefix(err.Error(), "json: unknown field") {
+			return Config{}, fmt.Errorf("unknown key %q", key)
+		}
+		var typeErr *json.UnmarshalTypeError
+		if errors.As(err, &typeErr) {
+			return Config{}, fmt.Errorf("%s: expected %s, got %s", key, typeErr.Type, typeErr.Value)
+		}
+		return Config{}, fmt.Errorf("%s: %v", key, err)
+	}
+	if cfg.Aliases == nil {
+		cfg.Aliases = map[string]string{}
+	}
+	return cfg, nil
+}
@@ -0,0 +1,145 @@
+package config
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+
+	"github.com/steipete/blucli/internal/secrets"
+)
+
+type SecretsConfig struct {
+	// Backend is file (default, 0600 secrets.json), keyring or encrypted.
+	Backend string `json:"backend,omitempty"`
+}
+
+const (
+	spotifyTokenKey  = "spotify.token"
+	scrobbleTokenKey = "scrobble.token"
+)
+
+// SecretsOptions selects the credential store for a config file;
+// BLU_SECRETS_BACKEND overrides secrets.backend.
+func SecretsOptions(configPath string, cfg Config) secrets.Options {
+	backend := os.Getenv("BLU_SECRETS_BACKEND")
+	if backend == "" {
+		backend = cfg.Secrets.Backend
+	}
+	return secrets.Options{
+		Backend:    backend,
+		Dir:        filepath.Dir(configPath),
+		Passphrase: os.Getenv("BLU_SECRETS_PASSPHRASE"),
+	}
+}
+
+// LoadSpotifyToken returns the Spotify token from the secrets store. A token
+// still inline in config.json (older versions) wins; SaveConfig moves it.
+func LoadSpotifyToken(path string, cfg Config) (SpotifyToken, error) {
+	if cfg.Spotify.Token != (SpotifyToken{}) {
+		return cfg.Spotify.Token, nil
+	}
+	path, err := configPath(path)
+	if err != nil {
+		return SpotifyToken{}, err
+	}
+	store, err := secrets.Open(SecretsOptions(path, cfg))
+	if err != nil {
+		return SpotifyToken{}, err
+	}
+	raw, err := store.Get(spotifyTokenKey)
+	if errors.Is(err, secrets.ErrNotFound) {
+		return SpotifyToken{}, nil
+	}
+	if err != nil {
+		return SpotifyToken{}, err
+	}
+	var tok SpotifyToken
+	if err := json.Unmarshal([]byte(raw), &tok); err != nil {
+		return SpotifyToken{}, err
+	}
+	return tok, nil
+}
+
+// ClearSpotifyToken removes the token from config.json and every secrets
+// backend.
+func ClearSpotifyToken(path string) error {
+	return clearSecret(path, spotifyTokenKey, func(c *Config) { c.Spotify.Token = SpotifyToken{} })
+}
+
+// LoadScrobbleToken returns the ListenBrainz/Maloja token from the secrets
+// store, or one still inline in config.json.
+func LoadScrobbleToken(path string, cfg Config) (string, error) {
+	if cfg.Scrobble.Token != "" {
+		return cfg.Scrobble.Token, nil
+	}
+	path, err := configPath(path)
+	if err != nil {
+		return "", err
+	}
+	store, err := secrets.Open(SecretsOptions(path, cfg))
+	if err != nil {
+		return "", err
+	}
+	tok, err := store.Get(scrobbleTokenKey)
+	if errors.Is(err, secrets.ErrNotFound) {
+		return "", nil
+	}
+	return tok, err
+}
+
+// ClearScrobbleToken removes the scrobble token from config.json and every
+// secrets backend.
+func ClearScrobbleToken(path string) error {
+	return clearSecret(path, scrobbleTokenKey, func(c *Config) { c.Scrobble.Token = "" })
+}
+
+func clearSecret(path, key string, clear func(*Config)) error {
+	path, err := configPath(path)
+	if err != nil {
+		return err
+	}
+	var cfg Config
+	err = Update(path, func(c *Config) error {
+		clear(c)
+		cfg = *c
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	return secrets.Scrub(SecretsOptions(path, cfg), key)
+}
+
+// hasInlineSecrets reports credentials that belong in the secrets store.
+func hasInlineSecrets(cfg Config) bool {
+	return cfg.Spotify.Token != (SpotifyToken{}) || cfg.Scrobble.Token != ""
+}
+
+// storeSecrets moves credentials out of cfg into the secrets store.
+func storeSecrets(path string, cfg Config) (Config, error) {
+	if !hasInlineSecrets(cfg) {
+		return cfg, nil
+	}
+	store, err := secrets.Open(SecretsOptions(path, cfg))
+	if err != nil {
+		return cfg, err
+	}
+	if cfg.Spotify.Token != (SpotifyToken{}) {
+		data, err := json.Marshal(cfg.Spotify.Token)
+		if err != nil {
+			return cfg, err
+		}
+		if err := store.Set(spotifyTokenKey, string(data)); err != nil {
+			return cfg, err
+		}
+		cfg.Spotify.Token = SpotifyToken{}
+	}
+	if cfg.Scrobble.Token != "" {
+		if err := store.Set(scrobbleTokenKey, cfg.Scrobble.Token); err != nil {
+			return cfg, err
+		}
+		cfg.Scrobble.Token = ""
+	}
+	return cfg, nil
+}
@@ -0,0 +1,122 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+)
+
+func TestMigrateSecrets_InlineSpotifyToken(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	path := filepath.Join(dir, "config.json")
+	data := `{"aliases":{"k":"10.0.0.1"},"spotify":{"client_id":"CID","token":{"access_token":"AT","refresh_token":"RT"}}}`
+	if err := os.WriteFile(path, []byte(data), 0o644); err != nil {
+		t.Fatalf("write: %v", err)
+	}
+
+	cfg, err := Load(LoadOptions{Path: path})
+	if err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+	if after, _ := os.ReadFile(path); string(after) != data {
+		t.Fatalf("Load rewrote config: %s", after)
+	}
+	if err := MigrateSecrets(path, cfg); err != nil {
+		t.Fatalf("MigrateSecrets: %v", err)
+	}
+	after, _ := os.ReadFile(path)
+	if strings.Contains(string(after), "access_token") || !strings.Contains(string(after), `"client_id": "CID"`) {
+		t.Fatalf("config = %s", after)
+	}
+	secrets, _ := os.ReadFile(filepath.Join(dir, "secrets.json"))
+	if !strings.Contains(string(secrets), "RT") {
+		t.Fatalf("secrets = %s", secrets)
+	}
+
+	tok, err := LoadSpotifyToken(path, Config{})
+	if err != nil || tok.AccessToken != "AT" || tok.RefreshToken != "RT" {
+		t.Fatalf("token = %+v, %v", tok, err)
+	}
+	if cfg.Spotify.Token.AccessToken != "AT" {
+		t.Fatalf("in-memory token = %+v", cfg.Spotify.Token)
+	}
+
+	if err := ClearSpotifyToken(path); err != nil {
+		t.Fatalf("Clear: %v", err)
+	}
+	if tok, err := LoadSpotifyToken(path, Config{}); err != nil || tok != (SpotifyToken{}) {
+		t.Fatalf("token after clear = %+v, %v", tok, err)
+	}
+}
+
+func TestMigrateSecrets_FailureKeepsConfig(t *testing.T) {
+	t.Setenv("BLU_SECRETS_BACKEND", "encrypted")
+	t.Setenv("BLU_SECRETS_PASSPHRASE", "")
+
+	dir := t.TempDir()
+	path := filepath.Join(dir, "config.json")
+	data := `{"scrobble":{"token":"LBTOKEN"}}`
+	if err := os.WriteFile(path, []byte(data), 0o644); err != nil {
+		t.Fatalf("write: %v", err)
+	}
+	cfg, err := Load(LoadOptions{Path: path})
+	if err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+	if err := MigrateSecrets(path, cfg); err == nil || !strings.Contains(err.Error(), "BLU_SECRETS_PASSPHRASE") {
+		t.Fatalf("MigrateSecrets err = %v", err)
+	}
+	if after, _ := os.ReadFile(path); string(after) != data {
+		t.Fatalf("config = %s; want untouched", after)
+	}
+}
+
+func TestMigrateSecrets_InlineScrobbleToken(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	path := filepath.Join(dir, "config.json")
+	data := `{"scrobble":{"url":"https://lb.example","token":"LBTOKEN"}}`
+	if err := os.WriteFile(path, []byte(data), 0o644); err != nil {
+		t.Fatalf("write: %v", err)
+	}
+
+	cfg, err := Load(LoadOptions{Path: path})
+	if err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+	if after, _ := os.ReadFile(path); string(after) != data {
+		t.Fatalf("Load rewrote config: %s", after)
+	}
+	if err := MigrateSecrets(path, cfg); err != nil {
+		t.Fatalf("MigrateSecrets: %v", err)
+	}
+	after, _ := os.ReadFile(path)
+	if strings.Contains(string(after), "LBTOKEN") || !strings.Contains(string(after), "lb.example") {
+		t.Fatalf("config = %s", after)
+	}
+	info, err := os.Stat(filepath.Join(dir, "secrets.json"))
+	if err != nil {
+		t.Fatalf("stat secrets.json: %v", err)
+	}
+	if runtime.GOOS != "windows" && info.Mode().Perm() != 0o600 {
+		t.Fatalf("secrets mode = %v; want 0600", info.Mode().Perm())
+	}
+	if tok, err := LoadScrobbleToken(path, Config{}); err != nil || tok != "LBTOKEN" {
+		t.Fatalf("token = %q, %v", tok, err)
+	}
+	if cfg.Scrobble.Token != "LBTOKEN" {
+		t.Fatalf("in-memory token = %q", cfg.Scrobble.Token)
+	}
+
+	if err := ClearScrobbleToken(path); err != nil {
+		t.Fatalf("Clear: %v", err)
+	}
+	if tok, err := LoadScrobbleToken(path, Config{}); err != nil || tok != "" {
+		t.Fatalf("token after clear = %q, %v", tok, err)
+	}
+}
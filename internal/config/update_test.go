@@ -0,0 +1,133 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestUpdateConcurrentWritersKeepAllChanges(t *testing.T) {
+	t.Parallel()
+
+	path := filepath.Join(t.TempDir(), "config.json")
+	const writers = 20
+	var wg sync.WaitGroup
+	errs := make(chan error, writers)
+	for i := range writers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			errs <- Update(path, func(c *Config) error {
+				c.Aliases[fmt.Sprintf("a%d", i)] = fmt.Sprintf("10.0.0.%d", i)
+				return nil
+			})
+		}()
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		if err != nil {
+			t.Fatalf("Update: %v", err)
+		}
+	}
+
+	cfg, err := Load(LoadOptions{Path: path})
+	if err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+	if len(cfg.Aliases) != writers {
+		t.Fatalf("aliases = %d; want %d: %v", len(cfg.Aliases), writers, cfg.Aliases)
+	}
+}
+
+func TestUpdateErrorLeavesFileUntouched(t *testing.T) {
+	t.Parallel()
+
+	path := filepath.Join(t.TempDir(), "config.json")
+	if err := SaveConfig(path, Config{DefaultDevice: "a"}); err != nil {
+		t.Fatalf("SaveConfig: %v", err)
+	}
+	before, _ := os.ReadFile(path)
+
+	boom := errors.New("boom")
+	err := Update(path, func(c *Config) error {
+		c.DefaultDevice = "b"
+		return boom
+	})
+	if !errors.Is(err, boom) {
+		t.Fatalf("err = %v", err)
+	}
+	if after, _ := os.ReadFile(path); string(after) != string(before) {
+		t.Fatalf("config = %q; want %q", after, before)
+	}
+}
+
+func TestWriteConfigFileDetectsConcurrentChange(t *testing.T) {
+	t.Parallel()
+
+	path := filepath.Join(t.TempDir(), "config.json")
+	if err := WriteConfigFile(path, []byte("{}\n"), []byte{}); err != nil {
+		t.Fatalf("create: %v", err)
+	}
+	if err := WriteConfigFile(path, []byte(`{"default_device":"a"}`), []byte("{}\n")); err != nil {
+		t.Fatalf("write: %v", err)
+	}
+	if err := WriteConfigFile(path, []byte("{}"), []byte("{}\n")); !errors.Is(err, ErrConfigChanged) {
+		t.Fatalf("stale write err = %v", err)
+	}
+}
+
+func TestWriteConfigFileMovesTokens(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	path := filepath.Join(dir, "config.json")
+	if err := WriteConfigFile(path, []byte(`{"scrobble":{"url":"https://lb.example","token":"LBTOKEN"}}`), []byte{}); err != nil {
+		t.Fatalf("write: %v", err)
+	}
+	after, _ := os.ReadFile(path)
+	if strings.Contains(string(after), "LBTOKEN") || !strings.Contains(string(after), "lb.example") {
+		t.Fatalf("config = %s", after)
+	}
+	if tok, err := LoadScrobbleToken(path, Config{}); err != nil || tok != "LBTOKEN" {
+		t.Fatalf("token = %q, %v", tok, err)
+	}
+}
+
+func TestUpdateDiscoveryCacheConcurrentWritersKeepAllEntries(t *testing.T) {
+	t.Parallel()
+
+	path := filepath.Join(t.TempDir(), "discovery.json")
+	const writers = 20
+	var wg sync.WaitGroup
+	errs := make(chan error, writers)
+	for i := range writers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			errs <- UpdateDiscoveryCache(path, func(c *DiscoveryCache) {
+				*c = c.Merge(time.Now(), []Device{{Host: fmt.Sprintf("10.0.0.%d", i), Port: 11000, Name: fmt.Sprintf("Player %d", i)}})
+			})
+		}()
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		if err != nil {
+			t.Fatalf("UpdateDiscoveryCache: %v", err)
+		}
+	}
+
+	cache, err := LoadDiscoveryCache(path)
+	if err != nil {
+		t.Fatalf("LoadDiscoveryCache: %v", err)
+	}
+	if len(cache.Devices) != writers {
+		t.Fatalf("devices = %d; want %d: %+v", len(cache.Devices), writers, cache.Devices)
+	}
+}
@@ -0,0 +1,287 @@
+package config
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"reflect"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/steipete/blucli/internal/secrets"
+)
+
+// ParseError is a config syntax or type error with its position in the file.
+type ParseError struct {
+	Line   int
+	Column int
+	Err    error
+}
+
+func (e *ParseError) Error() string {
+	return fmt.Sprintf("%d:%d: %v", e.Line, e.Column, e.Err)
+}
+
+func (e *ParseError) Unwrap() error { return e.Err }
+
+// Parse decodes config.json, reporting JSON errors with line and column.
+// Unknown keys are ignored so older binaries can read newer configs.
+func Parse(data []byte) (Config, error) {
+	var cfg Config
+	if err := json.Unmarshal(data, &cfg); err != nil {
+		return Config{}, parseError(data, err)
+	}
+	if cfg.Aliases == nil {
+		cfg.Aliases = map[string]string{}
+	}
+	return cfg, nil
+}
+
+func parseError(data []byte, err error) error {
+	var syntaxErr *json.SyntaxError
+	var typeErr *json.UnmarshalTypeError
+	switch {
+	case errors.As(err, &syntaxErr):
+		// Offset counts the offending byte; point at it unless input ran out.
+		offset := syntaxErr.Offset
+		if syntaxErr.Error() != "unexpected end of JSON input" {
+			offset--
+		}
+		line, col := position(data, offset)
+		return &ParseError{Line: line, Column: col, Err: errors.New(strings.TrimPrefix(syntaxErr.Error(), "json: "))}
+	case errors.As(err, &typeErr):
+		line, col := position(data, typeErr.Offset)
+		msg := fmt.Sprintf("%s: expected %s, got %s", typeErr.Field, typeErr.Type, typeErr.Value)
+		return &ParseError{Line: line, Column: col, Err: errors.New(msg)}
+	}
+	return err
+}
+
+// position converts a byte offset into a 1-based line and column.
+func position(data []byte, offset int64) (int, int) {
+	offset = min(max(offset, 0), int64(len(data)))
+	before := data[:offset]
+	line := bytes.Count(before, []byte("\n")) + 1
+	col := int(offset) - bytes.LastIndexByte(before, '\n')
+	return line, col
+}
+
+type Severity string
+
+const (
+	SeverityError   Severity = "error"
+	SeverityWarning Severity = "warning"
+)
+
+type Issue struct {
+	Severity Severity `json:"severity"`
+	Key      string   `json:"key,omitempty"`
+	Line     int      `json:"line,omitempty"`
+	Column   int      `json:"column,omitempty"`
+	Message  string   `json:"message"`
+}
+
+func (i Issue) String() string {
+	var b strings.Builder
+	if i.Line > 0 {
+		fmt.Fprintf(&b, "%d:%d: ", i.Line, i.Column)
+	}
+	b.WriteString(string(i.Severity))
+	b.WriteString(": ")
+	if i.Key != "" {
+		b.WriteString(i.Key)
+		b.WriteString(": ")
+	}
+	b.WriteString(i.Message)
+	return b.String()
+}
+
+// Validate checks config.json contents: syntax, unknown keys, and that
+// aliases and the default device point at something resolvable.
+func Validate(data []byte) []Issue {
+	if len(bytes.TrimSpace(data)) == 0 {
+		return nil
+	}
+	cfg, err := Parse(data)
+	if err != nil {
+		issue := Issue{Severity: SeverityError, Message: err.Error()}
+		var pe *ParseError
+		if errors.As(err, &pe) {
+			issue.Line, issue.Column, issue.Message = pe.Line, pe.Column, pe.Err.Error()
+		}
+		return []Issue{issue}
+	}
+
+	positions := keyPositions(data)
+	var issues []Issue
+	add := func(sev Severity, key, format string, args ...any) {
+		issue := Issue{Severity: sev, Key: key, Message: fmt.Sprintf(format, args...)}
+		if off, ok := positions[key]; ok {
+			issue.Line, issue.Column = position(data, off)
+		}
+		issues = append(issues, issue)
+	}
+
+	var raw map[string]any
+	if err := json.Unmarshal(data, &raw); err == nil {
+		for _, key := range unknownKeys(raw, reflect.TypeOf(Config{}), "") {
+			add(SeverityWarning, key, "unknown key")
+		}
+	}
+
+	names := make([]string, 0, len(cfg.Aliases))
+	for name := range cfg.Aliases {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		key := "aliases." + name
+		target := strings.TrimSpace(cfg.Aliases[name])
+		switch {
+		case strings.TrimSpace(name) == "":
+			add(SeverityError, key, "empty alias name")
+		case target == "":
+			add(SeverityError, key, "empty device")
+		default:
+			if _, chained := cfg.Aliases[target]; chained {
+				add(SeverityWarning, key, "points to alias %q; aliases are not chained", target)
+				continue
+			}
+			if err := checkDevice(target); err != nil {
+				add(SeverityError, key, "%v", err)
+			}
+		}
+	}
+
+	if def := strings.TrimSpace(cfg.DefaultDevice); def != "" {
+		if _, ok := cfg.Aliases[def]; !ok {
+			if err := checkDevice(def); err != nil {
+				add(SeverityError, "default_device", "%v", err)
+			}
+		}
+	}
+
+	for _, d := range []struct{ key, v string }{
+		{"defaults.timeout", cfg.Defaults.Timeout},
+		{"defaults.discover_timeout", cfg.Defaults.DiscoverTimeout},
+	} {
+		key, v := d.key, d.v
+		if v == "" {
+			continue
+		}
+		if d, err := time.ParseDuration(v); err != nil || d <= 0 {
+			add(SeverityError, key, "want a positive duration like 5s, got %q", v)
+		}
+	}
+
+	if v := strings.TrimSpace(cfg.Defaults.TraceHTTP); v != "" {
+		if _, err := ParseTraceHTTP(v); err != nil {
+			add(SeverityError, "defaults.trace_http", "want true, false or full, got %q", v)
+		}
+	}
+
+	if b := strings.ToLower(strings.TrimSpace(cfg.Secrets.Backend)); b != "" && !slices.Contains(secrets.Backends, b) {
+		add(SeverityError, "secrets.backend", "want one of %s, got %q", strings.Join(secrets.Backends, ", "), cfg.Secrets.Backend)
+	}
+
+	if u := strings.TrimSpace(cfg.Scrobble.URL); u != "" {
+		if parsed, err := url.Parse(u); err != nil || (parsed.Scheme != "http" && parsed.Scheme != "https") || parsed.Host == "" {
+			add(SeverityError, "scrobble.url", "want an http(s) URL, got %q", u)
+		}
+	}
+	return issues
+}
+
+// HasErrors reports whether any issue is an error rather than a warning.
+func HasErrors(issues []Issue) bool {
+	for _, i := range issues {
+		if i.Severity == SeverityError {
+			return true
+		}
+	}
+	return false
+}
+
+func checkDevice(s string) error {
+	d, err := ParseDevice(s)
+	if err != nil {
+		return fmt.Errorf("invalid device %q: %v", s, err)
+	}
+	if d.Port < 1 || d.Port > 65535 {
+		return fmt.Errorf("invalid device %q: port %d out of range", s, d.Port)
+	}
+	return nil
+}
+
+// unknownKeys lists dotted keys in raw that t (a struct type) does not
+// declare. Map-typed fields accept any key.
+func unknownKeys(raw map[string]any, t reflect.Type, prefix string) []string {
+	fields := map[string]reflect.Type{}
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		name, _, _ := strings.Cut(f.Tag.Get("json"), ",")
+		if name == "" || name == "-" {
+			continue
+		}
+		fields[name] = f.Type
+	}
+
+	var unknown []string
+	for key, v := range raw {
+		ft, ok := fields[key]
+		if !ok {
+			unknown = append(unknown, prefix+key)
+			continue
+		}
+		if nested, ok := v.(map[string]any); ok && ft.Kind() == reflect.Struct {
+			unknown = append(unknown, unknownKeys(nested, ft, prefix+key+".")...)
+		}
+	}
+	sort.Strings(unknown)
+	return unknown
+}
+
+// keyPositions maps dotted object keys to the byte offset of their opening
+// quote.
+func keyPositions(data []byte) map[string]int64 {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	positions := map[string]int64{}
+
+	var walk func(prefix string) error
+	walk = func(prefix string) error {
+		tok, err := dec.Token()
+		if err != nil {
+			return err
+		}
+		delim, ok := tok.(json.Delim)
+		if !ok {
+			return nil
+		}
+		for dec.More() {
+			path := prefix
+			if delim == '{' {
+				tok, err := dec.Token()
+				if err != nil {
+					return err
+				}
+				key, _ := tok.(string)
+				end := dec.InputOffset()
+				if start := bytes.LastIndexByte(data[:max(end-1, 0)], '"'); start >= 0 {
+					path = strings.TrimPrefix(prefix+"."+key, ".")
+					positions[path] = int64(start)
+				}
+			}
+			if err := walk(path); err != nil {
+				return err
+			}
+		}
+		_, err = dec.Token()
+		return err
+	}
+	_ = walk("")
+	return positions
+}
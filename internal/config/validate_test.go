@@ -0,0 +1,114 @@
+package config
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestLoad_ParseErrorHasPosition(t *testing.T) {
+	t.Parallel()
+
+	path := filepath.Join(t.TempDir(), "config.json")
+	if err := os.WriteFile(path, []byte("{\n  \"default_device\": \"x\",\n  \"aliases\": {\"a\": 1}\n}\n"), 0o644); err != nil {
+		t.Fatalf("write: %v", err)
+	}
+	_, err := Load(LoadOptions{Path: path})
+	var pe *ParseError
+	if !errors.As(err, &pe) || pe.Line != 3 {
+		t.Fatalf("err = %v; want ParseError on line 3", err)
+	}
+	if !strings.HasPrefix(err.Error(), path+":3:") || !strings.Contains(err.Error(), "aliases.a: expected string, got number") {
+		t.Fatalf("err = %q", err)
+	}
+
+	if _, err := Parse([]byte("{\n  \"x\": \n")); !errors.As(err, &pe) || pe.Line != 3 {
+		t.Fatalf("truncated: err = %v", err)
+	}
+}
+
+func TestValidate(t *testing.T) {
+	t.Parallel()
+
+	data := []byte(`{
+  "default_device": "den",
+  "aliases": {
+    "kitchen": "10.0.0.5:11000",
+    "den": "10.0.0.6:99999",
+    "office": "kitchen",
+    "empty": " "
+  },
+  "scrobble": {"url": "listenbrainz.org"},
+  "extra": true,
+  "defaults": {"timeout": "5", "discover_timeout": "2s", "trace_http": "loud"}
+}
+`)
+	issues := Validate(data)
+	got := make([]string, 0, len(issues))
+	for _, i := range issues {
+		got = append(got, i.String())
+	}
+	want := []string{
+		"10:3: warning: extra: unknown key",
+		`5:5: error: aliases.den: invalid device "10.0.0.6:99999": port 99999 out of range`,
+		"7:5: error: aliases.empty: empty device",
+		`6:5: warning: aliases.office: points to alias "kitchen"; aliases are not chained`,
+		`11:16: error: defaults.timeout: want a positive duration like 5s, got "5"`,
+		`11:58: error: defaults.trace_http: want true, false or full, got "loud"`,
+		`9:16: error: scrobble.url: want an http(s) URL, got "listenbrainz.org"`,
+	}
+	if strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Fatalf("issues =\n%s\nwant\n%s", strings.Join(got, "\n"), strings.Join(want, "\n"))
+	}
+	if !HasErrors(issues) {
+		t.Fatalf("HasErrors = false")
+	}
+
+	if issues := Validate([]byte(`{"aliases": {"a": "10.0.0.1"}, "default_device": "a", "defaults": {"trace_http": "full"}}`)); len(issues) != 0 {
+		t.Fatalf("issues = %v", issues)
+	}
+	if issues := Validate([]byte("{,}")); len(issues) != 1 || issues[0].Line != 1 || issues[0].Column != 2 {
+		t.Fatalf("syntax issues = %+v", issues)
+	}
+}
+
+func TestGetSetUnset(t *testing.T) {
+	t.Parallel()
+
+	cfg := Config{Aliases: map[string]string{}}
+	cfg, err := Set(cfg, "aliases.kitchen", "10.0.0.5")
+	if err != nil {
+		t.Fatalf("Set: %v", err)
+	}
+	if cfg, err = Set(cfg, "default_device", "123"); err != nil || cfg.DefaultDevice != "123" {
+		t.Fatalf("Set number as string: %v %q", err, cfg.DefaultDevice)
+	}
+	if cfg, err = Set(cfg, "spotify.token.expires_at", "2026-01-02T03:04:05Z"); err != nil || cfg.Spotify.Token.ExpiresAt.Year() != 2026 {
+		t.Fatalf("Set time: %v %v", err, cfg.Spotify.Token.ExpiresAt)
+	}
+	if cfg, err = Set(cfg, "aliases", `{"den":"10.0.0.6"}`); err != nil || cfg.Aliases["den"] != "10.0.0.6" || len(cfg.Aliases) != 1 {
+		t.Fatalf("Set object: %v %v", err, cfg.Aliases)
+	}
+
+	if v, err := Get(cfg, "aliases.den"); err != nil || v != "10.0.0.6" {
+		t.Fatalf("Get = %v, %v", v, err)
+	}
+	if _, err := Get(cfg, "aliases.nope"); !errors.Is(err, ErrKeyNotSet) {
+		t.Fatalf("Get missing err = %v", err)
+	}
+	if _, err := Set(cfg, "nope", "x"); err == nil || err.Error() != `unknown key "nope"` {
+		t.Fatalf("Set unknown err = %v", err)
+	}
+	if _, err := Set(cfg, "default_device.x", "y"); err == nil {
+		t.Fatalf("Set through string: want error")
+	}
+
+	if cfg, err = Unset(cfg, "aliases.den"); err != nil || len(cfg.Aliases) != 0 {
+		t.Fatalf("Unset: %v %v", err, cfg.Aliases)
+	}
+	if _, err := Unset(cfg, "aliases.den"); !errors.Is(err, ErrKeyNotSet) {
+		t.Fatalf("Unset missing err = %v", err)
+	}
+}
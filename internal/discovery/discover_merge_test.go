@@ -3,7 +3,11 @@ package discovery
 import (
 	"context"
 	"errors"
+	"net"
+	"strings"
 	"testing"
+
+	"github.com/steipete/blucli/internal/logging"
 )
 
 func TestDiscover_JoinsErrorIfBothFail(t *testing.T) {
@@ -37,6 +41,53 @@ func TestDiscover_ReturnsDevicesIfOneMechanismWorks(t *testing.T) {
 	}
 }
 
+func TestDiscover_LogsMechanismResults(t *testing.T) {
+	t.Parallel()
+
+	var logs strings.Builder
+	logger, _ := logging.New(&logs, "debug", "text")
+	ctx := logging.With(t.Context(), logger)
+	ctx = WithMDNSOverride(ctx, func(context.Context) ([]Device, error) {
+		return []Device{{ID: "a", Host: "1.1.1.1", Port: 11000, Name: "Kitchen", Type: "musc", Source: "mdns"}}, nil
+	})
+	ctx = WithLSDPOverride(ctx, func(context.Context) ([]Device, error) { return nil, errors.New("lsdp down") })
+
+	if _, err := Discover(ctx); err != nil {
+		t.Fatalf("err = %v", err)
+	}
+	for _, want := range []string{
+		`msg="discovery mechanism failed" mechanism=lsdp`,
+		`err="lsdp down"`,
+		`msg="discovered device" mechanism=mdns id=a name=Kitchen`,
+		`msg="discovery merged" sightings=1 players=1`,
+	} {
+		if !strings.Contains(logs.String(), want) {
+			t.Fatalf("logs missing %q:\n%s", want, logs.String())
+		}
+	}
+}
+
+func TestOpenLSDP_LogsBindFailure(t *testing.T) {
+	t.Parallel()
+
+	busy, err := net.ListenUDP("udp4", &net.UDPAddr{IP: net.IPv4zero})
+	if err != nil {
+		t.Skipf("udp unavailable: %v", err)
+	}
+	defer busy.Close()
+
+	var logs strings.Builder
+	logger, _ := logging.New(&logs, "info", "text")
+	ctx := WithLSDPPortOverride(logging.With(t.Context(), logger), busy.LocalAddr().(*net.UDPAddr).Port)
+	if conn, err := openLSDP(ctx); err == nil {
+		conn.Close()
+		t.Skip("port reuse allowed on this platform")
+	}
+	if got := logs.String(); !strings.Contains(got, `msg="lsdp bind failed"`) || !strings.Contains(got, "port in use") {
+		t.Fatalf("logs = %q", got)
+	}
+}
+
 func TestDiscover_MergesDuplicates(t *testing.T) {
 	t.Parallel()
 
@@ -2,13 +2,20 @@ package discovery
 
 import (
 	"context"
+	"encoding/hex"
 	"errors"
+	"maps"
 	"net"
+	"net/netip"
+	"slices"
 	"sort"
 	"strings"
 	"sync"
+	"time"
 
 	"github.com/grandcat/zeroconf"
+
+	"github.com/steipete/blucli/internal/logging"
 )
 
 type Device struct {
@@ -19,6 +26,20 @@ type Device struct {
 	Type    string `json:"type"`
 	Version string `json:"version,omitempty"`
 	Source  string `json:"source,omitempty"`
+	// MAC is the player's LSDP node ID (or from SyncStatus), a stable
+	// identity across DHCP address changes.
+	MAC string `json:"mac,omitempty"`
+	// NodeID is the raw LSDP node ID, for nodes whose ID is not a MAC.
+	NodeID string `json:"node_id,omitempty"`
+	// Roles lists what the node announced itself as (player, secondary,
+	// pair-slave, hub, server, ...).
+	Roles []string `json:"roles,omitempty"`
+	// Model, ModelName and Brand come from TXT records or SyncStatus.
+	Model     string `json:"model,omitempty"`
+	ModelName string `json:"model_name,omitempty"`
+	Brand     string `json:"brand,omitempty"`
+	// TXT holds every mDNS/LSDP TXT key the player advertised.
+	TXT map[string]string `json:"txt,omitempty"`
 }
 
 var serviceTypes = []string{"musc", "musp", "musz", "mush"}
@@ -38,66 +59,87 @@ func WithLSDPOverride(ctx context.Context, fn DiscoverFunc) context.Context {
 	return context.WithValue(ctx, lsdpOverrideKey{}, fn)
 }
 
+// Discover runs mDNS and LSDP (plus a subnet scan when configured with
+// WithScan) until ctx ends and merges their results by ID.
 func Discover(ctx context.Context) ([]Device, error) {
-	var (
-		mdnsDevices []Device
-		lsdpDevices []Device
-		mdnsErr     error
-		lsdpErr     error
-	)
-
-	mdnsFn := discoverMDNS
-	if v := ctx.Value(mdnsOverrideKey{}); v != nil {
-		if fn, ok := v.(DiscoverFunc); ok && fn != nil {
-			mdnsFn = fn
-		}
+	devices, _, err := DiscoverMechanisms(ctx)
+	return devices, err
+}
+
+// MechanismResult is what one discovery mechanism (mdns, lsdp, scan) found
+// on its own, before merging.
+type MechanismResult struct {
+	Mechanism string
+	Devices   []Device
+	Err       error
+	Elapsed   time.Duration
+}
+
+// DiscoverMechanisms is Discover that also returns each mechanism's own
+// result, for diagnostics.
+func DiscoverMechanisms(ctx context.Context) ([]Device, []MechanismResult, error) {
+	mdnsFn := DiscoverFunc(discoverMDNS)
+	if fn := overrideFunc(ctx, mdnsOverrideKey{}); fn != nil {
+		mdnsFn = fn
 	}
 
-	lsdpFn := discoverLSDP
-	if v := ctx.Value(lsdpOverrideKey{}); v != nil {
-		if fn, ok := v.(DiscoverFunc); ok && fn != nil {
-			lsdpFn = fn
-		}
+	lsdpFn := DiscoverFunc(discoverLSDP)
+	if fn := overrideFunc(ctx, lsdpOverrideKey{}); fn != nil {
+		lsdpFn = fn
+	}
+
+	results := []MechanismResult{{Mechanism: "mdns"}, {Mechanism: "lsdp"}}
+	fns := []DiscoverFunc{mdnsFn, lsdpFn}
+	if opts, ok := scanOptions(ctx); ok {
+		results = append(results, MechanismResult{Mechanism: "scan"})
+		fns = append(fns, func(ctx context.Context) ([]Device, error) { return scan(ctx, opts) })
 	}
 
 	var wg sync.WaitGroup
-	wg.Add(2)
-	go func() {
-		defer wg.Done()
-		mdnsDevices, mdnsErr = mdnsFn(ctx)
-	}()
-	go func() {
-		defer wg.Done()
-		lsdpDevices, lsdpErr = lsdpFn(ctx)
-	}()
+	for i, fn := range fns {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			start := time.Now()
+			results[i].Devices, results[i].Err = fn(ctx)
+			results[i].Elapsed = time.Since(start)
+		}()
+	}
 	wg.Wait()
 
-	// Prefer returning an error only if both mechanisms fail.
-	if mdnsErr != nil && lsdpErr != nil {
-		return nil, errors.Join(mdnsErr, lsdpErr)
+	log := logging.From(ctx)
+	for _, r := range results {
+		if r.Err != nil {
+			log.Info("discovery mechanism failed", "mechanism", r.Mechanism, "elapsed", r.Elapsed, "err", r.Err)
+		}
+		log.Debug("discovery mechanism done", "mechanism", r.Mechanism, "devices", len(r.Devices), "elapsed", r.Elapsed)
+		for _, d := range r.Devices {
+			log.Debug("discovered device", "mechanism", r.Mechanism, "id", d.ID, "name", d.Name, "type", d.Type)
+		}
 	}
 
-	seen := map[string]Device{}
-	for _, d := range mdnsDevices {
-		seen[d.ID] = d
+	// Prefer returning an error only if every mechanism fails.
+	failed := 0
+	errs := make([]error, 0, len(results))
+	for _, r := range results {
+		if r.Err != nil {
+			failed++
+			errs = append(errs, r.Err)
+		}
 	}
-	for _, d := range lsdpDevices {
-		if existing, ok := seen[d.ID]; ok {
-			if existing.Version == "" && d.Version != "" {
-				existing.Version = d.Version
-			}
-			if existing.Type == "" && d.Type != "" {
-				existing.Type = d.Type
-			}
-			if existing.Source == "" {
-				existing.Source = d.Source
-			} else if d.Source != "" && !strings.Contains(existing.Source, d.Source) {
-				existing.Source = existing.Source + "+" + d.Source
+	if failed == len(results) {
+		return nil, results, errors.Join(errs...)
+	}
+
+	seen := map[string]Device{}
+	for _, r := range results {
+		for _, d := range r.Devices {
+			if existing, ok := seen[d.ID]; ok {
+				seen[d.ID] = mergeDevice(existing, d)
+				continue
 			}
-			seen[d.ID] = existing
-			continue
+			seen[d.ID] = d
 		}
-		seen[d.ID] = d
 	}
 
 	devices := make([]Device, 0, len(seen))
@@ -105,16 +147,179 @@ func Discover(ctx context.Context) ([]Device, error) {
 		devices = append(devices, d)
 	}
 	sort.Slice(devices, func(i, j int) bool { return devices[i].ID < devices[j].ID })
-	return devices, nil
+	merged := mergePlayers(devices)
+	log.Debug("discovery merged", "sightings", len(devices), "players", len(merged))
+	return merged, results, nil
+}
+
+// mergePlayers folds every sighting of one physical player into one entry:
+// several service types on one host, or several addresses or zones (same
+// MAC, or same name over IPv4 and IPv6). The entry keeps the preferred
+// address and the primary role's endpoint. devices must be sorted by ID.
+func mergePlayers(devices []Device) []Device {
+	out := make([]Device, 0, len(devices))
+	for _, d := range devices {
+		i := slices.IndexFunc(out, func(o Device) bool { return samePlayer(o, d) })
+		if i < 0 {
+			out = append(out, d)
+			continue
+		}
+		keep, other := out[i], d
+		if preferEndpoint(d, keep) {
+			keep, other = d, keep
+		}
+		out[i] = mergeDevice(keep, other)
+	}
+	return out
+}
+
+// preferEndpoint reports whether a's endpoint beats b's: better address
+// first, then the primary service type, then the lower port.
+func preferEndpoint(a, b Device) bool {
+	if ra, rb := hostRank(a.Host), hostRank(b.Host); ra != rb {
+		return ra < rb
+	}
+	if ta, tb := typeRank(a.Type), typeRank(b.Type); ta != tb {
+		return ta < tb
+	}
+	return a.Port < b.Port
+}
+
+func typeRank(t string) int {
+	if i := slices.Index(serviceTypes, t); i >= 0 {
+		return i
+	}
+	return len(serviceTypes)
+}
+
+func samePlayer(a, b Device) bool {
+	// Several players can share a host (port forwards, emulators); only
+	// different service types there are one player.
+	if a.Host == b.Host && a.Type != "" && b.Type != "" && a.Type != b.Type {
+		return true
+	}
+	if a.MAC != "" && b.MAC != "" {
+		return NormalizeMAC(a.MAC) == NormalizeMAC(b.MAC)
+	}
+	if isIPv6Host(a.Host) == isIPv6Host(b.Host) {
+		return false
+	}
+	n := normalizeName(a.Name)
+	return n != "" && n == normalizeName(b.Name) && (a.Type == "" || b.Type == "" || a.Type == b.Type)
+}
+
+func hostRank(host string) int {
+	addr, err := netip.ParseAddr(host)
+	if err != nil {
+		return 5
+	}
+	return addrRank(addr)
+}
+
+func isIPv6Host(host string) bool {
+	addr, err := netip.ParseAddr(host)
+	return err == nil && addr.Is6() && !addr.Is4In6()
+}
+
+// mergeDevice fills gaps in existing from another mechanism's sighting.
+func mergeDevice(existing, d Device) Device {
+	if existing.Name == "" {
+		existing.Name = d.Name
+	}
+	if existing.Version == "" && d.Version != "" {
+		existing.Version = d.Version
+	}
+	if existing.Type == "" && d.Type != "" {
+		existing.Type = d.Type
+	}
+	if existing.MAC == "" {
+		existing.MAC = d.MAC
+	}
+	if existing.NodeID == "" {
+		existing.NodeID = d.NodeID
+	}
+	if existing.Model == "" {
+		existing.Model = d.Model
+	}
+	if existing.ModelName == "" {
+		existing.ModelName = d.ModelName
+	}
+	if existing.Brand == "" {
+		existing.Brand = d.Brand
+	}
+	existing.Roles = mergeRoles(existing.Roles, d.Roles)
+	existing.TXT = mergeTXT(existing.TXT, d.TXT)
+	if existing.Source == "" {
+		existing.Source = d.Source
+	} else if d.Source != "" && !strings.Contains(existing.Source, d.Source) {
+		existing.Source = existing.Source + "+" + d.Source
+	}
+	return existing
+}
+
+func mergeRoles(a, b []string) []string {
+	out := a
+	for _, r := range b {
+		if !slices.Contains(out, r) {
+			out = append(slices.Clip(out), r)
+		}
+	}
+	return out
+}
+
+// mergeTXT adds keys from b that a lacks, without modifying a.
+func mergeTXT(a, b map[string]string) map[string]string {
+	if len(b) == 0 {
+		return a
+	}
+	out := maps.Clone(a)
+	if out == nil {
+		out = map[string]string{}
+	}
+	for k, v := range b {
+		if _, ok := out[k]; !ok {
+			out[k] = v
+		}
+	}
+	return out
 }
 
 func discoverMDNS(ctx context.Context) ([]Device, error) {
+	seen := map[string]Device{}
+	err := browseMDNS(ctx, func(device Device) {
+		if existing, exists := seen[device.ID]; exists {
+			seen[device.ID] = mergeDevice(existing, device)
+			return
+		}
+		seen[device.ID] = device
+	})
+
+	devices := make([]Device, 0, len(seen))
+	for _, device := range seen {
+		devices = append(devices, device)
+	}
+	sort.Slice(devices, func(i, j int) bool { return devices[i].ID < devices[j].ID })
+	if len(devices) == 0 && err != nil {
+		return nil, err
+	}
+	return devices, nil
+}
+
+// browseMDNS browses all BluOS service types and reports every resolved
+// entry until ctx ends. The error collects resolvers that failed to start.
+func browseMDNS(ctx context.Context, found func(Device)) error {
 	var resolverErr error
 	out := make(chan *zeroconf.ServiceEntry, 64)
 
+	scope := scopeFor(ctx)
+	var opts []zeroconf.ClientOption
+	if scope.restricted() {
+		opts = append(opts, zeroconf.SelectIfaces(scope.ifaces))
+	}
+
 	var wg sync.WaitGroup
 	for _, t := range serviceTypes {
-		resolver, err := zeroconf.NewResolver(nil)
+		resolver, err := zeroconf.NewResolver(opts...)
 		if err != nil {
 			// keep going; LSDP may still work
 			resolverErr = errors.Join(resolverErr, err)
@@ -145,49 +350,31 @@ func discoverMDNS(ctx context.Context) ([]Device, error) {
 		close(out)
 	}()
 
-	seen := map[string]Device{}
-
 	for entry := range out {
 		if entry == nil {
 			continue
 		}
-		device, ok := deviceFromEntry(entry)
+		device, ok := deviceFromEntry(entry, scope)
 		if !ok {
 			continue
 		}
 		device.Source = "mdns"
-		if existing, exists := seen[device.ID]; exists {
-			if existing.Version == "" && device.Version != "" {
-				existing.Version = device.Version
-				seen[device.ID] = existing
-			}
-			continue
-		}
-		seen[device.ID] = device
-	}
-
-	devices := make([]Device, 0, len(seen))
-	for _, device := range seen {
-		devices = append(devices, device)
-	}
-	sort.Slice(devices, func(i, j int) bool { return devices[i].ID < devices[j].ID })
-	if len(devices) == 0 && resolverErr != nil {
-		return nil, resolverErr
+		found(device)
 	}
-	return devices, nil
+	return resolverErr
 }
 
-func deviceFromEntry(entry *zeroconf.ServiceEntry) (Device, bool) {
+func deviceFromEntry(entry *zeroconf.ServiceEntry, scope netScope) (Device, bool) {
 	if entry.Port == 0 {
 		return Device{}, false
 	}
 
-	ip := pickIPv4(entry.AddrIPv4)
-	if ip == nil {
+	addr, ok := scope.preferAddr(append(slices.Clone(entry.AddrIPv4), entry.AddrIPv6...))
+	if !ok {
 		return Device{}, false
 	}
 
-	host := ip.String()
+	host := addr.String()
 	id := net.JoinHostPort(host, itoa(entry.Port))
 
 	typ := strings.TrimSuffix(entry.Service, ".")
@@ -199,26 +386,71 @@ func deviceFromEntry(entry *zeroconf.ServiceEntry) (Device, bool) {
 		name = strings.TrimSuffix(strings.TrimSpace(entry.HostName), ".")
 	}
 
-	return Device{
+	txt := parseTXT(entry.Text)
+	d := Device{
 		ID:      id,
 		Host:    host,
 		Port:    entry.Port,
 		Name:    name,
 		Type:    typ,
-		Version: parseTXT(entry.Text)["version"],
-	}, true
+		Version: txt["version"],
+		MAC:     NormalizeMAC(txtValue(txt, "mac", "macaddr", "mac_address")),
+		Model:   txtValue(txt, "model", "model_no"),
+		Brand:   txtValue(txt, "brand", "manufacturer"),
+
+		ModelName: txtValue(txt, "modelName", "model_name"),
+	}
+	if role := typeRole(typ); role != "" {
+		d.Roles = []string{role}
+	}
+	if len(txt) > 0 {
+		d.TXT = txt
+	}
+	return d, true
 }
 
-func pickIPv4(ips []net.IP) net.IP {
-	for _, ip := range ips {
-		if ip == nil {
-			continue
+// typeRole maps an mDNS service type to the role of its LSDP class.
+func typeRole(typ string) string {
+	switch typ {
+	case "musc":
+		return "player"
+	case "musp":
+		return "secondary"
+	case "musz":
+		return "pair-slave"
+	case "mush":
+		return "hub"
+	default:
+		return ""
+	}
+}
+
+// txtValue returns the first non-empty value among keys (case-insensitive).
+func txtValue(txt map[string]string, keys ...string) string {
+	for _, key := range keys {
+		for k, v := range txt {
+			if strings.EqualFold(k, key) && v != "" {
+				return v
+			}
 		}
-		if v4 := ip.To4(); v4 != nil {
-			return v4
+	}
+	return ""
+}
+
+// NormalizeMAC lower-cases a MAC and adds colons when it parses, also for
+// bare 12-digit hex as found in some TXT records. It is the one form MACs
+// are compared in, here and in the discovery cache.
+func NormalizeMAC(s string) string {
+	s = strings.TrimSpace(s)
+	if len(s) == 12 {
+		if b, err := hex.DecodeString(s); err == nil {
+			return net.HardwareAddr(b).String()
 		}
 	}
-	return nil
+	if hw, err := net.ParseMAC(s); err == nil {
+		return hw.String()
+	}
+	return strings.ToLower(s)
 }
 
 func parseTXT(records []string) map[string]string {
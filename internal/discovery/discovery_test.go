@@ -19,7 +19,7 @@ func TestDeviceFromEntry(t *testing.T) {
 		Text:     []string{"version=4.2.1"},
 	}
 
-	device, ok := deviceFromEntry(entry)
+	device, ok := deviceFromEntry(entry, netScope{})
 	if !ok {
 		t.Fatalf("deviceFromEntry() ok = false")
 	}
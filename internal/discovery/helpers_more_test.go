@@ -1,23 +1,6 @@
 package discovery
 
-import (
-	"net"
-	"testing"
-)
-
-func TestPickIPv4(t *testing.T) {
-	t.Parallel()
-
-	if got := pickIPv4(nil); got != nil {
-		t.Fatalf("got=%v; want nil", got)
-	}
-	if got := pickIPv4([]net.IP{net.ParseIP("::1")}); got != nil {
-		t.Fatalf("got=%v; want nil", got)
-	}
-	if got := pickIPv4([]net.IP{nil, net.ParseIP("192.0.2.1")}); got == nil || got.String() != "192.0.2.1" {
-		t.Fatalf("got=%v; want 192.0.2.1", got)
-	}
-}
+import "testing"
 
 func TestParseTXT(t *testing.T) {
 	t.Parallel()
@@ -44,3 +27,22 @@ func TestItoa(t *testing.T) {
 		t.Fatalf("itoa(-7)=%q", got)
 	}
 }
+
+func TestNormalizeMAC(t *testing.T) {
+	t.Parallel()
+
+	for in, want := range map[string]string{
+		"90:56:82:9F:02:78": "90:56:82:9f:02:78",
+		"90-56-82-9f-02-78": "90:56:82:9f:02:78",
+		" 9056829F0278 ":    "90:56:82:9f:02:78",
+		"NOT-A-MAC":         "not-a-mac",
+		"":                  "",
+	} {
+		if got := NormalizeMAC(in); got != want {
+			t.Errorf("NormalizeMAC(%q) = %q; want %q", in, got, want)
+		}
+	}
+	if !sameDevice(Device{MAC: "9056829F0278"}, Device{MAC: "90:56:82:9f:02:78"}) {
+		t.Errorf("sameDevice: bare hex and colon MAC differ")
+	}
+}
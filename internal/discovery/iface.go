@@ -0,0 +1,190 @@
+package discovery
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/netip"
+	"slices"
+	"sort"
+	"strings"
+)
+
+type interfacesKey struct{}
+
+// WithInterfaces restricts discovery to the named network interfaces, for
+// multi-homed and Docker hosts where the default picks the wrong network.
+func WithInterfaces(ctx context.Context, names []string) context.Context {
+	return context.WithValue(ctx, interfacesKey{}, names)
+}
+
+// ParseInterfaces splits a comma-separated list of interface names and
+// checks that each one exists.
+func ParseInterfaces(s string) ([]string, error) {
+	ifaces, err := net.Interfaces()
+	if err != nil {
+		return nil, err
+	}
+	var names []string
+	for _, name := range strings.Split(s, ",") {
+		name = strings.TrimSpace(name)
+		if name == "" {
+			continue
+		}
+		if !slices.ContainsFunc(ifaces, func(iface net.Interface) bool { return iface.Name == name }) {
+			return nil, fmt.Errorf("interface %q: no such network interface", name)
+		}
+		names = append(names, name)
+	}
+	return names, nil
+}
+
+// netScope is the set of interfaces discovery runs on.
+type netScope struct {
+	// ifaces is nil when discovery is not restricted.
+	ifaces   []net.Interface
+	prefixes []netip.Prefix
+	// zone is attached to IPv6 link-local replies; empty when ambiguous.
+	zone string
+}
+
+func scopeFor(ctx context.Context) netScope {
+	all, _ := net.Interfaces()
+	var scope netScope
+	names, _ := ctx.Value(interfacesKey{}).([]string)
+	candidates := upInterfaces(all)
+	if len(names) > 0 {
+		scope.ifaces = []net.Interface{}
+		for _, iface := range all {
+			if slices.Contains(names, iface.Name) {
+				scope.ifaces = append(scope.ifaces, iface)
+				scope.prefixes = append(scope.prefixes, interfacePrefixes(iface)...)
+			}
+		}
+		candidates = scope.ifaces
+	}
+
+	// mDNS answers carry no interface, so a link-local zone is only known
+	// when exactly one candidate interface has IPv6 link-local addressing.
+	var zones []string
+	for _, iface := range candidates {
+		for _, p := range interfacePrefixes(iface) {
+			if p.Addr().Is6() && p.Addr().IsLinkLocalUnicast() {
+				zones = append(zones, iface.Name)
+				break
+			}
+		}
+	}
+	if len(zones) == 1 {
+		scope.zone = zones[0]
+	}
+	return scope
+}
+
+func (s netScope) restricted() bool { return s.ifaces != nil }
+
+// allows reports whether a reply from addr belongs to the scope.
+func (s netScope) allows(addr netip.Addr) bool {
+	if !s.restricted() {
+		return true
+	}
+	if addr.Zone() != "" {
+		return slices.Contains(s.interfaceNames(), addr.Zone())
+	}
+	for _, p := range s.prefixes {
+		if p.Contains(addr) {
+			return true
+		}
+	}
+	return false
+}
+
+func (s netScope) interfaceNames() []string {
+	names := make([]string, 0, len(s.ifaces))
+	for _, iface := range s.ifaces {
+		names = append(names, iface.Name)
+	}
+	return names
+}
+
+// interfaces returns the scoped interfaces, or all up, non-loopback ones.
+func (s netScope) interfaces() []net.Interface {
+	if s.restricted() {
+		return s.ifaces
+	}
+	all, _ := net.Interfaces()
+	return upInterfaces(all)
+}
+
+// preferAddr picks one address for a player that answered on several:
+// IPv4 first, then IPv6 ULA, global, link-local (only with a zone), and
+// IPv4 link-local last; ties go to the lowest address.
+func (s netScope) preferAddr(addrs []net.IP) (netip.Addr, bool) {
+	var candidates []netip.Addr
+	for _, ip := range addrs {
+		addr, ok := netip.AddrFromSlice(ip)
+		if !ok {
+			continue
+		}
+		addr = addr.Unmap()
+		if addr.IsUnspecified() || addr.IsLoopback() || addr.IsMulticast() {
+			continue
+		}
+		if addr.Is6() && addr.IsLinkLocalUnicast() {
+			if s.zone == "" {
+				continue
+			}
+			addr = addr.WithZone(s.zone)
+		}
+		if s.allows(addr) {
+			candidates = append(candidates, addr)
+		}
+	}
+	if len(candidates) == 0 {
+		return netip.Addr{}, false
+	}
+	sort.Slice(candidates, func(i, j int) bool {
+		ri, rj := addrRank(candidates[i]), addrRank(candidates[j])
+		if ri != rj {
+			return ri < rj
+		}
+		return candidates[i].Less(candidates[j])
+	})
+	return candidates[0], true
+}
+
+func addrRank(a netip.Addr) int {
+	switch {
+	case a.Is4() && !a.IsLinkLocalUnicast():
+		return 0
+	case a.Is6() && a.IsPrivate():
+		return 1
+	case a.Is6() && a.IsGlobalUnicast():
+		return 2
+	case a.Is6():
+		return 3
+	default:
+		return 4
+	}
+}
+
+func upInterfaces(ifaces []net.Interface) []net.Interface {
+	var out []net.Interface
+	for _, iface := range ifaces {
+		if iface.Flags&net.FlagUp != 0 && iface.Flags&net.FlagLoopback == 0 {
+			out = append(out, iface)
+		}
+	}
+	return out
+}
+
+func interfacePrefixes(iface net.Interface) []netip.Prefix {
+	addrs, _ := iface.Addrs()
+	var out []netip.Prefix
+	for _, addr := range addrs {
+		if p, err := netip.ParsePrefix(addr.String()); err == nil {
+			out = append(out, p)
+		}
+	}
+	return out
+}
@@ -0,0 +1,176 @@
+package discovery
+
+import (
+	"net"
+	"net/netip"
+	"strings"
+	"testing"
+
+	"github.com/grandcat/zeroconf"
+)
+
+func TestPreferAddr(t *testing.T) {
+	t.Parallel()
+
+	ips := func(ss ...string) []net.IP {
+		out := make([]net.IP, 0, len(ss))
+		for _, s := range ss {
+			out = append(out, net.ParseIP(s))
+		}
+		return out
+	}
+	tests := []struct {
+		name  string
+		scope netScope
+		ips   []net.IP
+		want  string
+	}{
+		{name: "none", ips: nil, want: ""},
+		{name: "loopback", ips: ips("::1", "127.0.0.1"), want: ""},
+		{name: "ipv4First", ips: ips("fd00::10", "192.168.1.20", "192.168.1.10"), want: "192.168.1.10"},
+		{name: "ulaBeforeGlobal", ips: ips("2001:db8::10", "fd00::10"), want: "fd00::10"},
+		{name: "linkLocalNeedsZone", ips: ips("fe80::1"), want: ""},
+		{name: "linkLocalZoned", scope: netScope{zone: "eth0"}, ips: ips("fe80::1", "169.254.3.4"), want: "fe80::1%eth0"},
+		{name: "ipv4LinkLocalLast", ips: ips("169.254.3.4"), want: "169.254.3.4"},
+		{
+			name: "restricted",
+			scope: netScope{
+				ifaces:   []net.Interface{{Name: "eth1"}},
+				prefixes: []netip.Prefix{netip.MustParsePrefix("10.0.5.2/24")},
+			},
+			ips:  ips("172.17.0.3", "10.0.5.40"),
+			want: "10.0.5.40",
+		},
+		{
+			name:  "restrictedZone",
+			scope: netScope{ifaces: []net.Interface{{Name: "eth1"}}, zone: "eth0"},
+			ips:   ips("fe80::1"),
+			want:  "",
+		},
+	}
+	for _, tt := range tests {
+		got, ok := tt.scope.preferAddr(tt.ips)
+		if tt.want == "" {
+			if ok {
+				t.Fatalf("%s: got %v; want none", tt.name, got)
+			}
+			continue
+		}
+		if !ok || got.String() != tt.want {
+			t.Fatalf("%s: got %v (%v); want %s", tt.name, got, ok, tt.want)
+		}
+	}
+}
+
+func TestDeviceFromEntryIPv6(t *testing.T) {
+	t.Parallel()
+
+	entry := &zeroconf.ServiceEntry{
+		ServiceRecord: zeroconf.ServiceRecord{Instance: "Kitchen", Service: "_musc._tcp"},
+		Port:          11000,
+		AddrIPv6:      []net.IP{net.ParseIP("fe80::1")},
+	}
+	d, ok := deviceFromEntry(entry, netScope{zone: "en0"})
+	if !ok {
+		t.Fatalf("deviceFromEntry() ok = false")
+	}
+	if d.Host != "fe80::1%en0" || d.ID != "[fe80::1%en0]:11000" {
+		t.Fatalf("device = %+v", d)
+	}
+}
+
+func TestMergeAddresses(t *testing.T) {
+	t.Parallel()
+
+	devices := []Device{
+		{ID: "10.0.0.5:11000", Host: "10.0.0.5", Port: 11000, Name: "Kitchen", MAC: "90:56:82:aa:bb:cc", Source: "lsdp"},
+		{ID: "172.17.0.9:11000", Host: "172.17.0.9", Port: 11000, Name: "Kitchen", MAC: "90:56:82:aa:bb:cc", Source: "scan"},
+		{ID: "192.168.1.7:11000", Host: "192.168.1.7", Port: 11000, Name: "Den", Type: "musc"},
+		{ID: "[fd00::7]:11000", Host: "fd00::7", Port: 11000, Name: "Den", Type: "musc", Version: "4.4.0", Source: "mdns"},
+		{ID: "192.168.1.8:11000", Host: "192.168.1.8", Port: 11000, Name: "Office"},
+		{ID: "192.168.1.9:11000", Host: "192.168.1.9", Port: 11000, Name: "Office"},
+	}
+	got := mergePlayers(devices)
+	if len(got) != 4 {
+		t.Fatalf("got %d devices: %+v", len(got), got)
+	}
+	if got[0].ID != "10.0.0.5:11000" || got[0].Source != "lsdp+scan" {
+		t.Fatalf("kitchen = %+v", got[0])
+	}
+	if got[1].ID != "192.168.1.7:11000" || got[1].Version != "4.4.0" {
+		t.Fatalf("den = %+v", got[1])
+	}
+	if got[2].Name != "Office" || got[3].Name != "Office" {
+		t.Fatalf("same-family namesakes must stay separate: %+v", got[2:])
+	}
+}
+
+func TestNetScopeAllows(t *testing.T) {
+	t.Parallel()
+
+	if !(netScope{}).allows(netip.MustParseAddr("172.17.0.3")) {
+		t.Fatalf("unrestricted scope must allow everything")
+	}
+	scope := netScope{
+		ifaces:   []net.Interface{{Name: "eth1"}},
+		prefixes: []netip.Prefix{netip.MustParsePrefix("10.0.5.2/24"), netip.MustParsePrefix("fd00::2/64")},
+	}
+	for addr, want := range map[string]bool{
+		"10.0.5.77":    true,
+		"172.17.0.3":   false,
+		"fd00::99":     true,
+		"fe80::1%eth1": true,
+		"fe80::1%eth0": false,
+	} {
+		if got := scope.allows(netip.MustParseAddr(addr)); got != want {
+			t.Fatalf("allows(%s) = %v; want %v", addr, got, want)
+		}
+	}
+}
+
+func TestParseInterfaces(t *testing.T) {
+	t.Parallel()
+
+	if _, err := ParseInterfaces("definitely-not-a-nic0"); err == nil {
+		t.Fatalf("expected error for unknown interface")
+	}
+	names, err := ParseInterfaces(" , ")
+	if err != nil || len(names) != 0 {
+		t.Fatalf("names = %v, err = %v", names, err)
+	}
+}
+
+func TestDeviceFromEntryTXT(t *testing.T) {
+	t.Parallel()
+
+	entry := &zeroconf.ServiceEntry{
+		ServiceRecord: zeroconf.ServiceRecord{Instance: "Kitchen", Service: "_musp._tcp"},
+		Port:          11000,
+		AddrIPv4:      []net.IP{net.ParseIP("192.168.1.10")},
+		Text:          []string{"version=4.4.0", "mac=9056829F0278", "model=N130", "modelName=NODE 2i", "zoneId=3"},
+	}
+	d, ok := deviceFromEntry(entry, netScope{})
+	if !ok {
+		t.Fatalf("deviceFromEntry() ok = false")
+	}
+	if d.MAC != "90:56:82:9f:02:78" || d.Model != "N130" || d.ModelName != "NODE 2i" || d.TXT["zoneId"] != "3" || len(d.Roles) != 1 || d.Roles[0] != "secondary" {
+		t.Fatalf("device = %+v", d)
+	}
+}
+
+func TestMergePlayersServiceTypes(t *testing.T) {
+	t.Parallel()
+
+	got := mergePlayers([]Device{
+		{ID: "10.0.0.5:11000", Host: "10.0.0.5", Port: 11000, Name: "Vault", Type: "musc", Roles: []string{"player"}, TXT: map[string]string{"a": "1"}},
+		{ID: "10.0.0.5:11010", Host: "10.0.0.5", Port: 11010, Name: "Vault", Type: "mush", Roles: []string{"hub"}, Model: "N132", TXT: map[string]string{"a": "2", "b": "3"}},
+		{ID: "10.0.0.5:12000", Host: "10.0.0.5", Port: 12000, Name: "Emulator", Type: "musc"},
+	})
+	if len(got) != 2 {
+		t.Fatalf("got = %+v", got)
+	}
+	v := got[0]
+	if v.ID != "10.0.0.5:11000" || v.Model != "N132" || strings.Join(v.Roles, ",") != "player,hub" || v.TXT["a"] != "1" || v.TXT["b"] != "3" {
+		t.Fatalf("vault = %+v", v)
+	}
+}
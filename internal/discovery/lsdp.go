@@ -6,12 +6,18 @@ import (
 	"errors"
 	"fmt"
 	"net"
+	"net/netip"
+	"slices"
 	"sort"
+	"syscall"
 	"time"
+
+	"github.com/steipete/blucli/internal/logging"
 )
 
 const lsdpPort = 11430
 
+// lsdpClasses are the device classes that are players.
 var lsdpClasses = []uint16{0x0001, 0x0003, 0x0006, 0x0008}
 
 type lsdpPortOverrideKey struct{}
@@ -21,6 +27,36 @@ func WithLSDPPortOverride(ctx context.Context, port int) context.Context {
 }
 
 func discoverLSDP(ctx context.Context) ([]Device, error) {
+	seen := map[string]Device{}
+	err := listenLSDP(ctx, 0, func(d Device) {
+		if existing, exists := seen[d.ID]; exists {
+			seen[d.ID] = mergeDevice(existing, d)
+			return
+		}
+		seen[d.ID] = d
+	}, func(gone Device) {
+		for id, d := range seen {
+			if d.NodeID == gone.NodeID {
+				delete(seen, id)
+			}
+		}
+	})
+	if err != nil {
+		return nil, err
+	}
+	return devicesFromMap(seen), nil
+}
+
+// lsdpConn is the shared LSDP socket: it broadcasts queries to the
+// interfaces in scope and reads every datagram on the LSDP port.
+type lsdpConn struct {
+	conn       *net.UDPConn
+	port       int
+	broadcasts []net.IP
+	scope      netScope
+}
+
+func openLSDP(ctx context.Context) (*lsdpConn, error) {
 	port := lsdpPort
 	if v := ctx.Value(lsdpPortOverrideKey{}); v != nil {
 		if p, ok := v.(int); ok && p > 0 && p <= 65535 {
@@ -31,99 +67,200 @@ func discoverLSDP(ctx context.Context) ([]Device, error) {
 	conn, err := net.ListenUDP("udp4", &net.UDPAddr{IP: net.IPv4zero, Port: port})
 	if err != nil {
 		// Port may be in use (BluOS Controller) or blocked; treat as soft failure.
+		logging.From(ctx).Info("lsdp bind failed", "port", port, "err", err, "reason", lsdpBindReason(err))
 		return nil, err
 	}
-	defer conn.Close()
-
 	_ = conn.SetReadBuffer(1 << 20)
 	_ = conn.SetWriteBuffer(1 << 20)
-	_ = conn.SetDeadline(time.Now().Add(2 * time.Second))
 
-	broadcastIPs := interfaceBroadcastIPs()
+	scope := scopeFor(ctx)
+	return &lsdpConn{conn: conn, port: port, broadcasts: interfaceBroadcastIPs(scope.interfaces()), scope: scope}, nil
+}
 
+// lsdpBindReason explains the usual causes of a failed LSDP bind.
+func lsdpBindReason(err error) string {
+	switch {
+	case errors.Is(err, syscall.EADDRINUSE):
+		return "port in use (another BluOS app, e.g. the Controller, or a second blu)"
+	case errors.Is(err, syscall.EACCES), errors.Is(err, syscall.EPERM):
+		return "not permitted (firewall or sandbox)"
+	default:
+		return "unknown"
+	}
+}
+
+func (c *lsdpConn) Close() error { return c.conn.Close() }
+
+func (c *lsdpConn) send(packet []byte) {
+	for _, dst := range c.broadcasts {
+		_ = c.conn.SetWriteDeadline(time.Now().Add(time.Second))
+		_, _ = c.conn.WriteToUDP(packet, &net.UDPAddr{IP: dst, Port: c.port})
+	}
+}
+
+// queryLoop sends a query-all on the startup schedule and then every
+// requery, when positive, until ctx ends.
+func (c *lsdpConn) queryLoop(ctx context.Context, requery time.Duration) {
 	query := buildLSDPQueryAll()
 	// Startup timing per doc: 7 packets at [0,1,2,3,5,7,10]s + random.
-	delays := []time.Duration{0, time.Second, 2 * time.Second, 3 * time.Second, 5 * time.Second, 7 * time.Second, 10 * time.Second}
-
-	go func() {
-		for _, d := range delays {
-			timer := time.NewTimer(d + time.Duration(rand250ms()))
-			select {
-			case <-ctx.Done():
-				timer.Stop()
-				return
-			case <-timer.C:
-				for _, dst := range broadcastIPs {
-					_, _ = conn.WriteToUDP(query, &net.UDPAddr{IP: dst, Port: port})
-				}
-			}
+	delays := []time.Duration{0, time.Second, time.Second, time.Second, 2 * time.Second, 2 * time.Second, 3 * time.Second}
+	for _, d := range delays {
+		timer := time.NewTimer(d + time.Duration(rand250ms()))
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return
+		case <-timer.C:
+			c.send(query)
+		}
+	}
+	if requery <= 0 {
+		return
+	}
+	ticker := time.NewTicker(requery)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			c.send(query)
 		}
-	}()
+	}
+}
 
-	seen := map[string]Device{}
+// read calls fn with every datagram until ctx ends or the socket fails.
+func (c *lsdpConn) read(ctx context.Context, fn func(packet []byte, from *net.UDPAddr)) {
 	buf := make([]byte, 2048)
-
 	for {
 		select {
 		case <-ctx.Done():
-			return devicesFromMap(seen), nil
+			return
 		default:
 		}
 
-		_ = conn.SetReadDeadline(time.Now().Add(150 * time.Millisecond))
-		n, _, err := conn.ReadFromUDP(buf)
+		_ = c.conn.SetReadDeadline(time.Now().Add(150 * time.Millisecond))
+		n, from, err := c.conn.ReadFromUDP(buf)
 		if err != nil {
 			var ne net.Error
 			if errors.As(err, &ne) && ne.Timeout() {
 				continue
 			}
 			// stop on permanent read error
-			return devicesFromMap(seen), nil
+			return
 		}
+		fn(buf[:n], from)
+	}
+}
+
+// listenLSDP queries on the startup schedule (and then every requery, when
+// positive) and reports every player announcement until ctx ends. Delete
+// messages for player classes are reported to gone with NodeID/MAC set.
+func listenLSDP(ctx context.Context, requery time.Duration, found, gone func(Device)) error {
+	c, err := openLSDP(ctx)
+	if err != nil {
+		return err
+	}
+	defer c.Close()
 
-		packet := buf[:n]
+	go c.queryLoop(ctx, requery)
+	c.read(ctx, func(packet []byte, _ *net.UDPAddr) {
 		msgs, ok := parseLSDP(packet)
 		if !ok {
-			continue
+			return
 		}
-		for _, announce := range msgs {
-			if announce.Address == nil {
-				continue
-			}
-			for _, record := range announce.Records {
-				if !isPlayerClass(record.Class) {
+		for _, msg := range msgs {
+			switch msg.Type {
+			case lsdpAnnounceMsg:
+				if addr, ok := netip.AddrFromSlice(msg.Address); !ok || !c.scope.allows(addr.Unmap()) {
 					continue
 				}
-				port := 11000
-				if p, ok := record.TXT["port"]; ok {
-					if parsed, perr := parsePort(p); perr == nil {
-						port = parsed
-					}
-				}
-				name := lsdpDeviceName(record.TXT)
-				host := announce.Address.String()
-				id := net.JoinHostPort(host, fmt.Sprintf("%d", port))
-				if _, exists := seen[id]; exists {
-					continue
+				for _, d := range announceDevices(msg) {
+					found(d)
 				}
-				seen[id] = Device{
-					ID:      id,
-					Host:    host,
-					Port:    port,
-					Name:    name,
-					Type:    classToType(record.Class),
-					Version: record.TXT["version"],
-					Source:  "lsdp",
+			case lsdpDeleteMsg:
+				if gone != nil && slices.ContainsFunc(msg.Classes, isPlayerClass) {
+					gone(Device{NodeID: nodeIDString(msg.NodeID), MAC: nodeMAC(msg.NodeID), Source: "lsdp"})
 				}
 			}
 		}
+	})
+	return nil
+}
+
+// announceDevices turns an announcement into one device per announced
+// port. Roles lists every class the node announced on that port; nodes
+// without a player class (keypads, manufacturing) are not devices.
+func announceDevices(msg lsdpMessage) []Device {
+	byPort := map[int]*Device{}
+	var ports []int
+	for _, record := range msg.Records {
+		port := 11000
+		if p, ok := record.TXT["port"]; ok {
+			if parsed, perr := parsePort(p); perr == nil {
+				port = parsed
+			}
+		}
+		d, ok := byPort[port]
+		if !ok {
+			host := msg.Address.String()
+			d = &Device{
+				ID:     net.JoinHostPort(host, fmt.Sprintf("%d", port)),
+				Host:   host,
+				Port:   port,
+				Source: "lsdp",
+				MAC:    nodeMAC(msg.NodeID),
+				NodeID: nodeIDString(msg.NodeID),
+			}
+			byPort[port] = d
+			ports = append(ports, port)
+		}
+		if role := classRole(record.Class); role != "" && !slices.Contains(d.Roles, role) {
+			d.Roles = append(d.Roles, role)
+		}
+		if !isPlayerClass(record.Class) {
+			continue
+		}
+		if d.Type == "" {
+			d.Type = classToType(record.Class)
+		}
+		if d.Name == "" {
+			d.Name = lsdpDeviceName(record.TXT)
+		}
+		if d.Version == "" {
+			d.Version = record.TXT["version"]
+		}
+		if d.Model == "" {
+			d.Model = txtValue(record.TXT, "model", "model_no")
+		}
+		d.TXT = mergeTXT(d.TXT, record.TXT)
+	}
+
+	var out []Device
+	for _, port := range ports {
+		if d := byPort[port]; d.Type != "" {
+			out = append(out, *d)
+		}
 	}
+	return out
 }
 
-type lsdpAnnounce struct {
+// LSDP message types.
+const (
+	lsdpAnnounceMsg = 'A'
+	lsdpDeleteMsg   = 'D'
+	lsdpQueryMsg    = 'Q'
+	// lsdpQueryUnicastMsg asks for unicast replies.
+	lsdpQueryUnicastMsg = 'R'
+)
+
+type lsdpMessage struct {
+	Type    byte
 	NodeID  []byte
 	Address net.IP
+	// Records are set for announcements, Classes for delete and query.
 	Records []lsdpRecord
+	Classes []uint16
 }
 
 type lsdpRecord struct {
@@ -131,77 +268,93 @@ type lsdpRecord struct {
 	TXT   map[string]string
 }
 
-func parseLSDP(packet []byte) ([]lsdpAnnounce, bool) {
+// parseLSDP returns the messages of a packet; ok is false when it is not
+// LSDP or contains no decodable message.
+func parseLSDP(packet []byte) ([]lsdpMessage, bool) {
+	msgs, err := parseLSDPMessages(packet)
+	return msgs, err == nil && len(msgs) > 0
+}
+
+// parseLSDPMessages decodes what it can; the error describes the first
+// problem (bad header, truncated or unknown message).
+func parseLSDPMessages(packet []byte) ([]lsdpMessage, error) {
 	if len(packet) < 6 {
-		return nil, false
+		return nil, errors.New("short packet")
 	}
 	hdrLen := int(packet[0])
 	if hdrLen < 6 || hdrLen > len(packet) {
-		return nil, false
+		return nil, fmt.Errorf("bad header length %d", hdrLen)
 	}
 	if string(packet[1:5]) != "LSDP" {
-		return nil, false
+		return nil, errors.New("not an LSDP packet")
 	}
 	// version := packet[5]
 
 	p := hdrLen
-	var announces []lsdpAnnounce
-	for p < len(packet) {
-		if p+1 > len(packet) {
-			break
+	var msgs []lsdpMessage
+	var firstErr error
+	fail := func(err error) {
+		if firstErr == nil {
+			firstErr = err
 		}
+	}
+	for p < len(packet) {
 		msgLen := int(packet[p])
 		if msgLen <= 0 || p+msgLen > len(packet) {
+			fail(fmt.Errorf("bad message length %d at offset %d", msgLen, p))
 			break
 		}
 		msg := packet[p : p+msgLen]
 		p += msgLen
 
 		if len(msg) < 2 {
+			fail(fmt.Errorf("empty message at offset %d", p-msgLen))
 			continue
 		}
+		var (
+			m  lsdpMessage
+			ok bool
+		)
 		switch msg[1] {
-		case 'A':
-			ann, ok := parseAnnounce(msg)
-			if ok {
-				announces = append(announces, ann)
-			}
+		case lsdpAnnounceMsg:
+			m, ok = parseAnnounce(msg)
+		case lsdpDeleteMsg:
+			m, ok = parseDelete(msg)
+		case lsdpQueryMsg, lsdpQueryUnicastMsg:
+			m, ok = parseQuery(msg)
 		default:
+			fail(fmt.Errorf("unknown message type %q", msg[1]))
+			continue
+		}
+		if !ok {
+			fail(fmt.Errorf("truncated %q message", msg[1]))
+			continue
 		}
+		msgs = append(msgs, m)
 	}
-	if len(announces) == 0 {
-		return nil, false
+	if len(msgs) == 0 && firstErr == nil {
+		firstErr = errors.New("no messages")
 	}
-	return announces, true
+	return msgs, firstErr
 }
 
-func parseAnnounce(msg []byte) (lsdpAnnounce, bool) {
+func parseAnnounce(msg []byte) (lsdpMessage, bool) {
 	// msg[0]=len, msg[1]='A'
 	i := 2
-	if i >= len(msg) {
-		return lsdpAnnounce{}, false
-	}
-	nodeIDLen := int(msg[i])
-	i++
-	if i+nodeIDLen > len(msg) {
-		return lsdpAnnounce{}, false
-	}
-	nodeID := append([]byte(nil), msg[i:i+nodeIDLen]...)
-	i += nodeIDLen
-
-	if i >= len(msg) {
-		return lsdpAnnounce{}, false
+	nodeID, ok := readNodeID(msg, &i)
+	if !ok || i >= len(msg) {
+		return lsdpMessage{}, false
 	}
 	addrLen := int(msg[i])
 	i++
-	if addrLen != 4 || i+addrLen > len(msg) {
-		return lsdpAnnounce{}, false
+	if (addrLen != 4 && addrLen != 16) || i+addrLen > len(msg) {
+		return lsdpMessage{}, false
 	}
-	address := net.IPv4(msg[i], msg[i+1], msg[i+2], msg[i+3])
+	address := net.IP(append([]byte(nil), msg[i:i+addrLen]...))
 	i += addrLen
 
 	if i >= len(msg) {
-		return lsdpAnnounce{}, false
+		return lsdpMessage{}, false
 	}
 	count := int(msg[i])
 	i++
@@ -209,36 +362,36 @@ func parseAnnounce(msg []byte) (lsdpAnnounce, bool) {
 	records := make([]lsdpRecord, 0, count)
 	for r := 0; r < count; r++ {
 		if i+2 > len(msg) {
-			return lsdpAnnounce{}, false
+			return lsdpMessage{}, false
 		}
 		class := binary.BigEndian.Uint16(msg[i : i+2])
 		i += 2
 
 		if i >= len(msg) {
-			return lsdpAnnounce{}, false
+			return lsdpMessage{}, false
 		}
 		txtCount := int(msg[i])
 		i++
 		txt := map[string]string{}
 		for t := 0; t < txtCount; t++ {
 			if i >= len(msg) {
-				return lsdpAnnounce{}, false
+				return lsdpMessage{}, false
 			}
 			keyLen := int(msg[i])
 			i++
 			if i+keyLen > len(msg) {
-				return lsdpAnnounce{}, false
+				return lsdpMessage{}, false
 			}
 			key := string(msg[i : i+keyLen])
 			i += keyLen
 
 			if i >= len(msg) {
-				return lsdpAnnounce{}, false
+				return lsdpMessage{}, false
 			}
 			valLen := int(msg[i])
 			i++
 			if i+valLen > len(msg) {
-				return lsdpAnnounce{}, false
+				return lsdpMessage{}, false
 			}
 			val := string(msg[i : i+valLen])
 			i += valLen
@@ -249,7 +402,61 @@ func parseAnnounce(msg []byte) (lsdpAnnounce, bool) {
 		records = append(records, lsdpRecord{Class: class, TXT: txt})
 	}
 
-	return lsdpAnnounce{NodeID: nodeID, Address: address, Records: records}, true
+	return lsdpMessage{Type: lsdpAnnounceMsg, NodeID: nodeID, Address: address, Records: records}, true
+}
+
+// parseDelete decodes len, 'D', nodeIDLen, nodeID, count, class...
+func parseDelete(msg []byte) (lsdpMessage, bool) {
+	i := 2
+	nodeID, ok := readNodeID(msg, &i)
+	if !ok {
+		return lsdpMessage{}, false
+	}
+	classes, ok := readClasses(msg, i)
+	if !ok {
+		return lsdpMessage{}, false
+	}
+	return lsdpMessage{Type: lsdpDeleteMsg, NodeID: nodeID, Classes: classes}, true
+}
+
+// parseQuery decodes len, 'Q'|'R', count, class...
+func parseQuery(msg []byte) (lsdpMessage, bool) {
+	classes, ok := readClasses(msg, 2)
+	if !ok {
+		return lsdpMessage{}, false
+	}
+	return lsdpMessage{Type: msg[1], Classes: classes}, true
+}
+
+func readNodeID(msg []byte, i *int) ([]byte, bool) {
+	if *i >= len(msg) {
+		return nil, false
+	}
+	n := int(msg[*i])
+	*i++
+	if *i+n > len(msg) {
+		return nil, false
+	}
+	id := append([]byte(nil), msg[*i:*i+n]...)
+	*i += n
+	return id, true
+}
+
+func readClasses(msg []byte, i int) ([]uint16, bool) {
+	if i >= len(msg) {
+		return nil, false
+	}
+	count := int(msg[i])
+	i++
+	if i+2*count > len(msg) {
+		return nil, false
+	}
+	classes := make([]uint16, 0, count)
+	for range count {
+		classes = append(classes, binary.BigEndian.Uint16(msg[i:i+2]))
+		i += 2
+	}
+	return classes, true
 }
 
 func buildLSDPQueryAll() []byte {
@@ -259,12 +466,33 @@ func buildLSDPQueryAll() []byte {
 }
 
 func isPlayerClass(class uint16) bool {
-	for _, c := range lsdpClasses {
-		if class == c {
-			return true
-		}
+	return slices.Contains(lsdpClasses, class)
+}
+
+// classRole names an LSDP device class; 0xFFFF means all classes (queries).
+func classRole(class uint16) string {
+	switch class {
+	case 0x0001:
+		return "player"
+	case 0x0002:
+		return "server"
+	case 0x0003:
+		return "secondary"
+	case 0x0004:
+		return "manufacturing"
+	case 0x0005:
+		return "keypad"
+	case 0x0006:
+		return "pair-slave"
+	case 0x0007:
+		return "web-app"
+	case 0x0008:
+		return "hub"
+	case 0xFFFF:
+		return "all"
+	default:
+		return ""
 	}
-	return false
 }
 
 func classToType(class uint16) string {
@@ -291,9 +519,8 @@ func lsdpDeviceName(txt map[string]string) string {
 	return ""
 }
 
-func interfaceBroadcastIPs() []net.IP {
+func interfaceBroadcastIPs(ifaces []net.Interface) []net.IP {
 	var out []net.IP
-	ifaces, _ := net.Interfaces()
 	for _, iface := range ifaces {
 		if (iface.Flags & net.FlagUp) == 0 {
 			continue
@@ -346,3 +573,19 @@ func rand250ms() int {
 	// Small, deterministic-ish jitter.
 	return int(time.Now().UnixNano() % int64(250*time.Millisecond/time.Millisecond))
 }
+
+// nodeIDString formats a node ID of any length as colon-separated hex.
+func nodeIDString(id []byte) string {
+	if len(id) == 0 {
+		return ""
+	}
+	return net.HardwareAddr(id).String()
+}
+
+// nodeMAC formats a 6-byte LSDP node ID, which BluOS sets to the MAC address.
+func nodeMAC(id []byte) string {
+	if len(id) != 6 {
+		return ""
+	}
+	return net.HardwareAddr(id).String()
+}
@@ -74,7 +74,7 @@ func TestDiscoverLSDP_ReceivesAnnounce(t *testing.T) {
 	}
 	found := false
 	for _, d := range devs {
-		if d.ID == "127.0.0.1:11000" && d.Type == "musc" && d.Version == "4.2.1" && d.Source == "lsdp" {
+		if d.ID == "127.0.0.1:11000" && d.Type == "musc" && d.Version == "4.2.1" && d.Source == "lsdp" && d.MAC == "90:56:82:9f:02:78" {
 			found = true
 		}
 	}
@@ -0,0 +1,109 @@
+package discovery
+
+import (
+	"context"
+	"encoding/hex"
+	"fmt"
+	"net"
+	"time"
+)
+
+// LSDPPacket is one raw datagram seen on the LSDP port, decoded as far as
+// possible.
+type LSDPPacket struct {
+	Time     time.Time     `json:"time"`
+	From     string        `json:"from"`
+	Size     int           `json:"size"`
+	Hex      string        `json:"hex"`
+	Messages []LSDPMessage `json:"messages,omitempty"`
+	// Error describes the first decoding problem, if any.
+	Error string `json:"error,omitempty"`
+}
+
+type LSDPMessage struct {
+	// Type is announce, delete, query or query-unicast.
+	Type    string       `json:"type"`
+	NodeID  string       `json:"node_id,omitempty"`
+	Address string       `json:"address,omitempty"`
+	Records []LSDPRecord `json:"records,omitempty"`
+	// Classes are the classes deleted or queried.
+	Classes []LSDPClass `json:"classes,omitempty"`
+}
+
+type LSDPClass struct {
+	// Class is the hex class ID, e.g. 0x0001.
+	Class string `json:"class"`
+	Role  string `json:"role,omitempty"`
+}
+
+type LSDPRecord struct {
+	LSDPClass
+	TXT map[string]string `json:"txt,omitempty"`
+}
+
+type LSDPDumpOptions struct {
+	// Query sends query-all packets on the startup schedule (and then every
+	// Requery, when positive); otherwise the dump only listens.
+	Query   bool
+	Requery time.Duration
+}
+
+// DumpLSDP reports every datagram on the LSDP port (including our own
+// queries, which loop back) by calling emit on the caller's goroutine until
+// ctx ends. It fails when the port cannot be bound.
+func DumpLSDP(ctx context.Context, opts LSDPDumpOptions, emit func(LSDPPacket)) error {
+	c, err := openLSDP(ctx)
+	if err != nil {
+		return err
+	}
+	defer c.Close()
+
+	if opts.Query {
+		go c.queryLoop(ctx, opts.Requery)
+	}
+	c.read(ctx, func(packet []byte, from *net.UDPAddr) {
+		emit(decodeLSDPPacket(time.Now(), from.String(), packet))
+	})
+	return nil
+}
+
+func decodeLSDPPacket(now time.Time, from string, packet []byte) LSDPPacket {
+	out := LSDPPacket{Time: now, From: from, Size: len(packet), Hex: hex.EncodeToString(packet)}
+	msgs, err := parseLSDPMessages(packet)
+	if err != nil {
+		out.Error = err.Error()
+	}
+	for _, msg := range msgs {
+		m := LSDPMessage{Type: messageTypeName(msg.Type), NodeID: nodeIDString(msg.NodeID)}
+		if msg.Address != nil {
+			m.Address = msg.Address.String()
+		}
+		for _, r := range msg.Records {
+			m.Records = append(m.Records, LSDPRecord{LSDPClass: lsdpClass(r.Class), TXT: r.TXT})
+		}
+		for _, c := range msg.Classes {
+			m.Classes = append(m.Classes, lsdpClass(c))
+		}
+		out.Messages = append(out.Messages, m)
+	}
+	return out
+}
+
+func lsdpClass(class uint16) LSDPClass {
+	return LSDPClass{Class: fmt.Sprintf("0x%04x", class), Role: classRole(class)}
+}
+
+func messageTypeName(t byte) string {
+	switch t {
+	case lsdpAnnounceMsg:
+		return "announce"
+	case lsdpDeleteMsg:
+		return "delete"
+	case lsdpQueryMsg:
+		return "query"
+	case lsdpQueryUnicastMsg:
+		return "query-unicast"
+	default:
+		return string(t)
+	}
+}
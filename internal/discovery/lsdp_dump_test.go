@@ -0,0 +1,204 @@
+package discovery
+
+import (
+	"context"
+	"encoding/binary"
+	"net"
+	"strings"
+	"testing"
+	"time"
+)
+
+// testLSDPPacket wraps messages in an LSDP v1 header.
+func testLSDPPacket(msgs ...[]byte) []byte {
+	packet := []byte{6, 'L', 'S', 'D', 'P', 1}
+	for _, m := range msgs {
+		packet = append(packet, m...)
+	}
+	return packet
+}
+
+type testRecord struct {
+	class uint16
+	txt   [][2]string
+}
+
+func testAnnounce(nodeID []byte, addr net.IP, records ...testRecord) []byte {
+	msg := []byte{0, 'A', byte(len(nodeID))}
+	msg = append(msg, nodeID...)
+	msg = append(msg, byte(len(addr)))
+	msg = append(msg, addr...)
+	msg = append(msg, byte(len(records)))
+	for _, r := range records {
+		msg = binary.BigEndian.AppendUint16(msg, r.class)
+		msg = append(msg, byte(len(r.txt)))
+		for _, kv := range r.txt {
+			msg = append(msg, byte(len(kv[0])))
+			msg = append(msg, kv[0]...)
+			msg = append(msg, byte(len(kv[1])))
+			msg = append(msg, kv[1]...)
+		}
+	}
+	msg[0] = byte(len(msg))
+	return msg
+}
+
+func testClassesMsg(typ byte, nodeID []byte, classes ...uint16) []byte {
+	msg := []byte{0, typ}
+	if typ == lsdpDeleteMsg {
+		msg = append(msg, byte(len(nodeID)))
+		msg = append(msg, nodeID...)
+	}
+	msg = append(msg, byte(len(classes)))
+	for _, c := range classes {
+		msg = binary.BigEndian.AppendUint16(msg, c)
+	}
+	msg[0] = byte(len(msg))
+	return msg
+}
+
+var testNodeID = []byte{0x90, 0x56, 0x82, 0x9f, 0x02, 0x78}
+
+func TestParseLSDPDeleteAndQuery(t *testing.T) {
+	t.Parallel()
+
+	msgs, ok := parseLSDP(testLSDPPacket(
+		testClassesMsg(lsdpDeleteMsg, testNodeID, 0x0001, 0x0002),
+		testClassesMsg(lsdpQueryUnicastMsg, nil, 0xFFFF),
+		testAnnounce(testNodeID, net.ParseIP("fd00::10"), testRecord{class: 0x0001}),
+	))
+	if !ok || len(msgs) != 3 {
+		t.Fatalf("msgs=%+v ok=%v", msgs, ok)
+	}
+	if msgs[0].Type != lsdpDeleteMsg || nodeIDString(msgs[0].NodeID) != "90:56:82:9f:02:78" || len(msgs[0].Classes) != 2 || msgs[0].Classes[1] != 0x0002 {
+		t.Fatalf("delete = %+v", msgs[0])
+	}
+	if msgs[1].Type != lsdpQueryUnicastMsg || len(msgs[1].Classes) != 1 || msgs[1].Classes[0] != 0xFFFF {
+		t.Fatalf("query = %+v", msgs[1])
+	}
+	if msgs[2].Address.String() != "fd00::10" {
+		t.Fatalf("announce address = %v", msgs[2].Address)
+	}
+
+	if _, err := parseLSDPMessages(testLSDPPacket([]byte{3, 'D', 9})); err == nil || !strings.Contains(err.Error(), "truncated") {
+		t.Fatalf("err = %v", err)
+	}
+	if _, err := parseLSDPMessages(testLSDPPacket([]byte{2, 'X'})); err == nil || !strings.Contains(err.Error(), "unknown message type") {
+		t.Fatalf("err = %v", err)
+	}
+}
+
+func TestAnnounceDevicesRoles(t *testing.T) {
+	t.Parallel()
+
+	addr := net.IPv4(192, 168, 1, 10).To4()
+	devices := announceDevices(lsdpMessage{Type: lsdpAnnounceMsg, NodeID: testNodeID, Address: addr, Records: []lsdpRecord{
+		{Class: 0x0001, TXT: map[string]string{"port": "11000", "name": "Vault", "version": "4.4.0"}},
+		{Class: 0x0002, TXT: map[string]string{"port": "11000"}},
+		{Class: 0x0003, TXT: map[string]string{"port": "11010", "name": "Zone B"}},
+	}})
+	if len(devices) != 2 {
+		t.Fatalf("devices = %+v", devices)
+	}
+	d := devices[0]
+	if d.ID != "192.168.1.10:11000" || d.Type != "musc" || d.Name != "Vault" || d.Version != "4.4.0" || d.NodeID != "90:56:82:9f:02:78" || strings.Join(d.Roles, ",") != "player,server" {
+		t.Fatalf("device = %+v", d)
+	}
+	if z := devices[1]; z.ID != "192.168.1.10:11010" || z.Type != "musp" || strings.Join(z.Roles, ",") != "secondary" {
+		t.Fatalf("zone = %+v", z)
+	}
+
+	keypad := announceDevices(lsdpMessage{Type: lsdpAnnounceMsg, NodeID: testNodeID, Address: addr, Records: []lsdpRecord{{Class: 0x0005}}})
+	if len(keypad) != 0 {
+		t.Fatalf("keypad is not a device: %+v", keypad)
+	}
+}
+
+func TestWatcherForgetsDeletedNodes(t *testing.T) {
+	t.Parallel()
+
+	var events []Event
+	w := &watcher{ttl: time.Hour, tracked: map[string]watched{}, emit: func(ev Event) { events = append(events, ev) }}
+	now := time.Now()
+	w.observe(Device{ID: "10.0.0.5:11000", Name: "Kitchen", NodeID: "90:56:82:9f:02:78"}, now)
+	w.observe(Device{ID: "10.0.0.6:11000", Name: "Den", NodeID: "90:56:82:00:00:01"}, now)
+	w.forget(Device{NodeID: "90:56:82:9f:02:78"}, now)
+
+	if len(events) != 3 || events[2].Kind != EventLeave || events[2].Device.Name != "Kitchen" {
+		t.Fatalf("events = %+v", events)
+	}
+	if _, ok := w.tracked["10.0.0.6:11000"]; !ok || len(w.tracked) != 1 {
+		t.Fatalf("tracked = %+v", w.tracked)
+	}
+}
+
+func TestDecodeLSDPPacket(t *testing.T) {
+	t.Parallel()
+
+	p := decodeLSDPPacket(time.Now(), "192.168.1.10:11430", testLSDPPacket(
+		testAnnounce(testNodeID, net.IPv4(192, 168, 1, 10).To4(), testRecord{class: 0x0008, txt: [][2]string{{"name", "Hub"}}}),
+	))
+	if p.Error != "" || len(p.Messages) != 1 || !strings.HasPrefix(p.Hex, "064c534450") {
+		t.Fatalf("packet = %+v", p)
+	}
+	m := p.Messages[0]
+	if m.Type != "announce" || m.Address != "192.168.1.10" || m.Records[0].Class != "0x0008" || m.Records[0].Role != "hub" || m.Records[0].TXT["name"] != "Hub" {
+		t.Fatalf("message = %+v", m)
+	}
+
+	bad := decodeLSDPPacket(time.Now(), "x", []byte("hello"))
+	if bad.Error == "" || bad.Size != 5 {
+		t.Fatalf("bad = %+v", bad)
+	}
+}
+
+func TestDumpLSDPAndDeletes(t *testing.T) {
+	// No t.Parallel: uses UDP port binding.
+
+	tmp, err := net.ListenUDP("udp4", &net.UDPAddr{IP: net.IPv4zero, Port: 0})
+	if err != nil {
+		t.Fatalf("listen udp: %v", err)
+	}
+	port := tmp.LocalAddr().(*net.UDPAddr).Port
+	_ = tmp.Close()
+
+	send := func(packets ...[]byte) {
+		time.Sleep(25 * time.Millisecond)
+		conn, err := net.DialUDP("udp4", nil, &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1), Port: port})
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		for _, p := range packets {
+			_, _ = conn.Write(p)
+			time.Sleep(10 * time.Millisecond)
+		}
+	}
+	announce := testLSDPPacket(testAnnounce(testNodeID, net.IPv4(127, 0, 0, 1).To4(), testRecord{class: 0x0001, txt: [][2]string{{"name", "Kitchen"}}}))
+	del := testLSDPPacket(testClassesMsg(lsdpDeleteMsg, testNodeID, 0x0001))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 350*time.Millisecond)
+	defer cancel()
+	ctx = WithLSDPPortOverride(ctx, port)
+	go send(announce, []byte("junk"))
+
+	var packets []LSDPPacket
+	if err := DumpLSDP(ctx, LSDPDumpOptions{}, func(p LSDPPacket) { packets = append(packets, p) }); err != nil {
+		t.Fatalf("DumpLSDP: %v", err)
+	}
+	if len(packets) != 2 || packets[0].Messages[0].Type != "announce" || packets[1].Error == "" {
+		t.Fatalf("packets = %+v", packets)
+	}
+
+	ctx, cancel = context.WithTimeout(context.Background(), 350*time.Millisecond)
+	defer cancel()
+	ctx = WithLSDPPortOverride(ctx, port)
+	go send(announce, del)
+	devices, err := discoverLSDP(ctx)
+	if err != nil {
+		t.Fatalf("discoverLSDP: %v", err)
+	}
+	if len(devices) != 0 {
+		t.Fatalf("deleted node still listed: %+v", devices)
+	}
+}
@@ -0,0 +1,252 @@
+package discovery
+
+import (
+	"context"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/netip"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ScanOptions configure the unicast subnet scan, a fallback for networks
+// that filter multicast/broadcast (VLANs, mesh Wi-Fi).
+type ScanOptions struct {
+	// Prefixes to probe; empty derives them from the interface addresses.
+	Prefixes []netip.Prefix
+	Port     int
+	Workers  int
+	// Rate caps probes per second.
+	Rate    int
+	Timeout time.Duration
+}
+
+const (
+	defaultScanWorkers = 64
+	defaultScanRate    = 256
+	defaultScanTimeout = 800 * time.Millisecond
+	// maxScanHosts keeps a typo like /8 from probing millions of hosts.
+	maxScanHosts = 4096
+)
+
+type scanKey struct{}
+
+// WithScan makes Discover (and Watch) also probe the given subnets.
+func WithScan(ctx context.Context, opts ScanOptions) context.Context {
+	return context.WithValue(ctx, scanKey{}, opts)
+}
+
+func scanOptions(ctx context.Context) (ScanOptions, bool) {
+	opts, ok := ctx.Value(scanKey{}).(ScanOptions)
+	return opts, ok
+}
+
+// ParseScanTargets parses "auto" (or "") and comma-separated CIDRs or
+// single addresses.
+func ParseScanTargets(s string) ([]netip.Prefix, error) {
+	s = strings.TrimSpace(s)
+	if s == "" || s == "auto" {
+		return nil, nil
+	}
+	var out []netip.Prefix
+	for _, part := range strings.Split(s, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		if !strings.Contains(part, "/") {
+			addr, err := netip.ParseAddr(part)
+			if err != nil {
+				return nil, fmt.Errorf("scan target %q: %w", part, err)
+			}
+			out = append(out, netip.PrefixFrom(addr, addr.BitLen()))
+			continue
+		}
+		prefix, err := netip.ParsePrefix(part)
+		if err != nil {
+			return nil, fmt.Errorf("scan target %q: %w", part, err)
+		}
+		out = append(out, prefix.Masked())
+	}
+	return out, nil
+}
+
+// scanHosts lists the addresses to probe, without network/broadcast
+// addresses.
+func scanHosts(prefixes []netip.Prefix) ([]netip.Addr, error) {
+	var hosts []netip.Addr
+	seen := map[netip.Addr]bool{}
+	for _, p := range prefixes {
+		if p.Addr().Is6() && p.Bits() < 116 {
+			return nil, fmt.Errorf("scan %s: IPv6 prefixes must be /116 or narrower", p)
+		}
+		first, bits := p.Masked().Addr(), p.Addr().BitLen()
+		var last netip.Addr
+		for a := first; a.IsValid() && p.Contains(a); a = a.Next() {
+			last = a
+			if !seen[a] {
+				seen[a] = true
+				hosts = append(hosts, a)
+			}
+			if len(hosts) > maxScanHosts {
+				return nil, fmt.Errorf("scan: more than %d hosts; use a narrower prefix", maxScanHosts)
+			}
+		}
+		if first.Is4() && bits-p.Bits() >= 2 {
+			hosts = removeAddr(hosts, first)
+			hosts = removeAddr(hosts, last)
+		}
+	}
+	return hosts, nil
+}
+
+func removeAddr(hosts []netip.Addr, a netip.Addr) []netip.Addr {
+	for i, h := range hosts {
+		if h == a {
+			return append(hosts[:i], hosts[i+1:]...)
+		}
+	}
+	return hosts
+}
+
+// localPrefixes derives scan prefixes from the IPv4 addresses of ifaces;
+// networks wider than /24 are narrowed to the host's own /24.
+func localPrefixes(ifaces []net.Interface) []netip.Prefix {
+	var out []netip.Prefix
+	for _, iface := range ifaces {
+		for _, prefix := range interfacePrefixes(iface) {
+			if !prefix.Addr().Is4() || prefix.Addr().IsLinkLocalUnicast() {
+				continue
+			}
+			if prefix.Bits() < 24 {
+				prefix = netip.PrefixFrom(prefix.Addr(), 24)
+			}
+			out = append(out, prefix.Masked())
+		}
+	}
+	return out
+}
+
+func scan(ctx context.Context, opts ScanOptions) ([]Device, error) {
+	prefixes := opts.Prefixes
+	if len(prefixes) == 0 {
+		prefixes = localPrefixes(scopeFor(ctx).interfaces())
+	}
+	if len(prefixes) == 0 {
+		return nil, errors.New("scan: no IPv4 interface to derive a subnet from")
+	}
+	hosts, err := scanHosts(prefixes)
+	if err != nil {
+		return nil, err
+	}
+
+	port := opts.Port
+	if port <= 0 {
+		port = 11000
+	}
+	workers := opts.Workers
+	if workers <= 0 {
+		workers = defaultScanWorkers
+	}
+	rate := opts.Rate
+	if rate <= 0 {
+		rate = defaultScanRate
+	}
+	timeout := opts.Timeout
+	if timeout <= 0 {
+		timeout = defaultScanTimeout
+	}
+	client := &http.Client{Timeout: timeout}
+
+	jobs := make(chan netip.Addr)
+	var (
+		mu    sync.Mutex
+		found []Device
+		wg    sync.WaitGroup
+	)
+	for range min(workers, len(hosts)) {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for addr := range jobs {
+				if d, ok := probeSyncStatus(ctx, client, addr, port); ok {
+					mu.Lock()
+					found = append(found, d)
+					mu.Unlock()
+				}
+			}
+		}()
+	}
+
+	tick := time.NewTicker(time.Second / time.Duration(rate))
+	defer tick.Stop()
+feed:
+	for _, addr := range hosts {
+		select {
+		case <-ctx.Done():
+			break feed
+		case <-tick.C:
+		}
+		select {
+		case <-ctx.Done():
+			break feed
+		case jobs <- addr:
+		}
+	}
+	close(jobs)
+	wg.Wait()
+
+	sort.Slice(found, func(i, j int) bool { return found[i].ID < found[j].ID })
+	return found, nil
+}
+
+// scanSyncStatus holds the SyncStatus attributes that identify a player.
+type scanSyncStatus struct {
+	XMLName xml.Name `xml:"SyncStatus"`
+	Name    string   `xml:"name,attr"`
+	Model   string   `xml:"model,attr"`
+	MAC     string   `xml:"mac,attr"`
+
+	Brand     string `xml:"brand,attr"`
+	ModelName string `xml:"modelName,attr"`
+}
+
+func probeSyncStatus(ctx context.Context, client *http.Client, addr netip.Addr, port int) (Device, bool) {
+	host := addr.String()
+	hostPort := net.JoinHostPort(host, strconv.Itoa(port))
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, "http://"+hostPort+"/SyncStatus", nil)
+	if err != nil {
+		return Device{}, false
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return Device{}, false
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return Device{}, false
+	}
+	var sync scanSyncStatus
+	if err := xml.NewDecoder(io.LimitReader(resp.Body, 64<<10)).Decode(&sync); err != nil {
+		return Device{}, false
+	}
+	return Device{
+		ID:     hostPort,
+		Host:   host,
+		Port:   port,
+		Name:   strings.TrimSpace(sync.Name),
+		Source: "scan",
+		MAC:    NormalizeMAC(sync.MAC),
+
+		Model:     sync.Model,
+		ModelName: sync.ModelName,
+		Brand:     sync.Brand,
+	}, true
+}
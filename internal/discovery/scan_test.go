@@ -0,0 +1,157 @@
+package discovery
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"net/netip"
+	"net/url"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParseScanTargets(t *testing.T) {
+	t.Parallel()
+
+	for _, s := range []string{"", "auto", " auto "} {
+		got, err := ParseScanTargets(s)
+		if err != nil || got != nil {
+			t.Fatalf("ParseScanTargets(%q) = %v, %v; want auto", s, got, err)
+		}
+	}
+
+	got, err := ParseScanTargets("192.168.1.7/24, 10.0.5.9")
+	if err != nil {
+		t.Fatalf("ParseScanTargets: %v", err)
+	}
+	want := []netip.Prefix{netip.MustParsePrefix("192.168.1.0/24"), netip.MustParsePrefix("10.0.5.9/32")}
+	if len(got) != len(want) || got[0] != want[0] || got[1] != want[1] {
+		t.Fatalf("got %v, want %v", got, want)
+	}
+
+	if _, err := ParseScanTargets("192.168.1.0/33"); err == nil {
+		t.Fatalf("expected error for bad prefix")
+	}
+	if _, err := ParseScanTargets("kitchen"); err == nil {
+		t.Fatalf("expected error for bad address")
+	}
+}
+
+func TestScanHosts(t *testing.T) {
+	t.Parallel()
+
+	hosts, err := scanHosts([]netip.Prefix{netip.MustParsePrefix("10.0.0.0/30"), netip.MustParsePrefix("10.0.0.1/32")})
+	if err != nil {
+		t.Fatalf("scanHosts: %v", err)
+	}
+	if len(hosts) != 2 || hosts[0].String() != "10.0.0.1" || hosts[1].String() != "10.0.0.2" {
+		t.Fatalf("hosts = %v", hosts)
+	}
+
+	hosts, err = scanHosts([]netip.Prefix{netip.MustParsePrefix("192.168.1.0/24")})
+	if err != nil || len(hosts) != 254 {
+		t.Fatalf("scanHosts /24 = %d hosts, %v", len(hosts), err)
+	}
+
+	if _, err := scanHosts([]netip.Prefix{netip.MustParsePrefix("10.0.0.0/16")}); err == nil || !strings.Contains(err.Error(), "narrower") {
+		t.Fatalf("expected too-many-hosts error, got %v", err)
+	}
+	if _, err := scanHosts([]netip.Prefix{netip.MustParsePrefix("fd00::/64")}); err == nil {
+		t.Fatalf("expected IPv6 width error")
+	}
+}
+
+func TestScanFindsBluOSPlayers(t *testing.T) {
+	t.Parallel()
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/SyncStatus" {
+			http.NotFound(w, r)
+			return
+		}
+		_, _ = w.Write([]byte(`<SyncStatus name="Kitchen" model="N130" mac="90:56:82:AA:BB:CC"/>`))
+	}))
+	t.Cleanup(srv.Close)
+	port := serverPort(t, srv)
+
+	devices, err := scan(context.Background(), ScanOptions{
+		Prefixes: []netip.Prefix{netip.MustParsePrefix("127.0.0.1/32")},
+		Port:     port,
+		Timeout:  time.Second,
+	})
+	if err != nil {
+		t.Fatalf("scan: %v", err)
+	}
+	if len(devices) != 1 {
+		t.Fatalf("devices = %+v", devices)
+	}
+	d := devices[0]
+	if d.Host != "127.0.0.1" || d.Port != port || d.Name != "Kitchen" || d.Source != "scan" || d.MAC != "90:56:82:aa:bb:cc" {
+		t.Fatalf("device = %+v", d)
+	}
+}
+
+func TestScanIgnoresOtherHTTPServers(t *testing.T) {
+	t.Parallel()
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte("<html>router login</html>"))
+	}))
+	t.Cleanup(srv.Close)
+
+	devices, err := scan(context.Background(), ScanOptions{
+		Prefixes: []netip.Prefix{netip.MustParsePrefix("127.0.0.1/32")},
+		Port:     serverPort(t, srv),
+		Timeout:  time.Second,
+	})
+	if err != nil {
+		t.Fatalf("scan: %v", err)
+	}
+	if len(devices) != 0 {
+		t.Fatalf("devices = %+v", devices)
+	}
+}
+
+func TestDiscoverMergesScanResults(t *testing.T) {
+	t.Parallel()
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(`<SyncStatus name="Kitchen" mac="90:56:82:aa:bb:cc"/>`))
+	}))
+	t.Cleanup(srv.Close)
+	port := serverPort(t, srv)
+	id := "127.0.0.1:" + strconv.Itoa(port)
+
+	ctx := WithMDNSOverride(context.Background(), func(context.Context) ([]Device, error) {
+		return []Device{{ID: id, Host: "127.0.0.1", Port: port, Name: "Kitchen", Type: "N130", Source: "mdns"}}, nil
+	})
+	ctx = WithLSDPOverride(ctx, func(context.Context) ([]Device, error) { return nil, nil })
+	ctx = WithScan(ctx, ScanOptions{Prefixes: []netip.Prefix{netip.MustParsePrefix("127.0.0.1/32")}, Port: port, Timeout: time.Second})
+
+	devices, err := Discover(ctx)
+	if err != nil {
+		t.Fatalf("Discover: %v", err)
+	}
+	if len(devices) != 1 {
+		t.Fatalf("devices = %+v", devices)
+	}
+	d := devices[0]
+	if d.Type != "N130" || d.MAC != "90:56:82:aa:bb:cc" || !strings.Contains(d.Source, "mdns") || !strings.Contains(d.Source, "scan") {
+		t.Fatalf("device = %+v", d)
+	}
+}
+
+func serverPort(t *testing.T, srv *httptest.Server) int {
+	t.Helper()
+	u, err := url.Parse(srv.URL)
+	if err != nil {
+		t.Fatalf("parse url: %v", err)
+	}
+	port, err := strconv.Atoi(u.Port())
+	if err != nil {
+		t.Fatalf("port: %v", err)
+	}
+	return port
+}
@@ -0,0 +1,324 @@
+package discovery
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+	"unicode"
+)
+
+// Event kinds reported by Watch.
+const (
+	EventJoin   = "join"
+	EventLeave  = "leave"
+	EventChange = "change"
+)
+
+type Event struct {
+	Kind   string    `json:"event"`
+	Time   time.Time `json:"time"`
+	Device Device    `json:"device"`
+	// Changes describes a change event, e.g. "version 4.2.1 -> 4.4.0".
+	Changes []string `json:"changes,omitempty"`
+}
+
+type WatchOptions struct {
+	// Interval between mDNS re-browses and LSDP re-queries.
+	Interval time.Duration
+	// TTL after which a player that was not heard from is reported as left.
+	TTL time.Duration
+}
+
+const (
+	DefaultWatchInterval = 10 * time.Second
+	DefaultWatchTTL      = 45 * time.Second
+)
+
+// source reports devices until ctx ends; gone reports a player that
+// announced its departure (LSDP delete).
+type source func(ctx context.Context, found, gone func(Device)) error
+
+type sighting struct {
+	device Device
+	gone   bool
+}
+
+// Watch keeps mDNS browsing and LSDP listening open (and rescans subnets
+// each interval when configured with WithScan) and calls emit (on the
+// caller's goroutine) for players joining, leaving (not heard from for TTL,
+// or an LSDP delete) and changing (new address, name or firmware
+// version). Sightings of one player's other services or address family are
+// folded into one row, as in Discover. It returns nil when ctx ends, or an
+// error when every mechanism failed.
+func Watch(ctx context.Context, opts WatchOptions, emit func(Event)) error {
+	if opts.Interval <= 0 {
+		opts.Interval = DefaultWatchInterval
+	}
+	if opts.TTL <= 0 {
+		opts.TTL = DefaultWatchTTL
+	}
+
+	mdns := source(func(ctx context.Context, found, _ func(Device)) error {
+		// zeroconf reports an instance once per browse; browse again each
+		// interval so live players keep refreshing their TTL.
+		for {
+			round, cancel := context.WithTimeout(ctx, opts.Interval)
+			got := false
+			err := browseMDNS(round, func(d Device) { got = true; found(d) })
+			cancel()
+			if ctx.Err() != nil {
+				return nil
+			}
+			if err != nil && !got {
+				return err
+			}
+		}
+	})
+	if fn := overrideFunc(ctx, mdnsOverrideKey{}); fn != nil {
+		mdns = pollSource(fn, opts.Interval)
+	}
+	lsdp := source(func(ctx context.Context, found, gone func(Device)) error {
+		return listenLSDP(ctx, opts.Interval, found, gone)
+	})
+	if fn := overrideFunc(ctx, lsdpOverrideKey{}); fn != nil {
+		lsdp = pollSource(fn, opts.Interval)
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	sources := []source{mdns, lsdp}
+	if scanOpts, ok := scanOptions(ctx); ok {
+		sources = append(sources, pollSource(func(ctx context.Context) ([]Device, error) { return scan(ctx, scanOpts) }, opts.Interval))
+	}
+	seenCh := make(chan sighting, 64)
+	report := func(s sighting) {
+		select {
+		case seenCh <- s:
+		case <-ctx.Done():
+		}
+	}
+	errCh := make(chan error, len(sources))
+	for _, src := range sources {
+		go func() {
+			errCh <- src(ctx,
+				func(d Device) { report(sighting{device: d}) },
+				func(d Device) { report(sighting{device: d, gone: true}) })
+		}()
+	}
+
+	w := &watcher{ttl: opts.TTL, tracked: map[string]watched{}, emit: emit}
+	sweep := time.NewTicker(max(opts.TTL/4, 50*time.Millisecond))
+	defer sweep.Stop()
+
+	var errs []error
+	for stopped := 0; ; {
+		select {
+		case <-ctx.Done():
+			return nil
+		case err := <-errCh:
+			stopped++
+			if err != nil {
+				errs = append(errs, err)
+			}
+			if stopped == len(sources) {
+				return errors.Join(errs...)
+			}
+		case s := <-seenCh:
+			if s.gone {
+				w.forget(s.device, time.Now())
+			} else {
+				w.observe(s.device, time.Now())
+			}
+		case now := <-sweep.C:
+			w.sweep(now)
+		}
+	}
+}
+
+func overrideFunc(ctx context.Context, key any) DiscoverFunc {
+	if fn, ok := ctx.Value(key).(DiscoverFunc); ok && fn != nil {
+		return fn
+	}
+	return nil
+}
+
+// pollSource turns a one-shot DiscoverFunc into a source that runs it once
+// per interval.
+func pollSource(fn DiscoverFunc, interval time.Duration) source {
+	return func(ctx context.Context, found, _ func(Device)) error {
+		for {
+			round, cancel := context.WithTimeout(ctx, interval)
+			devices, err := fn(round)
+			devices = slices.Clone(devices)
+			sort.Slice(devices, func(i, j int) bool { return devices[i].ID < devices[j].ID })
+			for _, d := range mergePlayers(devices) {
+				found(d)
+			}
+			if err != nil && !errors.Is(err, context.DeadlineExceeded) && ctx.Err() == nil {
+				cancel()
+				return err
+			}
+			<-round.Done()
+			cancel()
+			if ctx.Err() != nil {
+				return nil
+			}
+		}
+	}
+}
+
+type watched struct {
+	device Device
+	seen   time.Time
+}
+
+type watcher struct {
+	ttl     time.Duration
+	tracked map[string]watched
+	emit    func(Event)
+}
+
+func (w *watcher) observe(d Device, now time.Time) {
+	if cur, ok := w.tracked[d.ID]; ok {
+		next, changes := mergeSeen(cur.device, d)
+		w.tracked[d.ID] = watched{device: next, seen: now}
+		if len(changes) > 0 {
+			w.emit(Event{Kind: EventChange, Time: now, Device: next, Changes: changes})
+		}
+		return
+	}
+
+	// Another service (musc/musp/...) or address family of a tracked player:
+	// keep the preferred endpoint, report only real changes.
+	for id, cur := range w.tracked {
+		if !sibling(cur.device, d) {
+			continue
+		}
+		next, changes := mergeSeen(cur.device, d)
+		owner := cur.device
+		if preferEndpoint(d, owner) {
+			owner = d
+		}
+		next.ID, next.Host, next.Port, next.Type = owner.ID, owner.Host, owner.Port, owner.Type
+		delete(w.tracked, id)
+		w.tracked[next.ID] = watched{device: next, seen: now}
+		if len(changes) > 0 {
+			w.emit(Event{Kind: EventChange, Time: now, Device: next, Changes: changes})
+		}
+		return
+	}
+
+	for id, cur := range w.tracked {
+		if !sameDevice(cur.device, d) {
+			continue
+		}
+		delete(w.tracked, id)
+		next, changes := mergeSeen(cur.device, d)
+		changes = append([]string{fmt.Sprintf("address %s -> %s", id, d.ID)}, changes...)
+		w.tracked[d.ID] = watched{device: next, seen: now}
+		w.emit(Event{Kind: EventChange, Time: now, Device: next, Changes: changes})
+		return
+	}
+
+	w.tracked[d.ID] = watched{device: d, seen: now}
+	w.emit(Event{Kind: EventJoin, Time: now, Device: d})
+}
+
+// forget reports tracked players matching d's node ID or MAC as left.
+func (w *watcher) forget(d Device, now time.Time) {
+	var gone []string
+	for id, cur := range w.tracked {
+		if (d.NodeID != "" && cur.device.NodeID == d.NodeID) || (d.MAC != "" && cur.device.MAC != "" && NormalizeMAC(cur.device.MAC) == NormalizeMAC(d.MAC)) {
+			gone = append(gone, id)
+		}
+	}
+	sort.Strings(gone)
+	for _, id := range gone {
+		cur := w.tracked[id].device
+		delete(w.tracked, id)
+		w.emit(Event{Kind: EventLeave, Time: now, Device: cur})
+	}
+}
+
+func (w *watcher) sweep(now time.Time) {
+	var gone []string
+	for id, cur := range w.tracked {
+		if now.Sub(cur.seen) > w.ttl {
+			gone = append(gone, id)
+		}
+	}
+	sort.Strings(gone)
+	for _, id := range gone {
+		d := w.tracked[id].device
+		delete(w.tracked, id)
+		w.emit(Event{Kind: EventLeave, Time: now, Device: d})
+	}
+}
+
+// mergeSeen updates old with what a new sighting reports. Empty fields
+// (mDNS has no MAC, LSDP may lack a name) never count as changes, and
+// neither does the service type: a player announces several (musc, musp,
+// ...) and keeps the primary one.
+func mergeSeen(old, d Device) (Device, []string) {
+	next := old
+	next.ID, next.Host, next.Port = d.ID, d.Host, d.Port
+	var changes []string
+	update := func(field string, cur *string, v string) {
+		if v == "" || v == *cur {
+			return
+		}
+		if *cur != "" {
+			changes = append(changes, fmt.Sprintf("%s %s -> %s", field, *cur, v))
+		}
+		*cur = v
+	}
+	update("name", &next.Name, d.Name)
+	if d.Type != "" && (next.Type == "" || typeRank(d.Type) < typeRank(next.Type)) {
+		next.Type = d.Type
+	}
+	update("version", &next.Version, d.Version)
+	update("mac", &next.MAC, d.MAC)
+	if next.NodeID == "" {
+		next.NodeID = d.NodeID
+	}
+	next.Roles = mergeRoles(next.Roles, d.Roles)
+	if d.Source != "" && !strings.Contains(next.Source, d.Source) {
+		if next.Source == "" {
+			next.Source = d.Source
+		} else {
+			next.Source += "+" + d.Source
+		}
+	}
+	return next, changes
+}
+
+// sibling reports whether d is another service or address family of the
+// player cur, rather than the player having moved to a new address.
+func sibling(cur, d Device) bool {
+	return samePlayer(cur, d) && (cur.Host == d.Host || isIPv6Host(cur.Host) != isIPv6Host(d.Host))
+}
+
+// sameDevice matches sightings of one player at different addresses: by MAC
+// when both are known, else by name.
+func sameDevice(a, b Device) bool {
+	if a.MAC != "" && b.MAC != "" {
+		return NormalizeMAC(a.MAC) == NormalizeMAC(b.MAC)
+	}
+	n := normalizeName(a.Name)
+	return n != "" && n == normalizeName(b.Name)
+}
+
+func normalizeName(s string) string {
+	var b strings.Builder
+	for _, r := range strings.ToLower(strings.TrimSpace(s)) {
+		if unicode.IsLetter(r) || unicode.IsNumber(r) {
+			b.WriteRune(r)
+		}
+	}
+	return b.String()
+}
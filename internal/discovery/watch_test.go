@@ -0,0 +1,141 @@
+package discovery
+
+import (
+	"context"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestWatchReportsJoinChangeAndLeave(t *testing.T) {
+	t.Parallel()
+
+	kitchen := Device{ID: "10.0.0.5:11000", Host: "10.0.0.5", Port: 11000, Name: "Kitchen", Version: "4.2.1"}
+	den := Device{ID: "10.0.0.6:11000", Host: "10.0.0.6", Port: 11000, Name: "Den"}
+	upgraded := kitchen
+	upgraded.Version = "4.4.0"
+	moved := upgraded
+	moved.ID, moved.Host = "10.0.0.9:11000", "10.0.0.9"
+	rounds := [][]Device{{kitchen, den}, {upgraded, den}, {moved}}
+
+	var calls atomic.Int32
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	ctx = WithMDNSOverride(ctx, func(context.Context) ([]Device, error) {
+		i := int(calls.Add(1)) - 1
+		return rounds[min(i, len(rounds)-1)], nil
+	})
+	ctx = WithLSDPOverride(ctx, func(context.Context) ([]Device, error) { return nil, nil })
+
+	var events []string
+	err := Watch(ctx, WatchOptions{Interval: 20 * time.Millisecond, TTL: 150 * time.Millisecond}, func(ev Event) {
+		events = append(events, ev.Kind+" "+ev.Device.Name+" "+strings.Join(ev.Changes, ","))
+		if ev.Kind == EventLeave {
+			cancel()
+		}
+	})
+	if err != nil {
+		t.Fatalf("Watch: %v", err)
+	}
+
+	want := []string{
+		"join Kitchen ",
+		"join Den ",
+		"change Kitchen version 4.2.1 -> 4.4.0",
+		"change Kitchen address 10.0.0.5:11000 -> 10.0.0.9:11000",
+		"leave Den ",
+	}
+	if strings.Join(events, "\n") != strings.Join(want, "\n") {
+		t.Fatalf("events =\n%s\nwant\n%s", strings.Join(events, "\n"), strings.Join(want, "\n"))
+	}
+}
+
+func TestWatchMergesMultiServicePlayers(t *testing.T) {
+	t.Parallel()
+
+	// One player announcing musc and musp on the same port, another on
+	// separate ports: each round must fold into one row per player.
+	kitchen := Device{ID: "10.0.0.5:11000", Host: "10.0.0.5", Port: 11000, Type: "musc", Name: "Kitchen"}
+	kitchenMusp := kitchen
+	kitchenMusp.Type = "musp"
+	den := Device{ID: "10.0.0.6:11000", Host: "10.0.0.6", Port: 11000, Type: "musc", Name: "Den"}
+	denMusp := Device{ID: "10.0.0.6:11010", Host: "10.0.0.6", Port: 11010, Type: "musp", Name: "Den"}
+
+	var calls atomic.Int32
+	ctx, cancel := context.WithTimeout(context.Background(), 300*time.Millisecond)
+	defer cancel()
+	ctx = WithMDNSOverride(ctx, func(context.Context) ([]Device, error) {
+		if calls.Add(1)%2 == 0 {
+			return []Device{kitchenMusp, denMusp, kitchen, den}, nil
+		}
+		return []Device{kitchen, den, kitchenMusp, denMusp}, nil
+	})
+	ctx = WithLSDPOverride(ctx, func(context.Context) ([]Device, error) { return nil, nil })
+
+	var events []string
+	err := Watch(ctx, WatchOptions{Interval: 20 * time.Millisecond, TTL: time.Second}, func(ev Event) {
+		events = append(events, ev.Kind+" "+ev.Device.ID+" "+ev.Device.Type+" "+strings.Join(ev.Changes, ","))
+	})
+	if err != nil {
+		t.Fatalf("Watch: %v", err)
+	}
+	if calls.Load() < 3 {
+		t.Fatalf("rounds = %d; want several", calls.Load())
+	}
+	want := []string{"join 10.0.0.5:11000 musc ", "join 10.0.0.6:11000 musc "}
+	if strings.Join(events, "\n") != strings.Join(want, "\n") {
+		t.Fatalf("events =\n%s\nwant\n%s", strings.Join(events, "\n"), strings.Join(want, "\n"))
+	}
+}
+
+func TestWatcherFoldsStreamedServiceSightings(t *testing.T) {
+	t.Parallel()
+
+	// Live mDNS reports services one at a time, the secondary one first.
+	var events []string
+	w := &watcher{ttl: time.Minute, tracked: map[string]watched{}, emit: func(ev Event) {
+		events = append(events, ev.Kind+" "+ev.Device.ID+" "+strings.Join(ev.Changes, ","))
+	}}
+	musp := Device{ID: "10.0.0.6:11010", Host: "10.0.0.6", Port: 11010, Type: "musp", Name: "Den"}
+	musc := Device{ID: "10.0.0.6:11000", Host: "10.0.0.6", Port: 11000, Type: "musc", Name: "Den"}
+	v6 := Device{ID: "[fd00::6]:11000", Host: "fd00::6", Port: 11000, Type: "musc", Name: "Den"}
+	now := time.Now()
+	for i := range 4 {
+		w.observe(musp, now.Add(time.Duration(i)*time.Second))
+		w.observe(musc, now.Add(time.Duration(i)*time.Second))
+		w.observe(v6, now.Add(time.Duration(i)*time.Second))
+	}
+
+	if want := "join 10.0.0.6:11010 "; strings.Join(events, "\n") != want {
+		t.Fatalf("events = %q; want %q", events, want)
+	}
+	if len(w.tracked) != 1 || w.tracked["10.0.0.6:11000"].device.Type != "musc" {
+		t.Fatalf("tracked = %+v; want one musc row", w.tracked)
+	}
+}
+
+func TestWatchFailsWhenAllSourcesFail(t *testing.T) {
+	t.Parallel()
+
+	ctx := WithMDNSOverride(context.Background(), func(context.Context) ([]Device, error) { return nil, errTest("mdns down") })
+	ctx = WithLSDPOverride(ctx, func(context.Context) ([]Device, error) { return nil, errTest("lsdp down") })
+	err := Watch(ctx, WatchOptions{Interval: 10 * time.Millisecond}, func(Event) {})
+	if err == nil || !strings.Contains(err.Error(), "mdns down") || !strings.Contains(err.Error(), "lsdp down") {
+		t.Fatalf("err = %v", err)
+	}
+}
+
+func TestMergeSeenIgnoresMissingFields(t *testing.T) {
+	t.Parallel()
+
+	old := Device{ID: "a", Name: "Kitchen", Version: "1", Source: "mdns"}
+	next, changes := mergeSeen(old, Device{ID: "a", MAC: "90:56:82:9f:02:78", Source: "lsdp"})
+	if len(changes) != 0 || next.Name != "Kitchen" || next.MAC == "" || next.Source != "mdns+lsdp" {
+		t.Fatalf("next=%+v changes=%v", next, changes)
+	}
+}
+
+type errTest string
+
+func (e errTest) Error() string { return string(e) }
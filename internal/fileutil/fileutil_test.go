@@ -0,0 +1,64 @@
+package fileutil
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+)
+
+func TestWriteAtomicReplacesAndCleansUp(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	path := filepath.Join(dir, "sub", "state.json")
+	if err := WriteAtomic(path, []byte("one"), 0o600); err != nil {
+		t.Fatalf("WriteAtomic: %v", err)
+	}
+	if err := WriteAtomic(path, []byte("two"), 0o600); err != nil {
+		t.Fatalf("WriteAtomic: %v", err)
+	}
+	if data, _ := os.ReadFile(path); string(data) != "two" {
+		t.Fatalf("data = %q", data)
+	}
+	if runtime.GOOS != "windows" {
+		if fi, err := os.Stat(path); err != nil || fi.Mode().Perm() != 0o600 {
+			t.Fatalf("mode = %v, %v", fi.Mode(), err)
+		}
+	}
+	entries, _ := os.ReadDir(filepath.Dir(path))
+	for _, e := range entries {
+		if strings.Contains(e.Name(), ".tmp-") {
+			t.Fatalf("leftover temp file %s", e.Name())
+		}
+	}
+}
+
+func TestWithLockSerializesReadModifyWrite(t *testing.T) {
+	t.Parallel()
+
+	path := filepath.Join(t.TempDir(), "counter")
+	const n = 25
+	var wg sync.WaitGroup
+	for range n {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			err := WithLock(path, func() error {
+				data, _ := os.ReadFile(path)
+				v, _ := strconv.Atoi(string(data))
+				return WriteAtomic(path, []byte(strconv.Itoa(v+1)), 0o644)
+			})
+			if err != nil {
+				t.Errorf("WithLock: %v", err)
+			}
+		}()
+	}
+	wg.Wait()
+	if data, _ := os.ReadFile(path); string(data) != strconv.Itoa(n) {
+		t.Fatalf("counter = %q; want %d", data, n)
+	}
+}
@@ -0,0 +1,10 @@
+//go:build !unix && !windows
+
+package fileutil
+
+import "os"
+
+// Platforms without flock/LockFileEx (wasm, plan9) get no locking; writes
+// stay atomic.
+func lockFile(*os.File) error   { return nil }
+func unlockFile(*os.File) error { return nil }
@@ -0,0 +1,201 @@
+package history
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/steipete/blucli/internal/bluos"
+)
+
+func TestTrackerRecordsCompletedTrack(t *testing.T) {
+	t.Parallel()
+
+	t0 := time.Date(2026, 1, 2, 10, 0, 0, 0, time.UTC)
+	tr := NewTracker("10.0.0.2:11000", "")
+
+	a := bluos.Status{State: "play", Name: "Kitchen", Title: "T1", Artist: "A", Album: "B", Service: "Spotify", Secs: 0, TotLen: 200}
+	if _, ok := tr.Observe(a, t0); ok {
+		t.Fatalf("unexpected entry on first observation")
+	}
+	a.Secs = 30
+	if _, ok := tr.Observe(a, t0.Add(30*time.Second)); ok {
+		t.Fatalf("unexpected entry on same track")
+	}
+
+	b := bluos.Status{State: "play", Name: "Kitchen", Title: "T2", Artist: "A", Service: "Spotify", Secs: 1, TotLen: 180}
+	e, ok := tr.Observe(b, t0.Add(45*time.Second))
+	if !ok {
+		t.Fatalf("expected completed entry on track change")
+	}
+	if e.Title != "T1" || e.Artist != "A" || e.Album != "B" || e.Service != "Spotify" {
+		t.Fatalf("entry = %+v", e)
+	}
+	if e.Device != "10.0.0.2:11000" || e.DeviceName != "Kitchen" {
+		t.Fatalf("device = %q/%q", e.Device, e.DeviceName)
+	}
+	if e.ListenedSeconds != 45 {
+		t.Fatalf("ListenedSeconds = %d; want 45", e.ListenedSeconds)
+	}
+	if !e.StartedAt.Equal(t0) || e.LengthSeconds != 200 {
+		t.Fatalf("StartedAt=%v LengthSeconds=%d", e.StartedAt, e.LengthSeconds)
+	}
+}
+
+func TestTrackerPauseAndSeek(t *testing.T) {
+	t.Parallel()
+
+	t0 := time.Date(2026, 1, 2, 10, 0, 0, 0, time.UTC)
+	tr := NewTracker("d", "Room")
+
+	s := bluos.Status{State: "play", Title: "T", Artist: "A", Secs: 10, TotLen: 300}
+	tr.Observe(s, t0) // joined mid-track: 10s already played
+
+	s.State, s.Secs = "pause", 20
+	tr.Observe(s, t0.Add(10*time.Second))
+
+	// Paused for an hour; nothing accrues.
+	s.State = "play"
+	tr.Observe(s, t0.Add(time.Hour))
+
+	// Seek forward by 100s within 5s of wall time: count wall time only.
+	s.Secs = 125
+	tr.Observe(s, t0.Add(time.Hour+5*time.Second))
+
+	e, ok := tr.Flush(t0.Add(time.Hour + 5*time.Second))
+	if !ok {
+		t.Fatalf("expected entry on flush")
+	}
+	if e.ListenedSeconds != 25 {
+		t.Fatalf("ListenedSeconds = %d; want 25", e.ListenedSeconds)
+	}
+}
+
+func TestTrackerRepeatAndSkips(t *testing.T) {
+	t.Parallel()
+
+	t0 := time.Date(2026, 1, 2, 10, 0, 0, 0, time.UTC)
+	tr := NewTracker("d", "Room")
+
+	s := bluos.Status{State: "play", Title: "T", Artist: "A", Secs: 0, TotLen: 60}
+	tr.Observe(s, t0)
+	s.Secs = 58
+	tr.Observe(s, t0.Add(58*time.Second))
+	s.Secs = 1
+	e, ok := tr.Observe(s, t0.Add(61*time.Second))
+	if !ok || e.ListenedSeconds != 60 {
+		t.Fatalf("repeat: ok=%v entry=%+v", ok, e)
+	}
+
+	// A quick skip is dropped.
+	skip := bluos.Status{State: "play", Title: "Skip", Artist: "A", Secs: 0}
+	tr.Observe(skip, t0.Add(62*time.Second))
+	stop := bluos.Status{State: "stop"}
+	if _, ok := tr.Observe(stop, t0.Add(65*time.Second)); ok {
+		t.Fatalf("expected skip to be dropped")
+	}
+	if _, ok := tr.Flush(t0.Add(time.Hour)); ok {
+		t.Fatalf("expected nothing to flush when stopped")
+	}
+}
+
+func TestAppendLoadQuery(t *testing.T) {
+	t.Parallel()
+
+	path := filepath.Join(t.TempDir(), "sub", "history.jsonl")
+	t0 := time.Date(2026, 1, 2, 10, 0, 0, 0, time.UTC)
+	if err := Append(path,
+		Entry{Device: "a:1", DeviceName: "Kitchen", Title: "Late", StartedAt: t0.Add(time.Hour)},
+		Entry{Device: "b:1", DeviceName: "Living Room", Title: "Early", StartedAt: t0},
+	); err != nil {
+		t.Fatalf("Append: %v", err)
+	}
+	if err := Append(path, Entry{Device: "a:1", DeviceName: "Kitchen", Title: "Old", StartedAt: t0.Add(-48 * time.Hour)}); err != nil {
+		t.Fatalf("Append: %v", err)
+	}
+
+	entries, err := Load(path)
+	if err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+	if len(entries) != 3 || entries[0].Title != "Old" || entries[2].Title != "Late" {
+		t.Fatalf("entries = %+v", entries)
+	}
+
+	got := Query(entries, Filter{Since: t0.Add(-time.Hour)})
+	if len(got) != 2 {
+		t.Fatalf("since: got %d entries", len(got))
+	}
+	got = Query(entries, Filter{Device: "living"})
+	if len(got) != 1 || got[0].Title != "Early" {
+		t.Fatalf("device name: %+v", got)
+	}
+	got = Query(entries, Filter{Device: "a:1"})
+	if len(got) != 2 {
+		t.Fatalf("device id: %+v", got)
+	}
+
+	if missing, err := Load(filepath.Join(t.TempDir(), "none.jsonl")); err != nil || missing != nil {
+		t.Fatalf("missing file: %v %v", missing, err)
+	}
+
+	if err := os.WriteFile(path, []byte("{}\nnot json\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := Load(path); err == nil || !strings.Contains(err.Error(), ":2:") {
+		t.Fatalf("Load err = %v; want line number", err)
+	}
+}
+
+func TestParseSince(t *testing.T) {
+	t.Parallel()
+
+	now := time.Date(2026, 1, 10, 12, 0, 0, 0, time.UTC)
+	cases := map[string]time.Time{
+		"":           {},
+		"7d":         now.Add(-7 * 24 * time.Hour),
+		"2w":         now.Add(-14 * 24 * time.Hour),
+		"36h":        now.Add(-36 * time.Hour),
+		"2026-01-01": time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC),
+	}
+	for in, want := range cases {
+		got, err := ParseSince(in, now)
+		if err != nil {
+			t.Fatalf("ParseSince(%q): %v", in, err)
+		}
+		if !got.Equal(want) {
+			t.Fatalf("ParseSince(%q) = %v; want %v", in, got, want)
+		}
+	}
+	for _, bad := range []string{"xd", "-1h", "soon"} {
+		if _, err := ParseSince(bad, now); err == nil {
+			t.Fatalf("ParseSince(%q): want error", bad)
+		}
+	}
+}
+
+func TestSummarize(t *testing.T) {
+	t.Parallel()
+
+	entries := []Entry{
+		{Device: "a", DeviceName: "Kitchen", Artist: "X", Service: "Spotify", ListenedSeconds: 3600},
+		{Device: "a", DeviceName: "Kitchen", Artist: "x", Service: "Spotify", ListenedSeconds: 60},
+		{Device: "b", DeviceName: "Office", Artist: "Y", Service: "TuneIn", ListenedSeconds: 7200},
+		{Device: "c", Artist: "", ListenedSeconds: 10},
+	}
+	s := Summarize(entries, 1)
+	if s.Plays != 4 || s.ListenedSeconds != 10870 {
+		t.Fatalf("totals = %+v", s)
+	}
+	if len(s.TopArtists) != 1 || s.TopArtists[0].Name != "X" || s.TopArtists[0].Plays != 2 {
+		t.Fatalf("TopArtists = %+v", s.TopArtists)
+	}
+	if len(s.Rooms) != 3 || s.Rooms[0].Name != "Office" || s.Rooms[2].Name != "c" {
+		t.Fatalf("Rooms = %+v", s.Rooms)
+	}
+	if len(s.Services) != 3 || s.Services[0].Name != "TuneIn" || s.Services[2].Name != "(unknown)" {
+		t.Fatalf("Services = %+v", s.Services)
+	}
+}
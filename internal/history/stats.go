@@ -0,0 +1,80 @@
+package history
+
+import (
+	"sort"
+	"strings"
+)
+
+type Stats struct {
+	Plays           int `json:"plays"`
+	ListenedSeconds int `json:"listened_secs"`
+
+	TopArtists []Count `json:"top_artists"`
+	Rooms      []Count `json:"rooms"`
+	Services   []Count `json:"services"`
+}
+
+type Count struct {
+	Name            string `json:"name"`
+	Plays           int    `json:"plays"`
+	ListenedSeconds int    `json:"listened_secs"`
+}
+
+func Summarize(entries []Entry, top int) Stats {
+	artists := map[string]*Count{}
+	rooms := map[string]*Count{}
+	services := map[string]*Count{}
+
+	stats := Stats{}
+	for _, e := range entries {
+		stats.Plays++
+		stats.ListenedSeconds += e.ListenedSeconds
+
+		if artist := strings.TrimSpace(e.Artist); artist != "" {
+			tally(artists, artist, e)
+		}
+		room := strings.TrimSpace(e.DeviceName)
+		if room == "" {
+			room = e.Device
+		}
+		tally(rooms, room, e)
+		service := strings.TrimSpace(e.Service)
+		if service == "" {
+			service = "(unknown)"
+		}
+		tally(services, service, e)
+	}
+
+	stats.TopArtists = ranked(artists, top, func(c Count) int { return c.Plays })
+	stats.Rooms = ranked(rooms, 0, func(c Count) int { return c.ListenedSeconds })
+	stats.Services = ranked(services, 0, func(c Count) int { return c.ListenedSeconds })
+	return stats
+}
+
+func tally(m map[string]*Count, name string, e Entry) {
+	key := strings.ToLower(name)
+	c, ok := m[key]
+	if !ok {
+		c = &Count{Name: name}
+		m[key] = c
+	}
+	c.Plays++
+	c.ListenedSeconds += e.ListenedSeconds
+}
+
+func ranked(m map[string]*Count, limit int, by func(Count) int) []Count {
+	out := make([]Count, 0, len(m))
+	for _, c := range m {
+		out = append(out, *c)
+	}
+	sort.Slice(out, func(i, j int) bool {
+		if a, b := by(out[i]), by(out[j]); a != b {
+			return a > b
+		}
+		return out[i].Name < out[j].Name
+	})
+	if limit > 0 && len(out) > limit {
+		out = out[:limit]
+	}
+	return out
+}
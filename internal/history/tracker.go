@@ -0,0 +1,182 @@
+package history
+
+import (
+	"strings"
+	"time"
+
+	"github.com/steipete/blucli/internal/bluos"
+)
+
+// Quick skips are not worth recording.
+const MinListened = 5 * time.Second
+
+const (
+	seekSlack     = 2 * time.Second
+	restartWindow = 5 // secs
+)
+
+// BluOS keeps no history; track changes are inferred from metadata + secs.
+type Tracker struct {
+	device     string
+	deviceName string
+
+	current *play
+}
+
+type play struct {
+	key      string
+	entry    Entry
+	listened time.Duration
+	lastSecs int
+	lastSeen time.Time
+	playing  bool
+}
+
+func NewTracker(device, deviceName string) *Tracker {
+	return &Tracker{device: device, deviceName: deviceName}
+}
+
+func (t *Tracker) Observe(s bluos.Status, at time.Time) (Entry, bool) {
+	if name := strings.TrimSpace(s.Name); name != "" {
+		t.deviceName = name
+	}
+
+	key := trackKey(s)
+	playing := isPlaying(s.State)
+
+	if cur := t.current; cur != nil {
+		elapsed := at.Sub(cur.lastSeen)
+		if elapsed < 0 {
+			elapsed = 0
+		}
+		sameTrack := key == cur.key && !restarted(cur, s)
+		if cur.playing {
+			if sameTrack {
+				cur.listened += progress(cur.lastSecs, s.Secs, elapsed)
+			} else {
+				cur.listened += tail(cur, elapsed)
+			}
+		}
+		if sameTrack {
+			cur.lastSecs = s.Secs
+			cur.lastSeen = at
+			cur.playing = playing
+			if s.TotLen > 0 {
+				cur.entry.LengthSeconds = s.TotLen
+			}
+			return Entry{}, false
+		}
+	}
+
+	done, ok := t.finish()
+	if key != "" {
+		t.current = &play{
+			key: key,
+			entry: Entry{
+				Title:         strings.TrimSpace(s.Title),
+				Artist:        strings.TrimSpace(s.Artist),
+				Album:         strings.TrimSpace(s.Album),
+				Service:       strings.TrimSpace(s.Service),
+				StartedAt:     at.Add(-time.Duration(s.Secs) * time.Second),
+				LengthSeconds: s.TotLen,
+			},
+			lastSecs: s.Secs,
+			lastSeen: at,
+			playing:  playing,
+		}
+		if s.Secs > 0 && playing {
+			// Joined mid-track; count what was already played.
+			t.current.listened = time.Duration(s.Secs) * time.Second
+		}
+	}
+	return done, ok
+}
+
+func (t *Tracker) Current() (Entry, bool) {
+	cur := t.current
+	if cur == nil {
+		return Entry{}, false
+	}
+	e := cur.entry
+	e.Device = t.device
+	e.DeviceName = t.deviceName
+	e.ListenedSeconds = int(cur.listened / time.Second)
+	return e, true
+}
+
+func (t *Tracker) Flush(at time.Time) (Entry, bool) {
+	if cur := t.current; cur != nil && cur.playing {
+		elapsed := at.Sub(cur.lastSeen)
+		if elapsed > 0 {
+			cur.listened += tail(cur, elapsed)
+		}
+	}
+	return t.finish()
+}
+
+func (t *Tracker) finish() (Entry, bool) {
+	cur := t.current
+	t.current = nil
+	if cur == nil || cur.listened < MinListened {
+		return Entry{}, false
+	}
+	e := cur.entry
+	e.Device = t.device
+	e.DeviceName = t.deviceName
+	e.ListenedSeconds = int(cur.listened / time.Second)
+	return e, true
+}
+
+func progress(lastSecs, secs int, elapsed time.Duration) time.Duration {
+	delta := time.Duration(secs-lastSecs) * time.Second
+	if delta >= 0 && delta <= elapsed+seekSlack {
+		return delta
+	}
+	// Seek (either direction): fall back to wall time.
+	return elapsed
+}
+
+func tail(cur *play, elapsed time.Duration) time.Duration {
+	if cur.entry.LengthSeconds > 0 {
+		remaining := time.Duration(cur.entry.LengthSeconds-cur.lastSecs) * time.Second
+		if remaining < 0 {
+			remaining = 0
+		}
+		if elapsed > remaining {
+			return remaining
+		}
+	}
+	return elapsed
+}
+
+// Same metadata but secs jumped back to ~0 after a mostly played pass: repeat.
+func restarted(cur *play, s bluos.Status) bool {
+	if s.Secs >= cur.lastSecs || s.Secs > restartWindow {
+		return false
+	}
+	length := cur.entry.LengthSeconds
+	return length > 0 && cur.lastSecs >= length/2
+}
+
+func trackKey(s bluos.Status) string {
+	title := strings.TrimSpace(s.Title)
+	artist := strings.TrimSpace(s.Artist)
+	if title == "" && artist == "" {
+		return ""
+	}
+	return strings.ToLower(strings.Join([]string{
+		title,
+		artist,
+		strings.TrimSpace(s.Album),
+		strings.TrimSpace(s.Service),
+	}, "\x1f"))
+}
+
+func isPlaying(state string) bool {
+	switch strings.ToLower(strings.TrimSpace(state)) {
+	case "play", "stream":
+		return true
+	default:
+		return false
+	}
+}
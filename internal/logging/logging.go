@@ -0,0 +1,66 @@
+// Package logging carries a log/slog logger through a context, so library
+// code can log without a process-wide default (Run may execute
+// concurrently, e.g. in tests).
+package logging
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"strings"
+)
+
+// DefaultLevel keeps normal runs quiet: discovery, resolution and retry
+// details are logged at info/debug.
+const DefaultLevel = "warn"
+
+type loggerKey struct{}
+
+var discard = slog.New(slog.DiscardHandler)
+
+// With returns a context whose logger is l.
+func With(ctx context.Context, l *slog.Logger) context.Context {
+	return context.WithValue(ctx, loggerKey{}, l)
+}
+
+// From returns the context's logger, or one that discards everything.
+func From(ctx context.Context) *slog.Logger {
+	if l, ok := ctx.Value(loggerKey{}).(*slog.Logger); ok && l != nil {
+		return l
+	}
+	return discard
+}
+
+// ParseLevel accepts debug|info|warn|error (warning is an alias for warn).
+func ParseLevel(s string) (slog.Level, error) {
+	switch strings.ToLower(strings.TrimSpace(s)) {
+	case "debug":
+		return slog.LevelDebug, nil
+	case "info":
+		return slog.LevelInfo, nil
+	case "", "warn", "warning":
+		return slog.LevelWarn, nil
+	case "error":
+		return slog.LevelError, nil
+	default:
+		return 0, fmt.Errorf("unknown level %q (want debug|info|warn|error)", s)
+	}
+}
+
+// New builds a logger writing to w in format text (logfmt) or json.
+func New(w io.Writer, level, format string) (*slog.Logger, error) {
+	lvl, err := ParseLevel(level)
+	if err != nil {
+		return nil, err
+	}
+	opts := &slog.HandlerOptions{Level: lvl}
+	switch strings.ToLower(strings.TrimSpace(format)) {
+	case "", "text":
+		return slog.New(slog.NewTextHandler(w, opts)), nil
+	case "json":
+		return slog.New(slog.NewJSONHandler(w, opts)), nil
+	default:
+		return nil, fmt.Errorf("unknown format %q (want text|json)", format)
+	}
+}
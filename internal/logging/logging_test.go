@@ -0,0 +1,53 @@
+package logging
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+func TestNewFiltersByLevelAndFormats(t *testing.T) {
+	t.Parallel()
+
+	var buf strings.Builder
+	l, err := New(&buf, "info", "json")
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+	ctx := With(t.Context(), l)
+	From(ctx).Debug("hidden")
+	From(ctx).Info("device resolved", "step", "cache")
+
+	var rec map[string]any
+	if err := json.Unmarshal([]byte(buf.String()), &rec); err != nil {
+		t.Fatalf("json %q: %v", buf.String(), err)
+	}
+	if rec["msg"] != "device resolved" || rec["step"] != "cache" || rec["level"] != "INFO" {
+		t.Fatalf("record = %v", rec)
+	}
+
+	buf.Reset()
+	l, _ = New(&buf, "", "text")
+	l.Info("quiet by default")
+	l.Warn("loud", "k", "v")
+	if got := buf.String(); strings.Contains(got, "quiet") || !strings.Contains(got, "level=WARN msg=loud k=v") {
+		t.Fatalf("text = %q", got)
+	}
+}
+
+func TestFromWithoutLoggerDiscards(t *testing.T) {
+	t.Parallel()
+
+	From(t.Context()).Error("nobody listens")
+}
+
+func TestParseErrors(t *testing.T) {
+	t.Parallel()
+
+	if _, err := ParseLevel("trace"); err == nil {
+		t.Fatalf("ParseLevel(trace) err = nil")
+	}
+	if _, err := New(&strings.Builder{}, "info", "xml"); err == nil {
+		t.Fatalf("New(format=xml) err = nil")
+	}
+}
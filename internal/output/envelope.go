@@ -0,0 +1,79 @@
+package output
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"strings"
+
+	"github.com/steipete/blucli/internal/config"
+)
+
+type Envelope struct {
+	Schema string         `json:"schema"`
+	Device *config.Device `json:"device,omitempty"`
+	Data   any            `json:"data,omitempty"`
+	Error  *ErrorInfo     `json:"error,omitempty"`
+}
+
+type ErrorInfo struct {
+	Code    string `json:"code"`
+	Message string `json:"message"`
+}
+
+// SetDevice records the player a command talks to for the envelope.
+func (p *Printer) SetDevice(d config.Device) {
+	p.device = &d
+}
+
+// Finish emits the error envelope for a failed command. It is a no-op
+// unless --envelope is set and exitCode is non-zero.
+func (p *Printer) Finish(exitCode int) {
+	if !p.envelope || exitCode == 0 {
+		return
+	}
+	info := ErrorInfo{Code: "error", Message: fmt.Sprintf("exit status %d", exitCode)}
+	if p.failure != nil {
+		info = *p.failure
+	}
+	if exitCode == 2 {
+		info.Code = "usage"
+	}
+	p.printEncoded(Envelope{Schema: ErrorSchema, Device: p.device, Error: &info})
+}
+
+func (p *Printer) wrap(v any) Envelope {
+	return Envelope{Schema: SchemaName(v, p.schemas...), Device: p.device, Data: v}
+}
+
+func (p *Printer) recordFailure(format string, args []any) {
+	if !p.envelope || p.failure != nil {
+		return
+	}
+	p.failure = &ErrorInfo{Code: errorCode(format, args), Message: fmt.Sprintf(format, args...)}
+}
+
+func errorCode(format string, args []any) string {
+	for _, arg := range args {
+		err, ok := arg.(error)
+		if !ok {
+			continue
+		}
+		var netErr net.Error
+		var opErr *net.OpError
+		var dnsErr *net.DNSError
+		switch {
+		case errors.Is(err, context.DeadlineExceeded), errors.As(err, &netErr) && netErr.Timeout():
+			return "timeout"
+		case errors.Is(err, context.Canceled):
+			return "canceled"
+		case errors.As(err, &opErr), errors.As(err, &dnsErr):
+			return "unreachable"
+		}
+	}
+	if strings.HasPrefix(format, "device:") {
+		return "device"
+	}
+	return "error"
+}
@@ -0,0 +1,134 @@
+package output
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/steipete/blucli/internal/bluos"
+	"github.com/steipete/blucli/internal/config"
+)
+
+func TestPrinter_Print_Envelope(t *testing.T) {
+	t.Parallel()
+
+	var out bytes.Buffer
+	p := New(Options{Envelope: true, Stdout: &out, Stderr: &out})
+	p.SetDevice(config.Device{ID: "10.0.0.2:11000", Host: "10.0.0.2", Port: 11000})
+	p.Print(bluos.Status{State: "play", Volume: 10})
+
+	var env struct {
+		Schema string          `json:"schema"`
+		Device config.Device   `json:"device"`
+		Data   json.RawMessage `json:"data"`
+	}
+	if err := json.Unmarshal(out.Bytes(), &env); err != nil {
+		t.Fatalf("unmarshal: %v (%q)", err, out.String())
+	}
+	if env.Schema != "blu.status/v1" || env.Device.Host != "10.0.0.2" || !strings.Contains(string(env.Data), `"state": "play"`) {
+		t.Fatalf("envelope = %+v data=%s", env, env.Data)
+	}
+	out.Reset()
+
+	p.Print(struct{ X int }{1})
+	if !strings.Contains(out.String(), `"schema": "blu.value/v1"`) {
+		t.Fatalf("stdout = %q", out.String())
+	}
+}
+
+func TestPrinter_Finish_ErrorEnvelope(t *testing.T) {
+	t.Parallel()
+
+	var out, errOut bytes.Buffer
+	p := New(Options{Envelope: true, Stdout: &out, Stderr: &errOut})
+	p.Errorf("status: %v", &net.OpError{Op: "dial", Net: "tcp", Err: errors.New("connection refused")})
+	p.Errorf("second: %v", errors.New("ignored"))
+	p.Finish(1)
+
+	var env Envelope
+	if err := json.Unmarshal(out.Bytes(), &env); err != nil {
+		t.Fatalf("unmarshal: %v (%q)", err, out.String())
+	}
+	if env.Schema != ErrorSchema || env.Error == nil || env.Error.Code != "unreachable" || !strings.HasPrefix(env.Error.Message, "status: dial tcp") {
+		t.Fatalf("envelope = %+v", env)
+	}
+	if !strings.Contains(errOut.String(), "status: dial tcp") {
+		t.Fatalf("stderr = %q; want plain text too", errOut.String())
+	}
+
+	// Without --envelope, Finish prints nothing.
+	out.Reset()
+	New(Options{Stdout: &out, Stderr: &errOut}).Finish(1)
+	if out.Len() != 0 {
+		t.Fatalf("stdout = %q", out.String())
+	}
+}
+
+func TestErrorCode(t *testing.T) {
+	t.Parallel()
+
+	cases := []struct {
+		format string
+		args   []any
+		want   string
+	}{
+		{"device: %v", []any{errors.New("no device")}, "device"},
+		{"status: %v", []any{fmt.Errorf("get: %w", errTimeout{})}, "timeout"},
+		{"status: %v", []any{&net.DNSError{Err: "no such host", Name: "x"}}, "unreachable"},
+		{"queue: %v", []any{errors.New("boom")}, "error"},
+	}
+	for _, tc := range cases {
+		if got := errorCode(tc.format, tc.args); got != tc.want {
+			t.Fatalf("errorCode(%q, %v) = %q; want %q", tc.format, tc.args, got, tc.want)
+		}
+	}
+}
+
+type errTimeout struct{}
+
+func (errTimeout) Error() string   { return "timeout" }
+func (errTimeout) Timeout() bool   { return true }
+func (errTimeout) Temporary() bool { return true }
+
+func TestTypeSchema(t *testing.T) {
+	t.Parallel()
+
+	type inner struct {
+		N int `json:"n"`
+	}
+	type sample struct {
+		Name    string            `json:"name"`
+		Opt     string            `json:"opt,omitempty"`
+		Ptr     *inner            `json:"ptr"`
+		List    []inner           `json:"list,omitempty"`
+		Tags    map[string]string `json:"tags,omitempty"`
+		At      time.Time         `json:"at"`
+		Skipped string            `json:"-"`
+		hidden  int
+	}
+	_ = sample{}.hidden
+
+	s := typeSchema(reflect.TypeOf(sample{}), false)
+	props := s["properties"].(map[string]any)
+	if _, ok := props["Skipped"]; ok || len(props) != 6 {
+		t.Fatalf("properties = %v", props)
+	}
+	if !reflect.DeepEqual(s["required"], []string{"name", "ptr", "at"}) {
+		t.Fatalf("required = %v", s["required"])
+	}
+	if got := props["at"].(map[string]any)["format"]; got != "date-time" {
+		t.Fatalf("at = %v", props["at"])
+	}
+	if got := props["list"].(map[string]any)["type"]; got != "array" {
+		t.Fatalf("list = %v", props["list"])
+	}
+	if got := props["ptr"].(map[string]any)["type"]; !reflect.DeepEqual(got, []any{"object", "null"}) {
+		t.Fatalf("ptr = %v", props["ptr"])
+	}
+}
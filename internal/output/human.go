@@ -0,0 +1,138 @@
+package output
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/steipete/blucli/internal/bluos"
+	"github.com/steipete/blucli/internal/spotify"
+)
+
+// Human lets command-local types render their own human output.
+type Human interface {
+	PrintHuman(p *Printer)
+}
+
+const (
+	Bold   = "1"
+	Dim    = "2"
+	Red    = "31"
+	Green  = "32"
+	Yellow = "33"
+	Cyan   = "36"
+)
+
+// Paint wraps s in an SGR color when color output is enabled.
+func (p *Printer) Paint(code, s string) string {
+	if !p.color || code == "" || s == "" {
+		return s
+	}
+	return "\x1b[" + code + "m" + s + "\x1b[0m"
+}
+
+func (p *Printer) Color() bool { return p.color }
+
+// Width is the terminal width, or 0 when unknown.
+func (p *Printer) Width() int { return p.width }
+
+// Human reports plain human output: no structured mode, template or envelope.
+func (p *Printer) Human() bool {
+	return p.mode == ModeHuman && p.template == nil && !p.envelope
+}
+
+// Interactive reports human output to a terminal, where live views may
+// redraw the screen.
+func (p *Printer) Interactive() bool {
+	return p.tty && p.Human()
+}
+
+func (p *Printer) Printf(format string, args ...any) {
+	fmt.Fprintf(p.stdout, format, args...)
+}
+
+// Table prints an aligned, width-aware table. rowColor may return a Paint
+// code for a whole row (e.g. Red for failures).
+func (p *Printer) Table(header []string, rows [][]string, rowColor func(row int) string) {
+	var paint func(int, string) string
+	if p.color {
+		paint = func(row int, cell string) string {
+			if row < 0 {
+				return p.Paint(Bold, cell)
+			}
+			if rowColor == nil {
+				return cell
+			}
+			return p.Paint(rowColor(row), cell)
+		}
+	}
+	writeTable(p.stdout, header, rows, p.width, paint)
+}
+
+func (p *Printer) printLoaded(l bluos.LoadedResponse) {
+	service := strings.TrimSpace(l.Service)
+	if service == "" {
+		service = "preset"
+	}
+	if l.Entries > 0 {
+		fmt.Fprintf(p.stdout, "loaded %s (%d entries)\n", service, l.Entries)
+		return
+	}
+	fmt.Fprintf(p.stdout, "loaded %s\n", service)
+}
+
+func (p *Printer) printRawXML(raw string) {
+	raw = strings.TrimRight(raw, "\n")
+	if raw == "" {
+		fmt.Fprintln(p.stdout, "(empty response)")
+		return
+	}
+	fmt.Fprintln(p.stdout, raw)
+}
+
+func (p *Printer) printSpotifyDevices(dr spotify.DevicesResponse) {
+	if len(dr.Devices) == 0 {
+		fmt.Fprintln(p.stdout, "no spotify devices (open Spotify on a phone/desktop or play a Spotify source on the player)")
+		return
+	}
+	rows := make([][]string, 0, len(dr.Devices))
+	for _, d := range dr.Devices {
+		active := ""
+		if d.IsActive {
+			active = "*"
+		}
+		rows = append(rows, []string{active, strings.TrimSpace(d.Name), d.Type, strconv.Itoa(d.VolumePercent), d.ID})
+	}
+	p.Table([]string{"", "name", "type", "volume", "id"}, rows, func(i int) string {
+		if dr.Devices[i].IsActive {
+			return Green
+		}
+		return ""
+	})
+}
+
+func (p *Printer) printSpotifySearch(sr spotify.SearchResponse) {
+	if len(sr.Tracks.Items) == 0 && len(sr.Artists.Items) == 0 {
+		fmt.Fprintln(p.stdout, "no results")
+		return
+	}
+	if len(sr.Tracks.Items) > 0 {
+		fmt.Fprintln(p.stdout, p.Paint(Bold, "Tracks:"))
+		for i, t := range sr.Tracks.Items {
+			names := make([]string, 0, len(t.Artists))
+			for _, a := range t.Artists {
+				names = append(names, strings.TrimSpace(a.Name))
+			}
+			fmt.Fprintf(p.stdout, "%3d  %s — %s\n", i, strings.Join(names, ", "), strings.TrimSpace(t.Name))
+		}
+	}
+	if len(sr.Artists.Items) > 0 {
+		if len(sr.Tracks.Items) > 0 {
+			fmt.Fprintln(p.stdout)
+		}
+		fmt.Fprintln(p.stdout, p.Paint(Bold, "Artists:"))
+		for i, a := range sr.Artists.Items {
+			fmt.Fprintf(p.stdout, "%3d  %s\n", i, strings.TrimSpace(a.Name))
+		}
+	}
+}
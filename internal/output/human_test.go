@@ -0,0 +1,93 @@
+package output
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/steipete/blucli/internal/bluos"
+	"github.com/steipete/blucli/internal/spotify"
+)
+
+type humanValue struct{ n int }
+
+func (h humanValue) PrintHuman(p *Printer) { p.Printf("n=%d\n", h.n) }
+
+func TestPrinter_Print_HumanTypes(t *testing.T) {
+	t.Parallel()
+
+	var out bytes.Buffer
+	p := New(Options{Stdout: &out, Stderr: &out})
+
+	cases := []struct {
+		v    any
+		want string
+	}{
+		{humanValue{n: 3}, "n=3\n"},
+		{bluos.LoadedResponse{Service: "TuneIn", Entries: 1}, "loaded TuneIn (1 entries)\n"},
+		{bluos.LoadedResponse{}, "loaded preset\n"},
+		{bluos.SaveResponse{Entries: 12}, "saved 12 entries\n"},
+		{bluos.RawXML{XML: "<weird/>\n"}, "<weird/>\n"},
+		{spotify.DevicesResponse{}, "no spotify devices"},
+		{spotify.SearchResponse{}, "no results\n"},
+	}
+	for _, tc := range cases {
+		out.Reset()
+		p.Print(tc.v)
+		if got := out.String(); !strings.HasPrefix(got, tc.want) {
+			t.Fatalf("Print(%T) = %q; want %q", tc.v, got, tc.want)
+		}
+	}
+
+	out.Reset()
+	p.Print(spotify.DevicesResponse{Devices: []spotify.Device{
+		{ID: "d1", Name: "Office", Type: "Speaker", IsActive: true, VolumePercent: 40},
+		{ID: "d2", Name: "Phone", Type: "Smartphone"},
+	}})
+	lines := strings.Split(strings.TrimRight(out.String(), "\n"), "\n")
+	if len(lines) != 3 || !strings.HasPrefix(lines[1], "*  Office  Speaker") || !strings.HasPrefix(lines[2], "   Phone") {
+		t.Fatalf("devices = %q", lines)
+	}
+
+	out.Reset()
+	var sr spotify.SearchResponse
+	sr.Tracks.Items = []spotify.SearchTrack{{Name: "Song", URI: "spotify:track:1"}}
+	sr.Tracks.Items[0].Artists = append(sr.Tracks.Items[0].Artists, struct {
+		Name string `json:"name"`
+	}{Name: "Band"})
+	sr.Artists.Items = []spotify.SearchArtist{{Name: "Band"}}
+	p.Print(sr)
+	if got := out.String(); got != "Tracks:\n  0  Band — Song\n\nArtists:\n  0  Band\n" {
+		t.Fatalf("search = %q", got)
+	}
+}
+
+func TestPrinter_Color(t *testing.T) {
+	t.Parallel()
+
+	var out bytes.Buffer
+	plain := New(Options{Stdout: &out, Stderr: &out})
+	if got := plain.Paint(Red, "x"); got != "x" {
+		t.Fatalf("Paint without color = %q", got)
+	}
+
+	colored := New(Options{Color: true, Stdout: &out, Stderr: &out})
+	if got := colored.Paint(Red, "x"); got != "\x1b[31mx\x1b[0m" {
+		t.Fatalf("Paint = %q", got)
+	}
+
+	colored.Table([]string{"name", "result"}, [][]string{{"a", "ok"}, {"bb", "FAIL"}}, func(i int) string {
+		if i == 1 {
+			return Red
+		}
+		return ""
+	})
+	lines := strings.Split(strings.TrimRight(out.String(), "\n"), "\n")
+	if len(lines) != 3 {
+		t.Fatalf("lines = %q", lines)
+	}
+	// Colors wrap the text only, so padding still lines up.
+	if lines[1] != "a     ok" || lines[2] != "\x1b[31mbb\x1b[0m    \x1b[31mFAIL\x1b[0m" {
+		t.Fatalf("table = %q", lines)
+	}
+}
@@ -1,46 +1,140 @@
 package output
 
 import (
+	"cmp"
 	"encoding/json"
 	"fmt"
 	"io"
+	"slices"
 	"strconv"
 	"strings"
+	"text/template"
 
 	"github.com/steipete/blucli/internal/bluos"
+	"github.com/steipete/blucli/internal/config"
 	"github.com/steipete/blucli/internal/discovery"
+	"github.com/steipete/blucli/internal/history"
+	"github.com/steipete/blucli/internal/scrobble"
+	"github.com/steipete/blucli/internal/spotify"
 )
 
+const (
+	ModeHuman  = ""
+	ModeTable  = "table"
+	ModeCSV    = "csv"
+	ModeTSV    = "tsv"
+	ModeYAML   = "yaml"
+	ModeJSON   = "json"
+	ModeNDJSON = "ndjson"
+	// ModeMarkdown renders tables as GitHub-flavored Markdown.
+	ModeMarkdown = "markdown"
+)
+
+var Modes = []string{ModeTable, ModeCSV, ModeTSV, ModeMarkdown, ModeYAML, ModeJSON, ModeNDJSON}
+
+func ParseMode(s string) (string, error) {
+	s = strings.ToLower(strings.TrimSpace(s))
+	if s == "" || s == "text" || s == "human" {
+		return ModeHuman, nil
+	}
+	if s == "md" {
+		return ModeMarkdown, nil
+	}
+	for _, m := range Modes {
+		if s == m {
+			return m, nil
+		}
+	}
+	return "", fmt.Errorf("unknown output %q (want %s)", s, strings.Join(Modes, "|"))
+}
+
 type Options struct {
-	JSON   bool
+	JSON     bool
+	Mode     string
+	Envelope bool
+	Schemas  []Schema
+	Template *template.Template
+	Width    int
+	Color    bool
+	TTY      bool
+	// Quiet drops Infof notes.
+	Quiet  bool
 	Stdout io.Writer
 	Stderr io.Writer
 }
 
 type Printer struct {
-	json   bool
-	stdout io.Writer
-	stderr io.Writer
+	mode     string
+	envelope bool
+	schemas  []Schema
+	template *template.Template
+	width    int
+	color    bool
+	tty      bool
+	quiet    bool
+	stdout   io.Writer
+	stderr   io.Writer
+
+	device  *config.Device
+	failure *ErrorInfo
+	// failed is set when a value could not be rendered.
+	failed bool
 }
 
 func New(opts Options) *Printer {
+	mode := opts.Mode
+	if mode == ModeHuman && (opts.JSON || opts.Envelope) {
+		mode = ModeJSON
+	}
 	return &Printer{
-		json:   opts.JSON,
-		stdout: opts.Stdout,
-		stderr: opts.Stderr,
+		mode:     mode,
+		envelope: opts.Envelope,
+		schemas:  opts.Schemas,
+		template: opts.Template,
+		width:    opts.Width,
+		color:    opts.Color,
+		tty:      opts.TTY,
+		quiet:    opts.Quiet,
+		stdout:   opts.Stdout,
+		stderr:   opts.Stderr,
 	}
 }
 
 func (p *Printer) Stdout() io.Writer { return p.stdout }
 func (p *Printer) Stderr() io.Writer { return p.stderr }
 
+// Failed reports whether a printed value could not be rendered (e.g. a
+// --format template failed to execute), so the command must not exit 0.
+func (p *Printer) Failed() bool { return p.failed }
+
 func (p *Printer) Print(v any) {
-	if p.json {
+	if p.envelope {
+		p.printEncoded(p.wrap(v))
+		return
+	}
+	if p.template != nil {
+		p.printTemplate(v)
+		return
+	}
+	switch p.mode {
+	case ModeJSON:
 		p.printJSON(v)
 		return
+	case ModeNDJSON:
+		p.printNDJSON(v)
+		return
+	case ModeYAML:
+		p.printEncoded(v)
+		return
+	case ModeTable, ModeCSV, ModeTSV, ModeMarkdown:
+		if p.printTabular(v) {
+			return
+		}
 	}
 
 	switch value := v.(type) {
+	case Human:
+		value.PrintHuman(p)
 	case []discovery.Device:
 		p.printDevices(value)
 	case bluos.Status:
@@ -51,12 +145,34 @@ func (p *Printer) Print(v any) {
 		p.printPlaylist(value)
 	case bluos.Presets:
 		p.printPresets(value)
+	case bluos.LoadedResponse:
+		p.printLoaded(value)
+	case bluos.SaveResponse:
+		fmt.Fprintf(p.stdout, "saved %d entries\n", value.Entries)
+	case bluos.RawXML:
+		p.printRawXML(value.XML)
 	case bluos.Browse:
 		p.printBrowse(value)
 	case bluos.Playlists:
 		p.printPlaylists(value)
 	case bluos.RadioBrowse:
 		p.printRadioBrowse(value)
+	case history.Entry:
+		p.printHistory([]history.Entry{value})
+	case []history.Entry:
+		if len(value) == 0 {
+			fmt.Fprintln(p.stdout, "no history")
+			return
+		}
+		p.printHistory(value)
+	case history.Stats:
+		p.printHistoryStats(value)
+	case []scrobble.Listen:
+		p.printListens(value)
+	case spotify.DevicesResponse:
+		p.printSpotifyDevices(value)
+	case spotify.SearchResponse:
+		p.printSpotifySearch(value)
 	case map[string]any:
 		p.printJSON(value)
 	default:
@@ -65,6 +181,7 @@ func (p *Printer) Print(v any) {
 }
 
 func (p *Printer) Errorf(format string, args ...any) {
+	p.recordFailure(format, args)
 	fmt.Fprintf(p.stderr, format+"\n", args...)
 }
 
@@ -72,6 +189,26 @@ func (p *Printer) Warnf(format string, args ...any) {
 	fmt.Fprintf(p.stderr, "warn: "+format+"\n", args...)
 }
 
+// Infof prints a progress note on stderr for human output; --quiet and
+// machine-readable output drop it.
+func (p *Printer) Infof(format string, args ...any) {
+	if p.quiet || !p.Human() {
+		return
+	}
+	fmt.Fprintf(p.stderr, format+"\n", args...)
+}
+
+func (p *Printer) printEncoded(v any) {
+	if p.mode == ModeYAML {
+		if err := writeYAML(p.stdout, v); err != nil {
+			p.failed = true
+			fmt.Fprintf(p.stderr, "yaml: %v\n", err)
+		}
+		return
+	}
+	p.printJSON(v)
+}
+
 func (p *Printer) printJSON(v any) {
 	enc := json.NewEncoder(p.stdout)
 	enc.SetIndent("", "  ")
@@ -85,15 +222,25 @@ func (p *Printer) printDevices(devices []discovery.Device) {
 	}
 	for _, d := range devices {
 		extra := ""
+		if d.Type != "" {
+			extra = " (" + d.Type + ")"
+		}
 		if d.Version != "" {
-			extra = " v" + d.Version
+			extra += " v" + d.Version
+		}
+		if model := strings.TrimSpace(d.Brand + " " + cmp.Or(d.ModelName, d.Model)); model != "" {
+			extra += " · " + model
+		}
+		// Plain players are the norm; only call out other roles.
+		if len(d.Roles) > 0 && !slices.Equal(d.Roles, []string{"player"}) {
+			extra += " [" + strings.Join(d.Roles, ", ") + "]"
 		}
 		name := strings.TrimSpace(d.Name)
 		if name != "" {
-			fmt.Fprintf(p.stdout, "%s  %s (%s)%s\n", name, d.ID, d.Type, extra)
+			fmt.Fprintf(p.stdout, "%s  %s%s\n", name, d.ID, extra)
 			continue
 		}
-		fmt.Fprintf(p.stdout, "%s (%s)%s\n", d.ID, d.Type, extra)
+		fmt.Fprintf(p.stdout, "%s%s\n", d.ID, extra)
 	}
 }
 
@@ -215,6 +362,77 @@ func (p *Printer) printRadioBrowse(rb bluos.RadioBrowse) {
 	}
 }
 
+func (p *Printer) printHistory(entries []history.Entry) {
+	for _, e := range entries {
+		room := strings.TrimSpace(e.DeviceName)
+		if room == "" {
+			room = e.Device
+		}
+		extra := FormatSeconds(e.ListenedSeconds)
+		if service := strings.TrimSpace(e.Service); service != "" {
+			extra = service + " " + extra
+		}
+		fmt.Fprintf(p.stdout, "%s  %s  %s — %s  [%s]\n",
+			e.StartedAt.Local().Format("2006-01-02 15:04"),
+			room,
+			strings.TrimSpace(e.Artist),
+			strings.TrimSpace(e.Title),
+			extra,
+		)
+	}
+}
+
+func (p *Printer) printHistoryStats(s history.Stats) {
+	if s.Plays == 0 {
+		fmt.Fprintln(p.stdout, "no history")
+		return
+	}
+	fmt.Fprintf(p.stdout, "plays: %d  listened: %s\n", s.Plays, formatHours(s.ListenedSeconds))
+	sections := []struct {
+		title  string
+		counts []history.Count
+	}{
+		{"top artists", s.TopArtists},
+		{"rooms", s.Rooms},
+		{"services", s.Services},
+	}
+	for _, sec := range sections {
+		if len(sec.counts) == 0 {
+			continue
+		}
+		fmt.Fprintln(p.stdout)
+		fmt.Fprintln(p.stdout, sec.title+":")
+		for _, c := range sec.counts {
+			fmt.Fprintf(p.stdout, "  %-24s %4d plays  %s\n", c.Name, c.Plays, formatHours(c.ListenedSeconds))
+		}
+	}
+}
+
+func (p *Printer) printListens(listens []scrobble.Listen) {
+	if len(listens) == 0 {
+		fmt.Fprintln(p.stdout, "no queued listens")
+		return
+	}
+	for _, l := range listens {
+		fmt.Fprintf(p.stdout, "%s  %s  %s — %s\n", l.ListenedAt.Local().Format("2006-01-02 15:04"), l.Player, l.Artist, l.Track)
+	}
+}
+
+// FormatSeconds renders m:ss, or h:mm:ss from an hour on.
+func FormatSeconds(secs int) string {
+	if secs < 0 {
+		secs = 0
+	}
+	if secs >= 3600 {
+		return fmt.Sprintf("%d:%02d:%02d", secs/3600, secs/60%60, secs%60)
+	}
+	return fmt.Sprintf("%d:%02d", secs/60, secs%60)
+}
+
+func formatHours(secs int) string {
+	return fmt.Sprintf("%.1fh", float64(secs)/3600)
+}
+
 func ParseIntInRange(s string, min, max int) (int, error) {
 	s = strings.TrimSpace(s)
 	if s == "" {
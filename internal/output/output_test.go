@@ -7,6 +7,7 @@ import (
 
 	"github.com/steipete/blucli/internal/bluos"
 	"github.com/steipete/blucli/internal/discovery"
+	"github.com/steipete/blucli/internal/history"
 )
 
 func TestPrinter_Print_Text(t *testing.T) {
@@ -92,6 +93,35 @@ func TestPrinter_Print_Text(t *testing.T) {
 	}
 }
 
+func TestPrinter_Print_History(t *testing.T) {
+	t.Parallel()
+
+	var out bytes.Buffer
+	p := New(Options{Stdout: &out, Stderr: &bytes.Buffer{}})
+
+	p.Print([]history.Entry{})
+	if got := out.String(); !strings.Contains(got, "no history") {
+		t.Fatalf("stdout = %q; want no history", got)
+	}
+	out.Reset()
+
+	p.Print([]history.Entry{{Device: "d1", Artist: "A", Title: "T", Service: "Spotify", ListenedSeconds: 125}})
+	if got := out.String(); !strings.Contains(got, "d1  A — T  [Spotify 2:05]") {
+		t.Fatalf("stdout = %q; want history line", got)
+	}
+	out.Reset()
+
+	p.Print(history.Stats{
+		Plays:           1,
+		ListenedSeconds: 5400,
+		TopArtists:      []history.Count{{Name: "A", Plays: 1, ListenedSeconds: 5400}},
+		Rooms:           []history.Count{{Name: "Kitchen", Plays: 1, ListenedSeconds: 5400}},
+	})
+	if got := out.String(); !strings.Contains(got, "listened: 1.5h") || !strings.Contains(got, "top artists:") || !strings.Contains(got, "Kitchen") || strings.Contains(got, "services:") {
+		t.Fatalf("stdout = %q; want stats", got)
+	}
+}
+
 func TestPrinter_Print_JSON(t *testing.T) {
 	t.Parallel()
 
@@ -103,6 +133,27 @@ func TestPrinter_Print_JSON(t *testing.T) {
 	}
 }
 
+func TestPrinter_Infof(t *testing.T) {
+	t.Parallel()
+
+	for _, tc := range []struct {
+		name string
+		opts Options
+		want string
+	}{
+		{"human", Options{}, "flushed 2\n"},
+		{"quiet", Options{Quiet: true}, ""},
+		{"json", Options{JSON: true}, ""},
+	} {
+		var stderr bytes.Buffer
+		tc.opts.Stdout, tc.opts.Stderr = &bytes.Buffer{}, &stderr
+		New(tc.opts).Infof("flushed %d", 2)
+		if got := stderr.String(); got != tc.want {
+			t.Fatalf("%s: stderr = %q; want %q", tc.name, got, tc.want)
+		}
+	}
+}
+
 func TestParseIntInRange(t *testing.T) {
 	t.Parallel()
 
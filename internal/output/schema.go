@@ -0,0 +1,196 @@
+package output
+
+import (
+	"reflect"
+	"strings"
+	"time"
+
+	"github.com/steipete/blucli/internal/bluos"
+	"github.com/steipete/blucli/internal/config"
+	"github.com/steipete/blucli/internal/discovery"
+	"github.com/steipete/blucli/internal/history"
+	"github.com/steipete/blucli/internal/scrobble"
+	"github.com/steipete/blucli/internal/spotify"
+)
+
+const (
+	jsonSchemaDraft = "https://json-schema.org/draft/2020-12/schema"
+	schemaBaseURL   = "https://github.com/steipete/blucli/blob/main/docs/schemas/"
+
+	ErrorSchema = "blu.error/v1"
+)
+
+// Schema names the envelope payload for a Go type. Names are
+// "blu.<kind>/v<N>"; bump N whenever a field is removed or changes type.
+type Schema struct {
+	Name string
+	Type reflect.Type
+}
+
+func NewSchema(name string, sample any) Schema {
+	return Schema{Name: name, Type: reflect.TypeOf(sample)}
+}
+
+var builtinSchemas = []Schema{
+	NewSchema("blu.devices/v1", []discovery.Device{}),
+	NewSchema("blu.status/v1", bluos.Status{}),
+	NewSchema("blu.sync/v1", bluos.SyncStatus{}),
+	NewSchema("blu.queue/v1", bluos.Playlist{}),
+	NewSchema("blu.queue-saved/v1", bluos.SaveResponse{}),
+	NewSchema("blu.presets/v1", bluos.Presets{}),
+	NewSchema("blu.preset-loaded/v1", bluos.LoadedResponse{}),
+	NewSchema("blu.raw-xml/v1", bluos.RawXML{}),
+	NewSchema("blu.browse/v1", bluos.Browse{}),
+	NewSchema("blu.playlists/v1", bluos.Playlists{}),
+	NewSchema("blu.radio/v1", bluos.RadioBrowse{}),
+	NewSchema("blu.history/v1", []history.Entry{}),
+	NewSchema("blu.history-entry/v1", history.Entry{}),
+	NewSchema("blu.history-stats/v1", history.Stats{}),
+	NewSchema("blu.scrobble-queue/v1", []scrobble.Listen{}),
+	NewSchema("blu.spotify-devices/v1", spotify.DevicesResponse{}),
+	NewSchema("blu.spotify-search/v1", spotify.SearchResponse{}),
+}
+
+// Schemas returns the payload schemas for the types this package prints.
+func Schemas() []Schema {
+	return append([]Schema(nil), builtinSchemas...)
+}
+
+// SchemaName looks v's type up in extra (command-local types) and then in
+// the builtin schemas.
+func SchemaName(v any, extra ...Schema) string {
+	t := reflect.TypeOf(v)
+	for _, list := range [][]Schema{extra, builtinSchemas} {
+		for _, s := range list {
+			if s.Type == t {
+				return s.Name
+			}
+		}
+	}
+	return "blu.value/v1"
+}
+
+// FileName is the file the schema is published as, e.g. blu.status.v1.json.
+func (s Schema) FileName() string {
+	return strings.ReplaceAll(s.Name, "/", ".") + ".json"
+}
+
+// JSONSchema describes the full envelope with this schema's payload as data.
+func (s Schema) JSONSchema() map[string]any {
+	return map[string]any{
+		"$schema":  jsonSchemaDraft,
+		"$id":      schemaBaseURL + s.FileName(),
+		"title":    s.Name,
+		"type":     "object",
+		"required": []string{"schema", "data"},
+		"properties": map[string]any{
+			"schema": map[string]any{"const": s.Name},
+			"device": typeSchema(reflect.TypeOf(config.Device{}), false),
+			"data":   typeSchema(s.Type, nilable(s.Type)),
+		},
+	}
+}
+
+func ErrorJSONSchema() map[string]any {
+	s := Schema{Name: ErrorSchema}
+	return map[string]any{
+		"$schema":  jsonSchemaDraft,
+		"$id":      schemaBaseURL + s.FileName(),
+		"title":    ErrorSchema,
+		"type":     "object",
+		"required": []string{"schema", "error"},
+		"properties": map[string]any{
+			"schema": map[string]any{"const": ErrorSchema},
+			"device": typeSchema(reflect.TypeOf(config.Device{}), false),
+			"error":  typeSchema(reflect.TypeOf(ErrorInfo{}), false),
+		},
+	}
+}
+
+var timeType = reflect.TypeOf(time.Time{})
+
+// typeSchema mirrors encoding/json: nullable is set where a nil slice,
+// map or pointer would encode as null.
+func typeSchema(t reflect.Type, nullable bool) map[string]any {
+	if t == timeType {
+		return map[string]any{"type": "string", "format": "date-time"}
+	}
+
+	var s map[string]any
+	switch t.Kind() {
+	case reflect.Pointer:
+		s = typeSchema(t.Elem(), false)
+	case reflect.Bool:
+		s = map[string]any{"type": "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		s = map[string]any{"type": "integer"}
+	case reflect.Float32, reflect.Float64:
+		s = map[string]any{"type": "number"}
+	case reflect.String:
+		s = map[string]any{"type": "string"}
+	case reflect.Slice, reflect.Array:
+		if t.Elem().Kind() == reflect.Uint8 {
+			s = map[string]any{"type": "string", "contentEncoding": "base64"}
+			break
+		}
+		s = map[string]any{"type": "array", "items": typeSchema(t.Elem(), false)}
+	case reflect.Map:
+		s = map[string]any{"type": "object", "additionalProperties": typeSchema(t.Elem(), false)}
+	case reflect.Struct:
+		props := map[string]any{}
+		var required []string
+		structFields(t, props, &required)
+		s = map[string]any{"type": "object", "properties": props}
+		if len(required) > 0 {
+			s["required"] = required
+		}
+	default:
+		return map[string]any{}
+	}
+
+	if typ, ok := s["type"]; ok && nullable {
+		s["type"] = []any{typ, "null"}
+	}
+	return s
+}
+
+func structFields(t reflect.Type, props map[string]any, required *[]string) {
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		tag := f.Tag.Get("json")
+		if tag == "-" {
+			continue
+		}
+		name, opts, _ := strings.Cut(tag, ",")
+		if f.Anonymous && name == "" {
+			ft := f.Type
+			if ft.Kind() == reflect.Pointer {
+				ft = ft.Elem()
+			}
+			if ft.Kind() == reflect.Struct {
+				structFields(ft, props, required)
+				continue
+			}
+		}
+		if !f.IsExported() {
+			continue
+		}
+		if name == "" {
+			name = f.Name
+		}
+		optional := strings.Contains(","+opts+",", ",omitempty,") || strings.Contains(","+opts+",", ",omitzero,")
+		props[name] = typeSchema(f.Type, !optional && nilable(f.Type))
+		if !optional {
+			*required = append(*required, name)
+		}
+	}
+}
+
+func nilable(t reflect.Type) bool {
+	switch t.Kind() {
+	case reflect.Pointer, reflect.Slice, reflect.Map, reflect.Interface:
+		return true
+	}
+	return false
+}
@@ -0,0 +1,318 @@
+package output
+
+import (
+	"bytes"
+	"cmp"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/steipete/blucli/internal/bluos"
+	"github.com/steipete/blucli/internal/discovery"
+	"github.com/steipete/blucli/internal/history"
+	"github.com/steipete/blucli/internal/scrobble"
+)
+
+// Tabular lets command-local types opt into table/csv/tsv output. Header
+// names are lower_snake_case; table output upper-cases them.
+type Tabular interface {
+	TableHeader() []string
+	TableRows() [][]string
+}
+
+func (p *Printer) printTabular(v any) bool {
+	header, rows, ok := tableFor(v)
+	switch p.mode {
+	case ModeTable:
+		if !ok {
+			return false
+		}
+		writeTable(p.stdout, header, rows, p.width, nil)
+	case ModeCSV, ModeTSV:
+		if !ok {
+			header, rows = fieldRows(v)
+		}
+		comma := ','
+		if p.mode == ModeTSV {
+			comma = '\t'
+		}
+		if err := writeDelimited(p.stdout, comma, header, rows); err != nil {
+			p.Errorf("%s: %v", p.mode, err)
+		}
+	case ModeMarkdown:
+		if !ok {
+			header, rows = fieldRows(v)
+		}
+		writeMarkdown(p.stdout, header, rows)
+	default:
+		return false
+	}
+	return true
+}
+
+func (p *Printer) printNDJSON(v any) {
+	enc := json.NewEncoder(p.stdout)
+	if rv := reflect.ValueOf(v); rv.Kind() == reflect.Slice {
+		for i := 0; i < rv.Len(); i++ {
+			_ = enc.Encode(rv.Index(i).Interface())
+		}
+		return
+	}
+	_ = enc.Encode(v)
+}
+
+// fieldRows flattens values without a table shape into dotted field/value
+// pairs so csv/tsv output always has the same two columns.
+func fieldRows(v any) ([]string, [][]string) {
+	header := []string{"field", "value"}
+	data, err := json.Marshal(v)
+	if err != nil {
+		return header, nil
+	}
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	node, err := decodeOrdered(dec)
+	if err != nil {
+		return header, nil
+	}
+	var rows [][]string
+	var walk func(prefix string, node any)
+	walk = func(prefix string, node any) {
+		join := func(key string) string {
+			if prefix == "" {
+				return key
+			}
+			return prefix + "." + key
+		}
+		switch n := node.(type) {
+		case orderedMap:
+			for _, f := range n {
+				walk(join(f.key), f.value)
+			}
+		case []any:
+			for i, item := range n {
+				walk(join(strconv.Itoa(i)), item)
+			}
+		case nil:
+			rows = append(rows, []string{prefix, ""})
+		case string:
+			rows = append(rows, []string{prefix, n})
+		default:
+			rows = append(rows, []string{prefix, fmt.Sprint(n)})
+		}
+	}
+	walk("", node)
+	return header, rows
+}
+
+func tableFor(v any) ([]string, [][]string, bool) {
+	switch value := v.(type) {
+	case Tabular:
+		return value.TableHeader(), value.TableRows(), true
+	case []discovery.Device:
+		rows := make([][]string, 0, len(value))
+		for _, d := range value {
+			rows = append(rows, []string{strings.TrimSpace(d.Name), d.ID, d.Host, strconv.Itoa(d.Port), d.Type, d.Version, d.Source, d.MAC, strings.Join(d.Roles, " "), d.Brand, cmp.Or(d.ModelName, d.Model)})
+		}
+		return []string{"name", "id", "host", "port", "type", "version", "source", "mac", "roles", "brand", "model"}, rows, true
+	case bluos.Playlist:
+		rows := make([][]string, 0, len(value.Songs))
+		for _, s := range value.Songs {
+			rows = append(rows, []string{strconv.Itoa(s.ID), strings.TrimSpace(s.Artist), strings.TrimSpace(s.Title), strings.TrimSpace(s.Album), s.Service, s.Quality, strings.TrimSpace(s.Fn)})
+		}
+		return []string{"id", "artist", "title", "album", "service", "quality", "fn"}, rows, true
+	case bluos.Presets:
+		rows := make([][]string, 0, len(value.Presets))
+		for _, p := range value.Presets {
+			rows = append(rows, []string{strconv.Itoa(p.ID), strings.TrimSpace(p.Name), p.URL})
+		}
+		return []string{"id", "name", "url"}, rows, true
+	case bluos.Browse:
+		rows := make([][]string, 0, len(value.Items))
+		for i, item := range value.Items {
+			rows = append(rows, []string{strconv.Itoa(i), strings.TrimSpace(item.Text), item.Type, item.BrowseKey, item.PlayURL})
+		}
+		return []string{"index", "text", "type", "browse_key", "play_url"}, rows, true
+	case bluos.Playlists:
+		rows := make([][]string, 0, len(value.Names))
+		for _, n := range value.Names {
+			rows = append(rows, []string{strings.TrimSpace(n.Text), n.ID, strconv.Itoa(n.Tracks), strconv.Itoa(n.TimeSeconds)})
+		}
+		return []string{"name", "id", "tracks", "time_secs"}, rows, true
+	case bluos.RadioBrowse:
+		var rows [][]string
+		for _, cat := range value.Categories {
+			for _, item := range cat.Items {
+				rows = append(rows, []string{strings.TrimSpace(cat.Text), item.ID, strings.TrimSpace(item.Text), item.Type, item.URL})
+			}
+		}
+		for _, item := range value.Items {
+			rows = append(rows, []string{"", item.ID, strings.TrimSpace(item.Text), item.Type, item.URL})
+		}
+		return []string{"category", "id", "text", "type", "url"}, rows, true
+	case []history.Entry:
+		rows := make([][]string, 0, len(value))
+		for _, e := range value {
+			rows = append(rows, []string{
+				e.StartedAt.Format(time.RFC3339), e.Device, e.DeviceName, e.Artist, e.Title, e.Album, e.Service,
+				strconv.Itoa(e.ListenedSeconds), strconv.Itoa(e.LengthSeconds),
+			})
+		}
+		return []string{"started_at", "device", "device_name", "artist", "title", "album", "service", "listened_secs", "length_secs"}, rows, true
+	case []scrobble.Listen:
+		rows := make([][]string, 0, len(value))
+		for _, l := range value {
+			rows = append(rows, []string{l.ListenedAt.Format(time.RFC3339), l.Player, l.Artist, l.Track, l.Release})
+		}
+		return []string{"listened_at", "player", "artist", "track", "release"}, rows, true
+	default:
+		return nil, nil, false
+	}
+}
+
+func writeDelimited(w io.Writer, comma rune, header []string, rows [][]string) error {
+	cw := csv.NewWriter(w)
+	cw.Comma = comma
+	if err := cw.Write(header); err != nil {
+		return err
+	}
+	for _, row := range rows {
+		if err := cw.Write(row); err != nil {
+			return err
+		}
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+// writeMarkdown prints a GitHub-flavored Markdown table; pipes and
+// newlines in cells are escaped.
+func writeMarkdown(w io.Writer, header []string, rows [][]string) {
+	cell := strings.NewReplacer("|", `\|`, "\r\n", "<br>", "\n", "<br>")
+	line := func(cells []string) {
+		escaped := make([]string, len(cells))
+		for i, c := range cells {
+			escaped[i] = cell.Replace(c)
+		}
+		fmt.Fprintf(w, "| %s |\n", strings.Join(escaped, " | "))
+	}
+	line(header)
+	sep := make([]string, len(header))
+	for i := range sep {
+		sep[i] = "---"
+	}
+	line(sep)
+	for _, row := range rows {
+		line(row)
+	}
+}
+
+const minColumnWidth = 4
+
+// writeTable prints aligned columns. When maxWidth > 0 the widest columns are
+// truncated until the table fits. paint, if set, decorates padded cells of
+// row i (-1 for the header) so escape codes don't skew the alignment.
+func writeTable(w io.Writer, header []string, rows [][]string, maxWidth int, paint func(row int, cell string) string) {
+	if len(rows) == 0 {
+		fmt.Fprintln(w, "no rows")
+		return
+	}
+
+	upper := make([]string, len(header))
+	for i, h := range header {
+		upper[i] = strings.ToUpper(h)
+	}
+
+	widths := make([]int, len(header))
+	for i, h := range upper {
+		widths[i] = utf8.RuneCountInString(h)
+	}
+	for _, row := range rows {
+		for i := range widths {
+			if i < len(row) {
+				if n := utf8.RuneCountInString(row[i]); n > widths[i] {
+					widths[i] = n
+				}
+			}
+		}
+	}
+
+	// Drop columns that are empty for every row (e.g. no versions known).
+	keep := make([]bool, len(header))
+	for i := range header {
+		for _, row := range rows {
+			if i < len(row) && row[i] != "" {
+				keep[i] = true
+				break
+			}
+		}
+	}
+
+	if maxWidth > 0 {
+		fitWidths(widths, keep, maxWidth)
+	}
+
+	writeRow := func(row int, cells []string) {
+		var b strings.Builder
+		first := true
+		for i := range header {
+			if !keep[i] {
+				continue
+			}
+			if !first {
+				b.WriteString("  ")
+			}
+			first = false
+			cell := ""
+			if i < len(cells) {
+				cell = cells[i]
+			}
+			cell = templateTruncate(widths[i], cell)
+			padded := templatePad(widths[i], cell)
+			if paint != nil {
+				padded = paint(row, cell) + padded[len(cell):]
+			}
+			b.WriteString(padded)
+		}
+		fmt.Fprintln(w, strings.TrimRight(b.String(), " "))
+	}
+
+	writeRow(-1, upper)
+	for i, row := range rows {
+		writeRow(i, row)
+	}
+}
+
+func fitWidths(widths []int, keep []bool, maxWidth int) {
+	total := func() int {
+		sum, cols := 0, 0
+		for i, w := range widths {
+			if keep[i] {
+				sum += w
+				cols++
+			}
+		}
+		if cols > 1 {
+			sum += 2 * (cols - 1)
+		}
+		return sum
+	}
+	for total() > maxWidth {
+		widest := -1
+		for i, w := range widths {
+			if keep[i] && w > minColumnWidth && (widest < 0 || w > widths[widest]) {
+				widest = i
+			}
+		}
+		if widest < 0 {
+			return
+		}
+		widths[widest]--
+	}
+}
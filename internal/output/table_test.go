@@ -0,0 +1,176 @@
+package output
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/steipete/blucli/internal/bluos"
+	"github.com/steipete/blucli/internal/discovery"
+)
+
+func TestParseMode(t *testing.T) {
+	t.Parallel()
+
+	for in, want := range map[string]string{"": ModeHuman, "text": ModeHuman, "TABLE": ModeTable, " csv ": ModeCSV, "ndjson": ModeNDJSON, "md": ModeMarkdown, "Markdown": ModeMarkdown} {
+		got, err := ParseMode(in)
+		if err != nil || got != want {
+			t.Fatalf("ParseMode(%q) = %q, %v; want %q", in, got, err, want)
+		}
+	}
+	if _, err := ParseMode("xml"); err == nil {
+		t.Fatalf("expected error")
+	}
+}
+
+func TestPrinter_Print_Table(t *testing.T) {
+	t.Parallel()
+
+	var out bytes.Buffer
+	p := New(Options{Mode: ModeTable, Stdout: &out, Stderr: &out})
+
+	p.Print([]discovery.Device{
+		{ID: "10.0.0.2:11000", Host: "10.0.0.2", Port: 11000, Name: "Kitchen", Type: "mdns", Source: "mdns"},
+		{ID: "10.0.0.3:11000", Host: "10.0.0.3", Port: 11000, Name: "Living Room", Type: "lsdp", Source: "lsdp"},
+	})
+	lines := strings.Split(strings.TrimRight(out.String(), "\n"), "\n")
+	if len(lines) != 3 {
+		t.Fatalf("lines = %q", lines)
+	}
+	if lines[0] != "NAME         ID              HOST      PORT   TYPE  SOURCE" {
+		t.Fatalf("header = %q", lines[0])
+	}
+	if !strings.HasPrefix(lines[2], "Living Room  10.0.0.3:11000") {
+		t.Fatalf("row = %q", lines[2])
+	}
+	out.Reset()
+
+	// Values without a table shape use the human printer.
+	p.Print(bluos.Status{State: "play", Volume: 5})
+	if got := out.String(); !strings.Contains(got, "vol=5") {
+		t.Fatalf("stdout = %q", got)
+	}
+}
+
+func TestWriteTableFitsWidth(t *testing.T) {
+	t.Parallel()
+
+	var out bytes.Buffer
+	writeTable(&out, []string{"id", "text"}, [][]string{{"1", strings.Repeat("x", 40)}}, 20, nil)
+	for _, line := range strings.Split(strings.TrimRight(out.String(), "\n"), "\n") {
+		if n := len([]rune(line)); n > 20 {
+			t.Fatalf("line %q is %d wide", line, n)
+		}
+	}
+	if !strings.Contains(out.String(), "…") {
+		t.Fatalf("stdout = %q; want truncated", out.String())
+	}
+}
+
+func TestPrinter_Print_CSVAndTSV(t *testing.T) {
+	t.Parallel()
+
+	queue := bluos.Playlist{Songs: []bluos.PlaylistSong{
+		{ID: 0, Artist: "A, B", Title: "T1", Album: "X"},
+		{ID: 1, Artist: "C", Title: "T2"},
+	}}
+
+	var out bytes.Buffer
+	New(Options{Mode: ModeCSV, Stdout: &out, Stderr: &out}).Print(queue)
+	want := "id,artist,title,album,service,quality,fn\n0,\"A, B\",T1,X,,,\n1,C,T2,,,,\n"
+	if got := out.String(); got != want {
+		t.Fatalf("csv = %q; want %q", got, want)
+	}
+	out.Reset()
+
+	New(Options{Mode: ModeTSV, Stdout: &out, Stderr: &out}).Print(bluos.Presets{Presets: []bluos.Preset{{ID: 1, Name: "Jazz", URL: "u"}}})
+	if got := out.String(); got != "id\tname\turl\n1\tJazz\tu\n" {
+		t.Fatalf("tsv = %q", got)
+	}
+	out.Reset()
+
+	// Empty lists still emit the header.
+	New(Options{Mode: ModeCSV, Stdout: &out, Stderr: &out}).Print(bluos.Presets{})
+	if got := out.String(); got != "id,name,url\n" {
+		t.Fatalf("csv = %q", got)
+	}
+	out.Reset()
+
+	New(Options{Mode: ModeCSV, Stdout: &out, Stderr: &out}).Print(bluos.SyncStatus{Group: "G", Slaves: []bluos.SyncSlave{{ID: "s1", Port: 11000}}})
+	got := out.String()
+	if !strings.HasPrefix(got, "field,value\n") || !strings.Contains(got, "group,G\n") || !strings.Contains(got, "slaves.0.id,s1\n") {
+		t.Fatalf("csv = %q", got)
+	}
+}
+
+func TestPrinter_Print_NDJSON(t *testing.T) {
+	t.Parallel()
+
+	var out bytes.Buffer
+	p := New(Options{Mode: ModeNDJSON, Stdout: &out, Stderr: &out})
+	p.Print([]discovery.Device{{ID: "a"}, {ID: "b"}})
+
+	lines := strings.Split(strings.TrimRight(out.String(), "\n"), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("lines = %q", lines)
+	}
+	for _, line := range lines {
+		var d discovery.Device
+		if err := json.Unmarshal([]byte(line), &d); err != nil {
+			t.Fatalf("line %q: %v", line, err)
+		}
+	}
+}
+
+func TestPrinter_Print_YAML(t *testing.T) {
+	t.Parallel()
+
+	var out bytes.Buffer
+	p := New(Options{Mode: ModeYAML, Stdout: &out, Stderr: &out})
+	p.Print(bluos.SyncStatus{
+		Group:  "Living: Room",
+		Volume: 12,
+		Master: &bluos.SyncMaster{Host: "10.0.0.2", Port: 11000},
+		Slaves: []bluos.SyncSlave{{ID: "s1", Port: 11000}, {ID: "true", Port: 11001}},
+	})
+	want := `group: "Living: Room"
+volume: 12
+mute: false
+master:
+  host: 10.0.0.2
+  port: 11000
+slaves:
+  - id: s1
+    port: 11000
+  - id: "true"
+    port: 11001
+`
+	if got := out.String(); got != want {
+		t.Fatalf("yaml =\n%s\nwant\n%s", got, want)
+	}
+	out.Reset()
+
+	p.Print([]string{})
+	if got := out.String(); got != "[]\n" {
+		t.Fatalf("yaml = %q", got)
+	}
+}
+
+func TestPrinter_Print_Markdown(t *testing.T) {
+	t.Parallel()
+
+	var out bytes.Buffer
+	p := New(Options{Mode: ModeMarkdown, Stdout: &out, Stderr: &out})
+	p.Print([]discovery.Device{{ID: "10.0.0.2:11000", Host: "10.0.0.2", Port: 11000, Name: "Kitchen|Den", Source: "mdns"}})
+	lines := strings.Split(strings.TrimRight(out.String(), "\n"), "\n")
+	if len(lines) != 3 || !strings.HasPrefix(lines[0], "| name | id | host |") || !strings.HasPrefix(lines[1], "| --- | --- |") || !strings.HasPrefix(lines[2], `| Kitchen\|Den | 10.0.0.2:11000 |`) {
+		t.Fatalf("lines = %q", lines)
+	}
+
+	out.Reset()
+	p.Print(bluos.SyncStatus{Name: "Kitchen"})
+	if got := out.String(); !strings.HasPrefix(got, "| field | value |\n| --- | --- |\n") || !strings.Contains(got, "| name | Kitchen |") {
+		t.Fatalf("fallback = %q", got)
+	}
+}
@@ -0,0 +1,115 @@
+package output
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"text/template"
+	"time"
+	"unicode/utf8"
+)
+
+// ParseTemplate parses a --format template. Lists are rendered one element
+// per line; anything else is rendered once.
+func ParseTemplate(format string) (*template.Template, error) {
+	if strings.TrimSpace(format) == "" {
+		return nil, nil
+	}
+	format = strings.NewReplacer(`\n`, "\n", `\t`, "\t").Replace(format)
+	return template.New("format").Option("missingkey=zero").Funcs(templateFuncs).Parse(format)
+}
+
+var templateFuncs = template.FuncMap{
+	"duration": templateDuration,
+	"pad":      templatePad,
+	"truncate": templateTruncate,
+	"default":  templateDefault,
+	"upper":    func(v any) string { return strings.ToUpper(fmt.Sprint(v)) },
+}
+
+func (p *Printer) printTemplate(v any) {
+	items := []any{v}
+	if rv := reflect.ValueOf(v); rv.Kind() == reflect.Slice {
+		items = items[:0]
+		for i := 0; i < rv.Len(); i++ {
+			items = append(items, rv.Index(i).Interface())
+		}
+	}
+
+	var b strings.Builder
+	for _, item := range items {
+		b.Reset()
+		if err := p.template.Execute(&b, item); err != nil {
+			p.failed = true
+			p.Errorf("format: %v", err)
+			return
+		}
+		line := b.String()
+		if !strings.HasSuffix(line, "\n") {
+			line += "\n"
+		}
+		fmt.Fprint(p.stdout, line)
+	}
+}
+
+func templateDuration(v any) (string, error) {
+	switch d := v.(type) {
+	case time.Duration:
+		return FormatSeconds(int(d / time.Second)), nil
+	case int:
+		return FormatSeconds(d), nil
+	case int64:
+		return FormatSeconds(int(d)), nil
+	case float64:
+		return FormatSeconds(int(d)), nil
+	default:
+		return "", fmt.Errorf("duration: unsupported type %T", v)
+	}
+}
+
+// pad N s right-pads to N runes; a negative N left-pads (right-aligns).
+func templatePad(width int, v any) string {
+	s := fmt.Sprint(v)
+	n := utf8.RuneCountInString(s)
+	left := width < 0
+	if left {
+		width = -width
+	}
+	if n >= width {
+		return s
+	}
+	fill := strings.Repeat(" ", width-n)
+	if left {
+		return fill + s
+	}
+	return s + fill
+}
+
+func templateTruncate(width int, v any) string {
+	s := fmt.Sprint(v)
+	if width <= 0 {
+		return ""
+	}
+	if utf8.RuneCountInString(s) <= width {
+		return s
+	}
+	r := []rune(s)
+	if width == 1 {
+		return "…"
+	}
+	return string(r[:width-1]) + "…"
+}
+
+func templateDefault(def, v any) any {
+	if v == nil {
+		return def
+	}
+	rv := reflect.ValueOf(v)
+	if rv.IsZero() {
+		return def
+	}
+	if s, ok := v.(string); ok && strings.TrimSpace(s) == "" {
+		return def
+	}
+	return v
+}
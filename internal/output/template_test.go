@@ -0,0 +1,105 @@
+package output
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/steipete/blucli/internal/bluos"
+	"github.com/steipete/blucli/internal/discovery"
+)
+
+func TestPrinter_Print_Template(t *testing.T) {
+	t.Parallel()
+
+	tmpl, err := ParseTemplate(`{{.Artist | upper}} – {{truncate 6 .Title}} [{{duration .Secs}}/{{duration .TotLen}}] {{.Album | default "-"}}`)
+	if err != nil {
+		t.Fatalf("ParseTemplate: %v", err)
+	}
+	var out bytes.Buffer
+	p := New(Options{Template: tmpl, JSON: true, Stdout: &out, Stderr: &bytes.Buffer{}})
+
+	p.Print(bluos.Status{Artist: "Röyksopp", Title: "Eple (remix)", Secs: 65, TotLen: 3725})
+	if got, want := out.String(), "RÖYKSOPP – Eple … [1:05/1:02:05] -\n"; got != want {
+		t.Fatalf("stdout = %q; want %q", got, want)
+	}
+}
+
+func TestPrinter_Print_TemplateSlicePerLine(t *testing.T) {
+	t.Parallel()
+
+	tmpl, err := ParseTemplate(`{{pad 8 .Name}}|{{pad -6 .Port}}|`)
+	if err != nil {
+		t.Fatalf("ParseTemplate: %v", err)
+	}
+	var out bytes.Buffer
+	p := New(Options{Template: tmpl, Stdout: &out, Stderr: &bytes.Buffer{}})
+
+	p.Print([]discovery.Device{{Name: "Küche", Port: 11000}, {Name: "Office", Port: 1}})
+	want := "Küche   | 11000|\nOffice  |     1|\n"
+	if got := out.String(); got != want {
+		t.Fatalf("stdout = %q; want %q", got, want)
+	}
+
+	out.Reset()
+	tmpl, _ = ParseTemplate(`{{range .Songs}}{{.ID}} {{.Title}}\n{{end}}`)
+	p = New(Options{Template: tmpl, Stdout: &out, Stderr: &bytes.Buffer{}})
+	p.Print(bluos.Playlist{Songs: []bluos.PlaylistSong{{ID: 1, Title: "A"}, {ID: 2, Title: "B"}}})
+	if got := out.String(); got != "1 A\n2 B\n" {
+		t.Fatalf("stdout = %q", got)
+	}
+}
+
+func TestPrinter_Print_TemplateErrors(t *testing.T) {
+	t.Parallel()
+
+	if _, err := ParseTemplate(`{{.Title`); err == nil {
+		t.Fatalf("ParseTemplate: want parse error")
+	}
+	if tmpl, err := ParseTemplate("  "); err != nil || tmpl != nil {
+		t.Fatalf("empty format: tmpl=%v err=%v", tmpl, err)
+	}
+
+	tmpl, _ := ParseTemplate(`{{.Nope}}`)
+	var out, errOut bytes.Buffer
+	p := New(Options{Template: tmpl, Stdout: &out, Stderr: &errOut})
+	if p.Failed() {
+		t.Fatalf("Failed before printing")
+	}
+	p.Print(bluos.Status{})
+	if !strings.Contains(errOut.String(), "format:") || !p.Failed() {
+		t.Fatalf("stderr = %q failed=%v; want format error", errOut.String(), p.Failed())
+	}
+
+	tmpl, _ = ParseTemplate(`{{duration .}}`)
+	errOut.Reset()
+	p = New(Options{Template: tmpl, Stdout: &out, Stderr: &errOut})
+	p.Print("x")
+	if !strings.Contains(errOut.String(), "unsupported type") {
+		t.Fatalf("stderr = %q; want duration error", errOut.String())
+	}
+}
+
+func TestTemplateHelpers(t *testing.T) {
+	t.Parallel()
+
+	if got, _ := templateDuration(90 * time.Second); got != "1:30" {
+		t.Fatalf("duration = %q", got)
+	}
+	if got := templateTruncate(0, "abc"); got != "" {
+		t.Fatalf("truncate 0 = %q", got)
+	}
+	if got := templateTruncate(1, "abc"); got != "…" {
+		t.Fatalf("truncate 1 = %q", got)
+	}
+	if got := templateDefault("x", 0); got != "x" {
+		t.Fatalf("default zero = %v", got)
+	}
+	if got := templateDefault("x", nil); got != "x" {
+		t.Fatalf("default nil = %v", got)
+	}
+	if got := templateDefault("x", 3); got != 3 {
+		t.Fatalf("default value = %v", got)
+	}
+}
@@ -0,0 +1,196 @@
+package output
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// writeYAML renders v as YAML via its JSON encoding, so field names and
+// omitempty behave exactly like --json and key order is preserved.
+func writeYAML(w io.Writer, v any) error {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	node, err := decodeOrdered(dec)
+	if err != nil {
+		return err
+	}
+	var b strings.Builder
+	switch n := node.(type) {
+	case orderedMap:
+		if len(n) == 0 {
+			b.WriteString("{}\n")
+		}
+		writeYAMLMap(&b, n, 0)
+	case []any:
+		if len(n) == 0 {
+			b.WriteString("[]\n")
+		}
+		writeYAMLList(&b, n, 0)
+	default:
+		b.WriteString(yamlScalar(n) + "\n")
+	}
+	_, err = io.WriteString(w, b.String())
+	return err
+}
+
+type orderedMap []yamlField
+
+type yamlField struct {
+	key   string
+	value any
+}
+
+func decodeOrdered(dec *json.Decoder) (any, error) {
+	tok, err := dec.Token()
+	if err != nil {
+		return nil, err
+	}
+	switch t := tok.(type) {
+	case json.Delim:
+		switch t {
+		case '{':
+			m := orderedMap{}
+			for dec.More() {
+				keyTok, err := dec.Token()
+				if err != nil {
+					return nil, err
+				}
+				key, _ := keyTok.(string)
+				value, err := decodeOrdered(dec)
+				if err != nil {
+					return nil, err
+				}
+				m = append(m, yamlField{key: key, value: value})
+			}
+			_, err := dec.Token()
+			return m, err
+		case '[':
+			list := []any{}
+			for dec.More() {
+				value, err := decodeOrdered(dec)
+				if err != nil {
+					return nil, err
+				}
+				list = append(list, value)
+			}
+			_, err := dec.Token()
+			return list, err
+		}
+		return nil, fmt.Errorf("unexpected %v", t)
+	default:
+		return t, nil
+	}
+}
+
+func writeYAMLMap(b *strings.Builder, m orderedMap, indent int) {
+	prefix := strings.Repeat("  ", indent)
+	for _, f := range m {
+		b.WriteString(prefix + yamlKey(f.key) + ":")
+		writeYAMLValue(b, f.value, indent+1)
+	}
+}
+
+func writeYAMLList(b *strings.Builder, list []any, indent int) {
+	prefix := strings.Repeat("  ", indent)
+	for _, item := range list {
+		switch v := item.(type) {
+		case orderedMap:
+			if len(v) == 0 {
+				b.WriteString(prefix + "- {}\n")
+				continue
+			}
+			// First field shares the dash line; the rest align under it.
+			var sub strings.Builder
+			writeYAMLMap(&sub, v, indent+1)
+			b.WriteString(prefix + "- " + strings.TrimPrefix(sub.String(), prefix+"  "))
+		case []any:
+			if len(v) == 0 {
+				b.WriteString(prefix + "- []\n")
+				continue
+			}
+			b.WriteString(prefix + "-\n")
+			writeYAMLList(b, v, indent+1)
+		default:
+			b.WriteString(prefix + "- " + yamlScalar(v) + "\n")
+		}
+	}
+}
+
+func writeYAMLValue(b *strings.Builder, value any, indent int) {
+	switch v := value.(type) {
+	case orderedMap:
+		if len(v) == 0 {
+			b.WriteString(" {}\n")
+			return
+		}
+		b.WriteString("\n")
+		writeYAMLMap(b, v, indent)
+	case []any:
+		if len(v) == 0 {
+			b.WriteString(" []\n")
+			return
+		}
+		b.WriteString("\n")
+		writeYAMLList(b, v, indent)
+	default:
+		b.WriteString(" " + yamlScalar(v) + "\n")
+	}
+}
+
+func yamlKey(k string) string {
+	if k == "" || needsYAMLQuote(k) {
+		return strconv.Quote(k)
+	}
+	return k
+}
+
+func yamlScalar(v any) string {
+	switch t := v.(type) {
+	case nil:
+		return "null"
+	case bool:
+		return strconv.FormatBool(t)
+	case json.Number:
+		return t.String()
+	case string:
+		if t == "" || needsYAMLQuote(t) {
+			return strconv.Quote(t)
+		}
+		return t
+	default:
+		return strconv.Quote(fmt.Sprint(t))
+	}
+}
+
+func needsYAMLQuote(s string) bool {
+	switch strings.ToLower(s) {
+	case "true", "false", "yes", "no", "on", "off", "null", "~", "y", "n":
+		return true
+	}
+	if _, err := strconv.ParseFloat(s, 64); err == nil {
+		return true
+	}
+	if strings.TrimSpace(s) != s {
+		return true
+	}
+	if strings.ContainsAny(s[:1], "-?:,[]{}#&*!|>'\"%@`") {
+		return true
+	}
+	if strings.Contains(s, ": ") || strings.Contains(s, " #") || strings.HasSuffix(s, ":") {
+		return true
+	}
+	for _, r := range s {
+		if r < 0x20 || r == 0x7f {
+			return true
+		}
+	}
+	return false
+}
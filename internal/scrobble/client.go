@@ -0,0 +1,162 @@
+package scrobble
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+)
+
+const (
+	DefaultURL     = "https://api.listenbrainz.org"
+	maxBatch       = 100
+	submitPath     = "/1/submit-listens"
+	submissionName = "blu"
+)
+
+type Options struct {
+	URL           string
+	Token         string
+	ClientVersion string
+	HTTP          *http.Client
+}
+
+// Client speaks the ListenBrainz submit API, which ListenBrainz, Maloja
+// (/apis/listenbrainz) and most self-hosted scrobblers accept.
+type Client struct {
+	url     string
+	token   string
+	version string
+	http    *http.Client
+}
+
+func NewClient(opts Options) (*Client, error) {
+	token := strings.TrimSpace(opts.Token)
+	if token == "" {
+		return nil, errors.New("missing scrobble token")
+	}
+	base := strings.TrimRight(strings.TrimSpace(opts.URL), "/")
+	if base == "" {
+		base = DefaultURL
+	}
+	httpClient := opts.HTTP
+	if httpClient == nil {
+		httpClient = http.DefaultClient
+	}
+	return &Client{url: base, token: token, version: strings.TrimSpace(opts.ClientVersion), http: httpClient}, nil
+}
+
+type submitRequest struct {
+	ListenType string          `json:"listen_type"`
+	Payload    []submitPayload `json:"payload"`
+}
+
+type submitPayload struct {
+	ListenedAt    int64         `json:"listened_at,omitempty"`
+	TrackMetadata trackMetadata `json:"track_metadata"`
+}
+
+type trackMetadata struct {
+	ArtistName     string         `json:"artist_name"`
+	TrackName      string         `json:"track_name"`
+	ReleaseName    string         `json:"release_name,omitempty"`
+	AdditionalInfo additionalInfo `json:"additional_info"`
+}
+
+type additionalInfo struct {
+	DurationMS              int    `json:"duration_ms,omitempty"`
+	MusicService            string `json:"music_service_name,omitempty"`
+	MediaPlayer             string `json:"media_player"`
+	SubmissionClient        string `json:"submission_client"`
+	SubmissionClientVersion string `json:"submission_client_version,omitempty"`
+	Player                  string `json:"player_name,omitempty"`
+}
+
+func (c *Client) Submit(ctx context.Context, listens []Listen) (int, error) {
+	sent := 0
+	for len(listens) > 0 {
+		n := len(listens)
+		if n > maxBatch {
+			n = maxBatch
+		}
+		listenType := "import"
+		if n == 1 {
+			listenType = "single"
+		}
+		if err := c.post(ctx, listenType, listens[:n], true); err != nil {
+			return sent, err
+		}
+		sent += n
+		listens = listens[n:]
+	}
+	return sent, nil
+}
+
+func (c *Client) NowPlaying(ctx context.Context, l Listen) error {
+	return c.post(ctx, "playing_now", []Listen{l}, false)
+}
+
+func (c *Client) post(ctx context.Context, listenType string, listens []Listen, withTime bool) error {
+	body := submitRequest{ListenType: listenType}
+	for _, l := range listens {
+		p := submitPayload{
+			TrackMetadata: trackMetadata{
+				ArtistName:  l.Artist,
+				TrackName:   l.Track,
+				ReleaseName: l.Release,
+				AdditionalInfo: additionalInfo{
+					DurationMS:              l.Duration * 1000,
+					MusicService:            l.Service,
+					MediaPlayer:             "BluOS",
+					SubmissionClient:        submissionName,
+					SubmissionClientVersion: c.version,
+					Player:                  l.Player,
+				},
+			},
+		}
+		if withTime {
+			p.ListenedAt = l.ListenedAt.Unix()
+		}
+		body.Payload = append(body.Payload, p)
+	}
+
+	data, err := json.Marshal(body)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.url+submitPath, bytes.NewReader(data))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Authorization", "Token "+c.token)
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 4096))
+		return &StatusError{Code: resp.StatusCode, Body: strings.TrimSpace(string(msg))}
+	}
+	_, _ = io.Copy(io.Discard, resp.Body)
+	return nil
+}
+
+type StatusError struct {
+	Code int
+	Body string
+}
+
+func (e *StatusError) Error() string {
+	if e.Body == "" {
+		return fmt.Sprintf("scrobble: unexpected status: %d", e.Code)
+	}
+	return fmt.Sprintf("scrobble: unexpected status: %d: %s", e.Code, e.Body)
+}
@@ -0,0 +1,159 @@
+package scrobble
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"sync"
+
+	"github.com/steipete/blucli/internal/fileutil"
+)
+
+func LoadQueue(path string) ([]Listen, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	var listens []Listen
+	if err := json.Unmarshal(data, &listens); err != nil {
+		return nil, err
+	}
+	return listens, nil
+}
+
+// SaveQueue replaces the queue atomically (0600: listens are listening
+// history). Callers that read the queue first should hold its lock, as
+// Scrobbler does.
+func SaveQueue(path string, listens []Listen) error {
+	if len(listens) == 0 {
+		if err := os.Remove(path); err != nil && !errors.Is(err, os.ErrNotExist) {
+			return err
+		}
+		return nil
+	}
+	data, err := json.MarshalIndent(listens, "", "  ")
+	if err != nil {
+		return err
+	}
+	return fileutil.WriteAtomic(path, append(data, '\n'), 0o600)
+}
+
+// Scrobbler submits listens and keeps whatever could not be delivered in an
+// on-disk queue that is retried with the next submission. The queue is
+// read, submitted and saved under its file lock, so concurrent blu
+// processes neither lose nor double-submit listens.
+type Scrobbler struct {
+	client    *Client
+	queuePath string
+	mu        sync.Mutex
+}
+
+func NewScrobbler(client *Client, queuePath string) *Scrobbler {
+	return &Scrobbler{client: client, queuePath: queuePath}
+}
+
+func (s *Scrobbler) Scrobble(ctx context.Context, l Listen) error {
+	_, err := s.submit(ctx, []Listen{l})
+	return err
+}
+
+// Flush submits the queued listens and returns how many were accepted.
+func (s *Scrobbler) Flush(ctx context.Context) (int, error) {
+	s.mu.Lock()
+	queued, err := LoadQueue(s.queuePath)
+	s.mu.Unlock()
+	if err != nil {
+		return 0, err
+	}
+	if len(queued) == 0 {
+		return 0, nil
+	}
+	return s.submit(ctx, nil)
+}
+
+func (s *Scrobbler) NowPlaying(ctx context.Context, l Listen) error {
+	return s.client.NowPlaying(ctx, l)
+}
+
+func (s *Scrobbler) submit(ctx context.Context, listens []Listen) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var (
+		sent      int
+		rejected  []error
+		submitErr error
+		rest      []Listen
+	)
+	err := fileutil.WithLock(s.queuePath, func() error {
+		queued, err := LoadQueue(s.queuePath)
+		if err != nil {
+			return err
+		}
+		pending := append(queued, listens...)
+		if len(pending) == 0 {
+			return nil
+		}
+		sent, rest, rejected, submitErr = s.send(ctx, pending)
+		return SaveQueue(s.queuePath, rest)
+	})
+	if err != nil {
+		return sent, errors.Join(submitErr, err)
+	}
+	var errs []error
+	if len(rejected) > 0 {
+		// The server refused these listens themselves; retrying would fail
+		// forever.
+		errs = append(errs, fmt.Errorf("dropped %d listen(s): %w", len(rejected), rejected[0]))
+	}
+	if submitErr != nil {
+		errs = append(errs, fmt.Errorf("queued %d listen(s): %w", len(rest), submitErr))
+	}
+	return sent, errors.Join(errs...)
+}
+
+// send submits pending and returns what is left to queue after the first
+// retryable failure. A batch the server rejects (400) is resent one listen
+// at a time, so only the listens it refuses are dropped.
+func (s *Scrobbler) send(ctx context.Context, pending []Listen) (sent int, rest []Listen, rejected []error, err error) {
+	for len(pending) > 0 {
+		n, err := s.client.Submit(ctx, pending)
+		sent += n
+		pending = pending[n:]
+		if err == nil {
+			return sent, nil, rejected, nil
+		}
+		if !isRejected(err) {
+			return sent, pending, rejected, err
+		}
+		batch := min(len(pending), maxBatch)
+		if batch == 1 {
+			rejected = append(rejected, err)
+			pending = pending[1:]
+			continue
+		}
+		for i, l := range pending[:batch] {
+			if _, err := s.client.Submit(ctx, []Listen{l}); err != nil {
+				if !isRejected(err) {
+					return sent, pending[i:], rejected, err
+				}
+				rejected = append(rejected, err)
+				continue
+			}
+			sent++
+		}
+		pending = pending[batch:]
+	}
+	return sent, nil, rejected, nil
+}
+
+func isRejected(err error) bool {
+	var se *StatusError
+	return errors.As(err, &se) && se.Code == http.StatusBadRequest
+}
@@ -0,0 +1,79 @@
+package scrobble
+
+import (
+	"strings"
+	"time"
+
+	"github.com/steipete/blucli/internal/history"
+)
+
+const (
+	minTrackLength = 30 * time.Second
+	maxThreshold   = 4 * time.Minute
+)
+
+type Listen struct {
+	ListenedAt time.Time `json:"listened_at"`
+	Artist     string    `json:"artist"`
+	Track      string    `json:"track"`
+	Release    string    `json:"release,omitempty"`
+	Duration   int       `json:"duration_secs,omitempty"`
+	Service    string    `json:"service,omitempty"`
+	Player     string    `json:"player,omitempty"`
+}
+
+func FromEntry(e history.Entry) Listen {
+	player := strings.TrimSpace(e.DeviceName)
+	if player == "" {
+		player = e.Device
+	}
+	return Listen{
+		ListenedAt: e.StartedAt,
+		Artist:     strings.TrimSpace(e.Artist),
+		Track:      strings.TrimSpace(e.Title),
+		Release:    strings.TrimSpace(e.Album),
+		Duration:   e.LengthSeconds,
+		Service:    strings.TrimSpace(e.Service),
+		Player:     player,
+	}
+}
+
+// Standard scrobble rules: the track is longer than 30s and was played for
+// half its length or 4 minutes, whichever comes first. Streams without a
+// length must reach the 4 minute mark.
+func Eligible(e history.Entry) bool {
+	if !HasMetadata(e) {
+		return false
+	}
+	listened := time.Duration(e.ListenedSeconds) * time.Second
+	threshold := maxThreshold
+	if e.LengthSeconds > 0 {
+		length := time.Duration(e.LengthSeconds) * time.Second
+		if length <= minTrackLength {
+			return false
+		}
+		if half := length / 2; half < threshold {
+			threshold = half
+		}
+	}
+	return listened >= threshold
+}
+
+// Radio streams often report the station name or a stream URL as the title
+// and no artist; those are not scrobbled.
+func HasMetadata(e history.Entry) bool {
+	artist := strings.TrimSpace(e.Artist)
+	title := strings.TrimSpace(e.Title)
+	if artist == "" || title == "" {
+		return false
+	}
+	if strings.EqualFold(artist, title) {
+		return false
+	}
+	for _, s := range []string{artist, title} {
+		if strings.Contains(s, "://") {
+			return false
+		}
+	}
+	return true
+}
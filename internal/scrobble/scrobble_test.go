@@ -0,0 +1,271 @@
+package scrobble
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/steipete/blucli/internal/history"
+)
+
+func TestEligible(t *testing.T) {
+	t.Parallel()
+
+	base := history.Entry{Artist: "A", Title: "T"}
+	cases := []struct {
+		name     string
+		length   int
+		listened int
+		want     bool
+	}{
+		{"half played", 200, 100, true},
+		{"under half", 200, 99, false},
+		{"four minutes of a long track", 1200, 240, true},
+		{"long track not enough", 1200, 239, false},
+		{"too short track", 30, 30, false},
+		{"unknown length needs four minutes", 0, 240, true},
+		{"unknown length short", 0, 200, false},
+	}
+	for _, tc := range cases {
+		e := base
+		e.LengthSeconds = tc.length
+		e.ListenedSeconds = tc.listened
+		if got := Eligible(e); got != tc.want {
+			t.Fatalf("%s: Eligible = %v; want %v", tc.name, got, tc.want)
+		}
+	}
+
+	radio := []history.Entry{
+		{Title: "Radio Paradise", ListenedSeconds: 600},
+		{Artist: "FM4", Title: "fm4", ListenedSeconds: 600},
+		{Artist: "A", Title: "http://stream.example/live", ListenedSeconds: 600},
+	}
+	for _, e := range radio {
+		if Eligible(e) {
+			t.Fatalf("Eligible(%+v) = true; want false for radio without metadata", e)
+		}
+	}
+}
+
+type stubServer struct {
+	*httptest.Server
+	mu       sync.Mutex
+	requests []submitRequest
+	fail     atomic.Int32
+	// reject answers 400 for any request carrying this track name.
+	reject string
+}
+
+func newStubServer(t *testing.T) *stubServer {
+	t.Helper()
+	s := &stubServer{}
+	s.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost || r.URL.Path != "/apis/listenbrainz/1/submit-listens" {
+			http.NotFound(w, r)
+			return
+		}
+		if r.Header.Get("Authorization") != "Token secret" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		if code := s.fail.Load(); code != 0 {
+			w.WriteHeader(int(code))
+			_, _ = w.Write([]byte(`{"error":"nope"}`))
+			return
+		}
+		var req submitRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		for _, p := range req.Payload {
+			if s.reject != "" && p.TrackMetadata.TrackName == s.reject {
+				w.WriteHeader(http.StatusBadRequest)
+				_, _ = w.Write([]byte(`{"error":"invalid listen"}`))
+				return
+			}
+		}
+		s.mu.Lock()
+		s.requests = append(s.requests, req)
+		s.mu.Unlock()
+		_, _ = w.Write([]byte(`{"status":"ok"}`))
+	}))
+	t.Cleanup(s.Close)
+	return s
+}
+
+func (s *stubServer) received() []submitRequest {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]submitRequest(nil), s.requests...)
+}
+
+func TestClientSubmitAndNowPlaying(t *testing.T) {
+	t.Parallel()
+
+	srv := newStubServer(t)
+	client, err := NewClient(Options{URL: srv.URL + "/apis/listenbrainz/", Token: " secret ", ClientVersion: "v1"})
+	if err != nil {
+		t.Fatalf("NewClient: %v", err)
+	}
+
+	at := time.Unix(1700000000, 0)
+	l := Listen{ListenedAt: at, Artist: "A", Track: "T", Release: "R", Duration: 200, Service: "Qobuz", Player: "Kitchen"}
+	if n, err := client.Submit(context.Background(), []Listen{l}); err != nil || n != 1 {
+		t.Fatalf("Submit: n=%d err=%v", n, err)
+	}
+	if err := client.NowPlaying(context.Background(), l); err != nil {
+		t.Fatalf("NowPlaying: %v", err)
+	}
+
+	reqs := srv.received()
+	if len(reqs) != 2 {
+		t.Fatalf("requests = %d; want 2", len(reqs))
+	}
+	single := reqs[0]
+	if single.ListenType != "single" || len(single.Payload) != 1 {
+		t.Fatalf("single = %+v", single)
+	}
+	p := single.Payload[0]
+	if p.ListenedAt != at.Unix() || p.TrackMetadata.ArtistName != "A" || p.TrackMetadata.TrackName != "T" || p.TrackMetadata.ReleaseName != "R" {
+		t.Fatalf("payload = %+v", p)
+	}
+	info := p.TrackMetadata.AdditionalInfo
+	if info.DurationMS != 200000 || info.MusicService != "Qobuz" || info.SubmissionClient != "blu" || info.SubmissionClientVersion != "v1" || info.Player != "Kitchen" {
+		t.Fatalf("additional_info = %+v", info)
+	}
+	if reqs[1].ListenType != "playing_now" || reqs[1].Payload[0].ListenedAt != 0 {
+		t.Fatalf("playing_now = %+v", reqs[1])
+	}
+
+	if _, err := NewClient(Options{URL: srv.URL}); err == nil {
+		t.Fatalf("NewClient without token: want error")
+	}
+}
+
+func TestScrobblerQueuesOfflineAndRetries(t *testing.T) {
+	t.Parallel()
+
+	srv := newStubServer(t)
+	client, err := NewClient(Options{URL: srv.URL + "/apis/listenbrainz", Token: "secret"})
+	if err != nil {
+		t.Fatalf("NewClient: %v", err)
+	}
+	queuePath := filepath.Join(t.TempDir(), "queue.json")
+	s := NewScrobbler(client, queuePath)
+
+	srv.fail.Store(http.StatusServiceUnavailable)
+	if err := s.Scrobble(context.Background(), Listen{Artist: "A", Track: "1", ListenedAt: time.Unix(1, 0)}); err == nil || !strings.Contains(err.Error(), "queued 1") {
+		t.Fatalf("Scrobble err = %v; want queued", err)
+	}
+	if err := s.Scrobble(context.Background(), Listen{Artist: "A", Track: "2", ListenedAt: time.Unix(2, 0)}); err == nil {
+		t.Fatalf("Scrobble: want error while offline")
+	}
+	queued, err := LoadQueue(queuePath)
+	if err != nil || len(queued) != 2 {
+		t.Fatalf("queue = %+v err=%v", queued, err)
+	}
+
+	srv.fail.Store(0)
+	if err := s.Scrobble(context.Background(), Listen{Artist: "A", Track: "3", ListenedAt: time.Unix(3, 0)}); err != nil {
+		t.Fatalf("Scrobble: %v", err)
+	}
+	reqs := srv.received()
+	if len(reqs) != 1 || reqs[0].ListenType != "import" || len(reqs[0].Payload) != 3 {
+		t.Fatalf("requests = %+v", reqs)
+	}
+	if queued, _ := LoadQueue(queuePath); len(queued) != 0 {
+		t.Fatalf("queue not cleared: %+v", queued)
+	}
+	if n, err := s.Flush(context.Background()); err != nil || n != 0 {
+		t.Fatalf("Flush empty: n=%d err=%v", n, err)
+	}
+
+	srv.fail.Store(http.StatusBadRequest)
+	if err := s.Scrobble(context.Background(), Listen{Artist: "A", Track: "bad"}); err == nil || !strings.Contains(err.Error(), "dropped") {
+		t.Fatalf("Scrobble err = %v; want dropped", err)
+	}
+	if queued, _ := LoadQueue(queuePath); len(queued) != 0 {
+		t.Fatalf("rejected listen stayed queued: %+v", queued)
+	}
+}
+
+func TestScrobblerDropsOnlyRejectedListen(t *testing.T) {
+	t.Parallel()
+
+	srv := newStubServer(t)
+	srv.reject = "bad"
+	client, err := NewClient(Options{URL: srv.URL + "/apis/listenbrainz", Token: "secret"})
+	if err != nil {
+		t.Fatalf("NewClient: %v", err)
+	}
+	queuePath := filepath.Join(t.TempDir(), "queue.json")
+	backlog := []Listen{
+		{Artist: "A", Track: "1", ListenedAt: time.Unix(1, 0)},
+		{Artist: "A", Track: "bad", ListenedAt: time.Unix(2, 0)},
+		{Artist: "A", Track: "3", ListenedAt: time.Unix(3, 0)},
+	}
+	if err := SaveQueue(queuePath, backlog); err != nil {
+		t.Fatalf("SaveQueue: %v", err)
+	}
+
+	n, err := NewScrobbler(client, queuePath).Flush(context.Background())
+	if err == nil || !strings.Contains(err.Error(), "dropped 1 listen(s)") || strings.Contains(err.Error(), "queued") {
+		t.Fatalf("Flush err = %v; want one dropped", err)
+	}
+	if n != 2 {
+		t.Fatalf("Flush n = %d; want 2", n)
+	}
+	var tracks []string
+	for _, req := range srv.received() {
+		for _, p := range req.Payload {
+			tracks = append(tracks, p.TrackMetadata.TrackName)
+		}
+	}
+	if strings.Join(tracks, ",") != "1,3" {
+		t.Fatalf("accepted tracks = %v; want 1,3", tracks)
+	}
+	if queued, _ := LoadQueue(queuePath); len(queued) != 0 {
+		t.Fatalf("queue = %+v; want empty", queued)
+	}
+}
+
+func TestScrobblerQueueSurvivesConcurrentWriters(t *testing.T) {
+	t.Parallel()
+
+	srv := newStubServer(t)
+	srv.fail.Store(http.StatusServiceUnavailable)
+	client, err := NewClient(Options{URL: srv.URL + "/apis/listenbrainz", Token: "secret"})
+	if err != nil {
+		t.Fatalf("NewClient: %v", err)
+	}
+	queuePath := filepath.Join(t.TempDir(), "queue.json")
+
+	// Separate Scrobblers stand in for separate blu processes.
+	var wg sync.WaitGroup
+	for i := range 8 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_ = NewScrobbler(client, queuePath).Scrobble(context.Background(), Listen{Artist: "A", Track: strconv.Itoa(i)})
+		}()
+	}
+	wg.Wait()
+
+	if queued, err := LoadQueue(queuePath); err != nil || len(queued) != 8 {
+		t.Fatalf("queue = %d listens, err=%v; want 8", len(queued), err)
+	}
+	if info, err := os.Stat(queuePath); err != nil || (runtime.GOOS != "windows" && info.Mode().Perm() != 0o600) {
+		t.Fatalf("queue file = %v, %v; want mode 0600", info, err)
+	}
+}
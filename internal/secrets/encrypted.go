@@ -0,0 +1,158 @@
+package secrets
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/pbkdf2"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+
+	"github.com/steipete/blucli/internal/fileutil"
+)
+
+// pbkdf2Iterations follows the OWASP recommendation for PBKDF2-HMAC-SHA256.
+var pbkdf2Iterations = 600_000
+
+type encryptedFile struct {
+	Version    int    `json:"version"`
+	KDF        string `json:"kdf"`
+	Iterations int    `json:"iterations"`
+	Salt       []byte `json:"salt"`
+	Nonce      []byte `json:"nonce"`
+	Data       []byte `json:"data"`
+}
+
+// EncryptedStore keeps secrets in an AES-256-GCM encrypted 0600 file keyed
+// by a passphrase, for machines without a keyring (headless Linux).
+type EncryptedStore struct {
+	path       string
+	passphrase string
+}
+
+func NewEncryptedStore(path, passphrase string) *EncryptedStore {
+	return &EncryptedStore{path: path, passphrase: passphrase}
+}
+
+func (e *EncryptedStore) Name() string { return BackendEncrypted + " (" + e.path + ")" }
+
+func (e *EncryptedStore) Get(key string) (string, error) {
+	m, err := e.load()
+	if err != nil {
+		return "", err
+	}
+	v, ok := m[key]
+	if !ok {
+		return "", ErrNotFound
+	}
+	return v, nil
+}
+
+func (e *EncryptedStore) Set(key, value string) error {
+	return fileutil.WithLock(e.path, func() error {
+		m, err := e.load()
+		if err != nil {
+			return err
+		}
+		m[key] = value
+		return e.save(m)
+	})
+}
+
+func (e *EncryptedStore) Delete(key string) error {
+	return fileutil.WithLock(e.path, func() error {
+		m, err := e.load()
+		if err != nil {
+			return err
+		}
+		if _, ok := m[key]; !ok {
+			return ErrNotFound
+		}
+		delete(m, key)
+		if len(m) == 0 {
+			return e.remove()
+		}
+		return e.save(m)
+	})
+}
+
+func (e *EncryptedStore) remove() error {
+	if err := os.Remove(e.path); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+	return nil
+}
+
+func (e *EncryptedStore) load() (map[string]string, error) {
+	data, err := os.ReadFile(e.path)
+	if errors.Is(err, os.ErrNotExist) {
+		return map[string]string{}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var f encryptedFile
+	if err := json.Unmarshal(data, &f); err != nil {
+		return nil, fmt.Errorf("%s: %w", e.path, err)
+	}
+	if f.Version != 1 || f.KDF != "pbkdf2-sha256" {
+		return nil, fmt.Errorf("%s: unsupported format (version %d, kdf %q)", e.path, f.Version, f.KDF)
+	}
+	gcm, err := e.cipher(f.Salt, f.Iterations)
+	if err != nil {
+		return nil, err
+	}
+	plain, err := gcm.Open(nil, f.Nonce, f.Data, nil)
+	if err != nil {
+		return nil, fmt.Errorf("%s: wrong passphrase or corrupted file", e.path)
+	}
+	m := map[string]string{}
+	if err := json.Unmarshal(plain, &m); err != nil {
+		return nil, fmt.Errorf("%s: %w", e.path, err)
+	}
+	return m, nil
+}
+
+func (e *EncryptedStore) save(m map[string]string) error {
+	plain, err := json.Marshal(m)
+	if err != nil {
+		return err
+	}
+	f := encryptedFile{Version: 1, KDF: "pbkdf2-sha256", Iterations: pbkdf2Iterations, Salt: make([]byte, 16)}
+	if _, err := rand.Read(f.Salt); err != nil {
+		return err
+	}
+	gcm, err := e.cipher(f.Salt, f.Iterations)
+	if err != nil {
+		return err
+	}
+	f.Nonce = make([]byte, gcm.NonceSize())
+	if _, err := rand.Read(f.Nonce); err != nil {
+		return err
+	}
+	f.Data = gcm.Seal(nil, f.Nonce, plain, nil)
+
+	data, err := json.MarshalIndent(f, "", "  ")
+	if err != nil {
+		return err
+	}
+	return writePrivate(e.path, append(data, '\n'))
+}
+
+func (e *EncryptedStore) cipher(salt []byte, iterations int) (cipher.AEAD, error) {
+	if e.passphrase == "" {
+		return nil, errors.New("missing passphrase (set BLU_SECRETS_PASSPHRASE)")
+	}
+	key, err := pbkdf2.Key(sha256.New, e.passphrase, salt, iterations, 32)
+	if err != nil {
+		return nil, err
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
@@ -0,0 +1,90 @@
+package secrets
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+
+	"github.com/steipete/blucli/internal/fileutil"
+)
+
+// FileStore keeps secrets as a JSON object in a 0600 file.
+type FileStore struct {
+	path string
+}
+
+func NewFileStore(path string) *FileStore { return &FileStore{path: path} }
+
+func (f *FileStore) Name() string { return BackendFile + " (" + f.path + ")" }
+
+func (f *FileStore) Get(key string) (string, error) {
+	m, err := f.load()
+	if err != nil {
+		return "", err
+	}
+	v, ok := m[key]
+	if !ok {
+		return "", ErrNotFound
+	}
+	return v, nil
+}
+
+func (f *FileStore) Set(key, value string) error {
+	return fileutil.WithLock(f.path, func() error {
+		m, err := f.load()
+		if err != nil {
+			return err
+		}
+		m[key] = value
+		return f.save(m)
+	})
+}
+
+func (f *FileStore) Delete(key string) error {
+	return fileutil.WithLock(f.path, func() error {
+		m, err := f.load()
+		if err != nil {
+			return err
+		}
+		if _, ok := m[key]; !ok {
+			return ErrNotFound
+		}
+		delete(m, key)
+		if len(m) == 0 {
+			return os.Remove(f.path)
+		}
+		return f.save(m)
+	})
+}
+
+func (f *FileStore) load() (map[string]string, error) {
+	data, err := os.ReadFile(f.path)
+	if errors.Is(err, os.ErrNotExist) {
+		return map[string]string{}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	m := map[string]string{}
+	if err := json.Unmarshal(data, &m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+func (f *FileStore) save(m map[string]string) error {
+	data, err := json.MarshalIndent(m, "", "  ")
+	if err != nil {
+		return err
+	}
+	return writePrivate(f.path, append(data, '\n'))
+}
+
+// writePrivate atomically replaces path with a 0600 file.
+func writePrivate(path string, data []byte) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+		return err
+	}
+	return fileutil.WriteAtomic(path, data, 0o600)
+}
@@ -0,0 +1,145 @@
+package secrets
+
+import (
+	"bytes"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"runtime"
+	"strings"
+)
+
+// Keyring stores secrets in the macOS Keychain (security) or a Secret
+// Service keyring such as GNOME Keyring/KWallet (secret-tool). Both tools
+// read the secret from stdin so it never shows up in argv.
+type Keyring struct {
+	service string
+	scope   string
+	goos    string
+	getenv  func(string) string
+	look    func(string) (string, error)
+	run     func(stdin, name string, args ...string) (string, error)
+}
+
+// NewKeyring scopes items to a config directory (the account is
+// "<key>@<scope>") so separate --config setups do not share credentials.
+func NewKeyring(service, scope string) *Keyring {
+	return &Keyring{service: service, scope: scope, goos: runtime.GOOS, getenv: os.Getenv, look: exec.LookPath, run: runCommand}
+}
+
+func (k *Keyring) account(key string) string {
+	if k.scope == "" {
+		return key
+	}
+	return key + "@" + k.scope
+}
+
+func (k *Keyring) Name() string {
+	if k.goos == "darwin" {
+		return BackendKeyring + " (macOS Keychain)"
+	}
+	return BackendKeyring + " (Secret Service)"
+}
+
+// Available reports why the keyring cannot be used, or nil.
+func (k *Keyring) Available() error {
+	switch k.goos {
+	case "darwin":
+		if _, err := k.look("security"); err != nil {
+			return errors.New("keyring: security(1) not found")
+		}
+		return nil
+	case "linux", "freebsd", "openbsd", "netbsd":
+		if _, err := k.look("secret-tool"); err != nil {
+			return errors.New("keyring: secret-tool not found (install libsecret-tools, or use the encrypted backend)")
+		}
+		if k.getenv("DBUS_SESSION_BUS_ADDRESS") == "" {
+			return errors.New("keyring: no D-Bus session (headless? use the encrypted backend)")
+		}
+		return nil
+	}
+	return fmt.Errorf("keyring: not supported on %s", k.goos)
+}
+
+func (k *Keyring) Get(key string) (string, error) {
+	if k.goos == "darwin" {
+		out, err := k.run("", "security", "find-generic-password", "-s", k.service, "-a", k.account(key), "-w")
+		if err != nil {
+			if isExit(err, 44) {
+				return "", ErrNotFound
+			}
+			return "", fmt.Errorf("keyring: %w", err)
+		}
+		return strings.TrimSuffix(out, "\n"), nil
+	}
+	out, err := k.run("", "secret-tool", "lookup", "service", k.service, "account", k.account(key))
+	if err != nil {
+		// secret-tool exits 1 with no output for a missing item.
+		if isExit(err, 1) && out == "" {
+			return "", ErrNotFound
+		}
+		return "", fmt.Errorf("keyring: %w", err)
+	}
+	if out == "" {
+		return "", ErrNotFound
+	}
+	return out, nil
+}
+
+func (k *Keyring) Set(key, value string) error {
+	var err error
+	if k.goos == "darwin" {
+		// -X takes the password hex-encoded; `security -i` reads the command
+		// from stdin.
+		cmd := fmt.Sprintf("add-generic-password -U -s %s -a %s -X %s\n", quoteArg(k.service), quoteArg(k.account(key)), hex.EncodeToString([]byte(value)))
+		_, err = k.run(cmd, "security", "-i")
+	} else {
+		_, err = k.run(value, "secret-tool", "store", "--label", k.service+" "+key, "service", k.service, "account", k.account(key))
+	}
+	if err != nil {
+		return fmt.Errorf("keyring: %w", err)
+	}
+	return nil
+}
+
+func (k *Keyring) Delete(key string) error {
+	var err error
+	if k.goos == "darwin" {
+		_, err = k.run("", "security", "delete-generic-password", "-s", k.service, "-a", k.account(key))
+		if isExit(err, 44) {
+			return ErrNotFound
+		}
+	} else {
+		_, err = k.run("", "secret-tool", "clear", "service", k.service, "account", k.account(key))
+	}
+	if err != nil {
+		return fmt.Errorf("keyring: %w", err)
+	}
+	return nil
+}
+
+func runCommand(stdin, name string, args ...string) (string, error) {
+	cmd := exec.Command(name, args...)
+	cmd.Stdin = strings.NewReader(stdin)
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout, cmd.Stderr = &stdout, &stderr
+	if err := cmd.Run(); err != nil {
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return stdout.String(), fmt.Errorf("%s: %w: %s", name, err, msg)
+		}
+		return stdout.String(), fmt.Errorf("%s: %w", name, err)
+	}
+	return stdout.String(), nil
+}
+
+// quoteArg quotes for `security -i`, which splits its input like a shell.
+func quoteArg(s string) string {
+	return `"` + strings.NewReplacer(`\`, `\\`, `"`, `\"`).Replace(s) + `"`
+}
+
+func isExit(err error, code int) bool {
+	var exitErr *exec.ExitError
+	return errors.As(err, &exitErr) && exitErr.ExitCode() == code
+}
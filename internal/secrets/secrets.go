@@ -0,0 +1,95 @@
+// Package secrets stores credentials (e.g. the Spotify token) apart from
+// config.json: a 0600 file by default, the OS keyring, or a passphrase
+// encrypted file for headless machines.
+package secrets
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+var ErrNotFound = errors.New("secret not found")
+
+type Store interface {
+	Name() string
+	Get(key string) (string, error)
+	Set(key, value string) error
+	Delete(key string) error
+}
+
+const (
+	BackendFile      = "file"
+	BackendKeyring   = "keyring"
+	BackendEncrypted = "encrypted"
+)
+
+var Backends = []string{BackendFile, BackendKeyring, BackendEncrypted}
+
+const (
+	FileName          = "secrets.json"
+	EncryptedFileName = "secrets.enc"
+	keyringService    = "blu"
+)
+
+type Options struct {
+	Backend string
+	// Dir holds the file backends, normally next to config.json.
+	Dir        string
+	Passphrase string
+}
+
+func Open(opts Options) (Store, error) {
+	switch strings.ToLower(strings.TrimSpace(opts.Backend)) {
+	case "", BackendFile:
+		return NewFileStore(filepath.Join(opts.Dir, FileName)), nil
+	case BackendKeyring:
+		k := NewKeyring(keyringService, scope(opts.Dir))
+		if err := k.Available(); err != nil {
+			return nil, err
+		}
+		return k, nil
+	case BackendEncrypted:
+		if opts.Passphrase == "" {
+			return nil, errors.New("encrypted secrets need BLU_SECRETS_PASSPHRASE")
+		}
+		return NewEncryptedStore(filepath.Join(opts.Dir, EncryptedFileName), opts.Passphrase), nil
+	}
+	return nil, fmt.Errorf("unknown secrets backend %q (want %s)", opts.Backend, strings.Join(Backends, ", "))
+}
+
+// Scrub deletes key from every backend that may hold it, so switching
+// backends never strands a credential. An encrypted file is left alone
+// without the passphrase (it may hold other keys) and reported instead.
+func Scrub(opts Options, key string) error {
+	var errs []error
+	if err := NewFileStore(filepath.Join(opts.Dir, FileName)).Delete(key); err != nil && !errors.Is(err, ErrNotFound) {
+		errs = append(errs, err)
+	}
+
+	enc := NewEncryptedStore(filepath.Join(opts.Dir, EncryptedFileName), opts.Passphrase)
+	if opts.Passphrase != "" {
+		if err := enc.Delete(key); err != nil && !errors.Is(err, ErrNotFound) {
+			errs = append(errs, err)
+		}
+	} else if _, err := os.Stat(enc.path); err == nil {
+		errs = append(errs, fmt.Errorf("%s: removing %q needs BLU_SECRETS_PASSPHRASE", enc.path, key))
+	}
+
+	k := NewKeyring(keyringService, scope(opts.Dir))
+	if k.Available() == nil {
+		if err := k.Delete(key); err != nil && !errors.Is(err, ErrNotFound) {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+func scope(dir string) string {
+	if abs, err := filepath.Abs(dir); err == nil {
+		return abs
+	}
+	return dir
+}
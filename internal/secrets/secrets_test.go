@@ -0,0 +1,204 @@
+package secrets
+
+import (
+	"errors"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func TestFileStore(t *testing.T) {
+	t.Parallel()
+
+	path := filepath.Join(t.TempDir(), "secrets.json")
+	if err := os.WriteFile(path, []byte("{}\n"), 0o644); err != nil {
+		t.Fatalf("write: %v", err)
+	}
+	s := NewFileStore(path)
+	if _, err := s.Get("k"); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("Get missing err = %v", err)
+	}
+	if err := s.Set("k", "v"); err != nil {
+		t.Fatalf("Set: %v", err)
+	}
+	if v, err := s.Get("k"); err != nil || v != "v" {
+		t.Fatalf("Get = %q, %v", v, err)
+	}
+	if info, _ := os.Stat(path); runtime.GOOS != "windows" && info.Mode().Perm() != 0o600 {
+		t.Fatalf("mode = %v; want 0600", info.Mode().Perm())
+	}
+	if err := s.Delete("k"); err != nil {
+		t.Fatalf("Delete: %v", err)
+	}
+	if _, err := os.Stat(path); !os.IsNotExist(err) {
+		t.Fatalf("empty store not removed: %v", err)
+	}
+}
+
+func TestEncryptedStore(t *testing.T) {
+	pbkdf2Iterations = 1000
+
+	path := filepath.Join(t.TempDir(), "secrets.enc")
+	s := NewEncryptedStore(path, "hunter2")
+	if err := s.Set("spotify.token", `{"access_token":"AT"}`); err != nil {
+		t.Fatalf("Set: %v", err)
+	}
+	data, _ := os.ReadFile(path)
+	if strings.Contains(string(data), "AT") || !strings.Contains(string(data), `"kdf": "pbkdf2-sha256"`) {
+		t.Fatalf("file = %s", data)
+	}
+	if v, err := NewEncryptedStore(path, "hunter2").Get("spotify.token"); err != nil || v != `{"access_token":"AT"}` {
+		t.Fatalf("Get = %q, %v", v, err)
+	}
+	if _, err := NewEncryptedStore(path, "wrong").Get("spotify.token"); err == nil || !strings.Contains(err.Error(), "wrong passphrase") {
+		t.Fatalf("wrong passphrase err = %v", err)
+	}
+
+}
+
+func TestScrubEncryptedKeepsOtherKeys(t *testing.T) {
+	pbkdf2Iterations = 1000
+
+	dir := t.TempDir()
+	path := filepath.Join(dir, EncryptedFileName)
+	s := NewEncryptedStore(path, "hunter2")
+	if err := s.Set("spotify.token", "S"); err != nil {
+		t.Fatalf("Set: %v", err)
+	}
+	if err := s.Set("scrobble.token", "L"); err != nil {
+		t.Fatalf("Set: %v", err)
+	}
+
+	// Without the passphrase the file cannot be edited, so it is kept.
+	if err := Scrub(Options{Dir: dir}, "spotify.token"); err == nil || !strings.Contains(err.Error(), "needs BLU_SECRETS_PASSPHRASE") {
+		t.Fatalf("Scrub without passphrase err = %v", err)
+	}
+	if v, err := s.Get("spotify.token"); err != nil || v != "S" {
+		t.Fatalf("spotify.token = %q, %v", v, err)
+	}
+
+	if err := Scrub(Options{Dir: dir, Passphrase: "hunter2"}, "spotify.token"); err != nil {
+		t.Fatalf("Scrub: %v", err)
+	}
+	if _, err := s.Get("spotify.token"); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("spotify.token after Scrub err = %v", err)
+	}
+	if v, err := s.Get("scrobble.token"); err != nil || v != "L" {
+		t.Fatalf("scrobble.token = %q, %v", v, err)
+	}
+}
+
+type fakeCall struct {
+	stdin string
+	argv  string
+}
+
+func fakeKeyring(goos string, items map[string]string, calls *[]fakeCall) *Keyring {
+	k := NewKeyring("blu", "/cfg")
+	k.goos = goos
+	k.getenv = func(string) string { return "unix:path=/bus" }
+	k.look = func(name string) (string, error) { return "/usr/bin/" + name, nil }
+	k.run = func(stdin, name string, args ...string) (string, error) {
+		*calls = append(*calls, fakeCall{stdin: stdin, argv: name + " " + strings.Join(args, " ")})
+		account := args[len(args)-1]
+		switch {
+		case name == "security" && args[0] == "-i":
+			fields := strings.Fields(stdin)
+			items[strings.Trim(fields[5], `"`)] = fields[7]
+		case name == "security" && args[0] == "find-generic-password":
+			account = args[4]
+			if v, ok := items[account]; ok {
+				return v + "\n", nil
+			}
+			return "", exitError(44)
+		case name == "secret-tool" && args[0] == "store":
+			items[account] = stdin
+		case name == "secret-tool" && args[0] == "lookup":
+			if v, ok := items[account]; ok {
+				return v, nil
+			}
+			return "", exitError(1)
+		case name == "secret-tool" && args[0] == "clear":
+			delete(items, account)
+		}
+		return "", nil
+	}
+	return k
+}
+
+// exitError produces a real *exec.ExitError with the given code.
+func exitError(code int) error {
+	return exec.Command("sh", "-c", "exit "+strconv.Itoa(code)).Run()
+}
+
+func TestKeyringSecretTool(t *testing.T) {
+	t.Parallel()
+	if runtime.GOOS == "windows" {
+		t.Skip("needs sh for exit codes")
+	}
+
+	items := map[string]string{}
+	var calls []fakeCall
+	k := fakeKeyring("linux", items, &calls)
+
+	if _, err := k.Get("spotify.token"); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("Get missing err = %v", err)
+	}
+	if err := k.Set("spotify.token", "secret"); err != nil {
+		t.Fatalf("Set: %v", err)
+	}
+	if v, err := k.Get("spotify.token"); err != nil || v != "secret" {
+		t.Fatalf("Get = %q, %v", v, err)
+	}
+	for _, c := range calls {
+		if strings.Contains(c.argv, "secret") && !strings.Contains(c.argv, "secret-tool") {
+			t.Fatalf("secret leaked into argv: %q", c.argv)
+		}
+	}
+	if got := calls[1].argv; got != "secret-tool store --label blu spotify.token service blu account spotify.token@/cfg" {
+		t.Fatalf("store argv = %q", got)
+	}
+	if err := k.Delete("spotify.token"); err != nil || len(items) != 0 {
+		t.Fatalf("Delete: %v items=%v", err, items)
+	}
+}
+
+func TestKeyringMacOS(t *testing.T) {
+	t.Parallel()
+	if runtime.GOOS == "windows" {
+		t.Skip("needs sh for exit codes")
+	}
+
+	items := map[string]string{}
+	var calls []fakeCall
+	k := fakeKeyring("darwin", items, &calls)
+
+	if err := k.Set("spotify.token", "s3cr3t"); err != nil {
+		t.Fatalf("Set: %v", err)
+	}
+	if calls[0].argv != "security -i" || calls[0].stdin != "add-generic-password -U -s \"blu\" -a \"spotify.token@/cfg\" -X 733363723374\n" {
+		t.Fatalf("call = %+v", calls[0])
+	}
+	if _, err := k.Get("other"); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("Get missing err = %v", err)
+	}
+}
+
+func TestOpen(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	if s, err := Open(Options{Dir: dir}); err != nil || !strings.HasPrefix(s.Name(), "file (") {
+		t.Fatalf("Open default = %v, %v", s, err)
+	}
+	if _, err := Open(Options{Backend: "encrypted", Dir: dir}); err == nil || !strings.Contains(err.Error(), "BLU_SECRETS_PASSPHRASE") {
+		t.Fatalf("encrypted without passphrase err = %v", err)
+	}
+	if _, err := Open(Options{Backend: "vault", Dir: dir}); err == nil {
+		t.Fatalf("unknown backend: want error")
+	}
+}
@@ -0,0 +1,185 @@
+package termimg
+
+import (
+	"bufio"
+	"encoding/base64"
+	"fmt"
+	"image"
+	"image/color"
+	"io"
+	"strings"
+)
+
+const kittyChunk = 4096
+
+// writeKitty transmits a PNG with the kitty graphics protocol, split into
+// base64 chunks of at most 4096 bytes as the protocol requires.
+func writeKitty(w io.Writer, pngData []byte, cols int) error {
+	payload := base64.StdEncoding.EncodeToString(pngData)
+	bw := bufio.NewWriter(w)
+	first := true
+	for len(payload) > 0 {
+		n := min(kittyChunk, len(payload))
+		chunk := payload[:n]
+		payload = payload[n:]
+		more := 0
+		if len(payload) > 0 {
+			more = 1
+		}
+		if first {
+			fmt.Fprintf(bw, "\x1b_Ga=T,f=100,c=%d,m=%d;%s\x1b\\", cols, more, chunk)
+			first = false
+		} else {
+			fmt.Fprintf(bw, "\x1b_Gm=%d;%s\x1b\\", more, chunk)
+		}
+	}
+	bw.WriteString("\n")
+	return bw.Flush()
+}
+
+// writeITerm uses the iTerm2 inline image escape (also understood by WezTerm);
+// the terminal decodes the original bytes itself.
+func writeITerm(w io.Writer, data []byte, cols int) error {
+	_, err := fmt.Fprintf(w, "\x1b]1337;File=inline=1;size=%d;width=%d;preserveAspectRatio=1:%s\a\n",
+		len(data), cols, base64.StdEncoding.EncodeToString(data))
+	return err
+}
+
+// writeBlocks draws two pixels per cell with "▀": the foreground colors the
+// upper half, the background the lower one.
+func writeBlocks(w io.Writer, img image.Image, cols int) error {
+	rows := rowsFor(img.Bounds(), cols)
+	px := scale(img, cols, rows*2)
+	bw := bufio.NewWriter(w)
+	for y := 0; y < rows; y++ {
+		var fg, bg color.RGBA
+		for x := 0; x < cols; x++ {
+			top, bottom := px[2*y][x], px[2*y+1][x]
+			if x == 0 || top != fg {
+				fmt.Fprintf(bw, "\x1b[38;2;%d;%d;%dm", top.R, top.G, top.B)
+			}
+			if x == 0 || bottom != bg {
+				fmt.Fprintf(bw, "\x1b[48;2;%d;%d;%dm", bottom.R, bottom.G, bottom.B)
+			}
+			fg, bg = top, bottom
+			bw.WriteString("▀")
+		}
+		bw.WriteString("\x1b[0m\n")
+	}
+	return bw.Flush()
+}
+
+// sixelCellWidth is the assumed pixel width of a cell when sizing sixel
+// output, which is addressed in pixels rather than cells.
+const sixelCellWidth = 10
+
+// writeSixel quantizes to a 6x6x6 color cube and emits one band per six
+// pixel rows, run-length encoded per color.
+func writeSixel(w io.Writer, img image.Image, cols int) error {
+	width := cols * sixelCellWidth
+	b := img.Bounds()
+	height := max(1, width*b.Dy()/max(1, b.Dx()))
+	px := scale(img, width, height)
+
+	idx := make([][]uint8, height)
+	var used [216]bool
+	for y := range px {
+		idx[y] = make([]uint8, width)
+		for x, c := range px[y] {
+			i := cubeIndex(c)
+			idx[y][x] = i
+			used[i] = true
+		}
+	}
+
+	bw := bufio.NewWriter(w)
+	fmt.Fprintf(bw, "\x1bPq\"1;1;%d;%d", width, height)
+	for i, ok := range used {
+		if ok {
+			r, g, bl := i/36, (i/6)%6, i%6
+			fmt.Fprintf(bw, "#%d;2;%d;%d;%d", i, r*20, g*20, bl*20)
+		}
+	}
+
+	bits := make([]byte, width)
+	for top := 0; top < height; top += 6 {
+		var present [216]bool
+		for y := top; y < min(top+6, height); y++ {
+			for _, i := range idx[y] {
+				present[i] = true
+			}
+		}
+		firstColor := true
+		for c, ok := range present {
+			if !ok {
+				continue
+			}
+			for x := range bits {
+				var v byte
+				for dy := 0; dy < 6 && top+dy < height; dy++ {
+					if int(idx[top+dy][x]) == c {
+						v |= 1 << dy
+					}
+				}
+				bits[x] = v
+			}
+			if !firstColor {
+				bw.WriteByte('$')
+			}
+			firstColor = false
+			fmt.Fprintf(bw, "#%d", c)
+			writeSixelRuns(bw, bits)
+		}
+		bw.WriteByte('-')
+	}
+	bw.WriteString("\x1b\\\n")
+	return bw.Flush()
+}
+
+func writeSixelRuns(bw *bufio.Writer, bits []byte) {
+	for x := 0; x < len(bits); {
+		n := 1
+		for x+n < len(bits) && bits[x+n] == bits[x] {
+			n++
+		}
+		ch := string(rune('?' + bits[x]))
+		if n > 3 {
+			fmt.Fprintf(bw, "!%d%s", n, ch)
+		} else {
+			bw.WriteString(strings.Repeat(ch, n))
+		}
+		x += n
+	}
+}
+
+func cubeIndex(c color.RGBA) uint8 {
+	level := func(v uint8) int { return (int(v)*5 + 127) / 255 }
+	return uint8(level(c.R)*36 + level(c.G)*6 + level(c.B))
+}
+
+// scale box-averages img down (or nearest-samples it up) to w x h pixels.
+func scale(img image.Image, w, h int) [][]color.RGBA {
+	b := img.Bounds()
+	out := make([][]color.RGBA, h)
+	for y := 0; y < h; y++ {
+		out[y] = make([]color.RGBA, w)
+		y0 := b.Min.Y + y*b.Dy()/h
+		y1 := max(y0+1, b.Min.Y+(y+1)*b.Dy()/h)
+		for x := 0; x < w; x++ {
+			x0 := b.Min.X + x*b.Dx()/w
+			x1 := max(x0+1, b.Min.X+(x+1)*b.Dx()/w)
+			var r, g, bl, n uint64
+			for sy := y0; sy < y1; sy++ {
+				for sx := x0; sx < x1; sx++ {
+					cr, cg, cb, _ := img.At(sx, sy).RGBA()
+					r += uint64(cr)
+					g += uint64(cg)
+					bl += uint64(cb)
+					n++
+				}
+			}
+			out[y][x] = color.RGBA{R: uint8(r / n >> 8), G: uint8(g / n >> 8), B: uint8(bl / n >> 8), A: 0xff}
+		}
+	}
+	return out
+}
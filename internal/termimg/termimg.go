@@ -0,0 +1,126 @@
+// Package termimg renders images inline in terminals using the kitty graphics
+// protocol, iTerm2 inline images, sixel, or ANSI half-blocks as a fallback.
+package termimg
+
+import (
+	"bytes"
+	"fmt"
+	"image"
+	_ "image/gif"
+	_ "image/jpeg"
+	"image/png"
+	"io"
+	"strings"
+)
+
+type Protocol string
+
+const (
+	Auto   Protocol = "auto"
+	Kitty  Protocol = "kitty"
+	ITerm  Protocol = "iterm"
+	Sixel  Protocol = "sixel"
+	Blocks Protocol = "blocks"
+)
+
+var Protocols = []Protocol{Auto, Kitty, ITerm, Sixel, Blocks}
+
+func ParseProtocol(s string) (Protocol, error) {
+	switch p := Protocol(strings.ToLower(strings.TrimSpace(s))); p {
+	case "":
+		return Auto, nil
+	case Auto, Kitty, ITerm, Sixel, Blocks:
+		return p, nil
+	case "iterm2":
+		return ITerm, nil
+	case "ansi", "halfblocks":
+		return Blocks, nil
+	}
+	names := make([]string, 0, len(Protocols))
+	for _, p := range Protocols {
+		names = append(names, string(p))
+	}
+	return "", fmt.Errorf("unknown protocol %q (want %s)", s, strings.Join(names, ", "))
+}
+
+// Detect picks a protocol from the terminal environment. Terminals that do
+// not advertise themselves get half-blocks, which work anywhere with 24-bit
+// color.
+func Detect(getenv func(string) string) Protocol {
+	term := strings.ToLower(getenv("TERM"))
+	switch getenv("TERM_PROGRAM") {
+	case "iTerm.app", "WezTerm":
+		return ITerm
+	case "ghostty":
+		return Kitty
+	}
+	if getenv("KITTY_WINDOW_ID") != "" || strings.Contains(term, "kitty") || strings.Contains(term, "ghostty") {
+		return Kitty
+	}
+	if getenv("LC_TERMINAL") == "iTerm2" {
+		return ITerm
+	}
+	for _, t := range []string{"foot", "mlterm", "contour", "sixel"} {
+		if strings.Contains(term, t) {
+			return Sixel
+		}
+	}
+	return Blocks
+}
+
+type Options struct {
+	Protocol Protocol
+	// Columns is the rendered width in terminal cells.
+	Columns int
+}
+
+const DefaultColumns = 32
+
+// Render writes data (PNG, JPEG or GIF) to w followed by a newline.
+// Protocol must be resolved; Auto is treated as Blocks.
+func Render(w io.Writer, data []byte, opts Options) error {
+	cols := opts.Columns
+	if cols <= 0 {
+		cols = DefaultColumns
+	}
+
+	switch opts.Protocol {
+	case ITerm:
+		if _, _, err := image.DecodeConfig(bytes.NewReader(data)); err != nil {
+			return fmt.Errorf("decode image: %w", err)
+		}
+		return writeITerm(w, data, cols)
+	}
+
+	img, format, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return fmt.Errorf("decode image: %w", err)
+	}
+
+	switch opts.Protocol {
+	case Kitty:
+		if format != "png" {
+			var buf bytes.Buffer
+			if err := png.Encode(&buf, img); err != nil {
+				return err
+			}
+			data = buf.Bytes()
+		}
+		return writeKitty(w, data, cols)
+	case Sixel:
+		return writeSixel(w, img, cols)
+	default:
+		return writeBlocks(w, img, cols)
+	}
+}
+
+// cellAspect is the assumed height/width ratio of a terminal cell.
+const cellAspect = 2
+
+// rowsFor is the number of cell rows an image spans at cols columns.
+func rowsFor(b image.Rectangle, cols int) int {
+	if b.Dx() == 0 {
+		return 1
+	}
+	return max(1, (b.Dy()*cols+b.Dx()*cellAspect/2)/(b.Dx()*cellAspect))
+}
@@ -0,0 +1,150 @@
+package termimg
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"image/png"
+	"strings"
+	"testing"
+)
+
+func testPNG(t *testing.T, w, h int) []byte {
+	t.Helper()
+	img := image.NewRGBA(image.Rect(0, 0, w, h))
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			img.Set(x, y, color.RGBA{R: uint8(x * 255 / max(1, w-1)), G: uint8(y * 255 / max(1, h-1)), B: 0x40, A: 0xff})
+		}
+	}
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		t.Fatalf("encode: %v", err)
+	}
+	return buf.Bytes()
+}
+
+func TestDetect(t *testing.T) {
+	t.Parallel()
+
+	cases := []struct {
+		env  map[string]string
+		want Protocol
+	}{
+		{map[string]string{"TERM_PROGRAM": "iTerm.app"}, ITerm},
+		{map[string]string{"TERM_PROGRAM": "WezTerm"}, ITerm},
+		{map[string]string{"TERM": "xterm-kitty"}, Kitty},
+		{map[string]string{"TERM": "xterm-256color", "KITTY_WINDOW_ID": "1"}, Kitty},
+		{map[string]string{"TERM_PROGRAM": "ghostty"}, Kitty},
+		{map[string]string{"TERM": "foot"}, Sixel},
+		{map[string]string{"TERM": "xterm-256color"}, Blocks},
+		{map[string]string{}, Blocks},
+	}
+	for _, tc := range cases {
+		if got := Detect(func(k string) string { return tc.env[k] }); got != tc.want {
+			t.Fatalf("Detect(%v) = %q; want %q", tc.env, got, tc.want)
+		}
+	}
+}
+
+func TestParseProtocol(t *testing.T) {
+	t.Parallel()
+
+	for in, want := range map[string]Protocol{"": Auto, "Kitty": Kitty, "iterm2": ITerm, "sixel": Sixel, "ansi": Blocks} {
+		got, err := ParseProtocol(in)
+		if err != nil || got != want {
+			t.Fatalf("ParseProtocol(%q) = %q, %v; want %q", in, got, err, want)
+		}
+	}
+	if _, err := ParseProtocol("braille"); err == nil {
+		t.Fatalf("expected error")
+	}
+}
+
+func TestRenderBlocks(t *testing.T) {
+	t.Parallel()
+
+	var out bytes.Buffer
+	if err := Render(&out, testPNG(t, 40, 40), Options{Protocol: Blocks, Columns: 8}); err != nil {
+		t.Fatalf("Render: %v", err)
+	}
+	lines := strings.Split(strings.TrimSuffix(out.String(), "\n"), "\n")
+	if len(lines) != 4 {
+		t.Fatalf("lines = %d; want 4", len(lines))
+	}
+	for _, line := range lines {
+		if n := strings.Count(line, "▀"); n != 8 {
+			t.Fatalf("cells = %d; want 8: %q", n, line)
+		}
+		if !strings.HasPrefix(line, "\x1b[38;2;") || !strings.HasSuffix(line, "\x1b[0m") {
+			t.Fatalf("line = %q", line)
+		}
+	}
+}
+
+func TestRenderKittyChunks(t *testing.T) {
+	t.Parallel()
+
+	// Noise does not compress, so the payload spans several chunks.
+	img := image.NewGray(image.Rect(0, 0, 64, 64))
+	seed := uint32(1)
+	for i := range img.Pix {
+		seed = seed*1664525 + 1013904223
+		img.Pix[i] = uint8(seed >> 24)
+	}
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		t.Fatalf("encode: %v", err)
+	}
+	data := buf.Bytes()
+
+	var out bytes.Buffer
+	if err := Render(&out, data, Options{Protocol: Kitty, Columns: 20}); err != nil {
+		t.Fatalf("Render: %v", err)
+	}
+	got := out.String()
+	if !strings.HasPrefix(got, "\x1b_Ga=T,f=100,c=20,m=1;") {
+		t.Fatalf("prefix = %q", got[:min(40, len(got))])
+	}
+	chunks := strings.Count(got, "\x1b_G")
+	if chunks < 2 {
+		t.Fatalf("chunks = %d; want several", chunks)
+	}
+	if !strings.Contains(got, "\x1b_Gm=0;") {
+		t.Fatalf("missing final chunk")
+	}
+	for _, part := range strings.Split(got, "\x1b\\") {
+		if _, payload, ok := strings.Cut(part, ";"); ok && len(payload) > kittyChunk {
+			t.Fatalf("chunk too large: %d", len(payload))
+		}
+	}
+}
+
+func TestRenderITermAndSixel(t *testing.T) {
+	t.Parallel()
+
+	data := testPNG(t, 16, 16)
+	var out bytes.Buffer
+	if err := Render(&out, data, Options{Protocol: ITerm, Columns: 10}); err != nil {
+		t.Fatalf("Render iterm: %v", err)
+	}
+	if want := "\x1b]1337;File=inline=1;size="; !strings.HasPrefix(out.String(), want) || !strings.HasSuffix(out.String(), "\a\n") {
+		t.Fatalf("iterm = %q", out.String()[:min(60, out.Len())])
+	}
+
+	out.Reset()
+	if err := Render(&out, data, Options{Protocol: Sixel, Columns: 2}); err != nil {
+		t.Fatalf("Render sixel: %v", err)
+	}
+	got := out.String()
+	if !strings.HasPrefix(got, "\x1bPq\"1;1;20;20#") || !strings.HasSuffix(got, "-\x1b\\\n") {
+		t.Fatalf("sixel = %q", got)
+	}
+	if bands := strings.Count(got, "-"); bands != 4 {
+		t.Fatalf("bands = %d; want 4", bands)
+	}
+
+	if err := Render(&out, []byte("not an image"), Options{Protocol: Blocks}); err == nil {
+		t.Fatalf("expected decode error")
+	}
+}